@@ -29,6 +29,7 @@ import (
 	gcred "github.com/annetutil/gnetcli/pkg/credentials"
 	"github.com/annetutil/gnetcli/pkg/server"
 	pb "github.com/annetutil/gnetcli/pkg/server/proto"
+	"github.com/annetutil/gnetcli/pkg/streamer"
 )
 
 type ExecErrorType string
@@ -192,11 +193,15 @@ func main() {
 	if cfg.DefaultCmdTimeout > 0 {
 		serverOpts = append(serverOpts, server.WithDefaultCmdTimeout(cfg.DefaultCmdTimeout))
 	}
+	if cfg.ConnPoolMaxIdle > 0 {
+		serverOpts = append(serverOpts, server.WithConnPool(streamer.WithPoolMaxIdle(cfg.ConnPoolMaxIdle)))
+	}
 	devAuthApp := server.NewAuthApp(cfg.DevAuth, logger)
 	s, err := server.New(devAuthApp, cfg.DevConf, serverOpts...)
 	if err != nil {
 		logger.Panic("failed to load external device map. Check your config!", zap.Error(err))
 	}
+	defer s.Close()
 	pb.RegisterGnetcliServer(grpcServer, s)
 	reflection.Register(grpcServer)
 	ctx := context.Background()
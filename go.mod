@@ -11,6 +11,8 @@ require (
 	github.com/pkg/errors v0.8.1
 	github.com/pkg/sftp v1.13.6
 	github.com/stretchr/testify v1.8.4
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
 	go.uber.org/multierr v1.11.0
 	go.uber.org/zap v1.26.0
 	golang.org/x/crypto v0.14.0
@@ -418,6 +418,15 @@ type Config struct {
 	// considering this callback. If normal verification is disabled by
 	// setting InsecureSkipVerify then this callback will be considered but
 	// the verifiedChains argument will always be nil.
+	//
+	// Client calls it only when server sends certificate, so it isn't
+	// called with anonymous suites like DH_anon and PSK. Server calls it
+	// only if ClientAuth is RequestClientCert or higher; with
+	// RequestClientCert and VerifyClientCertIfGiven client may send no
+	// certificate, then rawCerts is empty. verifiedChains is set on server
+	// only with VerifyClientCertIfGiven and RequireAndVerifyClientCert.
+	// See PinPublicKeySHA256 for pinning which works without chain
+	// verification.
 	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
 
 	// PSK Server Function to provide a hint to the client about which identity
@@ -940,6 +949,11 @@ var (
 	varDefaultCipherSuites []uint16
 )
 
+// DefaultCipherSuites returns suites used when Config.CipherSuites is nil.
+func DefaultCipherSuites() []uint16 {
+	return append([]uint16(nil), defaultCipherSuites()...)
+}
+
 func defaultCipherSuites() []uint16 {
 	once.Do(initDefaultCipherSuites)
 	return varDefaultCipherSuites
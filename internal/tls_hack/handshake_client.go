@@ -341,20 +341,14 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 				}
 				c.verifiedChains, err = certs[0].Verify(opts)
 				if err != nil {
-					err := c.sendAlert(alertBadCertificate)
-					if err != nil {
-						return err
-					}
+					_ = c.sendAlert(alertBadCertificate)
 					return err
 				}
 			}
 
 			if c.config.VerifyPeerCertificate != nil {
 				if err := c.config.VerifyPeerCertificate(certMsg.certificates, c.verifiedChains); err != nil {
-					err := c.sendAlert(alertBadCertificate)
-					if err != nil {
-						return err
-					}
+					_ = c.sendAlert(alertBadCertificate)
 					return err
 				}
 			}
@@ -873,10 +873,7 @@ func (hs *serverHandshakeState) processCertsFromClient(certificates [][]byte) (c
 
 	if c.config.VerifyPeerCertificate != nil {
 		if err := c.config.VerifyPeerCertificate(certificates, c.verifiedChains); err != nil {
-			err := c.sendAlert(alertBadCertificate)
-			if err != nil {
-				return nil, err
-			}
+			_ = c.sendAlert(alertBadCertificate)
 			return nil, err
 		}
 	}
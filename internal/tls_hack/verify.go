@@ -0,0 +1,42 @@
+package tlshack
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrNoPeerCertificate is returned by PinPublicKeySHA256 when peer didn't send certificate,
+// e.g. with anonymous suites like TLS_DH_anon_WITH_AES_256_GCM_SHA384 or client without certificate.
+var ErrNoPeerCertificate = errors.New("tls: peer certificate is required for pinning")
+
+// PublicKeySHA256 returns SHA256 of DER encoded SubjectPublicKeyInfo of cert.
+func PublicKeySHA256(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return sum[:]
+}
+
+// PinPublicKeySHA256 returns VerifyPeerCertificate callback which accepts peer
+// only if SHA256 of its leaf certificate public key is one of pins.
+// It doesn't depend on chain verification, so it can be used along with InsecureSkipVerify for self-signed certificates.
+func PinPublicKeySHA256(pins ...[]byte) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrNoPeerCertificate
+		}
+		leaf, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return fmt.Errorf("tls: failed to parse peer certificate: %w", err)
+		}
+		sum := PublicKeySHA256(leaf)
+		for _, pin := range pins {
+			if bytes.Equal(sum, pin) {
+				return nil
+			}
+		}
+		return fmt.Errorf("tls: public key sha256 %s is not pinned", hex.EncodeToString(sum))
+	}
+}
@@ -0,0 +1,87 @@
+package tlshack
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func makeTestCertificate(t *testing.T) (Certificate, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Certificate{Certificate: [][]byte{der}, PrivateKey: key}, cert
+}
+
+func TestPinPublicKeySHA256(t *testing.T) {
+	serverCert, leaf := makeTestCertificate(t)
+	_, other := makeTestCertificate(t)
+	cases := []struct {
+		name string
+		pin  []byte
+		err  bool
+	}{
+		{name: "pinned", pin: PublicKeySHA256(leaf)},
+		{name: "not pinned", pin: PublicKeySHA256(other), err: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+			server := Server(serverConn, &Config{
+				Certificates:     []Certificate{serverCert},
+				CurvePreferences: []CurveID{CurveP256},
+			})
+			go func() {
+				_ = server.Handshake()
+				_ = server.Close()
+			}()
+			client := Client(clientConn, &Config{
+				InsecureSkipVerify:    true,
+				VerifyPeerCertificate: PinPublicKeySHA256(tc.pin),
+				CurvePreferences:      []CurveID{CurveP256},
+			})
+			err := client.Handshake()
+			if tc.err {
+				if err == nil || !strings.Contains(err.Error(), "is not pinned") {
+					t.Fatalf("expected pinning error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPinPublicKeySHA256NoCertificate(t *testing.T) {
+	err := PinPublicKeySHA256([]byte{1})(nil, nil)
+	if !errors.Is(err, ErrNoPeerCertificate) {
+		t.Fatalf("expected ErrNoPeerCertificate, got %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/annetutil/gnetcli/pkg/expr"
@@ -19,11 +20,62 @@ const (
 
 var ErrNotFoundAnswer = errors.New("not found answer")
 
+// BackpressureMode describes what to do when output callback is slower than device.
+type BackpressureMode int
+
+const (
+	// BackpressureBlock blocks reading until callback catches up.
+	// Output is never lost but slow callback stalls prompt detection and may trigger read timeouts.
+	BackpressureBlock BackpressureMode = iota
+	// BackpressureDropOldest drops the oldest queued chunk. Reading is never stalled but callback may miss data.
+	BackpressureDropOldest
+	// BackpressureError fails command when buffer is full.
+	BackpressureError
+)
+
+const defaultStreamBufferSize = 100
+
 type Res struct {
-	output []byte
-	error  []byte
-	status int
-	extra  map[string]interface{}
+	output    []byte
+	error     []byte
+	status    int
+	extra     map[string]interface{}
+	truncated bool
+	checksum  []byte
+	warnings  [][]byte
+	prompt    []byte
+	groups    map[string][]byte
+}
+
+type ResOption func(*Res)
+
+// ResWithTruncated marks result as possibly incomplete
+func ResWithTruncated() ResOption {
+	return func(h *Res) {
+		h.truncated = true
+	}
+}
+
+// ResWithChecksum sets checksum of output
+func ResWithChecksum(checksum []byte) ResOption {
+	return func(h *Res) {
+		h.checksum = checksum
+	}
+}
+
+// ResWithWarnings sets warning lines found in output
+func ResWithWarnings(warnings [][]byte) ResOption {
+	return func(h *Res) {
+		h.warnings = warnings
+	}
+}
+
+// ResWithPrompt sets prompt which terminated command and named groups captured by prompt expression
+func ResWithPrompt(prompt []byte, groups map[string][]byte) ResOption {
+	return func(h *Res) {
+		h.prompt = prompt
+		h.groups = groups
+	}
 }
 
 func (m *Res) GetExtra(key string) (interface{}, bool) {
@@ -43,6 +95,32 @@ func (m *Res) Status() int {
 	return m.status
 }
 
+// Truncated returns true if command was terminated not by prompt or exit (e.g. by timeout or size limit),
+// so output may be incomplete
+func (m *Res) Truncated() bool {
+	return m.truncated
+}
+
+// Checksum returns SHA-256 of cleaned output if it was requested with WithOutputChecksum
+func (m *Res) Checksum() []byte {
+	return m.checksum
+}
+
+// Warnings returns lines of output classified as warnings by device profile
+func (m *Res) Warnings() [][]byte {
+	return m.warnings
+}
+
+// Prompt returns prompt which terminated command, nil if command was not terminated by prompt
+func (m *Res) Prompt() []byte {
+	return m.prompt
+}
+
+// PromptGroups returns named capture groups of prompt expression, e.g. hostname or config context
+func (m *Res) PromptGroups() map[string][]byte {
+	return m.groups
+}
+
 func (m *Res) SetExtra(key string, value interface{}) {
 	if m.extra == nil {
 		m.extra = map[string]interface{}{}
@@ -54,13 +132,22 @@ func NewCmdRes(output []byte) CmdRes {
 	return NewCmdResFull(output, nil, 0, nil)
 }
 
-func NewCmdResFull(output, err []byte, status int, extra map[string]interface{}) CmdRes {
-	return &Res{
-		output: output,
-		error:  err,
-		status: status,
-		extra:  extra,
+func NewCmdResFull(output, err []byte, status int, extra map[string]interface{}, opts ...ResOption) CmdRes {
+	res := &Res{
+		output:    output,
+		error:     err,
+		status:    status,
+		extra:     extra,
+		truncated: false,
+		checksum:  nil,
+		warnings:  nil,
+		prompt:    nil,
+		groups:    nil,
 	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
 }
 
 // CmdRes is an interface for command result.
@@ -70,6 +157,11 @@ type CmdRes interface {
 	Status() int
 	SetExtra(string, interface{})
 	GetExtra(string) (interface{}, bool)
+	Truncated() bool
+	Checksum() []byte
+	Warnings() [][]byte
+	Prompt() []byte
+	PromptGroups() map[string][]byte
 }
 
 // Cmd is an interface for command.
@@ -91,25 +183,87 @@ type Cmd interface {
 	ErrorHandler(error) error
 	// GetAgentForward returns whether SSH agent should be forwarded during execution.
 	GetAgentForward() bool
+	// GetOutputChecksum returns whether checksum of output should be computed.
+	GetOutputChecksum() bool
+	// GetOutputCallback returns callback for output chunks as they are read from device.
+	GetOutputCallback() func([]byte)
+	// GetStreamBackpressure returns behavior and buffer size (in chunks) for slow output callback.
+	GetStreamBackpressure() (BackpressureMode, int)
+	// GetAcceptExitCodes returns non-zero exit codes treated as success in exec mode.
+	GetAcceptExitCodes() []int
+	// IsIdempotent returns whether command can be safely repeated after reconnect.
+	IsIdempotent() bool
+	// GetPagers returns command specific pagination prompts.
+	GetPagers() []Pager
+	// GetKeystrokeDelay returns delay between written bytes, zero means that input is written at once.
+	GetKeystrokeDelay() time.Duration
+	// GetDialog returns ordered questions and answers.
+	GetDialog() []QA
+	// GetErrorExprs returns expressions of device errors, ok is false if device defaults must be used.
+	GetErrorExprs() (exprs []expr.Expr, ok bool)
+	// GetExpectedPrompt returns prompt which terminates command instead of session prompt, nil if prompt is not changed.
+	GetExpectedPrompt() expr.Expr
+	// GetPromptTimeout returns maximum time of waiting for prompt after command is written, zero means no limit.
+	GetPromptTimeout() time.Duration
+	// GetFirstByteTimeout returns maximum time of waiting for output after command echo, zero means read timeout.
+	GetFirstByteTimeout() time.Duration
+	// GetIdleTimeout returns maximum time between chunks of output after the first one, zero means read timeout.
+	GetIdleTimeout() time.Duration
+	// GetNoWaitPrompt returns time of reading output after command is written, ok is false if prompt must be waited.
+	GetNoWaitPrompt() (grace time.Duration, ok bool)
+	// GetMaxOutputBytes returns maximum size of output, zero means no limit.
+	GetMaxOutputBytes() int
+	// GetVerify returns command which reads back applied config and expression expected in its output,
+	// expect is nil if command is not verified.
+	GetVerify() (readback []byte, expect expr.Expr)
 }
 
 // CmdImpl implements Cmd interface.
 type CmdImpl struct {
-	command         []byte
-	readTimeout     time.Duration
-	cmdTimeout      time.Duration
-	forward         bool
-	questionAnswers []Answer
-	exprCallbacks   []ExprCallback
-	errorHandler    func(error) error
-}
-
+	command          []byte
+	readTimeout      time.Duration
+	cmdTimeout       time.Duration
+	forward          bool
+	questionAnswers  []Answer
+	exprCallbacks    []ExprCallback
+	errorHandler     func(error) error
+	outputChecksum   bool
+	outputCallback   func([]byte)
+	streamMode       BackpressureMode
+	streamBuffer     int
+	acceptExitCodes  []int
+	idempotent       bool
+	pagers           []Pager
+	keystrokeDelay   time.Duration
+	dialog           []QA
+	errorExprs       []expr.Expr // nil means device defaults
+	expectedPrompt   expr.Expr
+	promptTimeout    time.Duration
+	firstByteTimeout time.Duration
+	idleTimeout      time.Duration
+	noWaitPrompt     bool
+	noWaitGrace      time.Duration
+	maxOutputBytes   int
+	verifyCmd        []byte
+	verifyExpr       expr.Expr
+}
+
+// GetQuestionExprs returns single expression which matches questions of all answers
+// and resolves ambiguity as described in WithAnswer. Its match holds reply of chosen answer in AnswerGroup.
 func (m CmdImpl) GetQuestionExprs() []expr.Expr {
-	exprs := []expr.Expr{}
+	res := answersExpr{}
 	for _, qa := range m.questionAnswers {
-		exprs = append(exprs, qa.GetExpr())
+		ex := qa.GetExpr()
+		if ex == nil {
+			continue
+		}
+		res.answers = append(res.answers, qa)
+		res.exprs = append(res.exprs, ex)
+	}
+	if len(res.exprs) == 0 {
+		return []expr.Expr{}
 	}
-	return exprs
+	return []expr.Expr{res}
 }
 
 func (m CmdImpl) Value() []byte {
@@ -132,6 +286,70 @@ func (m CmdImpl) GetAgentForward() bool {
 	return m.forward
 }
 
+func (m CmdImpl) GetOutputChecksum() bool {
+	return m.outputChecksum
+}
+
+func (m CmdImpl) GetOutputCallback() func([]byte) {
+	return m.outputCallback
+}
+
+func (m CmdImpl) GetStreamBackpressure() (BackpressureMode, int) {
+	return m.streamMode, m.streamBuffer
+}
+
+func (m CmdImpl) GetAcceptExitCodes() []int {
+	return m.acceptExitCodes
+}
+
+func (m CmdImpl) IsIdempotent() bool {
+	return m.idempotent
+}
+
+func (m CmdImpl) GetPagers() []Pager {
+	return m.pagers
+}
+
+func (m CmdImpl) GetKeystrokeDelay() time.Duration {
+	return m.keystrokeDelay
+}
+
+func (m CmdImpl) GetDialog() []QA {
+	return m.dialog
+}
+
+func (m CmdImpl) GetErrorExprs() ([]expr.Expr, bool) {
+	return m.errorExprs, m.errorExprs != nil
+}
+
+func (m CmdImpl) GetExpectedPrompt() expr.Expr {
+	return m.expectedPrompt
+}
+
+func (m CmdImpl) GetPromptTimeout() time.Duration {
+	return m.promptTimeout
+}
+
+func (m CmdImpl) GetFirstByteTimeout() time.Duration {
+	return m.firstByteTimeout
+}
+
+func (m CmdImpl) GetIdleTimeout() time.Duration {
+	return m.idleTimeout
+}
+
+func (m CmdImpl) GetNoWaitPrompt() (time.Duration, bool) {
+	return m.noWaitGrace, m.noWaitPrompt
+}
+
+func (m CmdImpl) GetMaxOutputBytes() int {
+	return m.maxOutputBytes
+}
+
+func (m CmdImpl) GetVerify() ([]byte, expr.Expr) {
+	return m.verifyCmd, m.verifyExpr
+}
+
 func (m CmdImpl) GetExprCallback() ([]string, map[string]string) {
 	var res []string
 	exprToCB := map[string]string{}
@@ -142,21 +360,24 @@ func (m CmdImpl) GetExprCallback() ([]string, map[string]string) {
 	return res, exprToCB
 }
 
+// QuestionHandler returns answer to question, if several answers match it one is chosen as described in WithAnswer.
 func (m CmdImpl) QuestionHandler(question []byte) ([]byte, error) {
-	for _, cmdAnswer := range m.questionAnswers {
-		ans, ok, err := cmdAnswer.Match(question)
+	var best *Answer
+	bestLen := 0
+	for i := range m.questionAnswers {
+		matchLen, ok, err := m.questionAnswers[i].match(question)
 		if err != nil {
-			return nil, err
-		}
-		if !ok {
-			continue
+			return nil, fmt.Errorf("regexp error %w", err)
 		}
-		if !cmdAnswer.notSendNL {
-			ans = append(ans, []byte("\n")...)
+		if ok && (best == nil || m.questionAnswers[i].beats(matchLen, *best, bestLen)) {
+			best = &m.questionAnswers[i]
+			bestLen = matchLen
 		}
-		return ans, nil
 	}
-	return nil, ErrNotFoundAnswer
+	if best == nil {
+		return nil, ErrNotFoundAnswer
+	}
+	return best.Reply(), nil
 }
 
 type CmdOption func(*CmdImpl)
@@ -172,6 +393,10 @@ func NewCmd(command string, opts ...CmdOption) Cmd {
 		errorHandler: func(err error) error {
 			return err
 		},
+		outputChecksum:  false,
+		streamMode:      BackpressureBlock,
+		streamBuffer:    defaultStreamBufferSize,
+		acceptExitCodes: nil,
 	}
 	for _, opt := range opts {
 		opt(&cmd)
@@ -187,6 +412,9 @@ func NewCmdList(commands []string, opts ...CmdOption) []Cmd {
 	return cmdList
 }
 
+// WithReadTimeout sets maximum time without data from device during command execution.
+// Timer is reset on every received chunk, on expiration command fails with error matching streamer.ErrReadTimeout.
+// Context deadline set by WithCmdTimeout wins if it is shorter.
 func WithReadTimeout(timeout time.Duration) CmdOption {
 	return func(h *CmdImpl) {
 		h.readTimeout = timeout
@@ -205,6 +433,18 @@ func WithAnswers(answers ...Answer) CmdOption {
 	}
 }
 
+// WithAnswer adds answer with priority to question in NewAnswer format.
+// If several answers match the same output, the answer with the highest priority wins,
+// ties are resolved by the longest matched text and then by order of addition.
+// Answers added without priority have priority 0.
+func WithAnswer(question, answer string, priority int) CmdOption {
+	qa := newAnswer(question, answer, false)
+	qa.priority = priority
+	return func(h *CmdImpl) {
+		h.questionAnswers = append(h.questionAnswers, qa)
+	}
+}
+
 func WithErrorIgnore() CmdOption {
 	return func(h *CmdImpl) {
 		h.errorHandler = func(err error) error {
@@ -225,31 +465,302 @@ func WithForwarding(forward bool) CmdOption {
 	}
 }
 
+// WithOutputChecksum enables computing of SHA-256 checksum of cleaned output, see CmdRes.Checksum
+func WithOutputChecksum() CmdOption {
+	return func(h *CmdImpl) {
+		h.outputChecksum = true
+	}
+}
+
+// WithOutputCallback sets callback which receives output chunks as they are read from device,
+// before prompt matching, terminal parsing and escape stripping. Callback is called from separate goroutine,
+// so it doesn't block reading, see WithStreamBackpressure for behavior when it is slower than device.
+func WithOutputCallback(cb func([]byte)) CmdOption {
+	return func(h *CmdImpl) {
+		h.outputCallback = cb
+	}
+}
+
+// WithStreamBackpressure sets behavior for output callback which is slower than device.
+// bufferSize is a number of chunks queued for callback. See BackpressureMode for trade-offs.
+func WithStreamBackpressure(mode BackpressureMode, bufferSize int) CmdOption {
+	return func(h *CmdImpl) {
+		h.streamMode = mode
+		h.streamBuffer = bufferSize
+	}
+}
+
+// WithAcceptExitCodes sets non-zero exit codes treated as success in exec mode, by default only zero is success
+// and other codes cause an error. Actual status is available in CmdRes.Status.
+func WithAcceptExitCodes(codes []int) CmdOption {
+	return func(h *CmdImpl) {
+		h.acceptExitCodes = append([]int{0}, codes...)
+	}
+}
+
+// Idempotent marks command as safe to repeat, so it may be replayed after connection is reestablished.
+func Idempotent() CmdOption {
+	return func(h *CmdImpl) {
+		h.idempotent = true
+	}
+}
+
+// WithKeystrokeDelay makes command and answers to be written byte by byte with delay,
+// for terminal servers which drop characters of fast input.
+func WithKeystrokeDelay(delay time.Duration) CmdOption {
+	return func(h *CmdImpl) {
+		h.keystrokeDelay = delay
+	}
+}
+
+// WithErrorExprs overrides device error expressions (see device.ErrorExprsProvider) for the command.
+func WithErrorExprs(exprs ...expr.Expr) CmdOption {
+	return func(h *CmdImpl) {
+		h.errorExprs = append([]expr.Expr{}, exprs...)
+	}
+}
+
+// WithoutErrorExprs disables device error expressions for the command.
+func WithoutErrorExprs() CmdOption {
+	return func(h *CmdImpl) {
+		h.errorExprs = []expr.Expr{}
+	}
+}
+
+// WithExpectedPrompt sets prompt returned by command which changes it, like switching context or VDC.
+// Once the prompt is matched, it replaces session prompt for subsequent commands.
+func WithExpectedPrompt(prompt expr.Expr) CmdOption {
+	return func(h *CmdImpl) {
+		h.expectedPrompt = prompt
+	}
+}
+
+// WithPromptTimeout limits waiting for prompt after command is written, even if device keeps sending output.
+// On expiration and on read timeout device returns error matching device.ErrPromptTimeout with output read so far.
+func WithPromptTimeout(timeout time.Duration) CmdOption {
+	return func(h *CmdImpl) {
+		h.promptTimeout = timeout
+	}
+}
+
+// WithFirstByteTimeout limits waiting for the first output of command after its echo,
+// so command which takes long to start can have it longer than timeout between chunks of output.
+// Expiration is handled like read timeout.
+func WithFirstByteTimeout(timeout time.Duration) CmdOption {
+	return func(h *CmdImpl) {
+		h.firstByteTimeout = timeout
+	}
+}
+
+// WithIdleTimeout sets maximum time without data from device once command started to output,
+// it overrides WithReadTimeout for this part of execution.
+func WithIdleTimeout(timeout time.Duration) CmdOption {
+	return func(h *CmdImpl) {
+		h.idleTimeout = timeout
+	}
+}
+
+// NoWaitPrompt returns command result right after command is written, for commands after which device
+// never returns prompt, like reload. Even echo is not read, see WithNoWaitGrace to read output.
+func NoWaitPrompt() CmdOption {
+	return func(h *CmdImpl) {
+		h.noWaitPrompt = true
+	}
+}
+
+// WithNoWaitGrace is NoWaitPrompt which reads output during grace, so confirmation questions can be answered.
+// Result contains output read so far, connection loss or prompt end it before grace expiration.
+func WithNoWaitGrace(grace time.Duration) CmdOption {
+	return func(h *CmdImpl) {
+		h.noWaitPrompt = true
+		h.noWaitGrace = grace
+	}
+}
+
+// WithMaxOutputBytes fails command with error matching device.ErrOutputTooLarge if its output exceeds n bytes.
+// Error contains output truncated to n bytes, the rest of output is read till prompt and discarded.
+// Streaming execution stops at the limit and leaves the device unusable.
+func WithMaxOutputBytes(n int) CmdOption {
+	return func(h *CmdImpl) {
+		h.maxOutputBytes = n
+	}
+}
+
+// WithVerify runs readbackCmd after successful command and checks that its output matches expect,
+// e.g. "show running-config | include hostname" and `hostname sw1`. Otherwise command fails with
+// error matching device.ErrVerifyFailed. It catches config lines which are rejected without error message.
+func WithVerify(readbackCmd string, expect expr.Expr) CmdOption {
+	return func(h *CmdImpl) {
+		h.verifyCmd = []byte(readbackCmd)
+		h.verifyExpr = expect
+	}
+}
+
+// QA is a step of dialog, Answer is written as is, so it must contain newline if device needs it.
+type QA struct {
+	Expr   expr.Expr
+	Answer []byte
+}
+
+// WithDialog sets ordered dialog, only the next step is matched against output,
+// so answer can't be sent to another step question. Command fails if prompt is returned before dialog is finished
+// or isn't returned after.
+func WithDialog(steps []QA) CmdOption {
+	return func(h *CmdImpl) {
+		h.dialog = steps
+	}
+}
+
+// WithPager adds pagination prompt, response is sent when it is matched in the middle of output.
+// Matched pager is not included in output.
+func WithPager(pagerExpr expr.Expr, response []byte) CmdOption {
+	return func(h *CmdImpl) {
+		h.pagers = append(h.pagers, NewPager(pagerExpr, response))
+	}
+}
+
+// Pager describes pagination prompt and keys requesting the next page.
+type Pager struct {
+	expr     expr.Expr
+	response []byte
+}
+
+func NewPager(pagerExpr expr.Expr, response []byte) Pager {
+	return Pager{expr: pagerExpr, response: response}
+}
+
+func (m Pager) GetExpr() expr.Expr {
+	return m.expr
+}
+
+func (m Pager) GetResponse() []byte {
+	return m.response
+}
+
 type Answer struct {
 	question  string
 	answer    string
 	notSendNL bool
+	priority  int
+	secret    bool
+	re        *regexp.Regexp // compiled question in /regexp/ format
+	reErr     error
+}
+
+func newAnswer(question, answer string, notSendNL bool) Answer {
+	res := Answer{question: question, answer: answer, notSendNL: notSendNL}
+	if isRegexpQuestion(question) {
+		res.re, res.reErr = regexp.Compile(question[1 : len(question)-1])
+	}
+	return res
+}
+
+func isRegexpQuestion(question string) bool {
+	return len(question) > 1 && question[0] == '/' && question[len(question)-1] == '/'
+}
+
+// Reply returns data written to device in answer.
+func (m Answer) Reply() []byte {
+	ans := []byte(m.answer)
+	if !m.notSendNL {
+		ans = append(ans, '\n')
+	}
+	return ans
 }
 
 func (m Answer) Match(question []byte) ([]byte, bool, error) {
-	if len(m.question) == 0 {
+	_, ok, err := m.match(question)
+	if err != nil {
+		return nil, false, fmt.Errorf("regexp error %w", err)
+	}
+	if !ok {
 		return nil, false, nil
 	}
-	if m.question[0] == '/' && m.question[len(m.question)-1] == '/' {
-		match, err := regexp.Match(m.question[1:len(m.question)-1], question)
-		if err != nil {
-			return nil, false, fmt.Errorf("regexp error %w", err)
+	return []byte(m.answer), true, nil
+}
+
+// match returns length of matched part of question.
+func (m Answer) match(question []byte) (int, bool, error) {
+	if len(m.question) == 0 {
+		return 0, false, nil
+	}
+	if isRegexpQuestion(m.question) {
+		if m.reErr != nil {
+			return 0, false, m.reErr
 		}
-		if match {
-			return []byte(m.answer), true, nil
+		loc := m.re.FindIndex(question)
+		if loc == nil {
+			return 0, false, nil
 		}
-	} else {
-		match := bytes.Equal([]byte(m.question), question)
-		if match {
-			return []byte(m.answer), true, nil
+		return loc[1] - loc[0], true, nil
+	}
+	if bytes.Equal([]byte(m.question), question) {
+		return len(question), true, nil
+	}
+	return 0, false, nil
+}
+
+// beats reports whether m with match of matchLen wins over other with match of otherLen,
+// other is added before m, so it wins complete tie.
+func (m Answer) beats(matchLen int, other Answer, otherLen int) bool {
+	if m.priority != other.priority {
+		return m.priority > other.priority
+	}
+	return matchLen > otherLen
+}
+
+// AnswerGroup is group of question match which holds reply of chosen answer, see CmdImpl.GetQuestionExprs.
+const AnswerGroup = "_answer"
+
+// SecretAnswerGroup is group of question match which holds answer if it is secret, see NewSecretAnswer.
+const SecretAnswerGroup = "_secretAnswer"
+
+// answersExpr matches the best answer according to priorities.
+type answersExpr struct {
+	answers []Answer
+	exprs   []expr.Expr
+}
+
+func (m answersExpr) Match(data []byte) (*expr.MatchRes, bool) {
+	var res *expr.MatchRes
+	best := -1
+	for i, ex := range m.exprs {
+		mRes, ok := ex.Match(data)
+		if !ok {
+			continue
+		}
+		if best < 0 || m.answers[i].beats(mRes.End-mRes.Start, m.answers[best], res.End-res.Start) {
+			best = i
+			res = mRes
 		}
 	}
-	return nil, false, nil
+	if best < 0 {
+		return nil, false
+	}
+	groups := make(map[string][]byte, len(res.GroupDict)+2)
+	for k, v := range res.GroupDict {
+		groups[k] = v
+	}
+	answer := m.answers[best]
+	groups[AnswerGroup] = answer.Reply()
+	if answer.secret {
+		groups[SecretAnswerGroup] = []byte(answer.answer)
+	}
+	return &expr.MatchRes{
+		Start:      res.Start,
+		End:        res.End,
+		GroupDict:  groups,
+		PatternNo:  best,
+		Underlying: res,
+	}, true
+}
+
+func (m answersExpr) Repr() string {
+	resList := make([]string, 0, len(m.exprs))
+	for _, ex := range m.exprs {
+		resList = append(resList, ex.Repr())
+	}
+	return strings.Join(resList, ",")
 }
 
 func (m Answer) GetExpr() expr.Expr {
@@ -257,7 +768,7 @@ func (m Answer) GetExpr() expr.Expr {
 		return nil
 	}
 	var res expr.Expr
-	if m.question[0] == '/' && m.question[len(m.question)-1] == '/' {
+	if isRegexpQuestion(m.question) {
 		res = expr.NewSimpleExpr().FromPattern(m.question[1 : len(m.question)-1])
 	} else {
 		res = expr.NewSimpleExpr().FromPattern(regexp.QuoteMeta(m.question))
@@ -266,11 +777,18 @@ func (m Answer) GetExpr() expr.Expr {
 }
 
 func NewAnswer(question, answer string, notSendNL bool) Answer {
-	return Answer{question: question, answer: answer, notSendNL: notSendNL}
+	return newAnswer(question, answer, notSendNL)
 }
 
 func NewAnswerWithNL(question, answer string) Answer {
-	return Answer{question: question, answer: answer, notSendNL: false}
+	return newAnswer(question, answer, false)
+}
+
+// NewSecretAnswer makes answer with newline which is not logged and is redacted in transcript, e.g. password.
+func NewSecretAnswer(question, answer string) Answer {
+	res := newAnswer(question, answer, false)
+	res.secret = true
+	return res
 }
 
 func WithExprCallback(exprCallbacks ...ExprCallback) CmdOption {
@@ -0,0 +1,116 @@
+package credentials
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrCertNotUser     = errors.New("ssh certificate is not a user certificate")
+	ErrCertExpired     = errors.New("ssh certificate is expired")
+	ErrCertNotYetValid = errors.New("ssh certificate is not yet valid")
+	ErrCertKeyMismatch = errors.New("ssh certificate doesn't match private key")
+	ErrNotACertificate = errors.New("file doesn't contain ssh certificate")
+)
+
+// CertificateCredentials is implemented by credentials with SSH user certificates.
+type CertificateCredentials interface {
+	GetCertSigners() ([]ssh.Signer, error)
+}
+
+type certificateFiles struct {
+	certPath string
+	keyPath  string
+}
+
+// WithCertificate adds SSH user certificate signed by CA which device trusts.
+// Files are read on every GetCertSigners call, so renewed short-lived certificate is picked up on the next connect.
+// Encrypted private key is decrypted with passphrase of credentials.
+func WithCertificate(certPath, keyPath string) CredentialsOption {
+	return func(h *SimpleCredentials) {
+		h.certificates = append(h.certificates, certificateFiles{certPath: certPath, keyPath: keyPath})
+	}
+}
+
+func (m SimpleCredentials) GetCertSigners() ([]ssh.Signer, error) {
+	signers := make([]ssh.Signer, 0, len(m.certificates))
+	for _, files := range m.certificates {
+		signer, err := LoadCertSigner(files.certPath, files.keyPath, m.passphrase)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	return signers, nil
+}
+
+// GetCertSigners returns certificate signers if creds implements CertificateCredentials.
+func GetCertSigners(creds Credentials) ([]ssh.Signer, error) {
+	if certCreds, ok := creds.(CertificateCredentials); ok {
+		return certCreds.GetCertSigners()
+	}
+	return nil, nil
+}
+
+// LoadCertSigner reads SSH user certificate and its private key and makes signer for public key authentication.
+// Certificate must be valid at the moment of call.
+func LoadCertSigner(certPath, keyPath string, passphrase Secret) (ssh.Signer, error) {
+	certData, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", certPath, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", certPath, ErrNotACertificate)
+	}
+	err = ValidateUserCert(cert, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", certPath, err)
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	var signer ssh.Signer
+	if len(passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(keyData, []byte(passphrase))
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, fmt.Errorf("%s: %w: %w", keyPath, ErrKeyPassphrase, err)
+		}
+	} else {
+		signer, err = ssh.ParsePrivateKey(keyData)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", keyPath, err)
+	}
+	certSigner, err := ssh.NewCertSigner(cert, signer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", certPath, ErrCertKeyMismatch, err)
+	}
+	return certSigner, nil
+}
+
+// ValidateUserCert checks that cert is a user certificate which is valid at now.
+func ValidateUserCert(cert *ssh.Certificate, now time.Time) error {
+	if cert.CertType != ssh.UserCert {
+		return ErrCertNotUser
+	}
+	unixNow := now.Unix()
+	if after := int64(cert.ValidAfter); after < 0 || unixNow < after {
+		return fmt.Errorf("%w: valid after %s", ErrCertNotYetValid, time.Unix(after, 0).UTC().Format(time.RFC3339))
+	}
+	before := int64(cert.ValidBefore)
+	if cert.ValidBefore != ssh.CertTimeInfinity && (unixNow >= before || before < 0) {
+		return fmt.Errorf("%w: valid before %s", ErrCertExpired, time.Unix(before, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package credentials
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeUserCert writes private key and certificate of its public key signed by ca.
+func writeUserCert(t *testing.T, ca ssh.Signer, certType uint32, validAfter, validBefore time.Time) (string, string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		CertType:        certType,
+		KeyId:           "user",
+		ValidPrincipals: []string{"user"},
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	certPath := filepath.Join(dir, "id_ed25519-cert.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+	require.NoError(t, os.WriteFile(certPath, ssh.MarshalAuthorizedKey(cert), 0o600))
+	return certPath, keyPath
+}
+
+func makeCA(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ca, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return ca
+}
+
+func TestWithCertificate(t *testing.T) {
+	ca := makeCA(t)
+	now := time.Now()
+	cases := []struct {
+		name        string
+		certType    uint32
+		validAfter  time.Time
+		validBefore time.Time
+		err         error
+	}{
+		{name: "valid", certType: ssh.UserCert, validAfter: now.Add(-time.Minute), validBefore: now.Add(time.Hour)},
+		{name: "host cert", certType: ssh.HostCert, validAfter: now.Add(-time.Minute), validBefore: now.Add(time.Hour), err: ErrCertNotUser},
+		{name: "expired", certType: ssh.UserCert, validAfter: now.Add(-time.Hour), validBefore: now.Add(-time.Minute), err: ErrCertExpired},
+		{name: "not yet valid", certType: ssh.UserCert, validAfter: now.Add(time.Hour), validBefore: now.Add(2 * time.Hour), err: ErrCertNotYetValid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			certPath, keyPath := writeUserCert(t, ca, tc.certType, tc.validAfter, tc.validBefore)
+			creds := NewSimpleCredentials(WithCertificate(certPath, keyPath))
+			signers, err := GetCertSigners(creds)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, signers, 1)
+			cert, ok := signers[0].PublicKey().(*ssh.Certificate)
+			require.True(t, ok)
+			require.Equal(t, "user", cert.KeyId)
+		})
+	}
+}
+
+func TestWithCertificateKeyMismatch(t *testing.T) {
+	ca := makeCA(t)
+	certPath, _ := writeUserCert(t, ca, ssh.UserCert, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	_, otherKeyPath := writeUserCert(t, ca, ssh.UserCert, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	_, err := LoadCertSigner(certPath, otherKeyPath, "")
+	require.ErrorIs(t, err, ErrCertKeyMismatch)
+}
@@ -0,0 +1,47 @@
+package credentials
+
+import (
+	"context"
+)
+
+// ContextCredentials is implemented by credentials which resolution does I/O (Vault, agent, cert fetch)
+// and must be aborted on cancellation of connect context.
+type ContextCredentials interface {
+	Credentials
+	GetUsernameContext(ctx context.Context) (string, error)
+	GetPrivateKeysContext(ctx context.Context) ([][]byte, error)
+	GetPassphraseContext(ctx context.Context) (Secret, error)
+}
+
+// GetUsername returns username using context-aware method if creds supports it.
+func GetUsername(ctx context.Context, creds Credentials) (string, error) {
+	if ctxCreds, ok := creds.(ContextCredentials); ok {
+		return ctxCreds.GetUsernameContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return creds.GetUsername()
+}
+
+// GetPrivateKeys returns private keys using context-aware method if creds supports it.
+func GetPrivateKeys(ctx context.Context, creds Credentials) ([][]byte, error) {
+	if ctxCreds, ok := creds.(ContextCredentials); ok {
+		return ctxCreds.GetPrivateKeysContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return creds.GetPrivateKeys(), nil
+}
+
+// GetPassphrase returns passphrase using context-aware method if creds supports it.
+func GetPassphrase(ctx context.Context, creds Credentials) (Secret, error) {
+	if ctxCreds, ok := creds.(ContextCredentials); ok {
+		return ctxCreds.GetPassphraseContext(ctx)
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return creds.GetPassphrase(), nil
+}
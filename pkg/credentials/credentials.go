@@ -7,15 +7,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/fs"
 	"os"
 	"os/user"
 
-	"github.com/kevinburke/ssh_config"
-	"github.com/mitchellh/go-homedir"
 	"go.uber.org/zap"
 )
 
+// ErrKeyPassphrase is returned when private key can't be decrypted with given passphrase.
+var ErrKeyPassphrase = errors.New("wrong private key passphrase")
+
 type Credentials interface {
 	GetUsername() (string, error)
 	GetPasswords(ctx context.Context) []Secret
@@ -24,13 +24,20 @@ type Credentials interface {
 	GetAgentSocket() string
 }
 
+// EnableSecretCredentials is implemented by credentials which have password for privileged mode of device.
+type EnableSecretCredentials interface {
+	GetEnableSecret() Secret
+}
+
 type SimpleCredentials struct {
-	username    string
-	passwords   []Secret
-	privKeys    [][]byte
-	passphrase  Secret
-	agentSocket string
-	logger      *zap.Logger
+	username     string
+	passwords    []Secret
+	privKeys     [][]byte
+	passphrase   Secret
+	agentSocket  string
+	enableSecret Secret
+	certificates []certificateFiles
+	logger       *zap.Logger
 }
 
 type CredentialsOption func(*SimpleCredentials)
@@ -97,6 +104,13 @@ func WithSSHAgentSocket(agentSocket string) CredentialsOption {
 	}
 }
 
+// WithEnableSecret sets password for privileged mode, e.g. for enable command on Cisco-like devices.
+func WithEnableSecret(secret Secret) CredentialsOption {
+	return func(h *SimpleCredentials) {
+		h.enableSecret = secret
+	}
+}
+
 func (m SimpleCredentials) GetUsername() (string, error) {
 	if len(m.username) != 0 {
 		return m.username, nil
@@ -124,6 +138,18 @@ func (m SimpleCredentials) GetAgentSocket() string {
 	return m.agentSocket
 }
 
+func (m SimpleCredentials) GetEnableSecret() Secret {
+	return m.enableSecret
+}
+
+// GetEnableSecret returns enable secret if creds implements EnableSecretCredentials.
+func GetEnableSecret(creds Credentials) Secret {
+	if enableCreds, ok := creds.(EnableSecretCredentials); ok {
+		return enableCreds.GetEnableSecret()
+	}
+	return ""
+}
+
 // GetDefaultAgentSocket returns default ssh authentication agent socket (read from SSH_AUTH_SOCK env)
 func GetDefaultAgentSocket() string {
 	return os.Getenv("SSH_AUTH_SOCK")
@@ -158,65 +184,18 @@ func (m Secret) Value() string {
 
 // GetUsernameFromConfig extracts User keyword value for given host from default ssh config.
 func GetUsernameFromConfig(host string) string {
-	return ssh_config.Get(host, "User")
+	username, _ := usernameFromConfig(defaultSSHConfig{}, host)
+	return username
 }
 
 // GetAgentSocketFromConfig computes SSH authentication agent socket path using default ssh config's IdentityAgent and ForwardAgent keywords.
-// IdentityAgent value supports tilde syntax, but it doesn't support %d, %h, %l and %r.
+// IdentityAgent value supports tilde syntax and tokens %%, %d, %h, %n, %r and %u.
 func GetAgentSocketFromConfig(host string) (string, error) {
-	// TODO:
-	// IdentityAgent and IdentityFile accept the tokens %%, %d, %h, %l, %r, and %u.
-	// use ExpandTokens()
-	ia, err := ssh_config.GetStrict(host, "IdentityAgent")
-	if err != nil {
-		return "", err
-	}
-	if ia == "none" {
-		return "", nil
-	}
-	expandedIa, err := homedir.Expand(ia)
-	if err != nil {
-		return "", err
-	}
-	if expandedIa == "SSH_AUTH_SOCK" || len(expandedIa) == 0 {
-		return GetDefaultAgentSocket(), nil
-	}
-	if ssh_config.Get(host, "ForwardAgent") == "yes" {
-		return GetDefaultAgentSocket(), nil
-	}
-
-	return expandedIa, nil
+	return agentSocketFromConfig(defaultSSHConfig{}, host)
 }
 
 // GetPrivateKeysFromConfig tries to extract PrivateKeys from default config's IdentityFiles specified for provided host.
-// IdentityFile value supports tilde syntax, but it doesn't support %d, %u, %l, %h and %r.
+// IdentityFile value supports tilde syntax and tokens %%, %d, %h, %n, %r and %u. Files which don't exist are skipped as OpenSSH does.
 func GetPrivateKeysFromConfig(host string) ([][]byte, error) {
-	identityFiles := ssh_config.GetAll(host, "IdentityFile")
-	privKeys := make([][]byte, 0, len(identityFiles))
-	for _, v := range identityFiles {
-		// todo:
-		// IdentityAgent and IdentityFile accept the tokens %%, %d, %h, %l, %r, and %u.
-		expandedPath, err := homedir.Expand(v)
-		if err != nil {
-			return nil, fmt.Errorf("failed to expand path of identity file %s: %w", v, err)
-		}
-		content, err := os.ReadFile(expandedPath)
-		if err != nil && isSSHConfigMadeUpDefaultFileError(identityFiles, err) {
-			return nil, nil
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to read identity file %s: %w", v, err)
-		}
-		privKeys = append(privKeys, content)
-	}
-	return privKeys, nil
-}
-
-// isSSHConfigMadeUpDefaultFileError checks if given error occurred because
-// ssh_config lib made up a default value for IdentityFile due to it's absence in config
-func isSSHConfigMadeUpDefaultFileError(identityFiles []string, err error) bool {
-	if len(identityFiles) == 1 && identityFiles[0] == "~/.ssh/identity" && errors.Is(err, fs.ErrNotExist) {
-		return true
-	}
-	return false
+	return privateKeysFromConfig(defaultSSHConfig{}, host)
 }
@@ -0,0 +1,61 @@
+package credentials
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+)
+
+// Fingerprint returns hash of credentials content. Credentials with the same fingerprint
+// authenticate the same way, secrets can't be recovered from it.
+func Fingerprint(ctx context.Context, creds Credentials) (string, error) {
+	if creds == nil {
+		return "", nil
+	}
+	username, err := GetUsername(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	keys, err := GetPrivateKeys(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	passphrase, err := GetPassphrase(ctx, creds)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	writeField(h, []byte(username))
+	passwords := creds.GetPasswords(ctx)
+	writeLen(h, len(passwords))
+	for _, password := range passwords {
+		writeField(h, []byte(password.Value()))
+	}
+	writeLen(h, len(keys))
+	for _, key := range keys {
+		writeField(h, key)
+	}
+	writeField(h, []byte(passphrase.Value()))
+	writeField(h, []byte(creds.GetAgentSocket()))
+	certSigners, err := GetCertSigners(creds)
+	if err != nil {
+		return "", err
+	}
+	// written only if present to keep fingerprints of credentials without certificates
+	for _, signer := range certSigners {
+		writeField(h, signer.PublicKey().Marshal())
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeLen(h hash.Hash, n int) {
+	_ = binary.Write(h, binary.BigEndian, uint64(n))
+}
+
+// writeField writes length-prefixed data, so fields can't be shifted into each other
+func writeField(h hash.Hash, data []byte) {
+	writeLen(h, len(data))
+	_, _ = h.Write(data)
+}
@@ -0,0 +1,49 @@
+package credentials
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/annetutil/gnetcli/pkg/gerror"
+)
+
+// NewFromPasswords makes credentials with several passwords, they are tried in given order until the first success.
+func NewFromPasswords(passwords []string, opts ...CredentialsOption) *SimpleCredentials {
+	secrets := make([]Secret, 0, len(passwords))
+	for _, password := range passwords {
+		secrets = append(secrets, Secret(password))
+	}
+	return NewSimpleCredentials(append([]CredentialsOption{WithPasswords(secrets)}, opts...)...)
+}
+
+// PasswordsError is returned when all passwords were rejected.
+// Tried holds 1-based positions of tried passwords, secrets themselves are not included.
+type PasswordsError struct {
+	Tried []int
+}
+
+func (e *PasswordsError) Error() string {
+	tried := make([]string, 0, len(e.Tried))
+	for _, no := range e.Tried {
+		tried = append(tried, fmt.Sprintf("#%d", no))
+	}
+	return fmt.Sprintf("all passwords were rejected, tried %s", strings.Join(tried, ", "))
+}
+
+// Is makes PasswordsError match gerror.AuthException and gerror.ErrAuthFailed.
+func (e *PasswordsError) Is(target error) bool {
+	if target == gerror.ErrAuthFailed {
+		return true
+	}
+	_, ok := target.(*gerror.AuthException)
+	return ok
+}
+
+// NewPasswordsError makes PasswordsError for first count passwords.
+func NewPasswordsError(count int) *PasswordsError {
+	tried := make([]int, 0, count)
+	for i := 1; i <= count; i++ {
+		tried = append(tried, i)
+	}
+	return &PasswordsError{Tried: tried}
+}
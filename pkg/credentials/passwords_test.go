@@ -0,0 +1,20 @@
+package credentials
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFromPasswords(t *testing.T) {
+	creds := NewFromPasswords([]string{"old", "new"}, WithUsername("user"))
+	require.Equal(t, []Secret{"old", "new"}, creds.GetPasswords(context.Background()))
+	username, err := creds.GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "user", username)
+}
+
+func TestPasswordsError(t *testing.T) {
+	require.Equal(t, "all passwords were rejected, tried #1, #2", NewPasswordsError(2).Error())
+}
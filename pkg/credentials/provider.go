@@ -0,0 +1,133 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Provider fetches credentials for host at connect time, so secrets from external storage
+// are not kept in memory longer than needed.
+type Provider interface {
+	Get(ctx context.Context, host string) (Credentials, error)
+}
+
+// ProviderFunc adapts function to Provider.
+type ProviderFunc func(ctx context.Context, host string) (Credentials, error)
+
+func (f ProviderFunc) Get(ctx context.Context, host string) (Credentials, error) {
+	return f(ctx, host)
+}
+
+// StaticProvider returns the same credentials for every host.
+type StaticProvider struct {
+	creds Credentials
+}
+
+func NewStaticProvider(creds Credentials) *StaticProvider {
+	return &StaticProvider{creds: creds}
+}
+
+func (m *StaticProvider) Get(ctx context.Context, host string) (Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.creds, nil
+}
+
+// ProviderCredentials fetches credentials for host from provider on every call, so fetched secrets are not kept.
+// Errors of provider are returned by methods which have error result, other methods return empty values.
+type ProviderCredentials struct {
+	provider Provider
+	host     string
+}
+
+var (
+	_ ContextCredentials      = (*ProviderCredentials)(nil)
+	_ EnableSecretCredentials = (*ProviderCredentials)(nil)
+	_ CertificateCredentials  = (*ProviderCredentials)(nil)
+)
+
+func NewProviderCredentials(provider Provider, host string) *ProviderCredentials {
+	return &ProviderCredentials{provider: provider, host: host}
+}
+
+// Get fetches credentials from provider.
+func (m *ProviderCredentials) Get(ctx context.Context) (Credentials, error) {
+	creds, err := m.provider.Get(ctx, m.host)
+	if err != nil {
+		return nil, fmt.Errorf("credentials provider error: %w", err)
+	}
+	return creds, nil
+}
+
+func (m *ProviderCredentials) GetUsername() (string, error) {
+	return m.GetUsernameContext(context.Background())
+}
+
+func (m *ProviderCredentials) GetPasswords(ctx context.Context) []Secret {
+	creds, err := m.Get(ctx)
+	if err != nil {
+		return nil
+	}
+	return creds.GetPasswords(ctx)
+}
+
+func (m *ProviderCredentials) GetPrivateKeys() [][]byte {
+	keys, _ := m.GetPrivateKeysContext(context.Background())
+	return keys
+}
+
+func (m *ProviderCredentials) GetPassphrase() Secret {
+	passphrase, _ := m.GetPassphraseContext(context.Background())
+	return passphrase
+}
+
+func (m *ProviderCredentials) GetAgentSocket() string {
+	creds, err := m.Get(context.Background())
+	if err != nil {
+		return ""
+	}
+	return creds.GetAgentSocket()
+}
+
+func (m *ProviderCredentials) GetEnableSecret() Secret {
+	creds, err := m.Get(context.Background())
+	if err != nil {
+		return ""
+	}
+	return GetEnableSecret(creds)
+}
+
+func (m *ProviderCredentials) GetCertSigners() ([]ssh.Signer, error) {
+	creds, err := m.Get(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return GetCertSigners(creds)
+}
+
+func (m *ProviderCredentials) GetUsernameContext(ctx context.Context) (string, error) {
+	creds, err := m.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return GetUsername(ctx, creds)
+}
+
+func (m *ProviderCredentials) GetPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	creds, err := m.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return GetPrivateKeys(ctx, creds)
+}
+
+func (m *ProviderCredentials) GetPassphraseContext(ctx context.Context) (Secret, error) {
+	creds, err := m.Get(ctx)
+	if err != nil {
+		return "", err
+	}
+	return GetPassphrase(ctx, creds)
+}
@@ -0,0 +1,36 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderCredentials(t *testing.T) {
+	password := Secret("old")
+	providerErr := error(nil)
+	provider := ProviderFunc(func(ctx context.Context, host string) (Credentials, error) {
+		if providerErr != nil {
+			return nil, providerErr
+		}
+		return NewSimpleCredentials(WithUsername(host), WithPassword(password), WithEnableSecret("enable")), nil
+	})
+	creds := NewProviderCredentials(provider, "device1")
+	ctx := context.Background()
+	username, err := GetUsername(ctx, creds)
+	require.NoError(t, err)
+	require.Equal(t, "device1", username)
+	require.Equal(t, []Secret{"old"}, creds.GetPasswords(ctx))
+	require.Equal(t, Secret("enable"), GetEnableSecret(creds))
+
+	// every call fetches credentials again
+	password = "new"
+	require.Equal(t, []Secret{"new"}, creds.GetPasswords(ctx))
+
+	providerErr = errors.New("sealed")
+	_, err = creds.GetUsername()
+	require.ErrorIs(t, err, providerErr)
+	require.Empty(t, creds.GetPasswords(ctx))
+}
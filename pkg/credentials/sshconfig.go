@@ -0,0 +1,216 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/kevinburke/ssh_config"
+	"github.com/mitchellh/go-homedir"
+)
+
+// SSHConfig is parsed OpenSSH client config file. Match blocks are not supported.
+type SSHConfig struct {
+	config *ssh_config.Config
+}
+
+// LoadSSHConfig parses OpenSSH client config file. Empty path means ~/.ssh/config.
+func LoadSSHConfig(path string) (*SSHConfig, error) {
+	if len(path) == 0 {
+		path = "~/.ssh/config"
+	}
+	expandedPath, err := homedir.Expand(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand ssh config path %s: %w", path, err)
+	}
+	f, err := os.Open(expandedPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	config, err := ssh_config.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh config %s: %w", path, err)
+	}
+	return &SSHConfig{config: config}, nil
+}
+
+// Get returns value of keyword for host alias or empty string if it is not set.
+func (m *SSHConfig) Get(alias, key string) (val string, err error) {
+	defer recoverMatch(&err)
+	return m.config.Get(alias, key)
+}
+
+// GetAll returns all values of keyword for host alias, e.g. of IdentityFile.
+func (m *SSHConfig) GetAll(alias, key string) (val []string, err error) {
+	defer recoverMatch(&err)
+	return m.config.GetAll(alias, key)
+}
+
+// ssh_config panics on Match directive
+func recoverMatch(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("unsupported ssh config: %v", r)
+	}
+}
+
+// Credentials makes credentials for host alias from User, IdentityFile, IdentityAgent and ForwardAgent keywords
+// in the same way as GetUsernameFromConfig, GetPrivateKeysFromConfig and GetAgentSocketFromConfig do for default config.
+// opts are applied after values from config.
+func (m *SSHConfig) Credentials(alias string, opts ...CredentialsOption) (*SimpleCredentials, error) {
+	var configOpts []CredentialsOption
+	username, err := usernameFromConfig(m, alias)
+	if err != nil {
+		return nil, err
+	}
+	if len(username) > 0 {
+		configOpts = append(configOpts, WithUsername(username))
+	}
+	privKeys, err := privateKeysFromConfig(m, alias)
+	if err != nil {
+		return nil, err
+	}
+	if len(privKeys) > 0 {
+		configOpts = append(configOpts, WithPrivateKeys(privKeys))
+	}
+	agentSocket, err := agentSocketFromConfig(m, alias)
+	if err != nil {
+		return nil, err
+	}
+	if len(agentSocket) > 0 {
+		configOpts = append(configOpts, WithSSHAgentSocket(agentSocket))
+	}
+	return NewSimpleCredentials(append(configOpts, opts...)...), nil
+}
+
+// FromSSHConfig makes credentials for host alias from OpenSSH client config file, see SSHConfig.Credentials.
+// Empty path means ~/.ssh/config.
+func FromSSHConfig(path, alias string, opts ...CredentialsOption) (*SimpleCredentials, error) {
+	config, err := LoadSSHConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return config.Credentials(alias, opts...)
+}
+
+// sshConfigSource is OpenSSH client config, default one or SSHConfig.
+type sshConfigSource interface {
+	Get(alias, key string) (string, error)
+	GetAll(alias, key string) ([]string, error)
+}
+
+// defaultSSHConfig is user and system ssh config used by ssh_config package functions.
+type defaultSSHConfig struct{}
+
+func (defaultSSHConfig) Get(alias, key string) (string, error) {
+	return ssh_config.GetStrict(alias, key)
+}
+
+func (defaultSSHConfig) GetAll(alias, key string) ([]string, error) {
+	return ssh_config.GetAllStrict(alias, key)
+}
+
+func usernameFromConfig(config sshConfigSource, host string) (string, error) {
+	return config.Get(host, "User")
+}
+
+func agentSocketFromConfig(config sshConfigSource, host string) (string, error) {
+	ia, err := config.Get(host, "IdentityAgent")
+	if err != nil {
+		return "", err
+	}
+	if ia == "none" {
+		return "", nil
+	}
+	tokens, err := newSSHConfigTokens(config, host)
+	if err != nil {
+		return "", err
+	}
+	expandedIa, err := homedir.Expand(tokens.expand(ia))
+	if err != nil {
+		return "", err
+	}
+	if expandedIa == "SSH_AUTH_SOCK" || len(expandedIa) == 0 {
+		return GetDefaultAgentSocket(), nil
+	}
+	forwardAgent, err := config.Get(host, "ForwardAgent")
+	if err != nil {
+		return "", err
+	}
+	if forwardAgent == "yes" {
+		return GetDefaultAgentSocket(), nil
+	}
+	return expandedIa, nil
+}
+
+func privateKeysFromConfig(config sshConfigSource, host string) ([][]byte, error) {
+	identityFiles, err := config.GetAll(host, "IdentityFile")
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := newSSHConfigTokens(config, host)
+	if err != nil {
+		return nil, err
+	}
+	privKeys := make([][]byte, 0, len(identityFiles))
+	for _, v := range identityFiles {
+		expandedPath, err := homedir.Expand(tokens.expand(v))
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand path of identity file %s: %w", v, err)
+		}
+		content, err := os.ReadFile(expandedPath)
+		// also covers default ~/.ssh/identity which ssh_config returns when IdentityFile is not set
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity file %s: %w", v, err)
+		}
+		privKeys = append(privKeys, content)
+	}
+	return privKeys, nil
+}
+
+type sshConfigTokens struct {
+	alias string
+	host  string
+	user  string
+}
+
+func newSSHConfigTokens(config sshConfigSource, alias string) (sshConfigTokens, error) {
+	username, err := config.Get(alias, "User")
+	if err != nil {
+		return sshConfigTokens{}, err
+	}
+	hostname, err := config.Get(alias, "HostName")
+	if err != nil {
+		return sshConfigTokens{}, err
+	}
+	if len(hostname) == 0 {
+		hostname = alias
+	}
+	return sshConfigTokens{alias: alias, host: hostname, user: username}, nil
+}
+
+// expand replaces %%, %d, %h, %n, %r and %u tokens.
+func (m sshConfigTokens) expand(val string) string {
+	if !strings.Contains(val, "%") {
+		return val
+	}
+	remoteUser := m.user
+	if len(remoteUser) == 0 {
+		remoteUser = GetLogin()
+	}
+	home, _ := homedir.Dir()
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", home,
+		"%h", m.host,
+		"%n", m.alias,
+		"%r", remoteUser,
+		"%u", GetLogin(),
+	)
+	return replacer.Replace(val)
+}
@@ -0,0 +1,55 @@
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSSHConfig(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "/tmp/ssh-agent.sock")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sw1.key"), []byte("key"), 0o600))
+	config := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(config, []byte(`
+Host sw*
+  User admin
+  IdentityFile `+dir+`/%h.key
+  IdentityFile `+dir+`/missing.key
+  IdentityAgent `+dir+`/agent.sock
+
+Host fwd
+  IdentityAgent `+dir+`/agent.sock
+  ForwardAgent yes
+
+Host *
+  User nobody
+`), 0o600))
+
+	creds, err := FromSSHConfig(config, "sw1")
+	require.NoError(t, err)
+	username, err := creds.GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "admin", username)
+	require.Equal(t, [][]byte{[]byte("key")}, creds.GetPrivateKeys())
+	require.Equal(t, filepath.Join(dir, "agent.sock"), creds.GetAgentSocket())
+
+	creds, err = FromSSHConfig(config, "router1", WithPassword("secret"))
+	require.NoError(t, err)
+	username, err = creds.GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "nobody", username)
+	require.Empty(t, creds.GetPrivateKeys())
+	require.Equal(t, "/tmp/ssh-agent.sock", creds.GetAgentSocket())
+
+	creds, err = FromSSHConfig(config, "fwd")
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/ssh-agent.sock", creds.GetAgentSocket())
+}
+
+func TestFromSSHConfigMissing(t *testing.T) {
+	_, err := FromSSHConfig(filepath.Join(t.TempDir(), "config"), "sw1")
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
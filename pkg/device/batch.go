@@ -0,0 +1,106 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+)
+
+// BatchError describes failure of command in RunBatch.
+type BatchError struct {
+	Index int // index of failed command
+	Cmd   gcmd.Cmd
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("command %d %q failed: %v", e.Index, e.Cmd.Value(), e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+type batchOptions struct {
+	continueOnError bool
+	configMode      bool
+}
+
+type BatchOption func(*batchOptions)
+
+// ContinueOnError makes RunBatch execute all commands despite errors.
+func ContinueOnError() BatchOption {
+	return func(h *batchOptions) {
+		h.continueOnError = true
+	}
+}
+
+// WithConfigMode makes RunBatch enter configuration mode before commands and exit it after,
+// device must implement ConfigModer. If configuration mode can't be entered commands are not executed
+// and *ConfigModeError is returned.
+func WithConfigMode() BatchOption {
+	return func(h *batchOptions) {
+		h.configMode = true
+	}
+}
+
+// RunBatch executes commands one by one and stops on the first failed command, e.g. one which output matches
+// error expression. It returns results collected so far, including result of failed command if there is one,
+// and *BatchError with index of failed command. ctx is checked before every command.
+// With ContinueOnError all commands are executed, results have the same length as commands with nil for
+// commands without result, error joins *BatchError of every failed command.
+// Command is failed if Execute returns error or result has not accepted non-zero status.
+func RunBatch(ctx context.Context, dev Device, commands []gcmd.Cmd, opts ...BatchOption) ([]gcmd.CmdRes, error) {
+	options := batchOptions{continueOnError: false, configMode: false}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if !options.configMode {
+		return runBatch(ctx, dev, commands, options)
+	}
+	configDev, ok := dev.(ConfigModer)
+	if !ok {
+		return nil, ErrConfigModeNotSupported
+	}
+	if err := configDev.EnterConfigMode(ctx); err != nil {
+		var configErr *ConfigModeError
+		if !errors.As(err, &configErr) {
+			err = &ConfigModeError{Err: err}
+		}
+		return nil, err
+	}
+	res, err := runBatch(ctx, dev, commands, options)
+	if exitErr := configDev.ExitConfigMode(ctx); exitErr != nil {
+		err = errors.Join(err, &ConfigModeError{Exit: true, Err: exitErr})
+	}
+	return res, err
+}
+
+func runBatch(ctx context.Context, dev Device, commands []gcmd.Cmd, options batchOptions) ([]gcmd.CmdRes, error) {
+	res := make([]gcmd.CmdRes, 0, len(commands))
+	var errs []error
+	for i, command := range commands {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, &BatchError{Index: i, Cmd: command, Err: err})
+			break
+		}
+		out, err := dev.Execute(command)
+		if err == nil && out != nil && out.Status() != 0 && !slices.Contains(command.GetAcceptExitCodes(), out.Status()) {
+			err = ThrowExecException(string(out.Error()))
+		}
+		if err != nil {
+			errs = append(errs, &BatchError{Index: i, Cmd: command, Err: err})
+			if !options.continueOnError {
+				if out != nil {
+					res = append(res, out)
+				}
+				break
+			}
+		}
+		res = append(res, out)
+	}
+	return res, errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// batchTestDevice fails commands starting with "bad"
+type batchTestDevice struct {
+	executed []string
+}
+
+func (m *batchTestDevice) Connect(ctx context.Context) error { return nil }
+
+func (m *batchTestDevice) Execute(command gcmd.Cmd) (gcmd.CmdRes, error) {
+	value := string(command.Value())
+	m.executed = append(m.executed, value)
+	if value == "bad" {
+		return gcmd.NewCmdRes([]byte("% Invalid input")), ThrowExecException("% Invalid input")
+	}
+	if value == "lost" {
+		return nil, errors.New("connection lost")
+	}
+	return gcmd.NewCmdRes([]byte(value)), nil
+}
+
+func (m *batchTestDevice) Download(paths []string) (map[string]streamer.File, error) { return nil, nil }
+
+func (m *batchTestDevice) Upload(paths map[string]streamer.File) error { return nil }
+
+func (m *batchTestDevice) Close() {}
+
+func (m *batchTestDevice) GetAux() map[string]any { return nil }
+
+func TestRunBatch(t *testing.T) {
+	commands := gcmd.NewCmdList([]string{"one", "bad", "two", "lost", "three"})
+
+	dev := &batchTestDevice{}
+	res, err := RunBatch(context.Background(), dev, commands)
+	var batchErr *BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 1, batchErr.Index)
+	require.ErrorIs(t, err, &ExecException{})
+	require.Len(t, res, 2)
+	require.Equal(t, "% Invalid input", string(res[1].Output()))
+	require.Equal(t, []string{"one", "bad"}, dev.executed)
+
+	dev = &batchTestDevice{}
+	res, err = RunBatch(context.Background(), dev, commands, ContinueOnError())
+	require.Error(t, err)
+	require.Len(t, res, 5)
+	require.Nil(t, res[3])
+	require.Equal(t, "three", string(res[4].Output()))
+	require.Len(t, err.(interface{ Unwrap() []error }).Unwrap(), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dev = &batchTestDevice{}
+	res, err = RunBatch(ctx, dev, commands)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Empty(t, res)
+	require.Empty(t, dev.executed)
+}
+
+type configModeTestDevice struct {
+	batchTestDevice
+	locked bool
+}
+
+func (m *configModeTestDevice) EnterConfigMode(ctx context.Context) error {
+	if m.locked {
+		return &ConfigModeError{Err: errors.New("configuration mode is locked")}
+	}
+	m.executed = append(m.executed, "enter")
+	return nil
+}
+
+func (m *configModeTestDevice) ExitConfigMode(ctx context.Context) error {
+	m.executed = append(m.executed, "exit")
+	return nil
+}
+
+func TestRunBatchConfigMode(t *testing.T) {
+	commands := gcmd.NewCmdList([]string{"one", "bad", "two"})
+
+	dev := &configModeTestDevice{}
+	_, err := RunBatch(context.Background(), dev, commands, WithConfigMode())
+	require.ErrorIs(t, err, &ExecException{})
+	require.Equal(t, []string{"enter", "one", "bad", "exit"}, dev.executed)
+
+	dev = &configModeTestDevice{locked: true}
+	res, err := RunBatch(context.Background(), dev, commands, WithConfigMode())
+	var configErr *ConfigModeError
+	require.ErrorAs(t, err, &configErr)
+	require.False(t, configErr.Exit)
+	require.Empty(t, res)
+	require.Empty(t, dev.executed)
+
+	_, err = RunBatch(context.Background(), &batchTestDevice{}, commands, WithConfigMode())
+	require.ErrorIs(t, err, ErrConfigModeNotSupported)
+}
@@ -5,6 +5,7 @@ package cisco
 
 import (
 	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
 	"github.com/annetutil/gnetcli/pkg/device/genericcli"
 	"github.com/annetutil/gnetcli/pkg/expr"
 	"github.com/annetutil/gnetcli/pkg/streamer"
@@ -26,9 +27,14 @@ const (
 		`|^% Invalid input` +
 		`|Permission denied.+\[Errno \d+\] Permission denied` +
 		`)`
+	warningExpression       = `^\r?%\s?(Warning|Note|Info)\b`
 	passwordExpression      = `.*Password:\s?$`
 	passwordErrorExpression = `\n\% Authentication failed(\r\n|\n)`
 	pagerExpression         = `\r\n --More-- $`
+	privilegedExpression    = `#$`
+	userExpression          = `>$`
+	configExpression        = `\(conf(ig)?(-[^)]+)*\)#$`
+	configLockExpression    = `Configuration mode (is )?locked`
 )
 
 var autoCommands = []cmd.Cmd{
@@ -49,8 +55,15 @@ func NewDevice(connector streamer.Connector, opts ...genericcli.GenericDeviceOpt
 			expr.NewSimpleExprLast200().FromPattern(pagerExpression)),
 		genericcli.WithQuestion(
 			expr.NewSimpleExprLast200().FromPattern(questionExpression)),
+		genericcli.WithWarning(
+			expr.NewSimpleExpr().FromPattern(warningExpression)),
 		genericcli.WithAutoCommands(autoCommands),
 		genericcli.WithTerminalParams(400, 0),
+		genericcli.WithEnable("enable", passwordExpression, expr.NewSimpleExpr().FromPattern(privilegedExpression)),
+		genericcli.WithConfigModeCommands("configure terminal", "end", expr.NewSimpleExpr().FromPattern(configLockExpression)),
+		genericcli.WithModePrompt(device.ModeConfig, expr.NewSimpleExpr().FromPattern(configExpression)),
+		genericcli.WithModePrompt(device.ModePrivileged, expr.NewSimpleExpr().FromPattern(privilegedExpression)),
+		genericcli.WithModePrompt(device.ModeUser, expr.NewSimpleExpr().FromPattern(userExpression)),
 	)
 	return genericcli.MakeGenericDevice(cli, connector, opts...)
 }
@@ -18,6 +18,19 @@ func TestErrors(t *testing.T) {
 	testutils.ExprTester(t, errorCases, errorExpression)
 }
 
+func TestWarnings(t *testing.T) {
+	cases := [][]byte{
+		[]byte("% Warning: use /31 mask on non point-to-point interface cautiously"),
+		[]byte("%Warning: Interface is not in the same VRF"),
+		[]byte("% Note: this command is deprecated"),
+	}
+	testutils.ExprTester(t, cases, warningExpression)
+	testutils.ExprTesterFalse(t, [][]byte{
+		[]byte("% Invalid input detected at '^' marker."),
+		[]byte("Interface Status: Warning threshold"),
+	}, warningExpression)
+}
+
 func TestPrompt(t *testing.T) {
 	errorCases := [][]byte{
 		[]byte("\r\ndcx1-j1#"),
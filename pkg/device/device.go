@@ -6,8 +6,13 @@ package device
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"time"
 
 	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
 	"github.com/annetutil/gnetcli/pkg/streamer"
 )
 
@@ -35,6 +40,79 @@ func ExecuteBulk(dev Device, commands []gcmd.Cmd) ([]gcmd.CmdRes, error) {
 	return res, nil
 }
 
+// ErrorExprsProvider is implemented by devices which detect unexpected errors in output.
+// Execute returns *ExecException with matched text along with result if one of expressions matches.
+type ErrorExprsProvider interface {
+	ErrorExprs() []expr.Expr
+}
+
+// StreamExecutor is implemented by devices which can return command output as it is read.
+// Returned reader must be closed before next command.
+type StreamExecutor interface {
+	ExecuteStream(command gcmd.Cmd) (io.ReadCloser, error)
+}
+
+// Enabler is implemented by devices which have privileged mode entered by separate password.
+// Empty password means enable secret from connection credentials, see credentials.WithEnableSecret.
+type Enabler interface {
+	Enable(ctx context.Context, password credentials.Secret) error
+}
+
+// Sudoer is implemented by Linux-based devices which run privileged commands with sudo.
+// Empty password means password from connection credentials.
+// Sudo returns command result and whether sudo asked for password, it doesn't if sudo timestamp is still valid.
+type Sudoer interface {
+	Sudo(ctx context.Context, command string, password credentials.Secret) (gcmd.CmdRes, bool, error)
+}
+
+// Mode is CLI mode of device, see ModeDetector.
+type Mode int
+
+const (
+	// ModeUnknown means that prompt matches none of mode prompts.
+	ModeUnknown Mode = iota
+	// ModeUser is unprivileged mode, like ">" prompt of Cisco.
+	ModeUser
+	// ModePrivileged is mode entered by Enabler.
+	ModePrivileged
+	// ModeConfig is mode entered by ConfigModer.
+	ModeConfig
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeUnknown:
+		return "unknown"
+	case ModeUser:
+		return "user"
+	case ModePrivileged:
+		return "privileged"
+	case ModeConfig:
+		return "config"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ModeDetector is implemented by devices which detect CLI mode by prompt, see ErrModeNotSupported.
+// CurrentMode requests new prompt, so it may be used to check mode after commands which change it.
+type ModeDetector interface {
+	CurrentMode(ctx context.Context) (Mode, error)
+}
+
+// ConfigModer is implemented by devices which have configuration mode, see WithConfigMode.
+// EnterConfigMode returns *ConfigModeError if mode can't be entered.
+type ConfigModer interface {
+	EnterConfigMode(ctx context.Context) error
+	ExitConfigMode(ctx context.Context) error
+}
+
+// Reloader is implemented by devices which can be restarted with reconnect, see ErrReloadNotSupported.
+// Reload returns when device is ready to execute commands again or reconnectTimeout is expired.
+type Reloader interface {
+	Reload(ctx context.Context, reconnectTimeout time.Duration) error
+}
+
 type SFTPSupport interface {
 	EnableSFTP()
 	SFTPSudoTry()
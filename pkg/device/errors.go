@@ -1,6 +1,11 @@
 package device
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+)
 
 type ExecException struct {
 	Data string
@@ -25,6 +30,94 @@ func ThrowExecException(data string) error {
 	return &ExecException{Data: data}
 }
 
+// ExitStatusError is returned in exec mode when command exit status is not accepted.
+type ExitStatusError struct {
+	Status int
+}
+
+func (e *ExitStatusError) Error() string {
+	return fmt.Sprintf("unexpected exit status %d", e.Status)
+}
+
+// DialogError is returned when command dialog is interrupted by prompt or prompt doesn't follow finished dialog.
+type DialogError struct {
+	Step  int // number of answered steps
+	Steps int
+	Err   error
+}
+
+func (e *DialogError) Error() string {
+	if e.Step == e.Steps {
+		return fmt.Sprintf("dialog finished but prompt was not returned: %v", e.Err)
+	}
+	return fmt.Sprintf("prompt returned after %d of %d dialog steps", e.Step, e.Steps)
+}
+
+func (e *DialogError) Unwrap() error {
+	return e.Err
+}
+
+// ErrPromptTimeout matches PromptTimeoutError.
+var ErrPromptTimeout = errors.New("prompt timeout")
+
+// PromptTimeoutError is returned when prompt is not matched in time after command output.
+// Res contains output read so far, Err is underlying timeout error.
+type PromptTimeoutError struct {
+	Res cmd.CmdRes
+	Err error
+}
+
+func (e *PromptTimeoutError) Error() string {
+	return fmt.Sprintf("prompt was not found after %d bytes of output: %v", len(e.Res.Output()), e.Err)
+}
+
+func (e *PromptTimeoutError) Is(target error) bool {
+	return target == ErrPromptTimeout
+}
+
+func (e *PromptTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ErrOutputTooLarge matches OutputTooLargeError.
+var ErrOutputTooLarge = errors.New("output too large")
+
+// OutputTooLargeError is returned when command output exceeds limit set by cmd.WithMaxOutputBytes.
+// Res contains output truncated to Limit bytes, it is nil for streaming execution which returns output by reader.
+type OutputTooLargeError struct {
+	Res   cmd.CmdRes
+	Limit int
+}
+
+func (e *OutputTooLargeError) Error() string {
+	return fmt.Sprintf("output exceeds %d bytes", e.Limit)
+}
+
+func (e *OutputTooLargeError) Is(target error) bool {
+	return target == ErrOutputTooLarge
+}
+
+// ErrVerifyFailed matches VerifyError.
+var ErrVerifyFailed = errors.New("config verification failed")
+
+// VerifyError is returned when output of readback command set by cmd.WithVerify doesn't match expectation.
+// Res is result of verified command, Observed is output of Readback.
+type VerifyError struct {
+	Command  []byte
+	Readback []byte
+	Expected string
+	Observed []byte
+	Res      cmd.CmdRes
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%q is not applied: output of %q doesn't match %s", e.Command, e.Readback, e.Expected)
+}
+
+func (e *VerifyError) Is(target error) bool {
+	return target == ErrVerifyFailed
+}
+
 type EchoReadException struct {
 	lastRead    []byte
 	promptFound bool // indicates if we found prompt after echo read error
@@ -57,3 +150,30 @@ func (e *QuestionException) Error() string {
 func ThrowQuestionException(question []byte) error {
 	return &QuestionException{Question: question}
 }
+
+// ErrConfigModeNotSupported is returned by RunBatch with WithConfigMode for devices without configuration mode.
+var ErrConfigModeNotSupported = errors.New("configuration mode is not supported by device")
+
+// ErrModeNotSupported is returned by CurrentMode if device has no mode prompts.
+var ErrModeNotSupported = errors.New("mode detection is not supported by device")
+
+// ErrReloadNotSupported is returned by Reload if device has no reload command.
+var ErrReloadNotSupported = errors.New("reload is not supported by device")
+
+// ConfigModeError is returned when configuration mode can't be entered, e.g. it is locked by another user,
+// or exited.
+type ConfigModeError struct {
+	Exit bool // error on exit
+	Err  error
+}
+
+func (e *ConfigModeError) Error() string {
+	if e.Exit {
+		return fmt.Sprintf("failed to exit configuration mode: %v", e.Err)
+	}
+	return fmt.Sprintf("failed to enter configuration mode: %v", e.Err)
+}
+
+func (e *ConfigModeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,82 @@
+package genericcli
+
+import (
+	"context"
+	"slices"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var _ device.ConfigModer = (*GenericDevice)(nil)
+
+type configModeParams struct {
+	enter      string
+	exit       string
+	errorExprs []expr.Expr // checked in addition to WithErrorExprs while in configuration mode
+}
+
+// WithConfigModeCommands sets commands for entering and exiting configuration mode, see device.WithConfigMode.
+// errorExprs are checked along with WithErrorExprs against output of commands in configuration mode
+// and of enter command, e.g. for message about configuration lock.
+func WithConfigModeCommands(enter, exit string, errorExprs ...expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.configMode = &configModeParams{
+			enter:      enter,
+			exit:       exit,
+			errorExprs: errorExprs,
+		}
+	}
+}
+
+// EnterConfigMode runs enter command, *device.ConfigModeError is returned if it fails.
+func (m *GenericDevice) EnterConfigMode(ctx context.Context) error {
+	if m.cli.configMode == nil {
+		return device.ErrConfigModeNotSupported
+	}
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	m.inConfigMode = true
+	res, err := m.Execute(cmd.NewCmd(m.cli.configMode.enter))
+	if err == nil && res.Status() != 0 {
+		err = device.ThrowExecException(string(res.Error()))
+	}
+	if err != nil {
+		m.inConfigMode = false
+		return &device.ConfigModeError{Err: err}
+	}
+	return nil
+}
+
+// ExitConfigMode runs exit command.
+func (m *GenericDevice) ExitConfigMode(ctx context.Context) error {
+	if m.cli.configMode == nil {
+		return device.ErrConfigModeNotSupported
+	}
+	m.inConfigMode = false
+	res, err := m.Execute(cmd.NewCmd(m.cli.configMode.exit))
+	if err == nil && res.Status() != 0 {
+		err = device.ThrowExecException(string(res.Error()))
+	}
+	return err
+}
+
+// InConfigMode returns whether device is in configuration mode entered by EnterConfigMode.
+func (m *GenericDevice) InConfigMode() bool {
+	return m.inConfigMode
+}
+
+// execCLI returns cli params for the current mode.
+func (m *GenericDevice) execCLI() GenericCLI {
+	if !m.inConfigMode || len(m.cli.configMode.errorExprs) == 0 {
+		return m.cli
+	}
+	cli := m.cli
+	cli.errorExprs = append(slices.Clone(cli.errorExprs), cli.configMode.errorExprs...)
+	return cli
+}
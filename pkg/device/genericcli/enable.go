@@ -0,0 +1,89 @@
+package genericcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var _ device.Enabler = (*GenericDevice)(nil)
+
+// ErrEnableNotSupported is returned by Enable if device has no WithEnable option.
+var ErrEnableNotSupported = errors.New("privileged mode is not supported by device")
+
+// ErrEnableFailed is returned by Enable if prompt is not privileged after enable command.
+var ErrEnableFailed = errors.New("failed to enter privileged mode")
+
+type enableParams struct {
+	command          string
+	passwordQuestion string    // regexp
+	privilegedPrompt expr.Expr // matched against prompt
+}
+
+// WithEnable sets command for entering privileged mode, regexp of its password question and
+// expression which matches prompt in privileged mode, e.g. `#$` for Cisco-like devices.
+func WithEnable(command, passwordQuestion string, privilegedPrompt expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.enable = &enableParams{
+			command:          command,
+			passwordQuestion: passwordQuestion,
+			privilegedPrompt: privilegedPrompt,
+		}
+	}
+}
+
+// Enable enters privileged mode, it does nothing if prompt is already privileged.
+// Empty password means enable secret from connector credentials.
+func (m *GenericDevice) Enable(ctx context.Context, password credentials.Secret) error {
+	if m.cli.enable == nil {
+		return ErrEnableNotSupported
+	}
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	privileged, err := m.isPrivileged(ctx)
+	if err != nil {
+		return err
+	}
+	if privileged {
+		m.logger.Debug("already in privileged mode")
+		return nil
+	}
+	if len(password) == 0 {
+		password = credentials.GetEnableSecret(m.connector.GetCredentials())
+	}
+	answer := cmd.NewSecretAnswer("/"+m.cli.enable.passwordQuestion+"/", password.Value())
+	_, err = m.Execute(cmd.NewCmd(m.cli.enable.command, cmd.WithAddAnswers(answer)))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrEnableFailed, err)
+	}
+	privileged, err = m.isPrivileged(ctx)
+	if err != nil {
+		return err
+	}
+	if !privileged {
+		return ErrEnableFailed
+	}
+	return nil
+}
+
+// isPrivileged requests new prompt and checks it.
+func (m *GenericDevice) isPrivileged(ctx context.Context) (bool, error) {
+	prompt, err := m.requestPrompt(ctx)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m.cli.enable.privilegedPrompt.Match(prompt)
+	m.logger.Debug("privileged mode check", zap.ByteString("prompt", prompt), zap.Bool("privileged", ok))
+	return ok, nil
+}
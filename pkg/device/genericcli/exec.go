@@ -0,0 +1,190 @@
+package genericcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// execution is state of command run shared by GenericExecute and GenericExecuteStream.
+// It holds expressions matched against output and answers pagers, questions, dialog steps and callbacks.
+type execution struct {
+	command     cmd.Cmd
+	connector   streamer.Connector
+	cli         GenericCLI
+	logger      *zap.Logger
+	delay       time.Duration
+	echo        expr.Expr
+	exprs       expr.ExprList
+	exprsAdd    []string
+	exprsAddMap map[string]string
+	pagers      []cmd.Pager
+	dialog      []cmd.QA
+	dialogStep  int
+	cbLimit     int
+}
+
+func newExecution(command cmd.Cmd, connector streamer.Connector, cli GenericCLI, logger *zap.Logger) *execution {
+	res := &execution{
+		command:   command,
+		connector: connector,
+		cli:       cli,
+		logger:    logger,
+		delay:     command.GetKeystrokeDelay(),
+		pagers:    command.GetPagers(),
+		dialog:    command.GetDialog(),
+		cbLimit:   100,
+	}
+	if cli.echoExprFormat != nil {
+		res.echo = cli.echoExprFormat(command)
+	} else {
+		res.echo = expr.NewSimpleExpr().FromPattern(fmt.Sprintf("%s%s", regexp.QuoteMeta(string(command.Value())), AnyNLPattern))
+	}
+	res.exprs = expr.NewSimpleExprListNamedOrdered([]expr.NamedExpr{
+		{Name: echoExprName, Exprs: []expr.Expr{res.echo}},
+		{Name: promptExprName, Exprs: []expr.Expr{commandPrompt(command, cli)}},
+		{Name: pagerExprName, Exprs: []expr.Expr{cli.pager}},
+		{Name: cmdQuestionExprName, Exprs: command.GetQuestionExprs()},
+		{Name: questionExprName, Exprs: []expr.Expr{cli.question}},
+	})
+	res.exprsAdd, res.exprsAddMap = command.GetExprCallback()
+	for _, exprCB := range res.exprsAdd {
+		res.exprs.Add(cbExprName, expr.NewSimpleExpr().FromPattern(exprCB))
+	}
+	for i, pager := range res.pagers {
+		res.exprs.Add(fmt.Sprintf("%s%d", cmdPagerExprName, i), pager.GetExpr())
+	}
+	if len(res.dialog) > 0 {
+		res.exprs.Add(dialogExprName, res.dialog[0].Expr)
+	}
+	return res
+}
+
+// writeCommand writes command followed by cli newline.
+func (m *execution) writeCommand(ctx context.Context) error {
+	err := m.write(ctx, m.command.Value())
+	if err == nil && len(m.cli.writeNewline) > 0 {
+		err = m.write(ctx, m.cli.writeNewline)
+	}
+	return err
+}
+
+func (m *execution) write(ctx context.Context, data []byte) error {
+	err := writeInput(ctx, m.connector, m.delay, data)
+	if err != nil {
+		return fmt.Errorf("write error %w", err)
+	}
+	return nil
+}
+
+// answer handles match which requires input: pager, command pager, dialog step, question or callback.
+// It returns output before match which belongs to command result, handled is false for other matches.
+func (m *execution) answer(ctx context.Context, match streamer.ReadRes, matchName string) (out []byte, handled bool, err error) {
+	mbefore := match.GetBefore()
+	switch {
+	case matchName == pagerExprName: // next page
+		out = mbefore
+		if store, ok := match.GetMatchedGroups()["store"]; ok {
+			out = append(slices.Clone(out), store...)
+		}
+		m.logger.Debug("auto answer to pager")
+		return out, true, m.write(ctx, []byte(` `))
+	case strings.HasPrefix(matchName, cmdPagerExprName): // next page, command pager
+		pagerNo, err := strconv.Atoi(strings.TrimPrefix(matchName, cmdPagerExprName))
+		if err != nil {
+			return nil, true, fmt.Errorf("unknown pager %s", matchName)
+		}
+		m.logger.Debug("auto answer to command pager", zap.Int("pager", pagerNo))
+		return mbefore, true, m.write(ctx, m.pagers[pagerNo].GetResponse())
+	case matchName == dialogExprName: // next step of dialog
+		m.logger.Debug("dialog answer", zap.Int("step", m.dialogStep))
+		err = m.write(ctx, m.dialog[m.dialogStep].Answer)
+		if err != nil {
+			return nil, true, err
+		}
+		m.exprs.Delete(dialogExprName)
+		m.dialogStep++
+		if m.dialogStep < len(m.dialog) {
+			m.exprs.Add(dialogExprName, m.dialog[m.dialogStep].Expr)
+		}
+		return nil, true, nil
+	case matchName == cmdQuestionExprName && match.GetMatchedGroups()[cmd.AnswerGroup] != nil: // answer is chosen by expr
+		groups := match.GetMatchedGroups()
+		m.logger.Debug("command question", zap.ByteString("question", match.GetMatched()))
+		if secret, ok := groups[cmd.SecretAnswerGroup]; ok {
+			addSecrets(m.connector, secret)
+		}
+		reply := groups[cmd.AnswerGroup]
+		// answer may be a password, so its content is not logged
+		m.logger.Debug("command answer", zap.Int("len", len(reply)))
+		return nil, true, m.write(ctx, reply)
+	case matchName == questionExprName || matchName == cmdQuestionExprName: // question
+		question := match.GetMatched()
+		m.logger.Debug("QuestionHandler question", zap.ByteString("question", question))
+		answer, err := m.command.QuestionHandler(question)
+		if err != nil {
+			if errors.Is(err, cmd.ErrNotFoundAnswer) {
+				return nil, true, device.ThrowQuestionException(question)
+			}
+			return nil, true, fmt.Errorf("QuestionHandler error %w", err)
+		}
+		// answer may be a password, so its content is not logged
+		m.logger.Debug("QuestionHandler answer", zap.Int("len", len(answer)))
+		return nil, true, m.write(ctx, answer)
+	case matchName == cbExprName: // ExprCallback
+		if m.cbLimit == 0 {
+			return nil, true, fmt.Errorf("callback limit")
+		}
+		m.cbLimit--
+		wr := m.exprsAddMap[m.exprsAdd[match.GetPatternNo()-3]]
+		m.logger.Debug("write callback result")
+		return nil, true, m.write(ctx, []byte(wr))
+	}
+	return nil, false, nil
+}
+
+// promptOutput returns output before prompt as it belongs to command result.
+func (m *execution) promptOutput(match streamer.ReadRes, mbefore []byte) []byte {
+	if m.cli.collapsePrompts {
+		mbefore = collapsePromptLines(mbefore, match.GetMatched())
+	}
+	// device may print pager right before prompt on last page
+	mbefore = stripTrailingPagers(mbefore, m.pagers)
+	if store, ok := match.GetMatchedGroups()["store"]; ok {
+		mbefore = append(slices.Clone(mbefore), store...)
+	}
+	return mbefore
+}
+
+// afterPrompt skips duplicate prompts and output printed after prompt, see WithCollapsePrompts and WithPostPromptDrain.
+func (m *execution) afterPrompt(ctx context.Context, matchedPrompt []byte) error {
+	if m.cli.collapsePrompts && m.cli.duplicatePrompt > 0 {
+		err := skipDuplicatePrompts(ctx, m.connector, matchedPrompt, m.cli.duplicatePrompt, m.logger)
+		if err != nil {
+			return err
+		}
+	}
+	return drainAfterPrompt(ctx, m.connector, m.cli, m.logger)
+}
+
+// timeoutError returns error found by cli error expression in data read before read timeout.
+// In some cases device messes up output, so prompt is not found after error.
+func (m *execution) timeoutError(err error) error {
+	var perr *streamer.ReadTimeoutException
+	if errors.As(err, &perr) {
+		return checkError(m.cli.error, perr.LastRead)
+	}
+	return nil
+}
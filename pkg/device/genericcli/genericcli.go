@@ -6,9 +6,12 @@ package genericcli
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -28,14 +31,20 @@ const AnyNLPattern = `(\r\n|\n)`
 const DefaultCLIConnectTimeout = 15 * time.Second
 
 const (
-	promptExprName    = "prompt"
-	passwdErrExprName = "passwordError"
-	questionExprName  = "question"
-	passwordExprName  = "password"
-	loginExprName     = "login"
-	pagerExprName     = "pager"
-	echoExprName      = "echo"
-	cbExprName        = "cb"
+	promptExprName      = "prompt"
+	passwdErrExprName   = "passwordError"
+	questionExprName    = "question"
+	cmdQuestionExprName = "cmdQuestion"
+	passwordExprName    = "password"
+	loginExprName       = "login"
+	loginBannerExprName = "loginBanner"
+	pagerExprName       = "pager"
+	echoExprName        = "echo"
+	cbExprName          = "cb"
+	cmdPagerExprName    = "cmdPager"
+	dialogExprName      = "dialog"
+	firstByteExprName   = "firstByte"
+	outputLimitExprName = "outputLimit"
 )
 
 var defaultWriteNewLine = []byte("\n") // const
@@ -56,6 +65,7 @@ type GenericCLI struct {
 	login            expr.Expr
 	password         expr.Expr
 	error            expr.Expr
+	warning          expr.Expr
 	question         expr.Expr
 	loginCB          []cmd.ExprCallback // used only during login, before first prompt
 	passwordError    expr.Expr
@@ -71,6 +81,21 @@ type GenericCLI struct {
 	defaultAnswers   []cmd.Answer
 	terminalParams   *terminalParams
 	connectTimeout   time.Duration
+	postPromptDrain  time.Duration
+	postPromptCB     func([]byte)
+	collapsePrompts  bool
+	duplicatePrompt  time.Duration
+	errorExprs       []expr.Expr
+	streamWindow     int
+	enable           *enableParams
+	configMode       *configModeParams
+	crOverwrite      bool
+	loginBanner      expr.Expr
+	loginAttempts    int
+	promptQuiet      time.Duration
+	reload           *reloadParams
+	sudo             *sudoParams
+	modePrompts      []modePrompt
 }
 
 func (m *GenericCLI) SetConnectTimeout(timeout time.Duration) time.Duration {
@@ -90,6 +115,29 @@ func WithLoginExprs(login, password, passwordError expr.Expr) GenericCLIOption {
 	}
 }
 
+// WithLoginPrompt sets expression of username prompt like "login:" or "User Name:".
+func WithLoginPrompt(login expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.login = login
+	}
+}
+
+// WithPasswordPrompt sets expression of password prompt. Without login prompt device is expected to ask only for password.
+func WithPasswordPrompt(password expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.password = password
+	}
+}
+
+// WithLoginAttempts limits number of passwords sent during login by Device.
+// Every attempt uses the next password, passwords are reused from the first one when attempts exceed them.
+// By default each password is tried once.
+func WithLoginAttempts(attempts int) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.loginAttempts = attempts
+	}
+}
+
 func WithAnswers(answers []cmd.Answer) GenericCLIOption {
 	return func(h *GenericCLI) {
 		h.defaultAnswers = answers
@@ -144,6 +192,14 @@ func WithEchoExprFn(fn func(cmd.Cmd) expr.Expr) GenericCLIOption {
 	}
 }
 
+// WithWarning sets expression for warning lines. It is matched against each line of output,
+// found lines are returned by CmdRes.Warnings and don't fail the command.
+func WithWarning(warning expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.warning = warning
+	}
+}
+
 // WithQuestion implements question
 func WithQuestion(question expr.Expr) GenericCLIOption {
 	return func(h *GenericCLI) {
@@ -186,18 +242,80 @@ func WithWriteNewLine(newline []byte) GenericCLIOption {
 	}
 }
 
+// WithErrorExprs sets expressions of unexpected device errors. Unlike error expression of MakeGenericCLI,
+// which marks result with status 1, match makes Execute to return *device.ExecException.
+// ExecuteStream checks them against every chunk of output and reports match by Read.
+// Commands can override them with cmd.WithErrorExprs or cmd.WithoutErrorExprs.
+// Vendor devices don't set them, so there are no error expressions by default.
+func WithErrorExprs(exprs ...expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.errorExprs = exprs
+	}
+}
+
+// WithCROverwrite renders text after bare \r over the beginning of line, as terminal does, instead of
+// dropping the line. It is useful for progress counters which rewrite only part of line. \r\n is not affected.
+func WithCROverwrite() GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.crOverwrite = true
+	}
+}
+
+// WithLoginBanner sets expression matching the end of banner or MOTD printed on every login.
+// Output up to the banner is skipped before looking for the first prompt, so prompt-like text in it is not taken as prompt.
+func WithLoginBanner(banner expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.loginBanner = banner
+	}
+}
+
 func WithConnectTimeout(connectTimeout time.Duration) GenericCLIOption {
 	return func(h *GenericCLI) {
 		h.connectTimeout = connectTimeout
 	}
 }
 
+// WithPostPromptDrain reads and discards data arriving during d after prompt was matched.
+// Some devices print async messages right after prompt, which otherwise would be attributed to the next command.
+func WithPostPromptDrain(d time.Duration) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.postPromptDrain = d
+	}
+}
+
+// WithPostPromptDrainCB sets callback for data read by WithPostPromptDrain instead of discarding it
+func WithPostPromptDrainCB(cb func([]byte)) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.postPromptCB = cb
+	}
+}
+
+// WithCollapsePrompts enables collapsing of consecutive identical prompt lines.
+// Some terminal servers print prompt several times, in this case the last one is used as output boundary.
+// wait is time to wait for duplicate prompt after the matched one, zero means that only duplicates
+// already read together with the output are collapsed.
+func WithCollapsePrompts(wait time.Duration) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.collapsePrompts = true
+		h.duplicatePrompt = wait
+	}
+}
+
+// WithPromptQuiescence accepts matched prompt only if it is the last read data and nothing else arrives during wait.
+// Otherwise the prompt is considered a part of output, like hostname in banner printed by show running-config.
+func WithPromptQuiescence(wait time.Duration) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.promptQuiet = wait
+	}
+}
+
 func MakeGenericCLI(prompt, error expr.Expr, opts ...GenericCLIOption) GenericCLI {
 	res := GenericCLI{
 		prompt:           prompt,
 		login:            nil,
 		password:         nil,
 		error:            error,
+		warning:          nil,
 		question:         nil,
 		passwordError:    nil,
 		pager:            nil,
@@ -212,6 +330,11 @@ func MakeGenericCLI(prompt, error expr.Expr, opts ...GenericCLIOption) GenericCL
 		terminalParams:   &terminalParams{w: 400, h: 0},
 		loginCB:          []cmd.ExprCallback{},
 		connectTimeout:   DefaultCLIConnectTimeout,
+		postPromptDrain:  0,
+		postPromptCB:     nil,
+		collapsePrompts:  false,
+		duplicatePrompt:  0,
+		streamWindow:     DefaultStreamWindow,
 	}
 	for _, opt := range opts {
 		opt(&res)
@@ -224,6 +347,10 @@ type GenericDevice struct {
 	connector    streamer.Connector
 	logger       *zap.Logger
 	cliConnected bool // whether connector.Init was called or not
+	inConfigMode bool
+	banner       []byte
+	initCommands []cmd.Cmd
+	reloadHooks  ReloadHooks
 }
 
 var _ device.Device = (*GenericDevice)(nil)
@@ -236,6 +363,15 @@ func WithDevLogger(logger *zap.Logger) GenericDeviceOption {
 	}
 }
 
+// WithDevInitCommands adds commands which run after login before the first user command,
+// e.g. disabling of pager with "terminal length 0". They run after auto commands of CLI.
+// Unlike auto commands, device error of init command fails connect unless command ignores it, see device.RunBatch.
+func WithDevInitCommands(commands ...cmd.Cmd) GenericDeviceOption {
+	return func(h *GenericDevice) {
+		h.initCommands = append(h.initCommands, commands...)
+	}
+}
+
 func WithDevAdditionalLoginCallbacks(cb []cmd.ExprCallback) GenericDeviceOption {
 	return func(h *GenericDevice) {
 		h.cli.loginCB = append(h.cli.loginCB, cb...)
@@ -279,6 +415,19 @@ func (m *GenericDevice) Connect(ctx context.Context) (err error) {
 func (m *GenericDevice) connectCLI(ctx context.Context) (err error) {
 	m.cliConnected = true
 	if m.connector.HasFeature(streamer.AutoLogin) && !m.cli.forceManualAuth {
+		var banner []byte
+		defer func() {
+			m.banner = parseBanner(banner)
+		}()
+		if m.cli.loginBanner != nil {
+			// skip banner before looking for prompt, it may contain prompt-like lines
+			match, err := m.connector.ReadTo(ctx, m.cli.loginBanner)
+			if err != nil {
+				return fmt.Errorf("login banner: %w", err)
+			}
+			banner = append(banner, match.GetBefore()...)
+			banner = append(banner, match.GetMatched()...)
+		}
 		exprMap := map[string][]expr.Expr{
 			promptExprName:   {m.cli.prompt},
 			questionExprName: {m.cli.question},
@@ -296,6 +445,7 @@ func (m *GenericDevice) connectCLI(ctx context.Context) (err error) {
 			if err != nil {
 				return err
 			}
+			banner = append(banner, match.GetBefore()...)
 			matchName := exprs.GetName(match.GetPatternNo())
 			switch matchName {
 			case promptExprName:
@@ -321,11 +471,14 @@ func (m *GenericDevice) connectCLI(ctx context.Context) (err error) {
 				if !seenOk {
 					return device.ThrowQuestionException(question)
 				}
-				_, err = m.connector.ReadTo(ctx, m.cli.prompt)
+				banner = append(banner, question...)
+				promptMatch, err := m.connector.ReadTo(ctx, m.cli.prompt)
 				if err != nil {
 					return err
 				}
+				banner = append(banner, promptMatch.GetBefore()...)
 			case cbExprName:
+				banner = append(banner, match.GetMatched()...)
 				pos := match.GetUnderlyingRes().GetPatternNo()
 				f := m.cli.loginCB[pos]
 				err := m.connector.Write(f.GetAns())
@@ -339,10 +492,11 @@ func (m *GenericDevice) connectCLI(ctx context.Context) (err error) {
 			break
 		}
 	} else { // login by Device
-		if m.cli.login == nil {
+		if m.cli.password == nil {
 			return ErrorCLILogin
 		}
-		err := genericLogin(ctx, m.connector, m.cli)
+		banner, err := genericLogin(ctx, m.connector, m.cli)
+		m.banner = parseBanner(banner)
 		if err != nil {
 			return err
 		}
@@ -355,7 +509,19 @@ func (m *GenericDevice) connectCLI(ctx context.Context) (err error) {
 	if err != nil {
 		return err
 	}
-	return err
+	// output is not needed, but device errors fail connect unless command ignores them
+	_, err = device.RunBatch(ctx, m, m.initCommands)
+	if err != nil {
+		return fmt.Errorf("init commands: %w", err)
+	}
+	return nil
+}
+
+// InitCommands returns commands executed after login: auto commands of CLI and WithDevInitCommands.
+func (m *GenericDevice) InitCommands() []cmd.Cmd {
+	res := make([]cmd.Cmd, 0, len(m.cli.autoCommands)+len(m.initCommands))
+	res = append(res, m.cli.autoCommands...)
+	return append(res, m.initCommands...)
 }
 
 func (m *GenericDevice) Execute(command cmd.Cmd) (cmd.CmdRes, error) {
@@ -368,7 +534,63 @@ func (m *GenericDevice) Execute(command cmd.Cmd) (cmd.CmdRes, error) {
 			return nil, err
 		}
 	}
-	return GenericExecute(command, m.connector, m.cli, m.logger)
+	observeDone := observeCommand(m.connector, command)
+	res, err := GenericExecute(command, m.connector, m.execCLI(), m.logger)
+	bytesRead := 0
+	if res != nil {
+		bytesRead = len(res.Output()) + len(res.Error())
+	}
+	observeDone(len(command.Value())+len(m.cli.writeNewline), bytesRead, err)
+	if _, noWait := command.GetNoWaitPrompt(); res != nil && !noWait {
+		// result is returned only after prompt is matched
+		m.switchPrompt(command)
+	}
+	if readback, expect := command.GetVerify(); err == nil && expect != nil {
+		err = m.verify(command, res, readback, expect)
+	}
+	return res, err
+}
+
+// verify runs readback command and checks its output, see cmd.WithVerify.
+func (m *GenericDevice) verify(command cmd.Cmd, res cmd.CmdRes, readback []byte, expect expr.Expr) error {
+	readbackRes, err := m.Execute(cmd.NewCmd(string(readback)))
+	if err != nil {
+		return fmt.Errorf("verify readback error %w", err)
+	}
+	if _, ok := expect.Match(readbackRes.Output()); ok {
+		return nil
+	}
+	return &device.VerifyError{
+		Command:  command.Value(),
+		Readback: readback,
+		Expected: expect.Repr(),
+		Observed: readbackRes.Output(),
+		Res:      res,
+	}
+}
+
+// switchPrompt makes prompt expected by command the session prompt.
+func (m *GenericDevice) switchPrompt(command cmd.Cmd) {
+	if prompt := command.GetExpectedPrompt(); prompt != nil {
+		m.logger.Debug("switch prompt", zap.String("prompt", prompt.Repr()))
+		m.cli.prompt = prompt
+	}
+}
+
+// observeCommand reports command to observer of connector if it supports one.
+func observeCommand(connector streamer.Connector, command cmd.Cmd) func(bytesWritten, bytesRead int, err error) {
+	if observer, ok := connector.(streamer.CommandObserver); ok {
+		return observer.ObserveCommand(command.Value())
+	}
+	return func(int, int, error) {}
+}
+
+// commandPrompt returns prompt which terminates command.
+func commandPrompt(command cmd.Cmd, cli GenericCLI) expr.Expr {
+	if prompt := command.GetExpectedPrompt(); prompt != nil {
+		return prompt
+	}
+	return cli.prompt
 }
 
 func (m *GenericDevice) Download(paths []string) (map[string]streamer.File, error) {
@@ -417,6 +639,11 @@ func (m *GenericDevice) GetAuthError() expr.Expr {
 	return m.cli.passwordError
 }
 
+// ErrorExprs returns expressions of unexpected device errors, see WithErrorExprs.
+func (m *GenericDevice) ErrorExprs() []expr.Expr {
+	return m.cli.errorExprs
+}
+
 func (m *GenericDevice) GetPrompt() expr.Expr {
 	return m.cli.prompt
 }
@@ -438,82 +665,118 @@ func (m *GenericDevice) SetCLIConnectTimeout(timeout time.Duration) time.Duratio
 	return m.cli.SetConnectTimeout(timeout)
 }
 
-func genericLogin(ctx context.Context, connector streamer.Connector, cli GenericCLI) (err error) {
-	if cli.login == nil {
-		return errors.New("login Expr is not set but required for login procedure")
+// Banner returns output printed by device before the first prompt, like banner, MOTD and last login line.
+// It is collected only when connector performs login itself.
+func (m *GenericDevice) Banner() []byte {
+	return m.banner
+}
+
+// parseBanner renders banner as terminal does.
+func parseBanner(data []byte) []byte {
+	res, err := terminal.Parse(data)
+	if err != nil {
+		return data
+	}
+	return bytes.TrimSpace(normalizeNewlines(res))
+}
+
+func genericLogin(ctx context.Context, connector streamer.Connector, cli GenericCLI) (banner []byte, err error) {
+	if cli.password == nil {
+		return nil, errors.New("password Expr is not set but required for login procedure")
 	}
 
 	passwords := connector.GetCredentials().GetPasswords(ctx)
 	if len(passwords) == 0 {
-		return errors.New("empty password")
+		return nil, errors.New("empty password")
+	}
+	attempts := len(passwords)
+	if cli.loginAttempts > 0 {
+		attempts = cli.loginAttempts
 	}
 
 	i := 0
-	checkExprs := []expr.NamedExpr{
+	checkExprs := []expr.NamedExpr{}
+	if cli.loginBanner != nil {
+		// banner goes first, it may contain prompt-like lines
+		checkExprs = append(checkExprs, expr.NamedExpr{Name: loginBannerExprName, Exprs: []expr.Expr{cli.loginBanner}})
+	}
+	checkExprs = append(checkExprs, []expr.NamedExpr{
 		{Name: loginExprName, Exprs: []expr.Expr{cli.login}},
 		{Name: passwordExprName, Exprs: []expr.Expr{cli.password}},
 		{Name: promptExprName, Exprs: []expr.Expr{cli.prompt}},
 		{Name: passwdErrExprName, Exprs: []expr.Expr{cli.passwordError}},
-	}
+	}...)
 
-	for i < len(passwords) {
+	for i < attempts {
 
 		exprsLogin := expr.NewSimpleExprListNamedOrdered(checkExprs)
 		readResLogin, err := connector.ReadTo(ctx, exprsLogin)
 		if err != nil {
-			return err
+			return banner, err
 		}
 
 		matchedExprNameLogin := exprsLogin.GetName(readResLogin.GetPatternNo())
-		if matchedExprNameLogin == loginExprName {
-			username, err := connector.GetCredentials().GetUsername()
+		if matchedExprNameLogin == loginBannerExprName {
+			banner = append(banner, readResLogin.GetBefore()...)
+			banner = append(banner, readResLogin.GetMatched()...)
+		} else if matchedExprNameLogin == loginExprName {
+			username, err := credentials.GetUsername(ctx, connector.GetCredentials())
 			if err != nil {
-				return err
+				return banner, err
 			}
 
 			err = connector.Write([]byte(username))
 			if err != nil {
-				return err
+				return banner, err
 			}
 			newline := cli.writeNewline
 			if len(newline) > 0 {
 				err := connector.Write(newline)
 				if err != nil {
-					return fmt.Errorf("write error %w", err)
+					return banner, fmt.Errorf("write error %w", err)
 				}
 			}
 		} else if matchedExprNameLogin == passwordExprName {
-			err = connector.Write([]byte(passwords[i].Value()))
+			err = connector.Write([]byte(passwords[i%len(passwords)].Value()))
 			if err != nil {
-				return err
+				return banner, err
 			}
 			newline := cli.writeNewline
 			if len(newline) > 0 {
 				err := connector.Write(newline)
 				if err != nil {
-					return fmt.Errorf("write error %w", err)
+					return banner, fmt.Errorf("write error %w", err)
 				}
 			}
 			i++
 		} else if matchedExprNameLogin == passwdErrExprName {
 			continue
 		} else if matchedExprNameLogin == promptExprName {
-			return nil
+			return append(banner, readResLogin.GetBefore()...), nil
 		}
 	}
 	exprs := expr.NewSimpleExprListNamedOrdered(checkExprs)
 	readResLogin, err := connector.ReadTo(ctx, exprs)
 	if err != nil {
-		return err
+		return banner, err
 	}
 
 	matchedExprNameLogin := exprs.GetName(readResLogin.GetPatternNo())
+	if matchedExprNameLogin == loginBannerExprName {
+		banner = append(banner, readResLogin.GetBefore()...)
+		banner = append(banner, readResLogin.GetMatched()...)
+		readResLogin, err = connector.ReadTo(ctx, exprs)
+		if err != nil {
+			return banner, err
+		}
+		matchedExprNameLogin = exprs.GetName(readResLogin.GetPatternNo())
+	}
 	if matchedExprNameLogin == promptExprName {
-		return nil
+		return append(banner, readResLogin.GetBefore()...), nil
 	}
 
-	return gerror.NewAuthException("cli auth user")
-
+	// passwords are cycled when attempts exceed them, so error holds each of them once and message holds number of attempts
+	return banner, gerror.NewAuthExceptionWrap(fmt.Sprintf("cli auth user, %d attempts", i), credentials.NewPasswordsError(min(i, len(passwords))))
 }
 
 func GenericExecute(command cmd.Cmd, connector streamer.Connector, cli GenericCLI, logger *zap.Logger) (cmd.CmdRes, error) {
@@ -523,62 +786,101 @@ func GenericExecute(command cmd.Cmd, connector streamer.Connector, cli GenericCL
 		ctx = newCtx
 		defer cancel()
 	}
-	if readTimeout := command.GetReadTimeout(); readTimeout > 0 {
+	idleTimeout := command.GetReadTimeout()
+	if timeout := command.GetIdleTimeout(); timeout > 0 {
+		idleTimeout = timeout
+	}
+	firstByteTimeout := command.GetFirstByteTimeout()
+	if readTimeout := firstByteTimeout; readTimeout > 0 || idleTimeout > 0 {
+		if readTimeout == 0 {
+			readTimeout = idleTimeout
+		}
 		prevTimeout := connector.SetReadTimeout(readTimeout)
 		defer connector.SetReadTimeout(prevTimeout)
+		if idleTimeout == 0 {
+			idleTimeout = prevTimeout
+		}
 	}
+	stopObserve := observeOutput(connector, command)
+	defer func() { _ = stopObserve() }()
+	exec := newExecution(command, connector, cli, logger)
 
-	err := connector.Write(command.Value())
+	err := exec.writeCommand(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("write error %w", err)
+		return nil, err
 	}
-	newline := cli.writeNewline
-	if len(newline) > 0 {
-		err := connector.Write(newline)
+
+	noWaitGrace, noWait := command.GetNoWaitPrompt()
+	if noWait && noWaitGrace <= 0 {
+		err = stopObserve()
 		if err != nil {
-			return nil, fmt.Errorf("write error %w", err)
+			return nil, fmt.Errorf("output callback error %w", err)
 		}
+		return cmd.NewCmdRes(nil), nil
 	}
-
-	// read echo
-	var expCmdEcho expr.Expr
-	if cli.echoExprFormat != nil {
-		expCmdEcho = cli.echoExprFormat(command)
-	} else {
-		expCmdEcho = expr.NewSimpleExpr().FromPattern(fmt.Sprintf("%s%s", regexp.QuoteMeta(string(command.Value())), AnyNLPattern))
-	}
-
-	var buffer bytes.Buffer
-	cmdQuestions := command.GetQuestionExprs()
-
-	questions := []expr.Expr{cli.question}
-	if len(cmdQuestions) > 0 {
-		questions = append(cmdQuestions, questions...)
+	promptCtx := ctx
+	if promptTimeout := command.GetPromptTimeout(); promptTimeout > 0 {
+		newCtx, cancel := context.WithTimeout(ctx, promptTimeout)
+		promptCtx = newCtx
+		defer cancel()
 	}
-	checkExprs := []expr.NamedExpr{
-		{Name: echoExprName, Exprs: []expr.Expr{expCmdEcho}},
-		{Name: promptExprName, Exprs: []expr.Expr{cli.prompt}},
-		{Name: pagerExprName, Exprs: []expr.Expr{cli.pager}},
-		{Name: questionExprName, Exprs: questions},
+	if noWait {
+		newCtx, cancel := context.WithTimeout(promptCtx, noWaitGrace)
+		promptCtx = newCtx
+		defer cancel()
 	}
-	exprs := expr.NewSimpleExprListNamedOrdered(checkExprs)
 
-	exprsAdd, exprsAddMap := command.GetExprCallback()
-	for _, exprCB := range exprsAdd {
-		exprs.Add("cb", expr.NewSimpleExpr().FromPattern(exprCB))
-	}
-	cbLimit := 100
+	var buffer bytes.Buffer
+	expCmdEcho := exec.echo
+	exprs := exec.exprs
 	seenEcho := false
+	// until output is started, read timeout is the first byte timeout
+	waitFirstByte := firstByteTimeout > 0
+	maxOutput := command.GetMaxOutputBytes()
+	// matches output which exceeds limit, so it is not accumulated while the rest of output is skipped till prompt
+	outputLimit := &outputLimitExpr{}
+	truncated := false
+	if echoStripped(connector) {
+		seenEcho = true
+		exprs.Delete(echoExprName)
+		if waitFirstByte {
+			exprs.Add(firstByteExprName, firstByteExpr{})
+		}
+		if maxOutput > 0 {
+			exprs.Add(outputLimitExprName, outputLimit)
+		}
+	}
+	var matchedPrompt []byte
+	var promptGroups map[string][]byte
 	for { // pager loop
-		match, err := connector.ReadTo(ctx, exprs)
+		if maxOutput > 0 {
+			if buffer.Len() > maxOutput {
+				truncated = true
+				buffer.Truncate(maxOutput)
+			}
+			outputLimit.left = maxOutput - buffer.Len()
+		}
+		match, err := connector.ReadTo(promptCtx, exprs)
 		if err != nil {
+			if truncated {
+				// prompt is not found after output, so device state is unknown
+				logger.Debug("skip of output after limit failed", zap.Error(err))
+				return nil, outputTooLarge(buffer.Bytes(), maxOutput)
+			}
+			if noWait {
+				if res, ok := noWaitResult(ctx, connector, err, buffer.Bytes(), seenEcho, expCmdEcho); ok {
+					return res, nil
+				}
+			}
+			if outputErr := exec.timeoutError(err); outputErr != nil {
+				return nil, outputErr
+			}
+			if len(exec.dialog) > 0 && exec.dialogStep == len(exec.dialog) {
+				return nil, &device.DialogError{Step: exec.dialogStep, Steps: len(exec.dialog), Err: err}
+			}
 			var perr *streamer.ReadTimeoutException
 			if errors.As(err, &perr) {
-				// in some cases device messing up with output
-				outputErr := checkError(cli.error, perr.LastRead)
-				if outputErr != nil {
-					return nil, outputErr
-				}
+				return nil, &device.PromptTimeoutError{Res: partialResult(buffer.Bytes(), unreadOutput(ctx, connector, perr.LastRead), seenEcho, expCmdEcho, cmd.ResWithTruncated()), Err: err}
 			}
 			return nil, err
 		}
@@ -588,11 +890,25 @@ func GenericExecute(command cmd.Cmd, connector streamer.Connector, cli GenericCL
 		if matchName == echoExprName {
 			seenEcho = true
 			exprs.Delete(echoExprName)
+			if waitFirstByte {
+				exprs.Add(firstByteExprName, firstByteExpr{})
+			}
+			if maxOutput > 0 {
+				exprs.Add(outputLimitExprName, outputLimit)
+			}
 			continue
 		}
+		if waitFirstByte {
+			waitFirstByte = false
+			exprs.Delete(firstByteExprName)
+			connector.SetReadTimeout(idleTimeout)
+			if matchName == firstByteExprName {
+				continue
+			}
+		}
 		mbefore := match.GetBefore()
 		if !seenEcho {
-			if matchName == questionExprName { // caught question before echo
+			if matchName == questionExprName || matchName == cmdQuestionExprName { // caught question before echo
 				// check for echo, drop it and proceed with question
 				termParsedEcho, err := terminal.ParseDropLastReturn(mbefore)
 				if err != nil {
@@ -647,51 +963,50 @@ func GenericExecute(command cmd.Cmd, connector streamer.Connector, cli GenericCL
 			// delete echo
 			mbefore = termParsedEcho[mres.End:]
 		}
-		if matchName == promptExprName {
-			buffer.Write(mbefore)
-			if store, ok := match.GetMatchedGroups()["store"]; ok {
-				buffer.Write(store)
-			}
-			break
-		} else if matchName == pagerExprName { // next page
-			buffer.Write(mbefore)
-			if store, ok := match.GetMatchedGroups()["store"]; ok {
-				buffer.Write(store)
-			}
-			logger.Debug("auto answer to pager")
-			err = connector.Write([]byte(` `))
+		if matchName == promptExprName && cli.promptQuiet > 0 {
+			quiet, err := isQuietAfterPrompt(promptCtx, connector, match, cli.promptQuiet)
 			if err != nil {
-				return nil, fmt.Errorf("write error %w", err)
+				return nil, err
 			}
-		} else if matchName == questionExprName { // question
-			question := match.GetMatched()
-			logger.Debug("QuestionHandler question", zap.ByteString("question", question))
-			answer, err := command.QuestionHandler(question)
-			if err != nil {
-				if errors.Is(err, cmd.ErrNotFoundAnswer) {
-					return nil, device.ThrowQuestionException(question)
-				}
-				return nil, fmt.Errorf("QuestionHandler error %w", err)
-			}
-			logger.Debug("QuestionHandler answer", zap.ByteString("answer", answer))
-			err = connector.Write(answer)
-			if err != nil {
-				return nil, fmt.Errorf("write error %w", err)
-			}
-		} else if matchName == "cb" { // ExprCallback
-			if cbLimit == 0 { // reset cbLimit in other cases
-				return nil, fmt.Errorf("callback limit")
+			if !quiet {
+				logger.Debug("prompt inside output", zap.ByteString("prompt", match.GetMatched()))
+				buffer.Write(mbefore)
+				buffer.Write(match.GetMatched())
+				continue
 			}
-			cbLimit--
-			wr := exprsAddMap[exprsAdd[matchId-3]]
-			logger.Debug("write callback result")
-			err := connector.Write([]byte(wr))
-			if err != nil {
-				return nil, fmt.Errorf("write error %w", err)
+		}
+		if matchName == promptExprName {
+			if exec.dialogStep < len(exec.dialog) {
+				return nil, &device.DialogError{Step: exec.dialogStep, Steps: len(exec.dialog)}
 			}
-		} else {
+			matchedPrompt = match.GetMatched()
+			promptGroups = match.GetMatchedGroups()
+			buffer.Write(exec.promptOutput(match, mbefore))
+			break
+		} else if matchName == outputLimitExprName {
+			buffer.Write(mbefore)
+			continue
+		}
+		out, handled, err := exec.answer(ctx, match, matchName)
+		if err != nil {
+			return nil, err
+		}
+		if !handled {
 			panic("unknown option")
 		}
+		buffer.Write(out)
+	}
+
+	err = exec.afterPrompt(ctx, matchedPrompt)
+	if err != nil {
+		return nil, err
+	}
+	err = stopObserve()
+	if err != nil {
+		return nil, fmt.Errorf("output callback error %w", err)
+	}
+	if maxOutput > 0 && (truncated || buffer.Len() > maxOutput) {
+		return nil, outputTooLarge(buffer.Bytes(), maxOutput)
 	}
 
 	res := buffer.Bytes()
@@ -707,20 +1022,260 @@ func GenericExecute(command cmd.Cmd, connector streamer.Connector, cli GenericCL
 		fondErr = command.ErrorHandler(fondErr)
 	}
 
+	if cli.crOverwrite {
+		res = terminal.CollapseReturns(res)
+	}
 	strippedRes, err := terminal.ParseDropLastReturn(res)
 	if err != nil {
 		return nil, err
 	}
 	strippedRes = normalizeNewlines(strippedRes)
+	var resOpts []cmd.ResOption
+	if matchedPrompt != nil {
+		// prompt expressions usually match line breaks around prompt
+		resOpts = append(resOpts, cmd.ResWithPrompt(bytes.Trim(matchedPrompt, "\r\n"), namedGroups(promptGroups)))
+	}
+	if command.GetOutputChecksum() {
+		checksum := sha256.Sum256(strippedRes)
+		resOpts = append(resOpts, cmd.ResWithChecksum(checksum[:]))
+	}
+	if cli.warning != nil && fondErr == nil {
+		if warnings := findWarnings(cli.warning, strippedRes); len(warnings) > 0 {
+			resOpts = append(resOpts, cmd.ResWithWarnings(warnings))
+		}
+	}
+	var unexpectedErr error
+	if fondErr == nil {
+		unexpectedErr = checkErrorExprs(commandErrorExprs(command, cli), strippedRes)
+	}
 	status := 0
 	var errorRes []byte
-	if fondErr != nil {
+	if fondErr != nil || unexpectedErr != nil {
 		errorRes = strippedRes
 		strippedRes = []byte{}
 		status = 1
 	}
-	ret := cmd.NewCmdResFull(strippedRes, errorRes, status, nil)
-	return ret, nil
+	ret := cmd.NewCmdResFull(strippedRes, errorRes, status, nil, resOpts...)
+	return ret, unexpectedErr
+}
+
+// namedGroups drops unnamed groups which are stored by expr under empty name.
+func namedGroups(groups map[string][]byte) map[string][]byte {
+	res := make(map[string][]byte, len(groups))
+	for name, value := range groups {
+		if name != "" {
+			res[name] = value
+		}
+	}
+	return res
+}
+
+// firstByteExpr matches empty string at start of any non-empty data, so it reports that data is read
+// and leaves it in connector buffer.
+type firstByteExpr struct{}
+
+func (firstByteExpr) Match(data []byte) (*expr.MatchRes, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	return &expr.MatchRes{GroupDict: map[string][]byte{}}, true
+}
+
+func (firstByteExpr) Repr() string {
+	return "firstByte"
+}
+
+// isQuietAfterPrompt checks that matched prompt is not followed by any data during wait.
+// Data read during wait is left in connector buffer.
+func isQuietAfterPrompt(ctx context.Context, connector streamer.Connector, match streamer.ReadRes, wait time.Duration) (bool, error) {
+	if len(match.GetAfter()) > 0 {
+		return false, nil
+	}
+	prevTimeout := connector.SetReadTimeout(wait)
+	defer connector.SetReadTimeout(prevTimeout)
+	_, err := connector.ReadTo(ctx, firstByteExpr{})
+	if err != nil {
+		var perr *streamer.ReadTimeoutException
+		var eofErr *streamer.EOFException
+		if (errors.As(err, &perr) || errors.As(err, &eofErr)) && ctx.Err() == nil {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// outputLimitExpr matches end of data which is longer than left bytes.
+type outputLimitExpr struct {
+	left int
+}
+
+func (m *outputLimitExpr) Match(data []byte) (*expr.MatchRes, bool) {
+	if len(data) <= m.left {
+		return nil, false
+	}
+	return &expr.MatchRes{Start: len(data), End: len(data), GroupDict: map[string][]byte{}}, true
+}
+
+func (m *outputLimitExpr) Repr() string {
+	return fmt.Sprintf("outputLimit(%d)", m.left)
+}
+
+// outputTooLarge returns error with output truncated to limit.
+func outputTooLarge(output []byte, limit int) error {
+	output = output[:min(len(output), limit)]
+	return &device.OutputTooLargeError{Res: partialResult(output, nil, true, nil, cmd.ResWithTruncated()), Limit: limit}
+}
+
+// observeOutput passes output to command callback if connector supports it. Returned function must be called
+// to stop observing, it returns error if callback missed data.
+func observeOutput(connector streamer.Connector, command cmd.Cmd) func() error {
+	cb := command.GetOutputCallback()
+	observer, ok := connector.(streamer.OutputObserver)
+	if cb == nil || !ok {
+		return func() error { return nil }
+	}
+	mode, size := command.GetStreamBackpressure()
+	dispatcher := streamer.NewOutputDispatcher(cb, mode, size)
+	var pushErr atomic.Pointer[error]
+	prev := observer.SetOutputCallback(func(data []byte) {
+		if err := dispatcher.Push(data); err != nil {
+			pushErr.CompareAndSwap(nil, &err)
+		}
+	})
+	var once sync.Once
+	stop := func() error {
+		once.Do(func() {
+			observer.SetOutputCallback(prev)
+			dispatcher.Close()
+		})
+		if err := pushErr.Load(); err != nil {
+			return *err
+		}
+		return nil
+	}
+	return stop
+}
+
+func drainAfterPrompt(ctx context.Context, connector streamer.Connector, cli GenericCLI, logger *zap.Logger) error {
+	if cli.postPromptDrain <= 0 {
+		return nil
+	}
+	drainer, ok := connector.(streamer.Drainer)
+	if !ok {
+		return nil
+	}
+	data, err := drainer.Drain(ctx, cli.postPromptDrain)
+	if err != nil {
+		return fmt.Errorf("drain error %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	logger.Debug("drained after prompt", zap.ByteString("data", data))
+	if cli.postPromptCB != nil {
+		cli.postPromptCB(data)
+	}
+	return nil
+}
+
+// addSecrets passes secrets to connector for redaction in transcript.
+func addSecrets(connector streamer.Connector, secrets ...[]byte) {
+	if redactor, ok := connector.(streamer.SecretRedactor); ok {
+		redactor.AddSecrets(secrets...)
+	}
+}
+
+// echoStripped returns whether connector removes echo of command, so it must not be expected.
+func echoStripped(connector streamer.Connector) bool {
+	stripper, ok := connector.(streamer.EchoStripper)
+	return ok && stripper.EchoStripped()
+}
+
+// writeInput writes data at once or byte by byte if delay is set.
+func writeInput(ctx context.Context, connector streamer.Connector, delay time.Duration, data []byte) error {
+	if delay <= 0 {
+		return connector.Write(data)
+	}
+	for i := range data {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+		err := connector.Write(data[i : i+1])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripTrailingPagers removes pager which ends data.
+func stripTrailingPagers(data []byte, pagers []cmd.Pager) []byte {
+	for _, pager := range pagers {
+		trimmed := bytes.TrimRight(data, " \t")
+		mres, ok := pager.GetExpr().Match(trimmed)
+		if ok && mres.End == len(trimmed) {
+			return data[:mres.Start]
+		}
+	}
+	return data
+}
+
+// collapsePromptLines removes trailing lines of data equal to prompt along with preceding newlines.
+func collapsePromptLines(data, prompt []byte) []byte {
+	prompt = bytes.TrimSpace(prompt)
+	if len(prompt) == 0 {
+		return data
+	}
+	for {
+		trimmed := bytes.TrimRight(data, "\r\n")
+		idx := bytes.LastIndexByte(trimmed, '\n')
+		if !bytes.Equal(bytes.TrimSpace(trimmed[idx+1:]), prompt) {
+			return data
+		}
+		if idx == -1 {
+			return trimmed[:0]
+		}
+		data = bytes.TrimSuffix(trimmed[:idx], []byte("\r"))
+	}
+}
+
+// skipDuplicatePrompts consumes copies of prompt arriving within wait after the matched prompt.
+func skipDuplicatePrompts(ctx context.Context, connector streamer.Connector, prompt []byte, wait time.Duration, logger *zap.Logger) error {
+	prompt = bytes.TrimSpace(prompt)
+	if len(prompt) == 0 {
+		return nil
+	}
+	duplicate := expr.NewSimpleExpr().FromPattern(`\A\s*` + regexp.QuoteMeta(string(prompt)))
+	prevTimeout := connector.SetReadTimeout(wait)
+	defer connector.SetReadTimeout(prevTimeout)
+	for {
+		_, err := connector.ReadTo(ctx, duplicate)
+		if err != nil {
+			// output of command is already read, so it is not our business to report EOF
+			var perr *streamer.ReadTimeoutException
+			var eofErr *streamer.EOFException
+			if (errors.As(err, &perr) || errors.As(err, &eofErr)) && ctx.Err() == nil {
+				return nil
+			}
+			return err
+		}
+		logger.Debug("skip duplicate prompt", zap.ByteString("prompt", prompt))
+	}
+}
+
+func findWarnings(warningExpression expr.Expr, data []byte) [][]byte {
+	var res [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if _, ok := warningExpression.Match(line); ok {
+			res = append(res, line)
+		}
+	}
+	return res
 }
 
 func checkError(errorExpression expr.Expr, data []byte) error {
@@ -732,6 +1287,66 @@ func checkError(errorExpression expr.Expr, data []byte) error {
 	return nil
 }
 
+// commandErrorExprs returns expressions of unexpected errors for command, command overrides cli expressions.
+func commandErrorExprs(command cmd.Cmd, cli GenericCLI) []expr.Expr {
+	if cmdExprs, ok := command.GetErrorExprs(); ok {
+		return cmdExprs
+	}
+	return cli.errorExprs
+}
+
+// checkErrorExprs returns *device.ExecException for the first of exprs matching data.
+func checkErrorExprs(exprs []expr.Expr, data []byte) error {
+	for _, errExpr := range exprs {
+		if err := checkError(errExpr, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partialResult makes result of command from output read before prompt timeout and unread data after it, see unreadOutput.
+func partialResult(output, unread []byte, seenEcho bool, echo expr.Expr, opts ...cmd.ResOption) cmd.CmdRes {
+	if !seenEcho {
+		if mres, ok := echo.Match(unread); ok {
+			unread = unread[mres.End:]
+		}
+	}
+	res := append(append([]byte{}, output...), unread...)
+	if parsed, err := terminal.ParseDropLastReturn(res); err == nil {
+		res = parsed
+	}
+	return cmd.NewCmdResFull(normalizeNewlines(res), nil, 0, nil, opts...)
+}
+
+// noWaitResult returns output read by command without prompt if err means end of its grace period or connection.
+func noWaitResult(ctx context.Context, connector streamer.Connector, err error, output []byte, seenEcho bool, echo expr.Expr) (cmd.CmdRes, bool) {
+	var perr *streamer.ReadTimeoutException
+	if errors.As(err, &perr) {
+		return partialResult(output, unreadOutput(ctx, connector, perr.LastRead), seenEcho, echo), true
+	}
+	var eofErr *streamer.EOFException
+	if errors.As(err, &eofErr) {
+		return partialResult(output, unreadOutput(ctx, connector, eofErr.LastRead), seenEcho, echo), true
+	}
+	return nil, false
+}
+
+// unreadOutput returns data read by connector after the last match. Read error keeps only the last bytes of it
+// in lastRead, so data is taken from connector buffer if connector is Drainer.
+func unreadOutput(ctx context.Context, connector streamer.Connector, lastRead []byte) []byte {
+	drainer, ok := connector.(streamer.Drainer)
+	if !ok {
+		return lastRead
+	}
+	// ctx may be already done, buffered data is returned anyway
+	data, err := drainer.Drain(context.WithoutCancel(ctx), time.Millisecond)
+	if err != nil {
+		return lastRead
+	}
+	return data
+}
+
 func normalizeNewlines(data []byte) []byte {
 	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
 	data = bytes.ReplaceAll(data, []byte(" \n"), []byte("\n"))
@@ -1,17 +1,31 @@
 package genericcli
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	gmock "github.com/annetutil/gnetcli/pkg/testutils/mock"
 
 	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
 	"github.com/annetutil/gnetcli/pkg/device"
 	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/gerror"
 	"github.com/annetutil/gnetcli/pkg/streamer"
+	"github.com/annetutil/gnetcli/pkg/streamer/ssh"
+	"github.com/annetutil/gnetcli/pkg/trace"
 )
 
 const (
@@ -19,6 +33,12 @@ const (
 	croppedQuestion = `\[Y/N\]:$`
 )
 
+// deviceRes is result of command terminated by <device> prompt of newDevice.
+func deviceRes(output []byte) cmd.CmdRes {
+	prompt := []byte("<device>")
+	return cmd.NewCmdResFull(output, nil, 0, nil, cmd.ResWithPrompt(prompt, map[string][]byte{"prompt": prompt}))
+}
+
 func newDevice(questionExpression string, connector streamer.Connector, logger *zap.Logger) GenericDevice {
 	promptExpression := `(\r\n|^)(?P<prompt>(<\w+>))$`
 	errorExpression := `(\r\n|^)Error: .+$`
@@ -32,6 +52,28 @@ func newDevice(questionExpression string, connector streamer.Connector, logger *
 	return MakeGenericDevice(cli, connector, WithDevLogger(logger))
 }
 
+func TestRunBatchErrorExpr(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithPrompt([]byte("\r\n<device>")),
+		streamer.RecorderWithResponse(`bad\n`, []byte("\r\n% Error: invalid input\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := device.RunBatch(context.Background(), &dev, cmd.NewCmdList([]string{"one", "bad", "two"}))
+	var batchErr *device.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 1, batchErr.Index)
+	require.ErrorIs(t, err, &device.ExecException{})
+	require.Len(t, res, 2)
+	require.Equal(t, [][]byte{[]byte("one"), []byte("\n"), []byte("bad"), []byte("\n")}, rec.Writes())
+}
+
 func TestQuestionWithoutAnswer(t *testing.T) {
 	logConfig := zap.NewDevelopmentConfig()
 	logger := zap.Must(logConfig.Build())
@@ -87,7 +129,7 @@ func TestQuestionCmdOverlap(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil)})
 }
 
 func TestQuestionWithAnswer(t *testing.T) {
@@ -118,7 +160,7 @@ func TestQuestionWithAnswer(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil)})
 }
 
 func TestMultipleQuestionsWithAnswer(t *testing.T) {
@@ -154,7 +196,91 @@ func TestMultipleQuestionsWithAnswer(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil)})
+}
+
+func TestAnswerPriority(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("save\n"),
+			gmock.SendEcho("save\r\n"),
+			gmock.Send("Save changes? [y/n]:"),
+			gmock.Expect("y\n"),
+			gmock.Send("<device>"),
+			gmock.Expect("save\n"),
+			gmock.SendEcho("save\r\n"),
+			gmock.Send("Save changes? [y/n]:"),
+			gmock.Expect("y\n"),
+			gmock.Send("<device>"),
+			gmock.Expect("save\n"),
+			gmock.SendEcho("save\r\n"),
+			gmock.Send("Save changes? [y/n]:"),
+			gmock.Expect("y\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{
+		// higher priority wins
+		cmd.NewCmd("save",
+			cmd.WithAnswer(`/Save changes\? \[y\/n\]:/`, "n", 0),
+			cmd.WithAnswer(`/\[y\/n\]:/`, "y", 10),
+		),
+		// longer match wins on equal priority
+		cmd.NewCmd("save",
+			cmd.WithAnswer(`/\[y\/n\]:/`, "n", 0),
+			cmd.WithAnswer(`/Save changes\? \[y\/n\]:/`, "y", 0),
+		),
+		// first added answer wins on equal priority and match
+		cmd.NewCmd("save",
+			cmd.WithAnswer(`/\[y\/n\]:/`, "y", 0),
+			cmd.WithAnswer(`/\[y\/n\]:/`, "n", 0),
+		),
+	}
+
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{deviceRes(nil), deviceRes(nil), deviceRes(nil)}, cmdRes)
+}
+
+// strictAnswerCmd fails if answer is chosen again by question text.
+type strictAnswerCmd struct {
+	cmd.Cmd
+}
+
+func (m strictAnswerCmd) QuestionHandler(question []byte) ([]byte, error) {
+	return nil, fmt.Errorf("answer to %q is chosen twice", question)
+}
+
+func TestAnswerChosenOnce(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`save\n`, []byte("Save changes? [y/n]:")),
+		streamer.RecorderWithResponse(`y\n`, []byte("saved\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(strictAnswerCmd{cmd.NewCmd("save",
+		cmd.WithAnswer(`/Save changes\? \[y\/n\]:$/`, "y", 0),
+		cmd.WithAnswer(`/Discard changes\? \[y\/n\]:$/`, "n", 10),
+	)})
+	require.NoError(t, err)
+	// answer is echoed
+	require.Equal(t, "y\nsaved", string(res.Output()))
 }
 
 func TestQuestionCmdAnswerDontMatchDeviceQuestion(t *testing.T) {
@@ -183,7 +309,7 @@ func TestQuestionCmdAnswerDontMatchDeviceQuestion(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil)})
 }
 
 func TestEscTermInEcho(t *testing.T) {
@@ -211,7 +337,7 @@ func TestEscTermInEcho(t *testing.T) {
 	}, actions, []cmd.Cmd{cmd.NewCmd("ip community-filter basic TEST index 10 permit 10000:999"), cmd.NewCmd("quit")}, logger)
 
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes([]byte("olo")), cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes([]byte("olo")), deviceRes(nil)})
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 }
@@ -240,7 +366,7 @@ func TestEscTermInEchoEmptyCmd(t *testing.T) {
 	}, actions, []cmd.Cmd{cmd.NewCmd("ip community-filter basic TEST index 10 permit 10000:999"), cmd.NewCmd("quit")}, logger)
 
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil), cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil), deviceRes(nil)})
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 }
@@ -276,7 +402,7 @@ func TestLoginCallback(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes([]byte("test ok"))})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes([]byte("test ok"))})
 }
 
 func TestQuestionWithAnswerNotSendNL(t *testing.T) {
@@ -307,5 +433,1500 @@ func TestQuestionWithAnswerNotSendNL(t *testing.T) {
 	require.NoError(t, err)
 	require.NoError(t, serverErr)
 	require.NoError(t, resErr)
-	require.Equal(t, cmdRes, []cmd.CmdRes{cmd.NewCmdRes(nil)})
+	require.Equal(t, cmdRes, []cmd.CmdRes{deviceRes(nil)})
+}
+
+func TestPostPromptDrain(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("test ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Sleep(1),
+			gmock.Send("\r\n%LINK-3-UPDOWN: Interface Eth1, changed state to up\r\n<device>"),
+			gmock.Expect("test2\n"),
+			gmock.SendEcho("test2\r\n"),
+			gmock.Send("test2 ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test"), cmd.NewCmd("test2")}
+	var drained []byte
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		promptExpression := `(\r\n|^)(?P<prompt>(<\w+>))$`
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(promptExpression),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)Error: .+$`),
+			WithPostPromptDrain(1500*time.Millisecond),
+			WithPostPromptDrainCB(func(data []byte) {
+				drained = append(drained, data...)
+			}),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{deviceRes([]byte("test ok")), deviceRes([]byte("test2 ok"))}, cmdRes)
+	require.Equal(t, "\r\n%LINK-3-UPDOWN: Interface Eth1, changed state to up\r\n<device>", string(drained))
+}
+
+func TestOutputChecksum(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("test ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test", cmd.WithOutputChecksum())}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Len(t, cmdRes, 1)
+	checksum := sha256.Sum256([]byte("test ok"))
+	require.Equal(t, checksum[:], cmdRes[0].Checksum())
+	require.False(t, cmdRes[0].Truncated())
+}
+
+func TestCollapsePromptLines(t *testing.T) {
+	require.Equal(t, "test ok", string(collapsePromptLines([]byte("test ok\r\n<device>\r\n<device>"), []byte("\r\n<device>"))))
+	require.Equal(t, "test ok\r\n", string(collapsePromptLines([]byte("test ok\r\n"), []byte("<device>"))))
+	require.Equal(t, "", string(collapsePromptLines([]byte("<device>"), []byte("<device>"))))
+	require.Equal(t, "<device> show\r\n", string(collapsePromptLines([]byte("<device> show\r\n"), []byte("<device>"))))
+}
+
+func TestCollapsePrompts(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			// prompt doubled in the same packet
+			gmock.Send("test ok\r\n<device>\r\n<device>"),
+			gmock.Expect("test2\n"),
+			gmock.SendEcho("test2\r\n"),
+			gmock.Send("test2 ok\r\n<device>"),
+			// late duplicate
+			gmock.Sleep(1),
+			gmock.Send("\r\n<device>"),
+			gmock.Expect("test3\n"),
+			gmock.SendEcho("test3\r\n"),
+			gmock.Send("test3 ok\r\n<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test"), cmd.NewCmd("test2"), cmd.NewCmd("test3")}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		promptExpression := `(\r\n|^)(?P<prompt>(<\w+>))$`
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(promptExpression),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)Error: .+$`),
+			WithCollapsePrompts(1500*time.Millisecond),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{
+		deviceRes([]byte("test ok")),
+		deviceRes([]byte("test2 ok")),
+		deviceRes([]byte("test3 ok")),
+	}, cmdRes)
+}
+
+func TestWarnings(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("% Warning: something is odd\r\ntest ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test")}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		promptExpression := `(\r\n|^)(?P<prompt>(<\w+>))$`
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(promptExpression),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+			WithWarning(expr.NewSimpleExpr().FromPattern(`^% Warning: `)),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Len(t, cmdRes, 1)
+	require.Equal(t, 0, cmdRes[0].Status())
+	require.Equal(t, "% Warning: something is odd\ntest ok", string(cmdRes[0].Output()))
+	require.Equal(t, [][]byte{[]byte("% Warning: something is odd")}, cmdRes[0].Warnings())
+}
+
+func TestTranscriptReplay(t *testing.T) {
+	logger := zap.NewNop()
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("test ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+	makeDev := func(connector streamer.Connector) device.Device {
+		promptExpression := `(\r\n|^)(?P<prompt>(<\w+>))$`
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(promptExpression),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)Error: .+$`),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}
+
+	transcript := bytes.Buffer{}
+	tw := trace.NewTranscriptWriter(&transcript)
+	cmds := []cmd.Cmd{cmd.NewCmd("test")}
+	recorded, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		connector.SetTrace(tw.Add)
+		return makeDev(connector)
+	}, gmock.ConcatMultipleSlices(dialog), cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.NoError(t, tw.Err())
+
+	replay, err := streamer.NewReplay(&transcript)
+	require.NoError(t, err)
+	dev := makeDev(replay)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmds[0])
+	require.NoError(t, err)
+	require.Equal(t, recorded[0], res)
+
+	_, err = dev.Execute(cmd.NewCmd("other"))
+	require.ErrorIs(t, err, streamer.ErrReplayMismatch)
+}
+
+func TestOutputCallback(t *testing.T) {
+	logger := zap.Must(zap.NewDevelopmentConfig().Build())
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("test ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	var streamed []byte
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test", cmd.WithOutputCallback(func(data []byte) {
+		streamed = append(streamed, data...)
+	}))}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{deviceRes([]byte("test ok"))}, cmdRes)
+	require.Equal(t, "test\r\ntest ok\r\n<device>", string(streamed))
+}
+
+func TestCmdPager(t *testing.T) {
+	logger := zap.NewNop()
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("line1\r\n--More--"),
+			gmock.Expect(" "),
+			gmock.Send("\rline2\r\n---(more)---"),
+			gmock.Expect("q"),
+			gmock.Send("\rline3\r\n--More--\r\n<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test",
+		cmd.WithPager(expr.NewSimpleExpr().FromPattern(`--More--`), []byte(" ")),
+		cmd.WithPager(expr.NewSimpleExpr().FromPattern(`---\(more\)---`), []byte("q")),
+	)}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Len(t, cmdRes, 1)
+	require.Equal(t, "line1\nline2\nline3\n", string(cmdRes[0].Output()))
+}
+
+func TestKeystrokeDelay(t *testing.T) {
+	logger := zap.NewNop()
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("Continue? [y/n]"),
+			gmock.Expect("y"),
+			gmock.Send("\r\ntest ok\r\n<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test",
+		cmd.WithKeystrokeDelay(10*time.Millisecond),
+		cmd.WithAnswers(cmd.NewAnswer("Continue? [y/n]", "y", true)),
+	)}
+	started := time.Now()
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Len(t, cmdRes, 1)
+	require.Equal(t, "\ntest ok", string(cmdRes[0].Output()))
+	require.GreaterOrEqual(t, time.Since(started), 30*time.Millisecond)
+}
+
+type writeRecorder struct {
+	streamer.Connector
+	written [][]byte
+}
+
+func (m *writeRecorder) Write(data []byte) error {
+	m.written = append(m.written, data)
+	return nil
+}
+
+func TestWriteInputDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	conn := &writeRecorder{}
+	err := writeInput(ctx, conn, time.Hour, []byte("test"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, [][]byte{[]byte("t")}, conn.written)
+}
+
+func TestDialog(t *testing.T) {
+	logger := zap.NewNop()
+	dialog := []cmd.QA{
+		{Expr: expr.NewSimpleExpr().FromPattern(`Destination filename \[startup-config\]\?`), Answer: []byte("\n")},
+		{Expr: expr.NewSimpleExpr().FromPattern(`Overwrite\? \[confirm\]`), Answer: []byte("y")},
+	}
+	makeDev := func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}
+
+	actions := []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("copy\n"),
+		gmock.SendEcho("copy\r\n"),
+		gmock.Send("Destination filename [startup-config]?"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nOverwrite? [confirm]"),
+		gmock.Expect("y"),
+		gmock.Send("\r\n[OK]\r\n<device>"),
+		gmock.Close(),
+	}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(makeDev, actions, []cmd.Cmd{cmd.NewCmd("copy", cmd.WithDialog(dialog))}, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Len(t, cmdRes, 1)
+	require.Equal(t, "\n[OK]", string(cmdRes[0].Output()))
+
+	// second question must not be answered before the first one
+	actions = []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("copy\n"),
+		gmock.SendEcho("copy\r\n"),
+		gmock.Send("Overwrite? [confirm]"),
+		gmock.Send("\r\n<device>"),
+		gmock.Close(),
+	}
+	_, resErr, _, err = gmock.RunCmd(makeDev, actions, []cmd.Cmd{cmd.NewCmd("copy", cmd.WithDialog(dialog))}, logger)
+	require.NoError(t, err)
+	var dialogErr *device.DialogError
+	require.ErrorAs(t, resErr, &dialogErr)
+	require.Equal(t, 0, dialogErr.Step)
+
+	// prompt is not returned after dialog
+	actions = []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("copy\n"),
+		gmock.SendEcho("copy\r\n"),
+		gmock.Send("Destination filename [startup-config]?"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nOverwrite? [confirm]"),
+		gmock.Expect("y"),
+		gmock.Close(),
+	}
+	_, resErr, _, err = gmock.RunCmd(makeDev, actions, []cmd.Cmd{cmd.NewCmd("copy", cmd.WithDialog(dialog))}, logger)
+	require.NoError(t, err)
+	require.ErrorAs(t, resErr, &dialogErr)
+	require.Equal(t, 2, dialogErr.Step)
+}
+
+func TestErrorExprs(t *testing.T) {
+	logger := zap.NewNop()
+	actions := []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("test\n"),
+		gmock.SendEcho("test\r\n"),
+		gmock.Send("syntax error, expecting <command>\r\n"),
+		gmock.Send("<device>"),
+		gmock.Close(),
+	}
+	makeDev := func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+			WithErrorExprs(expr.NewSimpleExpr().FromPattern(`syntax error`)),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}
+
+	_, resErr, serverErr, err := gmock.RunCmd(makeDev, actions, []cmd.Cmd{cmd.NewCmd("test")}, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	var execErr *device.ExecException
+	require.ErrorAs(t, resErr, &execErr)
+	require.Equal(t, "syntax error", execErr.Data)
+
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(makeDev, actions, []cmd.Cmd{cmd.NewCmd("test", cmd.WithoutErrorExprs())}, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, 0, cmdRes[0].Status())
+}
+
+func TestExecuteStreamErrorExprs(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithPrompt([]byte("\r\n<device>")),
+		streamer.RecorderWithResponse(`test\n`, []byte("\r\nsyntax error, expecting <command>\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithErrorExprs(expr.NewSimpleExpr().FromPattern(`syntax error`)),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+
+	reader, err := dev.ExecuteStream(cmd.NewCmd("test"))
+	require.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	var execErr *device.ExecException
+	require.ErrorAs(t, err, &execErr)
+	require.Equal(t, "syntax error", execErr.Data)
+	require.NoError(t, reader.Close())
+
+	reader, err = dev.ExecuteStream(cmd.NewCmd("test", cmd.WithoutErrorExprs()))
+	require.NoError(t, err)
+	out, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "syntax error")
+	require.NoError(t, reader.Close())
+}
+
+func TestExecuteStreamUnsupported(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	written := len(rec.Writes())
+
+	for _, opt := range []cmd.CmdOption{
+		cmd.NoWaitPrompt(),
+		cmd.WithNoWaitGrace(time.Second),
+		cmd.WithPromptTimeout(time.Second),
+		cmd.WithFirstByteTimeout(time.Second),
+		cmd.WithIdleTimeout(time.Second),
+	} {
+		_, err := dev.ExecuteStream(cmd.NewCmd("test", opt))
+		require.ErrorContains(t, err, "is not supported in stream mode")
+	}
+	// command is not written to device
+	require.Len(t, rec.Writes(), written)
+}
+
+func TestExecuteStreamConfigMode(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithPrompt([]byte("\r\n<device>")),
+		streamer.RecorderWithResponse(`interface\n`, []byte("\r\n% Incomplete command.\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithConfigModeCommands("configure terminal", "end", expr.NewSimpleExpr().FromPattern(`% Incomplete command`)),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	require.NoError(t, dev.EnterConfigMode(context.Background()))
+
+	reader, err := dev.ExecuteStream(cmd.NewCmd("interface"))
+	require.NoError(t, err)
+	_, err = io.ReadAll(reader)
+	var execErr *device.ExecException
+	require.ErrorAs(t, err, &execErr)
+	require.NoError(t, reader.Close())
+}
+
+func TestRecorderDryRun(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithPrompt([]byte("\r\n<device>")),
+		streamer.RecorderWithResponse(`reboot\n`, []byte("Continue? [y/n]")),
+		streamer.RecorderWithResponse(`y`, []byte("\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("interface eth0"))
+	require.NoError(t, err)
+	require.Empty(t, res.Output())
+	_, err = dev.Execute(cmd.NewCmd("reboot", cmd.WithAnswers(cmd.NewAnswer("Continue? [y/n]", "y", true))))
+	require.NoError(t, err)
+
+	expected := [][]byte{[]byte("interface eth0"), []byte("\n"), []byte("reboot"), []byte("\n"), []byte("y")}
+	require.Equal(t, expected, rec.Writes())
+}
+
+func TestReplayFixture(t *testing.T) {
+	fixture := `# greeting
+{"read": "<device>"}
+{"write_re": "show interface \\w+", "read": "show interface eth0"}
+{"write": "\n", "read": "\r\neth0 is up\r\n<device>"}
+`
+	replay, err := streamer.NewReplayFixture(strings.NewReader(fixture))
+	require.NoError(t, err)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, replay)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show interface eth0"))
+	require.NoError(t, err)
+	require.Equal(t, "eth0 is up", string(res.Output()))
+	require.NoError(t, replay.Verify())
+
+	replay, err = streamer.NewReplayFixture(strings.NewReader(fixture))
+	require.NoError(t, err)
+	dev = MakeGenericDevice(cli, replay)
+	require.NoError(t, dev.Connect(context.Background()))
+	_, err = dev.Execute(cmd.NewCmd("show version"))
+	require.ErrorIs(t, err, streamer.ErrReplayMismatch)
+	require.ErrorIs(t, replay.Verify(), streamer.ErrReplayMismatch)
+}
+
+func TestExecuteStream(t *testing.T) {
+	var page1, page2 strings.Builder
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&page1, "line %d\r\n", i)
+		fmt.Fprintf(&page2, "line %d\r\n", i+100)
+	}
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithPrompt([]byte("\r\n<device>")),
+		streamer.RecorderWithResponse(`show tech\n`, []byte(page1.String()+"--More--")),
+		streamer.RecorderWithResponse(` `, []byte("\r"+page2.String()+"<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithStreamWindow(64),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	showTech := cmd.NewCmd("show tech", cmd.WithPager(expr.NewSimpleExpr().FromPattern(`--More--`), []byte(" ")))
+
+	reader, err := dev.ExecuteStream(showTech)
+	require.NoError(t, err)
+	var out bytes.Buffer
+	buf := make([]byte, 1024)
+	for {
+		n, err := reader.Read(buf)
+		out.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.NoError(t, reader.Close())
+	// prompt expression consumes the last newline
+	expected := strings.TrimSuffix(strings.ReplaceAll(page1.String()+page2.String(), "\r\n", "\n"), "\n")
+	require.Equal(t, expected, out.String())
+
+	// closing unread stream skips the rest of output
+	reader, err = dev.ExecuteStream(showTech)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	res, err := dev.Execute(cmd.NewCmd("interface eth0"))
+	require.NoError(t, err)
+	require.Empty(t, res.Output())
+}
+
+func TestWindowExpr(t *testing.T) {
+	window := &windowExpr{exprs: expr.NewSimpleExpr().FromPattern(`<\w+>$`), window: 8, flush: true}
+	mRes, ok := window.Match([]byte("line1\nline2\nline3"))
+	require.True(t, ok)
+	require.Equal(t, flushPatternNo, mRes.PatternNo)
+	require.Equal(t, 6, mRes.Start)
+
+	mRes, ok = window.Match([]byte("line1\n<device>"))
+	require.True(t, ok)
+	require.Equal(t, 0, mRes.PatternNo)
+
+	_, ok = window.Match([]byte("line1"))
+	require.False(t, ok)
+}
+
+type echoStrippingRecorder struct {
+	*streamer.Recorder
+}
+
+func (m echoStrippingRecorder) EchoStripped() bool {
+	return true
+}
+
+func TestEchoStripped(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithResponse(`show version\n`, []byte("version 1\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, echoStrippingRecorder{rec})
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show version"))
+	require.NoError(t, err)
+	require.Equal(t, "version 1", string(res.Output()))
+}
+
+// runEnable calls Enable on device connected to mock server.
+func runEnable(t *testing.T, dialog []gmock.Action, creds credentials.Credentials, password credentials.Secret) error {
+	logger := zap.NewNop()
+	server, err := gmock.NewMockSSHServer(dialog, gmock.WithLogger(logger))
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run(context.Background())
+	}()
+	host, port := server.GetAddress()
+	connector := ssh.NewStreamer(host, creds, ssh.WithPort(port), ssh.WithLogger(logger))
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>[\w\-]+)(>|#)$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithEnable("enable", `Password:\s?$`, expr.NewSimpleExpr().FromPattern(`#$`)),
+	)
+	dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dev.Connect(ctx))
+	enableErr := dev.Enable(ctx, password)
+	dev.Close()
+	require.NoError(t, <-serverErr)
+	return enableErr
+}
+
+func TestEnable(t *testing.T) {
+	creds := credentials.NewSimpleCredentials(credentials.WithEnableSecret("secret"))
+	err := runEnable(t, []gmock.Action{
+		gmock.Send("switch>"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch>"),
+		gmock.Expect("enable\n"),
+		gmock.SendEcho("enable\r\n"),
+		gmock.Send("Password: "),
+		gmock.Expect("secret\n"),
+		gmock.Send("\r\nswitch#"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch#"),
+		gmock.Close(),
+	}, creds, "")
+	require.NoError(t, err)
+
+	// already privileged
+	err = runEnable(t, []gmock.Action{
+		gmock.Send("switch#"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch#"),
+		gmock.Close(),
+	}, creds, "")
+	require.NoError(t, err)
+
+	err = runEnable(t, []gmock.Action{
+		gmock.Send("switch>"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch>"),
+		gmock.Expect("enable\n"),
+		gmock.SendEcho("enable\r\n"),
+		gmock.Send("Password: "),
+		gmock.Expect("wrong\n"),
+		gmock.Send("\r\n% Bad secrets\r\n\r\nswitch>"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch>"),
+		gmock.Close(),
+	}, creds, "wrong")
+	require.ErrorIs(t, err, ErrEnableFailed)
+}
+
+func TestEnableRedaction(t *testing.T) {
+	server, err := gmock.NewMockSSHServer([]gmock.Action{
+		gmock.Send("switch>"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch>"),
+		gmock.Expect("enable\n"),
+		gmock.SendEcho("enable\r\n"),
+		gmock.Send("Password: "),
+		gmock.Expect("topsecret\n"),
+		gmock.Send("\r\nswitch#"),
+		gmock.Expect("\n"),
+		gmock.Send("\r\nswitch#"),
+		gmock.Close(),
+	}, gmock.WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run(context.Background())
+	}()
+	host, port := server.GetAddress()
+	var transcript bytes.Buffer
+	creds := credentials.NewSimpleCredentials(credentials.WithEnableSecret("topsecret"))
+	connector := ssh.NewStreamer(host, creds, ssh.WithPort(port), ssh.WithLogger(zap.NewNop()),
+		ssh.WithTranscript(&transcript, trace.TranscriptWithRedaction()))
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>[\w\-]+)(>|#)$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithEnable("enable", `Password:\s?$`, expr.NewSimpleExpr().FromPattern(`#$`)),
+	)
+	core, logs := observer.New(zapcore.DebugLevel)
+	dev := MakeGenericDevice(cli, connector, WithDevLogger(zap.New(core)))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dev.Connect(ctx))
+	require.NoError(t, dev.Enable(ctx, ""))
+	dev.Close()
+	require.NoError(t, <-serverErr)
+
+	require.NotContains(t, transcript.String(), "topsecret")
+	for _, entry := range logs.All() {
+		require.NotContains(t, fmt.Sprint(entry.ContextMap()), "topsecret")
+	}
+}
+
+func TestConfigMode(t *testing.T) {
+	logger := zap.NewNop()
+	newConfigDevice := func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>[\w\-]+(\(config[^)]*\))?)#$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+			WithConfigModeCommands("configure terminal", "end",
+				expr.NewSimpleExpr().FromPattern(`Configuration mode is locked`),
+				expr.NewSimpleExpr().FromPattern(`(\r\n|^)% Incomplete command`)),
+		)
+		dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}
+	runBatch := func(dialog []gmock.Action, commands []cmd.Cmd) ([]cmd.CmdRes, error) {
+		server, err := gmock.NewMockSSHServer(dialog, gmock.WithLogger(logger))
+		require.NoError(t, err)
+		serverErr := make(chan error, 1)
+		go func() {
+			serverErr <- server.Run(context.Background())
+		}()
+		host, port := server.GetAddress()
+		dev := newConfigDevice(ssh.NewStreamer(host, credentials.NewSimpleCredentials(), ssh.WithPort(port), ssh.WithLogger(logger)))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, dev.Connect(ctx))
+		res, batchErr := device.RunBatch(ctx, dev, commands, device.WithConfigMode())
+		dev.Close()
+		require.NoError(t, <-serverErr)
+		return res, batchErr
+	}
+
+	res, err := runBatch([]gmock.Action{
+		gmock.Send("switch#"),
+		gmock.Expect("configure terminal\n"),
+		gmock.SendEcho("configure terminal\r\n"),
+		gmock.Send("switch(config)#"),
+		gmock.Expect("hostname sw1\n"),
+		gmock.SendEcho("hostname sw1\r\n"),
+		gmock.Send("sw1(config)#"),
+		gmock.Expect("interface\n"),
+		gmock.SendEcho("interface\r\n"),
+		gmock.Send("% Incomplete command.\r\n\r\nsw1(config)#"),
+		gmock.Expect("end\n"),
+		gmock.SendEcho("end\r\n"),
+		gmock.Send("sw1#"),
+		gmock.Close(),
+	}, cmd.NewCmdList([]string{"hostname sw1", "interface", "description"}))
+	var batchErr *device.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, 1, batchErr.Index)
+	require.Len(t, res, 2)
+
+	res, err = runBatch([]gmock.Action{
+		gmock.Send("switch#"),
+		gmock.Expect("configure terminal\n"),
+		gmock.SendEcho("configure terminal\r\n"),
+		gmock.Send("Configuration mode is locked by process '3' user 'admin'.\r\nswitch#"),
+		gmock.Close(),
+	}, cmd.NewCmdList([]string{"hostname sw1"}))
+	var configErr *device.ConfigModeError
+	require.ErrorAs(t, err, &configErr)
+	require.Empty(t, res)
+}
+
+func TestCROverwrite(t *testing.T) {
+	// device rewrites only the counter
+	output := "Sector 1 of 3\rSector 2\rSector 3\r\nCopy 10%\rCopy 55%\rCopy 100%\r\nDone\r\n<device>"
+	for _, overwrite := range []bool{false, true} {
+		rec := streamer.NewRecorder(
+			streamer.RecorderWithGreeting([]byte("<device>")),
+			streamer.RecorderWithEcho(),
+			streamer.RecorderWithResponse(`copy\n`, []byte(output)),
+		)
+		opts := []GenericCLIOption{}
+		if overwrite {
+			opts = append(opts, WithCROverwrite())
+		}
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+			opts...,
+		)
+		dev := MakeGenericDevice(cli, rec)
+		require.NoError(t, dev.Connect(context.Background()))
+		res, err := dev.Execute(cmd.NewCmd("copy"))
+		require.NoError(t, err)
+		if overwrite {
+			require.Equal(t, "Sector 3 of 3\nCopy 100%\nDone", string(res.Output()))
+		} else {
+			require.Equal(t, "Sector 3\nCopy 100%\nDone", string(res.Output()))
+		}
+	}
+}
+
+func TestPromptInResult(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`show\n`, []byte("ok\r\n[~device-ospf]")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>[<\[]~?(?P<host>[\w\-]+?)(-(?P<view>\w+))?[>\]])$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+	require.Equal(t, "[~device-ospf]", string(res.Prompt()))
+	require.Equal(t, "device", string(res.PromptGroups()["host"]))
+	require.Equal(t, "ospf", string(res.PromptGroups()["view"]))
+}
+
+func TestLoginBanner(t *testing.T) {
+	logger := zap.NewNop()
+	dialog := [][]gmock.Action{
+		{
+			gmock.Send("Authorized access only\r\n<device>"),
+			gmock.Send(" logs are monitored\r\nLast login: Mon Oct 12 10:00:00\r\n<device>"),
+			gmock.Expect("test\n"),
+			gmock.SendEcho("test\r\n"),
+			gmock.Send("test ok\r\n"),
+			gmock.Send("<device>"),
+			gmock.Close(),
+		},
+	}
+
+	actions := gmock.ConcatMultipleSlices(dialog)
+	cmds := []cmd.Cmd{cmd.NewCmd("test")}
+	var dev GenericDevice
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		cli := MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+			WithLoginBanner(expr.NewSimpleExpr().FromPattern(`Last login: .+\r\n`)),
+		)
+		dev = MakeGenericDevice(cli, connector, WithDevLogger(logger))
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{deviceRes([]byte("test ok"))}, cmdRes)
+	require.Equal(t, "Authorized access only\n<device> logs are monitored\nLast login: Mon Oct 12 10:00:00", string(dev.Banner()))
+}
+
+func TestLoginBannerManualAuth(t *testing.T) {
+	fixture := `{"read": "Password:"}
+{"write": "secret\n", "read": "\r\nAuthorized access only\r\n<device>"}
+{"read": " logs are monitored\r\nLast login: Mon Oct 12 10:00:00\r\n<device>"}`
+	creds := credentials.NewSimpleCredentials(credentials.WithPasswords([]credentials.Secret{"secret"}))
+	replay, err := streamer.NewReplayFixture(strings.NewReader(fixture),
+		streamer.ReplayWithAutoLogin(false), streamer.ReplayWithCredentials(creds))
+	require.NoError(t, err)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithPasswordPrompt(expr.NewSimpleExprLast200().FromPattern(`Password:$`)),
+		WithLoginBanner(expr.NewSimpleExpr().FromPattern(`Last login: .+\r\n`)),
+	)
+	dev := MakeGenericDevice(cli, replay, WithDevLogger(zap.NewNop()))
+	require.NoError(t, dev.Connect(context.Background()))
+	require.NoError(t, dev.connectCLI(context.Background()))
+	require.NoError(t, replay.Verify())
+	require.Equal(t, "Authorized access only\n<device> logs are monitored\nLast login: Mon Oct 12 10:00:00", string(dev.Banner()))
+}
+
+func TestExpectedPrompt(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`switchto vdc two\n`, []byte("switched\r\ntwo#")),
+		streamer.RecorderWithResponse(`show\n`, []byte("ok\r\ntwo#")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	vdcPrompt := expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>\w+#)$`)
+	res, err := dev.Execute(cmd.NewCmd("switchto vdc two", cmd.WithExpectedPrompt(vdcPrompt)))
+	require.NoError(t, err)
+	require.Equal(t, "switched", string(res.Output()))
+	require.Equal(t, "two#", string(res.Prompt()))
+	res, err = dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+	require.Equal(t, vdcPrompt, dev.GetPrompt())
+}
+
+func TestPromptTimeout(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`ping\n`, []byte("reply 1\r\nreply 2\r\n")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	started := time.Now()
+	_, err := dev.Execute(cmd.NewCmd("ping", cmd.WithPromptTimeout(100*time.Millisecond)))
+	require.Less(t, time.Since(started), 5*time.Second)
+	require.ErrorIs(t, err, device.ErrPromptTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	var promptErr *device.PromptTimeoutError
+	require.ErrorAs(t, err, &promptErr)
+	require.Equal(t, "reply 1\nreply 2\n", string(promptErr.Res.Output()))
+	require.True(t, promptErr.Res.Truncated())
+}
+
+func TestPromptTimeoutLongOutput(t *testing.T) {
+	output := strings.Repeat("reply line\r\n", 1000)
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`ping\n`, []byte(output)),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	_, err := dev.Execute(cmd.NewCmd("ping", cmd.WithPromptTimeout(100*time.Millisecond)))
+	var promptErr *device.PromptTimeoutError
+	require.ErrorAs(t, err, &promptErr)
+	require.Greater(t, len(output), 4096)
+	require.Equal(t, strings.Repeat("reply line\n", 1000), string(promptErr.Res.Output()))
+	require.True(t, promptErr.Res.Truncated())
+}
+
+func TestFirstByteTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	slowStart := gmock.ConcatMultipleSlices([][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("show\n"),
+			gmock.SendEcho("show\r\n"),
+			gmock.Sleep(1),
+			gmock.Send("line 1\r\n"),
+			gmock.Send("line 2\r\n<device>"),
+			gmock.Close(),
+		},
+	})
+	// slow start doesn't trip idle timeout
+	cmds := []cmd.Cmd{cmd.NewCmd("show", cmd.WithFirstByteTimeout(3*time.Second), cmd.WithIdleTimeout(300*time.Millisecond))}
+	cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, slowStart, cmds, logger)
+	require.NoError(t, err)
+	require.NoError(t, serverErr)
+	require.NoError(t, resErr)
+	require.Equal(t, []cmd.CmdRes{deviceRes([]byte("line 1\nline 2"))}, cmdRes)
+
+	// but first byte timeout does
+	cmds = []cmd.Cmd{cmd.NewCmd("show", cmd.WithFirstByteTimeout(300*time.Millisecond), cmd.WithIdleTimeout(3*time.Second))}
+	_, resErr, _, err = gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, slowStart, cmds, logger)
+	require.NoError(t, err)
+	require.ErrorIs(t, resErr, device.ErrPromptTimeout)
+}
+
+func TestIdleTimeout(t *testing.T) {
+	logger := zap.NewNop()
+	actions := gmock.ConcatMultipleSlices([][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("show\n"),
+			gmock.SendEcho("show\r\n"),
+			gmock.Send("line 1\r\n"),
+			gmock.Sleep(1),
+			gmock.Send("line 2\r\n<device>"),
+			gmock.Close(),
+		},
+	})
+	cmds := []cmd.Cmd{cmd.NewCmd("show", cmd.WithFirstByteTimeout(3*time.Second), cmd.WithIdleTimeout(300*time.Millisecond))}
+	_, resErr, _, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+		dev := newDevice(fullQuestion, connector, logger)
+		return &dev
+	}, actions, cmds, logger)
+	require.NoError(t, err)
+	require.ErrorIs(t, resErr, device.ErrPromptTimeout)
+	var promptErr *device.PromptTimeoutError
+	require.ErrorAs(t, resErr, &promptErr)
+	require.Equal(t, "line 1\n", string(promptErr.Res.Output()))
+}
+
+func TestPromptQuiescence(t *testing.T) {
+	logger := zap.NewNop()
+	// banner of config contains line looking like prompt
+	actions := gmock.ConcatMultipleSlices([][]gmock.Action{
+		{
+			gmock.Send("<device>"),
+			gmock.Expect("show running-config\n"),
+			gmock.SendEcho("show running-config\r\n"),
+			gmock.Send("header login\r\n<device>"),
+			gmock.Sleep(1),
+			gmock.Send("\r\nsysname device\r\n<device>"),
+			gmock.Close(),
+		},
+	})
+	cmds := []cmd.Cmd{cmd.NewCmd("show running-config")}
+	for _, quiescence := range []time.Duration{0, 1500 * time.Millisecond} {
+		cmdRes, resErr, serverErr, err := gmock.RunCmd(func(connector streamer.Connector) device.Device {
+			cli := MakeGenericCLI(
+				expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+				expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+				WithPromptQuiescence(quiescence),
+			)
+			dev := MakeGenericDevice(cli, connector, WithDevLogger(logger))
+			return &dev
+		}, actions, cmds, logger)
+		require.NoError(t, err)
+		require.NoError(t, resErr)
+		if quiescence == 0 {
+			// premature termination
+			require.Equal(t, []cmd.CmdRes{deviceRes([]byte("header login"))}, cmdRes)
+		} else {
+			require.NoError(t, serverErr)
+			require.Equal(t, []cmd.CmdRes{deviceRes([]byte("header login\n<device>\nsysname device"))}, cmdRes)
+		}
+	}
+}
+
+func TestNoWaitPrompt(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`reload\n`, []byte("Proceed with reload? [confirm]")),
+		streamer.RecorderWithResponse(`y\n`, []byte("\r\nrebooting\r\n")),
+		streamer.RecorderWithResponse(`monitor capture\n`, []byte("capturing\r\n")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithQuestion(expr.NewSimpleExprLast200().FromPattern(`\[confirm\]$`)),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	started := time.Now()
+	res, err := dev.Execute(cmd.NewCmd("reload",
+		cmd.WithNoWaitGrace(300*time.Millisecond),
+		cmd.WithAddAnswers(cmd.NewAnswerWithNL("Proceed with reload? [confirm]", "y")),
+	))
+	require.NoError(t, err)
+	require.Less(t, time.Since(started), 5*time.Second)
+	// answer is echoed by device
+	require.Equal(t, "y\n\nrebooting\n", string(res.Output()))
+
+	res, err = dev.Execute(cmd.NewCmd("monitor capture", cmd.NoWaitPrompt()))
+	require.NoError(t, err)
+	require.Empty(t, res.Output())
+}
+
+// reopeningRecorder is Recorder which is replaced by new one on Reopen.
+type reopeningRecorder struct {
+	*streamer.Recorder
+	makeRecorder func() *streamer.Recorder
+	reopened     int
+}
+
+func (m *reopeningRecorder) Reopen(ctx context.Context) error {
+	m.reopened++
+	m.Recorder = m.makeRecorder()
+	return m.Recorder.Init(ctx)
+}
+
+func TestReload(t *testing.T) {
+	makeRecorder := func() *streamer.Recorder {
+		return streamer.NewRecorder(
+			streamer.RecorderWithGreeting([]byte("<device>")),
+			streamer.RecorderWithEcho(),
+			streamer.RecorderWithResponse(`reload\n`, []byte("Proceed with reload? [confirm]")),
+			streamer.RecorderWithResponse(`\n`, []byte("\r\nrebooting\r\n")),
+			streamer.RecorderWithResponse(`show\n`, []byte("ok\r\n<device>")),
+		)
+	}
+	conn := &reopeningRecorder{Recorder: makeRecorder(), makeRecorder: makeRecorder}
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithQuestion(expr.NewSimpleExprLast200().FromPattern(`\[confirm\]$`)),
+		WithReload("reload", cmd.NewAnswerWithNL("Proceed with reload? [confirm]", "")),
+	)
+	downCalls, upCalls := 0, 0
+	dev := MakeGenericDevice(cli, conn, WithDevReloadHooks(ReloadHooks{
+		Down: func(ctx context.Context, connector streamer.Connector) error {
+			downCalls++
+			return nil
+		},
+		Up: func(ctx context.Context) error {
+			upCalls++
+			if upCalls < 3 {
+				return errors.New("not yet")
+			}
+			return nil
+		},
+		PollInterval: 10 * time.Millisecond,
+	}))
+	require.NoError(t, dev.Connect(context.Background()))
+	require.NoError(t, dev.Reload(context.Background(), 5*time.Second))
+	require.Equal(t, 1, downCalls)
+	require.Equal(t, 3, upCalls)
+	require.Equal(t, 1, conn.reopened)
+	res, err := dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+
+	dev = MakeGenericDevice(MakeGenericCLI(cli.prompt, cli.error), conn)
+	require.ErrorIs(t, dev.Reload(context.Background(), time.Second), device.ErrReloadNotSupported)
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	var flood strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&flood, "line %d\r\n", i)
+	}
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`show\n`, []byte("line 1\r\nline 2\r\n<device>")),
+		streamer.RecorderWithResponse(`debug all\n`, []byte(flood.String()+"<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithStreamWindow(64),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show", cmd.WithMaxOutputBytes(100)))
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2", string(res.Output()))
+
+	_, err = dev.Execute(cmd.NewCmd("show", cmd.WithMaxOutputBytes(10)))
+	require.ErrorIs(t, err, device.ErrOutputTooLarge)
+	var sizeErr *device.OutputTooLargeError
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, "line 1\nli", string(sizeErr.Res.Output()))
+	require.True(t, sizeErr.Res.Truncated())
+	res, err = dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2", string(res.Output()))
+
+	started := time.Now()
+	_, err = dev.Execute(cmd.NewCmd("debug all", cmd.WithMaxOutputBytes(1000)))
+	require.Less(t, time.Since(started), 5*time.Second)
+	require.ErrorAs(t, err, &sizeErr)
+	require.Equal(t, strings.ReplaceAll(flood.String()[:1000], "\r\n", "\n"), string(sizeErr.Res.Output()))
+	require.True(t, sizeErr.Res.Truncated())
+	// rest of flood is skipped till prompt
+	res, err = dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2", string(res.Output()))
+
+	rec = streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		// prompt never comes
+		streamer.RecorderWithResponse(`debug all\n`, []byte(flood.String())),
+	)
+	dev = MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	reader, err := dev.ExecuteStream(cmd.NewCmd("debug all", cmd.WithMaxOutputBytes(1000)))
+	require.NoError(t, err)
+	out, err := io.ReadAll(reader)
+	require.ErrorIs(t, err, device.ErrOutputTooLarge)
+	require.Len(t, out, 1000)
+	require.NoError(t, reader.Close())
+}
+
+func TestInitCommands(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`terminal length 0\n`, []byte("pager disabled\r\n<device>")),
+		streamer.RecorderWithResponse(`show\n`, []byte("ok\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	initCmd := cmd.NewCmd("terminal length 0")
+	dev := MakeGenericDevice(cli, rec, WithDevInitCommands(initCmd))
+	require.Len(t, dev.InitCommands(), 1)
+	require.Equal(t, initCmd.Value(), dev.InitCommands()[0].Value())
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+}
+
+func TestInitCommandsError(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`terminal length 0\n`, []byte("% Error: unknown command\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+	)
+	dev := MakeGenericDevice(cli, rec, WithDevInitCommands(cmd.NewCmd("terminal length 0")))
+	require.NoError(t, dev.Connect(context.Background()))
+	_, err := dev.Execute(cmd.NewCmd("show"))
+	var batchErr *device.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, "terminal length 0", string(batchErr.Cmd.Value()))
+	var execErr *device.ExecException
+	require.ErrorAs(t, err, &execErr)
+}
+
+func TestAutoCommandsError(t *testing.T) {
+	rec := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`terminal monitor disable\n`, []byte("% Error: unknown command\r\n<device>")),
+		streamer.RecorderWithResponse(`show\n`, []byte("ok\r\n<device>")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		// vendor auto commands may be unsupported by some models or versions
+		WithAutoCommands([]cmd.Cmd{cmd.NewCmd("terminal monitor disable")}),
+	)
+	dev := MakeGenericDevice(cli, rec)
+	require.NoError(t, dev.Connect(context.Background()))
+	res, err := dev.Execute(cmd.NewCmd("show"))
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+}
+
+func TestLoginPrompts(t *testing.T) {
+	cases := []struct {
+		name      string
+		fixture   string
+		passwords []credentials.Secret
+		opts      []GenericCLIOption
+		err       error
+	}{
+		{
+			name: "username and password",
+			fixture: `{"read": "User Name:"}
+{"write": "admin\n", "read": "\r\nPassword:"}
+{"write": "secret\n", "read": "\r\n<device>"}`,
+			passwords: []credentials.Secret{"secret"},
+			opts:      []GenericCLIOption{WithLoginPrompt(expr.NewSimpleExprLast200().FromPattern(`User Name:$`))},
+		},
+		{
+			name: "password only with re-prompt",
+			fixture: `{"read": "Password:"}
+{"write": "wrong\n", "read": "\r\nPassword:"}
+{"write": "secret\n", "read": "\r\n<device>"}`,
+			passwords: []credentials.Secret{"wrong", "secret"},
+		},
+		{
+			name: "attempts exceeded",
+			fixture: `{"read": "Password:"}
+{"write": "wrong\n", "read": "\r\nPassword:"}
+{"write": "wrong\n", "read": "\r\nPassword:"}`,
+			passwords: []credentials.Secret{"wrong"},
+			opts:      []GenericCLIOption{WithLoginAttempts(2)},
+			err:       gerror.ErrAuthFailed,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			creds := credentials.NewSimpleCredentials(credentials.WithUsername("admin"), credentials.WithPasswords(tc.passwords))
+			replay, err := streamer.NewReplayFixture(strings.NewReader(tc.fixture),
+				streamer.ReplayWithAutoLogin(false), streamer.ReplayWithCredentials(creds))
+			require.NoError(t, err)
+			opts := append([]GenericCLIOption{WithPasswordPrompt(expr.NewSimpleExprLast200().FromPattern(`Password:$`))}, tc.opts...)
+			cli := MakeGenericCLI(
+				expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+				expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+				opts...,
+			)
+			dev := MakeGenericDevice(cli, replay)
+			require.NoError(t, dev.Connect(context.Background()))
+			err = dev.connectCLI(context.Background())
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				var authErr *gerror.AuthException
+				require.ErrorAs(t, err, &authErr)
+				var passwordsErr *credentials.PasswordsError
+				require.ErrorAs(t, err, &passwordsErr)
+				require.Equal(t, []int{1}, passwordsErr.Tried)
+				require.ErrorContains(t, err, "2 attempts")
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, replay.Verify())
+		})
+	}
+}
+
+func TestSudo(t *testing.T) {
+	conn := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("user@host:~$ ")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithCredentials(credentials.NewSimpleCredentials(credentials.WithPassword("secret"))),
+		streamer.RecorderWithResponse(`sudo ls\n`, []byte("[sudo] password for user: ")),
+		streamer.RecorderWithResponse(`secret\n`, []byte("\r\nfile\r\nuser@host:~$ ")),
+		streamer.RecorderWithResponse(`sudo id\n`, []byte("uid=0(root)\r\nuser@host:~$ ")),
+		streamer.RecorderWithResponse(`sudo cat\n`, []byte("[sudo] password for user: ")),
+		streamer.RecorderWithResponse(`wrong\n`, []byte("\r\nSorry, try again.\r\n[sudo] password for user: ")),
+		streamer.RecorderWithResponse("\x03", []byte("\r\nsudo: 1 incorrect password attempt\r\nuser@host:~$ ")),
+	)
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>\S+@\S+:\S*\$ )$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)-bash: .+$`),
+		WithSudo(""),
+	)
+	dev := MakeGenericDevice(cli, conn, WithDevLogger(zap.NewNop()))
+	ctx := context.Background()
+	require.NoError(t, dev.Connect(ctx))
+
+	res, asked, err := dev.Sudo(ctx, "ls", "")
+	require.NoError(t, err)
+	require.True(t, asked)
+	require.Contains(t, string(res.Output()), "file")
+
+	res, asked, err = dev.Sudo(ctx, "id", "")
+	require.NoError(t, err)
+	require.False(t, asked)
+	require.Equal(t, "uid=0(root)", string(res.Output()))
+
+	_, asked, err = dev.Sudo(ctx, "cat", "wrong")
+	require.ErrorIs(t, err, ErrSudoFailed)
+	require.True(t, asked)
+
+	plain := MakeGenericDevice(MakeGenericCLI(nil, nil), conn)
+	_, _, err = plain.Sudo(ctx, "ls", "")
+	require.ErrorIs(t, err, ErrSudoNotSupported)
+}
+
+func TestSudoRedaction(t *testing.T) {
+	server, err := gmock.NewMockSSHServer([]gmock.Action{
+		gmock.Send("user@host:~$ "),
+		gmock.Expect("sudo ls\n"),
+		gmock.SendEcho("sudo ls\r\n"),
+		gmock.Send("[sudo] password for user: "),
+		gmock.Expect("topsecret\n"),
+		gmock.Send("\r\nfile\r\nuser@host:~$ "),
+		gmock.Close(),
+	}, gmock.WithLogger(zap.NewNop()))
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run(context.Background())
+	}()
+	host, port := server.GetAddress()
+	var transcript bytes.Buffer
+	connector := ssh.NewStreamer(host, credentials.NewSimpleCredentials(), ssh.WithPort(port), ssh.WithLogger(zap.NewNop()),
+		ssh.WithTranscript(&transcript, trace.TranscriptWithRedaction()))
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>\S+@\S+:\S*\$ )$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)-bash: .+$`),
+		WithSudo(""),
+	)
+	core, logs := observer.New(zapcore.DebugLevel)
+	dev := MakeGenericDevice(cli, connector, WithDevLogger(zap.New(core)))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dev.Connect(ctx))
+	res, asked, err := dev.Sudo(ctx, "ls", "topsecret")
+	require.NoError(t, err)
+	require.True(t, asked)
+	require.Contains(t, string(res.Output()), "file")
+	dev.Close()
+	require.NoError(t, <-serverErr)
+
+	require.NotContains(t, transcript.String(), "topsecret")
+	for _, entry := range logs.All() {
+		require.NotContains(t, fmt.Sprint(entry.ContextMap()), "topsecret")
+	}
+}
+
+func TestCurrentMode(t *testing.T) {
+	cli := MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>[\w\-]+(\(config[^)]*\))?)[>#]$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		WithModePrompt(device.ModeConfig, expr.NewSimpleExpr().FromPattern(`\(config[^)]*\)#$`)),
+		WithModePrompt(device.ModePrivileged, expr.NewSimpleExpr().FromPattern(`#$`)),
+		WithModePrompt(device.ModeUser, expr.NewSimpleExpr().FromPattern(`>$`)),
+	)
+	for prompt, mode := range map[string]device.Mode{
+		"sw>":              device.ModeUser,
+		"sw#":              device.ModePrivileged,
+		"sw(config)#":      device.ModeConfig,
+		"sw(config-line)#": device.ModeConfig,
+	} {
+		conn := streamer.NewRecorder(
+			streamer.RecorderWithGreeting([]byte(prompt)),
+			streamer.RecorderWithPrompt([]byte("\r\n"+prompt)),
+		)
+		dev := MakeGenericDevice(cli, conn, WithDevLogger(zap.NewNop()))
+		require.NoError(t, dev.Connect(context.Background()))
+		res, err := dev.CurrentMode(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, mode, res, prompt)
+	}
+
+	plain := MakeGenericDevice(MakeGenericCLI(nil, nil), streamer.NewRecorder())
+	_, err := plain.CurrentMode(context.Background())
+	require.ErrorIs(t, err, device.ErrModeNotSupported)
+}
+
+func TestVerify(t *testing.T) {
+	conn := streamer.NewRecorder(
+		streamer.RecorderWithGreeting([]byte("<device>")),
+		streamer.RecorderWithEcho(),
+		streamer.RecorderWithResponse(`show hostname\n`, []byte("hostname sw1\r\n<device>")),
+		streamer.RecorderWithPrompt([]byte("<device>")),
+	)
+	dev := MakeGenericDevice(MakeGenericCLI(
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+		expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)Error: .+$`),
+	), conn, WithDevLogger(zap.NewNop()))
+	require.NoError(t, dev.Connect(context.Background()))
+
+	_, err := dev.Execute(cmd.NewCmd("hostname sw1",
+		cmd.WithVerify("show hostname", expr.NewSimpleExpr().FromPattern(`hostname sw1`))))
+	require.NoError(t, err)
+
+	_, err = dev.Execute(cmd.NewCmd("hostname sw2",
+		cmd.WithVerify("show hostname", expr.NewSimpleExpr().FromPattern(`hostname sw2`))))
+	require.ErrorIs(t, err, device.ErrVerifyFailed)
+	var verifyErr *device.VerifyError
+	require.ErrorAs(t, err, &verifyErr)
+	require.Equal(t, "hostname sw2", string(verifyErr.Command))
+	require.Equal(t, "hostname sw1", string(verifyErr.Observed))
 }
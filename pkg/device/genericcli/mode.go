@@ -0,0 +1,67 @@
+package genericcli
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var _ device.ModeDetector = (*GenericDevice)(nil)
+
+type modePrompt struct {
+	mode   device.Mode
+	prompt expr.Expr
+}
+
+// WithModePrompt declares expression which matches prompt in mode, see CurrentMode.
+// Expressions are checked in order of adding, so more specific ones go first,
+// e.g. `\(config\)#$` for configuration mode before `#$` for privileged mode.
+func WithModePrompt(mode device.Mode, prompt expr.Expr) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.modePrompts = append(h.modePrompts, modePrompt{mode: mode, prompt: prompt})
+	}
+}
+
+// CurrentMode requests new prompt and returns mode of the first matching expression set by WithModePrompt,
+// or device.ModeUnknown if none matches.
+func (m *GenericDevice) CurrentMode(ctx context.Context) (device.Mode, error) {
+	if len(m.cli.modePrompts) == 0 {
+		return device.ModeUnknown, device.ErrModeNotSupported
+	}
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return device.ModeUnknown, err
+		}
+	}
+	prompt, err := m.requestPrompt(ctx)
+	if err != nil {
+		return device.ModeUnknown, err
+	}
+	mode := device.ModeUnknown
+	for _, mp := range m.cli.modePrompts {
+		if _, ok := mp.prompt.Match(prompt); ok {
+			mode = mp.mode
+			break
+		}
+	}
+	m.logger.Debug("mode check", zap.ByteString("prompt", prompt), zap.Stringer("mode", mode))
+	return mode, nil
+}
+
+// requestPrompt writes newline and returns matched prompt.
+func (m *GenericDevice) requestPrompt(ctx context.Context) ([]byte, error) {
+	err := m.connector.Write(m.cli.writeNewline)
+	if err != nil {
+		return nil, fmt.Errorf("write error %w", err)
+	}
+	res, err := m.connector.ReadTo(ctx, m.cli.prompt)
+	if err != nil {
+		return nil, fmt.Errorf("prompt read error %w", err)
+	}
+	return res.GetMatched(), nil
+}
@@ -0,0 +1,158 @@
+package genericcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+var _ device.Reloader = (*GenericDevice)(nil)
+
+// DefaultReloadPollInterval is a default interval between reconnect attempts of Reload.
+const DefaultReloadPollInterval = 5 * time.Second
+
+// reloadGrace is time of reading output of reload command for answering its questions.
+const reloadGrace = time.Second
+
+// ErrReopenNotSupported is returned by Reload if connector doesn't implement streamer.Reopener.
+var ErrReopenNotSupported = errors.New("connector can't be reopened")
+
+type reloadParams struct {
+	command string
+	answers []cmd.Answer
+}
+
+// WithReload sets command which restarts device and answers to its confirmation questions,
+// e.g. "reload" and answer to "Proceed with reload? [confirm]".
+func WithReload(command string, answers ...cmd.Answer) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.reload = &reloadParams{
+			command: command,
+			answers: answers,
+		}
+	}
+}
+
+// ReloadHooks tunes detection of device restart by Reload.
+type ReloadHooks struct {
+	// Down returns when device is down after reload command. Default waits until device closes connection,
+	// so it must be set for devices which don't do it, e.g. behind console server.
+	Down func(ctx context.Context, connector streamer.Connector) error
+	// Up returns nil when device is ready for connection, e.g. its port answers to streamer.Probe.
+	// It is called before every reconnect attempt, default is to try to connect.
+	Up func(ctx context.Context) error
+	// PollInterval is interval between reconnect attempts, zero means DefaultReloadPollInterval.
+	PollInterval time.Duration
+}
+
+// WithDevReloadHooks sets hooks of Reload.
+func WithDevReloadHooks(hooks ReloadHooks) GenericDeviceOption {
+	return func(h *GenericDevice) {
+		h.reloadHooks = hooks
+	}
+}
+
+// Reload runs reload command, waits for device to go down and connects and logs in again,
+// see ReloadHooks. Attempts to connect are made until reconnectTimeout is expired.
+func (m *GenericDevice) Reload(ctx context.Context, reconnectTimeout time.Duration) error {
+	if m.cli.reload == nil {
+		return device.ErrReloadNotSupported
+	}
+	reopener, ok := m.connector.(streamer.Reopener)
+	if !ok {
+		return ErrReopenNotSupported
+	}
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := m.Execute(cmd.NewCmd(m.cli.reload.command,
+		cmd.WithNoWaitGrace(reloadGrace),
+		cmd.WithAddAnswers(m.cli.reload.answers...),
+	))
+	if err != nil {
+		return fmt.Errorf("reload command: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, reconnectTimeout)
+	defer cancel()
+	down := m.reloadHooks.Down
+	if down == nil {
+		down = waitClosed
+	}
+	err = down(ctx, m.connector)
+	if err != nil {
+		return fmt.Errorf("wait for device down: %w", err)
+	}
+	m.logger.Debug("device is down")
+	m.connector.Close()
+	m.cliConnected = false
+	m.inConfigMode = false
+
+	interval := m.reloadHooks.PollInterval
+	if interval <= 0 {
+		interval = DefaultReloadPollInterval
+	}
+	for {
+		err = m.reconnect(ctx, reopener)
+		if err == nil {
+			return nil
+		}
+		m.logger.Debug("reconnect after reload", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("reconnect after reload: %w", errors.Join(ctx.Err(), err))
+		case <-time.After(interval):
+		}
+	}
+}
+
+// reconnect makes single attempt to connect and log in.
+func (m *GenericDevice) reconnect(ctx context.Context, reopener streamer.Reopener) error {
+	if m.reloadHooks.Up != nil {
+		err := m.reloadHooks.Up(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cli.connectTimeout)
+	defer cancel()
+	err := reopener.Reopen(ctx)
+	if err != nil {
+		return err
+	}
+	err = m.connectCLI(ctx)
+	if err != nil {
+		// device may be not ready for login yet
+		m.connector.Close()
+		m.cliConnected = false
+		return err
+	}
+	return nil
+}
+
+// waitClosed reads and drops output until connection is closed.
+func waitClosed(ctx context.Context, connector streamer.Connector) error {
+	prevTimeout := connector.SetReadTimeout(0)
+	defer connector.SetReadTimeout(prevTimeout)
+	anyData := expr.NewSimpleExpr().FromPattern(`(?s).+`)
+	for {
+		_, err := connector.ReadTo(ctx, anyData)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// EOF or other error of closed connection
+			return nil
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package genericcli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+	"github.com/annetutil/gnetcli/pkg/terminal"
+)
+
+// DefaultStreamWindow is size of output tail kept for prompt matching by ExecuteStream.
+const DefaultStreamWindow = 4096
+
+const flushPatternNo = -1
+
+var _ device.StreamExecutor = (*GenericDevice)(nil)
+
+// WithStreamWindow sets size of output tail which ExecuteStream keeps for prompt matching.
+// It must be larger than the longest prompt, pager or question.
+func WithStreamWindow(size int) GenericCLIOption {
+	return func(h *GenericCLI) {
+		h.streamWindow = size
+	}
+}
+
+// windowExpr matches exprs and, if nothing is found, splits off data beyond window on line boundary.
+type windowExpr struct {
+	exprs  expr.Expr
+	window int
+	flush  bool
+}
+
+func (m *windowExpr) Match(data []byte) (*expr.MatchRes, bool) {
+	if mRes, ok := m.exprs.Match(data); ok {
+		return mRes, true
+	}
+	if !m.flush || len(data) <= m.window {
+		return nil, false
+	}
+	cut := len(data) - m.window
+	if nl := bytes.LastIndexByte(data[:cut], '\n'); nl >= 0 {
+		cut = nl + 1
+	} else if esc := bytes.LastIndexByte(data[:cut], 0x1b); esc >= 0 && cut-esc < 32 {
+		// don't split escape sequence
+		cut = esc
+	}
+	if cut == 0 {
+		return nil, false
+	}
+	return &expr.MatchRes{Start: cut, End: cut, PatternNo: flushPatternNo}, true
+}
+
+func (m *windowExpr) Repr() string {
+	return fmt.Sprintf("window(%d, %s)", m.window, m.exprs.Repr())
+}
+
+// ExecuteStream runs command and returns its output as it is read from device.
+// Reader must be closed, Close reads the rest of output up to prompt.
+func (m *GenericDevice) ExecuteStream(command cmd.Cmd) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.cli.connectTimeout)
+	defer cancel()
+	m.logger.Debug("exec stream", zap.ByteString("command", command.Value()))
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	res, err := GenericExecuteStream(command, m.connector, m.execCLI(), m.logger)
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := res.(*streamReader); ok && command.GetExpectedPrompt() != nil {
+		r.finish = append(r.finish, func() {
+			if r.done {
+				m.switchPrompt(command)
+			}
+		})
+	}
+	return res, nil
+}
+
+type streamReader struct {
+	*execution
+	ctx         context.Context
+	cancel      context.CancelFunc
+	window      *windowExpr
+	errorExprs  []expr.Expr
+	pending     []byte
+	emitted     int // size of output passed to reader
+	done        bool
+	broken      bool
+	closed      bool
+	err         error
+	finish      []func()
+	stopObserve func() error
+}
+
+// GenericExecuteStream is streaming version of GenericExecute. It keeps only last cli.streamWindow bytes of output
+// for matching, everything before is passed to reader. Result callback, warnings and checksum are not supported,
+// error expression and error expressions of WithErrorExprs are checked against each chunk and reported by Read.
+// Commands with dialog, NoWaitPrompt, prompt timeout, first byte or idle timeout are rejected,
+// cmd timeout and read timeout are supported.
+func GenericExecuteStream(command cmd.Cmd, connector streamer.Connector, cli GenericCLI, logger *zap.Logger) (io.ReadCloser, error) {
+	if len(command.GetDialog()) > 0 {
+		return nil, errors.New("dialog is not supported in stream mode")
+	}
+	if _, noWait := command.GetNoWaitPrompt(); noWait {
+		return nil, errors.New("no wait prompt is not supported in stream mode")
+	}
+	switch {
+	case command.GetPromptTimeout() > 0:
+		return nil, errors.New("prompt timeout is not supported in stream mode")
+	case command.GetFirstByteTimeout() > 0:
+		return nil, errors.New("first byte timeout is not supported in stream mode")
+	case command.GetIdleTimeout() > 0:
+		return nil, errors.New("idle timeout is not supported in stream mode")
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if cmdTimeout := command.GetCmdTimeout(); cmdTimeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), cmdTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	r := &streamReader{
+		execution:  newExecution(command, connector, cli, logger),
+		ctx:        ctx,
+		cancel:     cancel,
+		errorExprs: commandErrorExprs(command, cli),
+	}
+	if readTimeout := command.GetReadTimeout(); readTimeout > 0 {
+		prevTimeout := connector.SetReadTimeout(readTimeout)
+		r.finish = append(r.finish, func() { connector.SetReadTimeout(prevTimeout) })
+	}
+	r.stopObserve = observeOutput(connector, command)
+	err := r.writeCommand(ctx)
+	if err != nil {
+		r.stop()
+		return nil, err
+	}
+	window := cli.streamWindow
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	r.window = &windowExpr{exprs: r.exprs, window: window}
+	if echoStripped(connector) {
+		r.exprs.Delete(echoExprName)
+		r.window.flush = true
+	}
+
+	// echo errors are reported right away
+	for !r.window.flush && !r.done {
+		if err := r.next(); err != nil {
+			r.stop()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (m *streamReader) Read(p []byte) (int, error) {
+	for len(m.pending) == 0 {
+		if m.err != nil {
+			return 0, m.err
+		}
+		if m.done {
+			return 0, io.EOF
+		}
+		if err := m.next(); err != nil {
+			m.broken = true
+			m.err = err
+		}
+	}
+	n := copy(p, m.pending)
+	m.pending = m.pending[n:]
+	return n, nil
+}
+
+// Close reads and discards the rest of output, so next command can be executed.
+func (m *streamReader) Close() error {
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	var err error
+	for !m.done && !m.broken {
+		m.pending = nil
+		if err = m.next(); err != nil {
+			break
+		}
+	}
+	m.stop()
+	return err
+}
+
+func (m *streamReader) stop() {
+	for _, fn := range m.finish {
+		fn()
+	}
+	_ = m.stopObserve()
+	m.cancel()
+}
+
+// next reads till the next match and handles it.
+func (m *streamReader) next() error {
+	match, err := m.connector.ReadTo(m.ctx, m.window)
+	if err != nil {
+		if outputErr := m.timeoutError(err); outputErr != nil {
+			return outputErr
+		}
+		return err
+	}
+	matchId := match.GetPatternNo()
+	if matchId == flushPatternNo {
+		return m.emit(match.GetBefore(), false)
+	}
+	matchName := m.exprs.GetName(matchId)
+	if matchName == echoExprName {
+		m.exprs.Delete(echoExprName)
+		m.window.flush = true
+		return nil
+	}
+	if !m.window.flush {
+		return device.ThrowEchoReadException(match.GetBefore(), matchName == promptExprName)
+	}
+	if matchName == promptExprName {
+		m.done = true
+		if err := m.emit(m.promptOutput(match, match.GetBefore()), true); err != nil {
+			return err
+		}
+		return m.afterPrompt(m.ctx, match.GetMatched())
+	}
+	out, handled, err := m.answer(m.ctx, match, matchName)
+	if err != nil {
+		return err
+	}
+	if !handled {
+		return fmt.Errorf("unknown expr name %q", matchName)
+	}
+	return m.emit(out, false)
+}
+
+// emit adds chunk of output to pending data.
+func (m *streamReader) emit(data []byte, last bool) error {
+	var err error
+	if m.cli.crOverwrite {
+		data = terminal.CollapseReturns(data)
+	}
+	if last {
+		data, err = terminal.ParseDropLastReturn(data)
+	} else {
+		data, err = terminal.Parse(data)
+	}
+	if err != nil {
+		return err
+	}
+	data = normalizeNewlines(data)
+	if limit := m.command.GetMaxOutputBytes(); limit > 0 && m.emitted+len(data) > limit {
+		// the rest of output is not read, so connection is unusable
+		m.pending = append(m.pending, data[:limit-m.emitted]...)
+		m.emitted = limit
+		m.broken = true
+		m.err = &device.OutputTooLargeError{Limit: limit}
+		return nil
+	}
+	m.emitted += len(data)
+	m.pending = append(m.pending, data...)
+	if foundErr := checkError(m.cli.error, data); foundErr != nil && m.err == nil {
+		m.err = m.command.ErrorHandler(foundErr)
+	}
+	if unexpectedErr := checkErrorExprs(m.errorExprs, data); unexpectedErr != nil && m.err == nil {
+		m.err = unexpectedErr
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package genericcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var _ device.Sudoer = (*GenericDevice)(nil)
+
+// DefaultSudoPasswordQuestion matches default password prompt of sudo.
+const DefaultSudoPasswordQuestion = `\[sudo\] password for [^:\r\n]+: ?$`
+
+// sudoInterrupt is written instead of password if sudo asks it again, it makes sudo exit.
+const sudoInterrupt = "\x03"
+
+// ErrSudoNotSupported is returned by Sudo if device has no WithSudo option.
+var ErrSudoNotSupported = errors.New("sudo is not supported by device")
+
+// ErrSudoFailed is returned by Sudo if password is rejected.
+var ErrSudoFailed = errors.New("sudo password is rejected")
+
+type sudoParams struct {
+	passwordQuestion string // regexp
+}
+
+// WithSudo enables Sudo for Linux-based devices, passwordQuestion is regexp of sudo password prompt,
+// empty value means DefaultSudoPasswordQuestion.
+func WithSudo(passwordQuestion string) GenericCLIOption {
+	return func(h *GenericCLI) {
+		if len(passwordQuestion) == 0 {
+			passwordQuestion = DefaultSudoPasswordQuestion
+		}
+		h.sudo = &sudoParams{passwordQuestion: passwordQuestion}
+	}
+}
+
+// sudoCmd tracks password questions of sudo.
+type sudoCmd struct {
+	cmd.Cmd
+	question expr.Expr
+	asked    bool
+	failed   bool
+}
+
+// GetQuestionExprs returns expression which does not choose answer, so every question goes to QuestionHandler.
+func (m *sudoCmd) GetQuestionExprs() []expr.Expr {
+	return []expr.Expr{m.question}
+}
+
+func (m *sudoCmd) QuestionHandler(question []byte) ([]byte, error) {
+	ans, err := m.Cmd.QuestionHandler(question)
+	if err != nil {
+		return nil, err
+	}
+	if !m.ask() {
+		return []byte(sudoInterrupt), nil
+	}
+	return ans, nil
+}
+
+// ask records password question, it returns false if password is asked again after "Sorry, try again."
+func (m *sudoCmd) ask() bool {
+	if m.asked {
+		m.failed = true
+		return false
+	}
+	m.asked = true
+	return true
+}
+
+// Sudo runs command with sudo and answers its password question.
+// Empty password means the first password from connector credentials.
+func (m *GenericDevice) Sudo(ctx context.Context, command string, password credentials.Secret) (cmd.CmdRes, bool, error) {
+	if m.cli.sudo == nil {
+		return nil, false, ErrSudoNotSupported
+	}
+	if !m.cliConnected {
+		err := m.connectCLI(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if creds := m.connector.GetCredentials(); len(password) == 0 && creds != nil {
+		if passwords := creds.GetPasswords(ctx); len(passwords) > 0 {
+			password = passwords[0]
+		}
+	}
+	addSecrets(m.connector, []byte(password.Value()))
+	answer := cmd.NewSecretAnswer("/"+m.cli.sudo.passwordQuestion+"/", password.Value())
+	sudo := &sudoCmd{
+		Cmd:      cmd.NewCmd("sudo "+command, cmd.WithAddAnswers(answer)),
+		question: expr.NewSimpleExpr().FromPattern(m.cli.sudo.passwordQuestion),
+	}
+	res, err := m.Execute(sudo)
+	if err != nil {
+		return res, sudo.asked, fmt.Errorf("sudo: %w", err)
+	}
+	if sudo.failed {
+		return res, true, ErrSudoFailed
+	}
+	return res, sudo.asked, nil
+}
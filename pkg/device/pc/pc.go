@@ -5,6 +5,7 @@ package pc
 
 import (
 	"context"
+	"slices"
 
 	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
 	"github.com/annetutil/gnetcli/pkg/credentials"
@@ -62,7 +63,17 @@ func (m *Device) Execute(command gcmd.Cmd) (gcmd.CmdRes, error) {
 			return nil, err
 		}
 	}
-	return m.connector.Cmd(ctx, string(command.Value()))
+	if command.IsIdempotent() {
+		ctx = streamer.WithIdempotent(ctx)
+	}
+	res, err := m.connector.Cmd(ctx, string(command.Value()))
+	if err != nil {
+		return res, err
+	}
+	if res.Status() != 0 && !slices.Contains(command.GetAcceptExitCodes(), res.Status()) {
+		return res, &device.ExitStatusError{Status: res.Status()}
+	}
+	return res, nil
 }
 
 func (m *Device) Download(paths []string) (map[string]streamer.File, error) {
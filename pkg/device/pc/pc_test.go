@@ -0,0 +1,49 @@
+package pc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+type statusConnector struct {
+	streamer.Connector
+	status int
+}
+
+func (m *statusConnector) Cmd(ctx context.Context, cmd string) (gcmd.CmdRes, error) {
+	return gcmd.NewCmdResFull([]byte("out"), nil, m.status, nil), nil
+}
+
+func TestExecuteAcceptExitCodes(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		opts    []gcmd.CmdOption
+		wantErr bool
+	}{
+		{name: "zero", status: 0},
+		{name: "non-zero", status: 1, wantErr: true},
+		{name: "accepted", status: 1, opts: []gcmd.CmdOption{gcmd.WithAcceptExitCodes([]int{1})}},
+		{name: "not accepted", status: 2, opts: []gcmd.CmdOption{gcmd.WithAcceptExitCodes([]int{1})}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dev := NewDevice(&statusConnector{status: tc.status})
+			res, err := dev.Execute(gcmd.NewCmd("grep x", tc.opts...))
+			require.NotNil(t, res)
+			require.Equal(t, tc.status, res.Status())
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			var statusErr *device.ExitStatusError
+			require.ErrorAs(t, err, &statusErr)
+			require.Equal(t, tc.status, statusErr.Status)
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package device
+
+import (
+	"bytes"
+	"errors"
+)
+
+var ErrSectionNotFound = errors.New("section not found")
+
+// SectionRules describes how config sections are delimited.
+type SectionRules struct {
+	// Delimiters are lines which terminate section regardless of indentation, e.g. "!" for Cisco or "#" for Huawei.
+	Delimiters []string
+}
+
+var DefaultSectionRules = SectionRules{
+	Delimiters: []string{"!", "#"},
+}
+
+// ExtractSection returns config section started by line name using DefaultSectionRules.
+func ExtractSection(output []byte, name string) ([]byte, error) {
+	return ExtractSectionWithRules(output, name, DefaultSectionRules)
+}
+
+// ExtractSectionWithRules returns config section started by line name.
+// Section consists of the header line and all following lines indented deeper than header,
+// it ends on a line with the same or less indentation, on an empty line or on a delimiter.
+func ExtractSectionWithRules(output []byte, name string, rules SectionRules) ([]byte, error) {
+	lines := bytes.Split(bytes.ReplaceAll(output, []byte("\r\n"), []byte("\n")), []byte("\n"))
+	header := bytes.TrimSpace([]byte(name))
+	start := -1
+	startIndent := 0
+	for i, line := range lines {
+		if bytes.Equal(bytes.TrimSpace(line), header) {
+			start = i
+			startIndent = indentLen(line)
+			break
+		}
+	}
+	if start < 0 {
+		return nil, ErrSectionNotFound
+	}
+	end := start + 1
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || isDelimiter(trimmed, rules.Delimiters) || indentLen(line) <= startIndent {
+			break
+		}
+	}
+	return bytes.Join(lines[start:end], []byte("\n")), nil
+}
+
+func indentLen(line []byte) int {
+	return len(line) - len(bytes.TrimLeft(line, " \t"))
+}
+
+func isDelimiter(line []byte, delimiters []string) bool {
+	for _, delimiter := range delimiters {
+		if string(line) == delimiter {
+			return true
+		}
+	}
+	return false
+}
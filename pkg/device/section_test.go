@@ -0,0 +1,51 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractSection(t *testing.T) {
+	config := "" +
+		"hostname switch\r\n" +
+		"!\r\n" +
+		"interface Ethernet1\r\n" +
+		" description uplink\r\n" +
+		" no shutdown\r\n" +
+		"!\r\n" +
+		"interface Ethernet2\r\n" +
+		" shutdown\r\n" +
+		"router bgp 65000\r\n" +
+		" neighbor 10.0.0.1 remote-as 65001\r\n" +
+		" address-family ipv4\r\n" +
+		"  network 10.0.0.0/8\r\n"
+
+	res, err := ExtractSection([]byte(config), "interface Ethernet1")
+	require.NoError(t, err)
+	require.Equal(t, "interface Ethernet1\n description uplink\n no shutdown", string(res))
+
+	res, err = ExtractSection([]byte(config), "interface Ethernet2")
+	require.NoError(t, err)
+	require.Equal(t, "interface Ethernet2\n shutdown", string(res))
+
+	res, err = ExtractSection([]byte(config), "address-family ipv4")
+	require.NoError(t, err)
+	require.Equal(t, " address-family ipv4\n  network 10.0.0.0/8", string(res))
+
+	_, err = ExtractSection([]byte(config), "interface Ethernet3")
+	require.ErrorIs(t, err, ErrSectionNotFound)
+}
+
+func TestExtractSectionHuawei(t *testing.T) {
+	config := "" +
+		"#\n" +
+		"interface 10GE1/0/1\n" +
+		" port mode 10G\n" +
+		"#\n" +
+		"interface 10GE1/0/2\n"
+
+	res, err := ExtractSection([]byte(config), "interface 10GE1/0/1")
+	require.NoError(t, err)
+	require.Equal(t, "interface 10GE1/0/1\n port mode 10G", string(res))
+}
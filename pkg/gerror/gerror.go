@@ -1,16 +1,66 @@
 package gerror
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Connect failure reasons, connect errors of streamers match them with errors.Is.
+var (
+	// ErrAuthFailed matches errors caused by rejected credentials.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrNoAuthMethod matches errors when server doesn't accept any of offered authentication methods.
+	ErrNoAuthMethod    = errors.New("no matching authentication method")
+	ErrConnRefused     = errors.New("connection refused")
+	ErrHostKeyMismatch = errors.New("host key mismatch")
+	ErrTimeout         = errors.New("timeout")
+	ErrNoRouteToHost   = errors.New("no route to host")
+)
+
+// ClassifyNetError returns ErrConnRefused, ErrNoRouteToHost or ErrTimeout if err is caused by such network failure, otherwise nil.
+func ClassifyNetError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrConnRefused
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return ErrNoRouteToHost
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+	return nil
+}
 
 type AuthException struct {
 	msg string
+	err error
 }
 
 func (m *AuthException) Error() string {
+	if m.err != nil {
+		return fmt.Sprintf("auth error %s: %s", m.msg, m.err)
+	}
 	return fmt.Sprintf("auth error %s", m.msg)
 }
 
+// Unwrap returns detail of auth failure, e.g. credentials.PasswordsError.
+func (m *AuthException) Unwrap() error {
+	return m.err
+}
+
 func (m *AuthException) Is(target error) bool {
+	if target == ErrAuthFailed {
+		return true
+	}
 	if _, ok := target.(*AuthException); ok {
 		return true
 	}
@@ -20,3 +70,8 @@ func (m *AuthException) Is(target error) bool {
 func NewAuthException(msg string) error {
 	return &AuthException{msg: msg}
 }
+
+// NewAuthExceptionWrap makes AuthException with err as detail.
+func NewAuthExceptionWrap(msg string, err error) error {
+	return &AuthException{msg: msg, err: err}
+}
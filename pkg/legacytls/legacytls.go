@@ -0,0 +1,214 @@
+// Package legacytls connects to device APIs which support only ancient TLS,
+// like TLS 1.0 with RSA key exchange, SSLv3 or anonymous suites.
+// It is built on the fork of crypto/tls from Go 1.8 with additional suites.
+package legacytls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	tlshack "github.com/annetutil/gnetcli/internal/tls_hack"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// Config is TLS config of the fork, fields are the same as in crypto/tls.Config of Go 1.8.
+type Config = tlshack.Config
+
+// Conn is TLS connection made by the fork.
+type Conn = tlshack.Conn
+
+const (
+	VersionSSL30 = tlshack.VersionSSL30
+	VersionTLS10 = tlshack.VersionTLS10
+	VersionTLS11 = tlshack.VersionTLS11
+	VersionTLS12 = tlshack.VersionTLS12
+)
+
+// Suites with RSA key exchange, which are often the only ones on old devices.
+const (
+	TLS_RSA_WITH_AES_128_CBC_SHA    = tlshack.TLS_RSA_WITH_AES_128_CBC_SHA
+	TLS_RSA_WITH_AES_256_CBC_SHA    = tlshack.TLS_RSA_WITH_AES_256_CBC_SHA
+	TLS_RSA_WITH_AES_128_CBC_SHA256 = tlshack.TLS_RSA_WITH_AES_128_CBC_SHA256
+	TLS_RSA_WITH_AES_256_CBC_SHA256 = tlshack.TLS_RSA_WITH_AES_256_CBC_SHA256
+	TLS_RSA_WITH_AES_128_GCM_SHA256 = tlshack.TLS_RSA_WITH_AES_128_GCM_SHA256
+	TLS_RSA_WITH_AES_256_GCM_SHA384 = tlshack.TLS_RSA_WITH_AES_256_GCM_SHA384
+)
+
+// Insecure suites, they require Dialer.AllowInsecure.
+const (
+	TLS_RSA_WITH_RC4_128_SHA            = tlshack.TLS_RSA_WITH_RC4_128_SHA
+	TLS_RSA_WITH_3DES_EDE_CBC_SHA       = tlshack.TLS_RSA_WITH_3DES_EDE_CBC_SHA
+	TLS_ECDHE_RSA_WITH_RC4_128_SHA      = tlshack.TLS_ECDHE_RSA_WITH_RC4_128_SHA
+	TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA = tlshack.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA
+	TLS_DH_anon_WITH_AES_128_CBC_SHA    = tlshack.TLS_DH_anon_WITH_AES_128_CBC_SHA
+	TLS_DH_anon_WITH_AES_256_CBC_SHA    = tlshack.TLS_DH_anon_WITH_AES_256_CBC_SHA
+	TLS_DH_anon_WITH_AES_256_GCM_SHA384 = tlshack.TLS_DH_anon_WITH_AES_256_GCM_SHA384
+	TLS_ECDH_anon_WITH_AES_256_CBC_SHA  = tlshack.TLS_ECDH_anon_WITH_AES_256_CBC_SHA
+)
+
+// ErrInsecureConfig is returned when config is insecure and Dialer.AllowInsecure is not set.
+var ErrInsecureConfig = errors.New("legacytls: insecure config is not allowed")
+
+// HandshakeError is returned when TLS handshake fails, e.g. when server doesn't support any of suites.
+type HandshakeError struct {
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("legacytls: handshake error: %v", e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoPeerCertificate is returned by PinPublicKeySHA256 callback when server didn't send certificate.
+var ErrNoPeerCertificate = tlshack.ErrNoPeerCertificate
+
+// PinPublicKeySHA256 returns Config.VerifyPeerCertificate callback which accepts server
+// only if SHA256 of its leaf certificate public key is one of pins.
+// Config with InsecureSkipVerify and this callback is not considered insecure.
+func PinPublicKeySHA256(pins ...[]byte) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return tlshack.PinPublicKeySHA256(pins...)
+}
+
+// insecureSuites have broken ciphers or don't authenticate server.
+var insecureSuites = map[uint16]bool{
+	tlshack.TLS_RSA_WITH_RC4_128_SHA:            true,
+	tlshack.TLS_ECDHE_RSA_WITH_RC4_128_SHA:      true,
+	tlshack.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:    true,
+	tlshack.TLS_RSA_WITH_3DES_EDE_CBC_SHA:       true,
+	tlshack.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA: true,
+	tlshack.TLS_DH_anon_WITH_AES_128_CBC_SHA:    true,
+	tlshack.TLS_DH_anon_WITH_AES_256_CBC_SHA:    true,
+	tlshack.TLS_DH_anon_WITH_AES_128_CBC_SHA256: true,
+	tlshack.TLS_DH_anon_WITH_AES_256_CBC_SHA256: true,
+	tlshack.TLS_DH_anon_WITH_AES_128_GCM_SHA256: true,
+	tlshack.TLS_DH_anon_WITH_AES_256_GCM_SHA384: true,
+	tlshack.TLS_ECDH_anon_WITH_AES_256_CBC_SHA:  true,
+}
+
+// DefaultConfig returns config which verifies server certificate with system roots
+// and allows TLS 1.0 - 1.2 with RSA key exchange and AES suites.
+func DefaultConfig(serverName string) *Config {
+	return &Config{
+		ServerName: serverName,
+		MinVersion: VersionTLS10,
+		CipherSuites: []uint16{
+			TLS_RSA_WITH_AES_128_GCM_SHA256,
+			TLS_RSA_WITH_AES_256_GCM_SHA384,
+			TLS_RSA_WITH_AES_128_CBC_SHA,
+			TLS_RSA_WITH_AES_256_CBC_SHA,
+			TLS_RSA_WITH_AES_128_CBC_SHA256,
+			TLS_RSA_WITH_AES_256_CBC_SHA256,
+		},
+	}
+}
+
+// CheckConfig returns error matching ErrInsecureConfig if config allows SSLv3, insecure suites
+// or skips server certificate verification without VerifyPeerCertificate.
+// Nil CipherSuites means default suites of the fork, which include 3DES, so such config is insecure.
+func CheckConfig(config *Config) error {
+	if config.MinVersion != 0 && config.MinVersion < VersionTLS10 {
+		return fmt.Errorf("%w: SSLv3 is enabled", ErrInsecureConfig)
+	}
+	if config.InsecureSkipVerify && config.VerifyPeerCertificate == nil {
+		return fmt.Errorf("%w: server certificate is not verified", ErrInsecureConfig)
+	}
+	suites := config.CipherSuites
+	if suites == nil {
+		suites = tlshack.DefaultCipherSuites()
+	}
+	for _, suite := range suites {
+		if insecureSuites[suite] {
+			return fmt.Errorf("%w: %s is enabled", ErrInsecureConfig, SuiteName(suite))
+		}
+	}
+	return nil
+}
+
+// Dialer makes TLS connections using the fork.
+type Dialer struct {
+	// NetDialer is used for TCP connection, net.Dialer is used if it is nil.
+	NetDialer streamer.Dialer
+	// Config is used for handshake, DefaultConfig is used if it is nil.
+	// Empty ServerName is taken from dialed address.
+	Config *Config
+	// AllowInsecure acknowledges that Config may enable SSLv3, RC4, 3DES or anonymous suites
+	// or may skip server certificate verification.
+	AllowInsecure bool
+}
+
+var _ streamer.Dialer = (*Dialer)(nil)
+
+// DialContext connects to addr and makes TLS handshake, ctx covers both.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.dial(ctx, network, addr)
+}
+
+func (d *Dialer) dial(ctx context.Context, network, addr string) (*Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	config := d.Config
+	if config == nil {
+		config = DefaultConfig(host)
+	} else if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName = host
+	}
+	if !d.AllowInsecure {
+		err := CheckConfig(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d.handshake(ctx, network, addr, config)
+}
+
+// handshake connects to addr and makes handshake with config as is.
+func (d *Dialer) handshake(ctx context.Context, network, addr string, config *Config) (*Conn, error) {
+	var netDialer streamer.Dialer = &net.Dialer{}
+	if d.NetDialer != nil {
+		netDialer = d.NetDialer
+	}
+	rawConn, err := netDialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn := tlshack.Client(rawConn, config)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = rawConn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	err = conn.Handshake()
+	close(done)
+	<-stopped
+	if ctx.Err() != nil {
+		_ = rawConn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		_ = rawConn.Close()
+		return nil, &HandshakeError{Err: err}
+	}
+	return conn, nil
+}
+
+// DialTLS connects to addr over TCP with config, see Dialer for details.
+// Insecure config is rejected, use Dialer with AllowInsecure for it.
+func DialTLS(ctx context.Context, addr string, config *Config) (*Conn, error) {
+	d := &Dialer{Config: config}
+	return d.dial(ctx, "tcp", addr)
+}
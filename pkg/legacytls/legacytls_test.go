@@ -0,0 +1,128 @@
+package legacytls
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	tlshack "github.com/annetutil/gnetcli/internal/tls_hack"
+)
+
+// runServer accepts TLS connections with RSA certificate and writes "ok" to it.
+func runServer(t *testing.T, suites []uint16) (string, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	config := &tlshack.Config{
+		Certificates: []tlshack.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		CipherSuites: suites,
+		MinVersion:   tlshack.VersionSSL30,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				server := tlshack.Server(conn, config)
+				defer server.Close()
+				if server.Handshake() != nil {
+					return
+				}
+				_, _ = server.Write([]byte("ok"))
+			}()
+		}
+	}()
+	return listener.Addr().String(), cert
+}
+
+func TestDialTLS(t *testing.T) {
+	addr, cert := runServer(t, []uint16{TLS_RSA_WITH_AES_128_CBC_SHA})
+	config := DefaultConfig("")
+	config.RootCAs = x509.NewCertPool()
+	config.RootCAs.AddCert(cert)
+
+	conn, err := DialTLS(context.Background(), addr, config)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, TLS_RSA_WITH_AES_128_CBC_SHA, conn.ConnectionState().CipherSuite)
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(data))
+}
+
+func TestDialTLSUnknownAuthority(t *testing.T) {
+	addr, _ := runServer(t, []uint16{TLS_RSA_WITH_AES_128_CBC_SHA})
+	_, err := DialTLS(context.Background(), addr, nil)
+	var authErr x509.UnknownAuthorityError
+	require.ErrorAs(t, err, &authErr)
+}
+
+func TestDialTLSInsecure(t *testing.T) {
+	addr, _ := runServer(t, []uint16{TLS_RSA_WITH_RC4_128_SHA})
+	config := &Config{
+		InsecureSkipVerify: true,
+		CipherSuites:       []uint16{TLS_RSA_WITH_RC4_128_SHA},
+	}
+	_, err := DialTLS(context.Background(), addr, config)
+	require.ErrorIs(t, err, ErrInsecureConfig)
+
+	dialer := &Dialer{Config: config, AllowInsecure: true}
+	conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, TLS_RSA_WITH_RC4_128_SHA, conn.(*Conn).ConnectionState().CipherSuite)
+}
+
+func TestCheckConfig(t *testing.T) {
+	require.NoError(t, CheckConfig(DefaultConfig("device")))
+	require.ErrorIs(t, CheckConfig(&Config{MinVersion: VersionSSL30}), ErrInsecureConfig)
+	require.ErrorIs(t, CheckConfig(&Config{CipherSuites: []uint16{TLS_RSA_WITH_3DES_EDE_CBC_SHA}}), ErrInsecureConfig)
+	// default suites of the fork include 3DES
+	require.ErrorIs(t, CheckConfig(&Config{ServerName: "device"}), ErrInsecureConfig)
+	pinned := DefaultConfig("")
+	pinned.InsecureSkipVerify = true
+	pinned.VerifyPeerCertificate = PinPublicKeySHA256()
+	require.NoError(t, CheckConfig(pinned))
+}
+
+func TestLegacyInsecureConfig(t *testing.T) {
+	addr, _ := runServer(t, []uint16{TLS_RSA_WITH_AES_128_CBC_SHA, TLS_RSA_WITH_3DES_EDE_CBC_SHA})
+	config := LegacyInsecureConfig("")
+	_, err := DialTLS(context.Background(), addr, config)
+	require.ErrorIs(t, err, ErrInsecureConfig)
+
+	suites, err := OfferedSuites(context.Background(), addr, config)
+	require.NoError(t, err)
+	require.Equal(t, []uint16{TLS_RSA_WITH_3DES_EDE_CBC_SHA}, suites)
+
+	dialer := &Dialer{Config: config, AllowInsecure: true}
+	conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.Equal(t, "TLS_RSA_WITH_3DES_EDE_CBC_SHA", SuiteName(conn.(*Conn).ConnectionState().CipherSuite))
+}
@@ -0,0 +1,97 @@
+package legacytls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	tlshack "github.com/annetutil/gnetcli/internal/tls_hack"
+)
+
+var suiteNames = map[uint16]string{
+	tlshack.TLS_RSA_WITH_AES_128_CBC_SHA:        "TLS_RSA_WITH_AES_128_CBC_SHA",
+	tlshack.TLS_RSA_WITH_AES_256_CBC_SHA:        "TLS_RSA_WITH_AES_256_CBC_SHA",
+	tlshack.TLS_RSA_WITH_AES_128_CBC_SHA256:     "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	tlshack.TLS_RSA_WITH_AES_256_CBC_SHA256:     "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	tlshack.TLS_RSA_WITH_AES_128_GCM_SHA256:     "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	tlshack.TLS_RSA_WITH_AES_256_GCM_SHA384:     "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	tlshack.TLS_RSA_WITH_RC4_128_SHA:            "TLS_RSA_WITH_RC4_128_SHA",
+	tlshack.TLS_ECDHE_RSA_WITH_RC4_128_SHA:      "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tlshack.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:    "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tlshack.TLS_RSA_WITH_3DES_EDE_CBC_SHA:       "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tlshack.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	tlshack.TLS_DH_anon_WITH_AES_128_CBC_SHA:    "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	tlshack.TLS_DH_anon_WITH_AES_256_CBC_SHA:    "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	tlshack.TLS_DH_anon_WITH_AES_128_CBC_SHA256: "TLS_DH_anon_WITH_AES_128_CBC_SHA256",
+	tlshack.TLS_DH_anon_WITH_AES_256_CBC_SHA256: "TLS_DH_anon_WITH_AES_256_CBC_SHA256",
+	tlshack.TLS_DH_anon_WITH_AES_128_GCM_SHA256: "TLS_DH_anon_WITH_AES_128_GCM_SHA256",
+	tlshack.TLS_DH_anon_WITH_AES_256_GCM_SHA384: "TLS_DH_anon_WITH_AES_256_GCM_SHA384",
+	tlshack.TLS_ECDH_anon_WITH_AES_256_CBC_SHA:  "TLS_ECDH_anon_WITH_AES_256_CBC_SHA",
+}
+
+// SuiteName returns IANA name of suite or its hex code if it is unknown.
+func SuiteName(suite uint16) string {
+	if name, ok := suiteNames[suite]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", suite)
+}
+
+// LegacyInsecureConfig returns config for old load balancers and management cards which
+// negotiate only 3DES or RC4 suites, from SSLv3 up to TLS 1.2. Server certificate is not verified,
+// set VerifyPeerCertificate, e.g. to PinPublicKeySHA256, to authenticate server.
+// Dialer.AllowInsecure is required for it.
+func LegacyInsecureConfig(serverName string) *Config {
+	return &Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+		MinVersion:         VersionSSL30,
+		CipherSuites: []uint16{
+			TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+			TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+			TLS_RSA_WITH_RC4_128_SHA,
+			TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+		},
+	}
+}
+
+// OfferedSuites makes handshake for every suite of Config and returns suites accepted by server at addr.
+// Server certificate is not verified during probing, so insecure Config doesn't require AllowInsecure.
+func (d *Dialer) OfferedSuites(ctx context.Context, network, addr string) ([]uint16, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	config := d.Config
+	if config == nil {
+		config = DefaultConfig(host)
+	}
+	var res []uint16
+	for _, suite := range config.CipherSuites {
+		probe := config.Clone()
+		if probe.ServerName == "" {
+			probe.ServerName = host
+		}
+		probe.CipherSuites = []uint16{suite}
+		probe.InsecureSkipVerify = true
+		probe.VerifyPeerCertificate = nil
+		conn, err := d.handshake(ctx, network, addr, probe)
+		if err != nil {
+			var handshakeErr *HandshakeError
+			if errors.As(err, &handshakeErr) {
+				continue
+			}
+			return nil, err
+		}
+		_ = conn.Close()
+		res = append(res, suite)
+	}
+	return res, nil
+}
+
+// OfferedSuites reports which suites of config are accepted by server at addr, see Dialer.OfferedSuites.
+func OfferedSuites(ctx context.Context, addr string, config *Config) ([]uint16, error) {
+	d := &Dialer{Config: config}
+	return d.OfferedSuites(ctx, "tcp", addr)
+}
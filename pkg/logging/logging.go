@@ -0,0 +1,70 @@
+// Package logging defines minimal structured logger used by ssh streamer and tunnel, and adapter for slog.
+// Adapter for zap is in zaplog package. Other streamers and devices take *zap.Logger.
+package logging
+
+import (
+	"fmt"
+)
+
+// Logger is a structured logger.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+}
+
+// Field is a key-value pair attached to log message.
+type Field struct {
+	Key   string
+	Value any
+}
+
+func String(key string, val string) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Strings(key string, val []string) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Int(key string, val int) Field {
+	return Field{Key: key, Value: val}
+}
+
+func Int64(key string, val int64) Field {
+	return Field{Key: key, Value: val}
+}
+
+// ByteString is a field with UTF-8 encoded data like device output.
+func ByteString(key string, val []byte) Field {
+	return Field{Key: key, Value: Text(val)}
+}
+
+func Stringer(key string, val fmt.Stringer) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Error is a field with key "error".
+func Error(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+func Any(key string, val any) Field {
+	return Field{Key: key, Value: val}
+}
+
+// Text is value of ByteString field, it distinguishes text from binary []byte values.
+type Text []byte
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...Field) {}
+func (nopLogger) Info(string, ...Field)  {}
+func (nopLogger) Warn(string, ...Field)  {}
+func (nopLogger) Error(string, ...Field) {}
+
+// Nop returns logger which discards everything.
+func Nop() Logger {
+	return nopLogger{}
+}
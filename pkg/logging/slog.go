@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlog returns Logger writing to slog logger. Fields are converted to attributes with the same keys.
+func NewSlog(logger *slog.Logger) Logger {
+	if logger == nil {
+		return Nop()
+	}
+	return slogLogger{logger: logger}
+}
+
+func (m slogLogger) Debug(msg string, fields ...Field) {
+	m.write(slog.LevelDebug, msg, fields)
+}
+
+func (m slogLogger) Info(msg string, fields ...Field) {
+	m.write(slog.LevelInfo, msg, fields)
+}
+
+func (m slogLogger) Warn(msg string, fields ...Field) {
+	m.write(slog.LevelWarn, msg, fields)
+}
+
+func (m slogLogger) Error(msg string, fields ...Field) {
+	m.write(slog.LevelError, msg, fields)
+}
+
+func (m slogLogger) write(level slog.Level, msg string, fields []Field) {
+	ctx := context.Background()
+	if !m.logger.Enabled(ctx, level) {
+		return
+	}
+	m.logger.LogAttrs(ctx, level, msg, slogAttrs(fields)...)
+}
+
+func slogAttrs(fields []Field) []slog.Attr {
+	res := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case Text:
+			res = append(res, slog.String(field.Key, string(v)))
+		case error:
+			res = append(res, slog.String(field.Key, v.Error()))
+		case fmt.Stringer:
+			res = append(res, slog.String(field.Key, v.String()))
+		default:
+			res = append(res, slog.Any(field.Key, v))
+		}
+	}
+	return res
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := NewSlog(slog.New(handler))
+	logger.Debug("read", ByteString("data", []byte("ok")), Int("n", 2))
+	logger.Error("failed", Error(errors.New("boom")), String("host", "sw1"))
+	logger.Info("retry")
+	logger.Warn("skipping")
+	require.Equal(t, "level=DEBUG msg=read data=ok n=2\nlevel=ERROR msg=failed error=boom host=sw1\nlevel=INFO msg=retry\nlevel=WARN msg=skipping\n", buf.String())
+}
+
+func TestSlogLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSlog(slog.New(slog.NewTextHandler(buf, nil)))
+	logger.Debug("read")
+	require.Zero(t, buf.Len())
+	NewSlog(nil).Debug("read")
+}
@@ -0,0 +1,64 @@
+// Package zaplog adapts zap logger to logging.Logger.
+package zaplog
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+)
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// New returns logging.Logger writing to zap logger.
+func New(logger *zap.Logger) logging.Logger {
+	if logger == nil {
+		return logging.Nop()
+	}
+	return zapLogger{logger: logger}
+}
+
+func (m zapLogger) Debug(msg string, fields ...logging.Field) {
+	m.write(zapcore.DebugLevel, msg, fields)
+}
+
+func (m zapLogger) Info(msg string, fields ...logging.Field) {
+	m.write(zapcore.InfoLevel, msg, fields)
+}
+
+func (m zapLogger) Warn(msg string, fields ...logging.Field) {
+	m.write(zapcore.WarnLevel, msg, fields)
+}
+
+func (m zapLogger) Error(msg string, fields ...logging.Field) {
+	m.write(zapcore.ErrorLevel, msg, fields)
+}
+
+func (m zapLogger) write(level zapcore.Level, msg string, fields []logging.Field) {
+	ce := m.logger.Check(level, msg)
+	if ce == nil {
+		return
+	}
+	ce.Write(zapFields(fields)...)
+}
+
+func zapFields(fields []logging.Field) []zap.Field {
+	res := make([]zap.Field, 0, len(fields))
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case logging.Text:
+			res = append(res, zap.ByteString(field.Key, v))
+		case error:
+			res = append(res, zap.NamedError(field.Key, v))
+		case fmt.Stringer:
+			res = append(res, zap.Stringer(field.Key, v))
+		default:
+			res = append(res, zap.Any(field.Key, v))
+		}
+	}
+	return res
+}
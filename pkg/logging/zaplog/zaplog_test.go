@@ -0,0 +1,39 @@
+package zaplog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+)
+
+func TestZap(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := New(zap.New(core))
+	logger.Debug("read", logging.ByteString("data", []byte("ok")), logging.Int("n", 2))
+	logger.Error("failed", logging.Error(errors.New("boom")), logging.String("host", "sw1"))
+	logger.Info("retry")
+	logger.Warn("skipping")
+
+	entries := logs.AllUntimed()
+	require.Len(t, entries, 4)
+	require.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	require.Equal(t, map[string]any{"data": "ok", "n": int64(2)}, entries[0].ContextMap())
+	require.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	require.Equal(t, map[string]any{"error": "boom", "host": "sw1"}, entries[1].ContextMap())
+	require.Equal(t, zapcore.InfoLevel, entries[2].Level)
+	require.Equal(t, zapcore.WarnLevel, entries[3].Level)
+}
+
+func TestZapLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	logger := New(zap.New(core))
+	logger.Debug("read")
+	require.Zero(t, logs.Len())
+	New(nil).Debug("read")
+}
@@ -38,6 +38,7 @@ type Config struct {
 	Debug              bool          `config:"debug,short=d,description=Set debug log level"`
 	DefaultReadTimeout time.Duration `config:"default-read-timeout,description=Default read timeout" yaml:"default_read_timeout"`
 	DefaultCmdTimeout  time.Duration `config:"default-cmd-timeout,description=Default command timeout" yaml:"default_cmd_timeout"`
+	ConnPoolMaxIdle    time.Duration `config:"conn-pool-max-idle,description=Keep idle device connections of ExecStream for reuse" yaml:"conn_pool_max_idle"`
 }
 
 type LogConfig struct {
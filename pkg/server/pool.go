@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// session is a connected device kept in connection pool between calls.
+type session struct {
+	streamer.Connector
+	dev   device.Device
+	trace *MultiTraceImp
+}
+
+var _ streamer.KeepAliver = (*session)(nil)
+
+func (m *session) KeepAlive(ctx context.Context) error {
+	if keepAliver, ok := m.Connector.(streamer.KeepAliver); ok {
+		return keepAliver.KeepAlive(ctx)
+	}
+	return nil
+}
+
+type sessionArgs struct {
+	hostname string
+	params   hostParams
+}
+
+// sessionArgsKey passes sessionArgs from Get to factory of pool.
+type sessionArgsKey struct{}
+
+// sessionPool keeps device sessions for reuse, sessions are keyed by host and all connection params.
+type sessionPool struct {
+	pool *streamer.Pool
+}
+
+// WithConnPool enables reuse of device connections between ExecStream calls.
+// See streamer.PoolOption for limits of idle connections.
+func WithConnPool(opts ...streamer.PoolOption) Option {
+	return func(h *Server) {
+		h.poolOpts = append([]streamer.PoolOption{}, opts...)
+		h.poolEnabled = true
+	}
+}
+
+func newSessionPool(factory func(ctx context.Context, hostname string, params hostParams) (*session, error), opts ...streamer.PoolOption) *sessionPool {
+	pool := streamer.NewPool(func(ctx context.Context, key string) (streamer.Connector, error) {
+		args, ok := ctx.Value(sessionArgsKey{}).(sessionArgs)
+		if !ok {
+			return nil, fmt.Errorf("no session args for key %s", key)
+		}
+		return factory(ctx, args.hostname, args.params)
+	}, opts...)
+	return &sessionPool{pool: pool}
+}
+
+// Get returns idle session for host with the same params or connects new one.
+func (m *sessionPool) Get(ctx context.Context, hostname string, params hostParams) (*session, error) {
+	key, err := sessionKey(ctx, hostname, params)
+	if err != nil {
+		return nil, err
+	}
+	// factory is called by Get, so args are not kept after it
+	ctx = context.WithValue(ctx, sessionArgsKey{}, sessionArgs{hostname: hostname, params: params})
+	conn, err := m.pool.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(*session), nil
+}
+
+func (m *sessionPool) Put(sess *session) {
+	m.pool.Put(sess)
+}
+
+func (m *sessionPool) Discard(sess *session) {
+	m.pool.Discard(sess)
+}
+
+func (m *sessionPool) Close() {
+	m.pool.Close()
+}
+
+// connectSession makes and connects device, trace of the session is set per call.
+func (m *Server) connectSession(ctx context.Context, hostname string, params hostParams) (*session, error) {
+	tr := NewMultiTrace()
+	connector, err := m.makeConnector(hostname, params, tr.Add, m.log.With(zap.String("cmd_host", hostname)))
+	if err != nil {
+		return nil, err
+	}
+	devFab, ok := m.deviceMaps[params.GetDevice()]
+	if !ok {
+		return nil, fmt.Errorf("unknown device %v", params.GetDevice())
+	}
+	dev := devFab(connector)
+	err = dev.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &session{Connector: connector, dev: dev, trace: tr}, nil
+}
+
+// getSession returns session from pool if it is enabled, otherwise new session.
+func (m *Server) getSession(ctx context.Context, hostname string, params hostParams) (*session, error) {
+	if m.pool == nil {
+		return m.connectSession(ctx, hostname, params)
+	}
+	return m.pool.Get(ctx, hostname, params)
+}
+
+// releaseSession returns session to pool, session is closed if pool is disabled or execution failed.
+func (m *Server) releaseSession(sess *session, execErr error) {
+	switch {
+	case m.pool == nil:
+		sess.dev.Close()
+	case execErr != nil:
+		m.pool.Discard(sess)
+	default:
+		m.pool.Put(sess)
+	}
+}
+
+func sessionKey(ctx context.Context, hostname string, params hostParams) (string, error) {
+	fingerprint, err := credentials.Fingerprint(ctx, params.creds)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{hostname, params.device, params.ip.String(), strconv.Itoa(params.port),
+		params.proxyJump, params.controlPath, params.host, fingerprint}, "/"), nil
+}
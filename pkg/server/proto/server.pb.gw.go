@@ -126,6 +126,27 @@ func request_Gnetcli_ExecChat_0(ctx context.Context, marshaler runtime.Marshaler
 	return stream, metadata, nil
 }
 
+func request_Gnetcli_ExecStream_0(ctx context.Context, marshaler runtime.Marshaler, client GnetcliClient, req *http.Request, pathParams map[string]string) (Gnetcli_ExecStreamClient, runtime.ServerMetadata, error) {
+	var protoReq CMD
+	var metadata runtime.ServerMetadata
+
+	if err := marshaler.NewDecoder(req.Body).Decode(&protoReq); err != nil && err != io.EOF {
+		return nil, metadata, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	stream, err := client.ExecStream(ctx, &protoReq)
+	if err != nil {
+		return nil, metadata, err
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return nil, metadata, err
+	}
+	metadata.HeaderMD = header
+	return stream, metadata, nil
+
+}
+
 func request_Gnetcli_AddDevice_0(ctx context.Context, marshaler runtime.Marshaler, client GnetcliClient, req *http.Request, pathParams map[string]string) (proto.Message, runtime.ServerMetadata, error) {
 	var protoReq Device
 	var metadata runtime.ServerMetadata
@@ -336,6 +357,13 @@ func RegisterGnetcliHandlerServer(ctx context.Context, mux *runtime.ServeMux, se
 		return
 	})
 
+	mux.Handle("POST", pattern_Gnetcli_ExecStream_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		err := status.Error(codes.Unimplemented, "streaming calls are not yet supported in the in-process transport")
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+		return
+	})
+
 	mux.Handle("POST", pattern_Gnetcli_AddDevice_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
@@ -550,6 +578,28 @@ func RegisterGnetcliHandlerClient(ctx context.Context, mux *runtime.ServeMux, cl
 
 	})
 
+	mux.Handle("POST", pattern_Gnetcli_ExecStream_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		var err error
+		var annotatedContext context.Context
+		annotatedContext, err = runtime.AnnotateContext(ctx, mux, req, "/gnetcli.Gnetcli/ExecStream", runtime.WithHTTPPathPattern("/api/v1/exec_stream"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := request_Gnetcli_ExecStream_0(annotatedContext, inboundMarshaler, client, req, pathParams)
+		annotatedContext = runtime.NewServerMetadataContext(annotatedContext, md)
+		if err != nil {
+			runtime.HTTPError(annotatedContext, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_Gnetcli_ExecStream_0(annotatedContext, mux, outboundMarshaler, w, req, func() (proto.Message, error) { return resp.Recv() }, mux.GetForwardResponseOptions()...)
+
+	})
+
 	mux.Handle("POST", pattern_Gnetcli_AddDevice_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
 		ctx, cancel := context.WithCancel(req.Context())
 		defer cancel()
@@ -670,6 +720,8 @@ var (
 
 	pattern_Gnetcli_ExecChat_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"gnetcli.Gnetcli", "ExecChat"}, ""))
 
+	pattern_Gnetcli_ExecStream_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"api", "v1", "exec_stream"}, ""))
+
 	pattern_Gnetcli_AddDevice_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"api", "v1", "add_device"}, ""))
 
 	pattern_Gnetcli_ExecNetconf_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"api", "v1", "exec_netconf"}, ""))
@@ -688,6 +740,8 @@ var (
 
 	forward_Gnetcli_ExecChat_0 = runtime.ForwardResponseStream
 
+	forward_Gnetcli_ExecStream_0 = runtime.ForwardResponseStream
+
 	forward_Gnetcli_AddDevice_0 = runtime.ForwardResponseMessage
 
 	forward_Gnetcli_ExecNetconf_0 = runtime.ForwardResponseMessage
@@ -22,6 +22,7 @@ type GnetcliClient interface {
 	SetupHostParams(ctx context.Context, in *HostParams, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Exec(ctx context.Context, in *CMD, opts ...grpc.CallOption) (*CMDResult, error)
 	ExecChat(ctx context.Context, opts ...grpc.CallOption) (Gnetcli_ExecChatClient, error)
+	ExecStream(ctx context.Context, in *CMD, opts ...grpc.CallOption) (Gnetcli_ExecStreamClient, error)
 	AddDevice(ctx context.Context, in *Device, opts ...grpc.CallOption) (*DeviceResult, error)
 	ExecNetconf(ctx context.Context, in *CMDNetconf, opts ...grpc.CallOption) (*CMDResult, error)
 	ExecNetconfChat(ctx context.Context, opts ...grpc.CallOption) (Gnetcli_ExecNetconfChatClient, error)
@@ -86,6 +87,38 @@ func (x *gnetcliExecChatClient) Recv() (*CMDResult, error) {
 	return m, nil
 }
 
+func (c *gnetcliClient) ExecStream(ctx context.Context, in *CMD, opts ...grpc.CallOption) (Gnetcli_ExecStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gnetcli_ServiceDesc.Streams[1], "/gnetcli.Gnetcli/ExecStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gnetcliExecStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gnetcli_ExecStreamClient interface {
+	Recv() (*CMDResult, error)
+	grpc.ClientStream
+}
+
+type gnetcliExecStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *gnetcliExecStreamClient) Recv() (*CMDResult, error) {
+	m := new(CMDResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *gnetcliClient) AddDevice(ctx context.Context, in *Device, opts ...grpc.CallOption) (*DeviceResult, error) {
 	out := new(DeviceResult)
 	err := c.cc.Invoke(ctx, "/gnetcli.Gnetcli/AddDevice", in, out, opts...)
@@ -105,7 +138,7 @@ func (c *gnetcliClient) ExecNetconf(ctx context.Context, in *CMDNetconf, opts ..
 }
 
 func (c *gnetcliClient) ExecNetconfChat(ctx context.Context, opts ...grpc.CallOption) (Gnetcli_ExecNetconfChatClient, error) {
-	stream, err := c.cc.NewStream(ctx, &Gnetcli_ServiceDesc.Streams[1], "/gnetcli.Gnetcli/ExecNetconfChat", opts...)
+	stream, err := c.cc.NewStream(ctx, &Gnetcli_ServiceDesc.Streams[2], "/gnetcli.Gnetcli/ExecNetconfChat", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -160,6 +193,7 @@ type GnetcliServer interface {
 	SetupHostParams(context.Context, *HostParams) (*emptypb.Empty, error)
 	Exec(context.Context, *CMD) (*CMDResult, error)
 	ExecChat(Gnetcli_ExecChatServer) error
+	ExecStream(*CMD, Gnetcli_ExecStreamServer) error
 	AddDevice(context.Context, *Device) (*DeviceResult, error)
 	ExecNetconf(context.Context, *CMDNetconf) (*CMDResult, error)
 	ExecNetconfChat(Gnetcli_ExecNetconfChatServer) error
@@ -181,6 +215,9 @@ func (UnimplementedGnetcliServer) Exec(context.Context, *CMD) (*CMDResult, error
 func (UnimplementedGnetcliServer) ExecChat(Gnetcli_ExecChatServer) error {
 	return status.Errorf(codes.Unimplemented, "method ExecChat not implemented")
 }
+func (UnimplementedGnetcliServer) ExecStream(*CMD, Gnetcli_ExecStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ExecStream not implemented")
+}
 func (UnimplementedGnetcliServer) AddDevice(context.Context, *Device) (*DeviceResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddDevice not implemented")
 }
@@ -271,6 +308,27 @@ func (x *gnetcliExecChatServer) Recv() (*CMD, error) {
 	return m, nil
 }
 
+func _Gnetcli_ExecStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CMD)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GnetcliServer).ExecStream(m, &gnetcliExecStreamServer{stream})
+}
+
+type Gnetcli_ExecStreamServer interface {
+	Send(*CMDResult) error
+	grpc.ServerStream
+}
+
+type gnetcliExecStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *gnetcliExecStreamServer) Send(m *CMDResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Gnetcli_AddDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Device)
 	if err := dec(in); err != nil {
@@ -408,6 +466,11 @@ var Gnetcli_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ExecStream",
+			Handler:       _Gnetcli_ExecStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "ExecNetconfChat",
 			Handler:       _Gnetcli_ExecNetconfChat_Handler,
@@ -128,20 +128,22 @@ class HostParams(_message.Message):
     def __init__(self, host: _Optional[str] = ..., credentials: _Optional[_Union[Credentials, _Mapping]] = ..., port: _Optional[int] = ..., device: _Optional[str] = ..., ip: _Optional[str] = ...) -> None: ...
 
 class CMDResult(_message.Message):
-    __slots__ = ("out", "out_str", "error", "error_str", "trace", "status")
+    __slots__ = ("out", "out_str", "error", "error_str", "trace", "status", "chunk")
     OUT_FIELD_NUMBER: _ClassVar[int]
     OUT_STR_FIELD_NUMBER: _ClassVar[int]
     ERROR_FIELD_NUMBER: _ClassVar[int]
     ERROR_STR_FIELD_NUMBER: _ClassVar[int]
     TRACE_FIELD_NUMBER: _ClassVar[int]
     STATUS_FIELD_NUMBER: _ClassVar[int]
+    CHUNK_FIELD_NUMBER: _ClassVar[int]
     out: bytes
     out_str: str
     error: bytes
     error_str: str
     trace: _containers.RepeatedCompositeFieldContainer[CMDTraceItem]
     status: int
-    def __init__(self, out: _Optional[bytes] = ..., out_str: _Optional[str] = ..., error: _Optional[bytes] = ..., error_str: _Optional[str] = ..., trace: _Optional[_Iterable[_Union[CMDTraceItem, _Mapping]]] = ..., status: _Optional[int] = ...) -> None: ...
+    chunk: bool
+    def __init__(self, out: _Optional[bytes] = ..., out_str: _Optional[str] = ..., error: _Optional[bytes] = ..., error_str: _Optional[str] = ..., trace: _Optional[_Iterable[_Union[CMDTraceItem, _Mapping]]] = ..., status: _Optional[int] = ..., chunk: bool = ...) -> None: ...
 
 class DeviceResult(_message.Message):
     __slots__ = ("res", "error")
@@ -30,6 +30,11 @@ class GnetcliStub(object):
                 request_serializer=server__pb2.CMD.SerializeToString,
                 response_deserializer=server__pb2.CMDResult.FromString,
                 )
+        self.ExecStream = channel.unary_stream(
+                '/gnetcli.Gnetcli/ExecStream',
+                request_serializer=server__pb2.CMD.SerializeToString,
+                response_deserializer=server__pb2.CMDResult.FromString,
+                )
         self.AddDevice = channel.unary_unary(
                 '/gnetcli.Gnetcli/AddDevice',
                 request_serializer=server__pb2.Device.SerializeToString,
@@ -78,6 +83,12 @@ class GnetcliServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def ExecStream(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
     def AddDevice(self, request, context):
         """Missing associated documentation comment in .proto file."""
         context.set_code(grpc.StatusCode.UNIMPLEMENTED)
@@ -126,6 +137,11 @@ def add_GnetcliServicer_to_server(servicer, server):
                     request_deserializer=server__pb2.CMD.FromString,
                     response_serializer=server__pb2.CMDResult.SerializeToString,
             ),
+            'ExecStream': grpc.unary_stream_rpc_method_handler(
+                    servicer.ExecStream,
+                    request_deserializer=server__pb2.CMD.FromString,
+                    response_serializer=server__pb2.CMDResult.SerializeToString,
+            ),
             'AddDevice': grpc.unary_unary_rpc_method_handler(
                     servicer.AddDevice,
                     request_deserializer=server__pb2.Device.FromString,
@@ -212,6 +228,23 @@ class Gnetcli(object):
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
 
+    @staticmethod
+    def ExecStream(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(request, target, '/gnetcli.Gnetcli/ExecStream',
+            server__pb2.CMD.SerializeToString,
+            server__pb2.CMDResult.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
     @staticmethod
     def AddDevice(request,
             target,
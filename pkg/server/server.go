@@ -60,6 +60,9 @@ type Server struct {
 	devAuthApp         authApp
 	defaultReadTimeout time.Duration
 	defaultCmdTimeout  time.Duration
+	poolEnabled        bool
+	poolOpts           []streamer.PoolOption
+	pool               *sessionPool
 }
 
 type hostParams struct {
@@ -162,6 +165,19 @@ func (m *Server) makeConnectArg(hostname string, params hostParams) (string, int
 }
 
 func (m *Server) makeDevice(hostname string, params hostParams, add func(op gtrace.Operation, data []byte), logger *zap.Logger) (device.Device, error) {
+	connector, err := m.makeConnector(hostname, params, add, logger)
+	if err != nil {
+		return nil, err
+	}
+	devFab, ok := m.deviceMaps[params.GetDevice()]
+	if !ok {
+		return nil, fmt.Errorf("unknown device %v", params.GetDevice())
+	}
+	devInited := devFab(connector)
+	return devInited, nil
+}
+
+func (m *Server) makeConnector(hostname string, params hostParams, add func(op gtrace.Operation, data []byte), logger *zap.Logger) (streamer.Connector, error) {
 	var creds credentials.Credentials
 	paramCreds := params.GetCredentials()
 	if paramCreds != nil {
@@ -173,7 +189,6 @@ func (m *Server) makeDevice(hostname string, params hostParams, add func(op gtra
 		}
 		creds = defcreds
 	}
-	deviceType := params.GetDevice()
 	streamerOpts := []ssh.StreamerOption{ssh.WithLogger(logger), ssh.WithTrace(add)}
 	connHost, port := m.makeConnectArg(hostname, params)
 	if port > 0 {
@@ -201,13 +216,7 @@ func (m *Server) makeDevice(hostname string, params hostParams, add func(op gtra
 	if params.controlPath != "" {
 		streamerOpts = append(streamerOpts, ssh.WithSSHControlFIle(params.controlPath))
 	}
-	connector := ssh.NewStreamer(connHost, creds, streamerOpts...)
-	devFab, ok := m.deviceMaps[deviceType]
-	if !ok {
-		return nil, fmt.Errorf("unknown device %v", deviceType)
-	}
-	devInited := devFab(connector)
-	return devInited, nil
+	return ssh.NewStreamer(connHost, creds, streamerOpts...), nil
 }
 
 func (m *Server) ExecChat(stream pb.Gnetcli_ExecChatServer) error {
@@ -251,13 +260,7 @@ func (m *Server) ExecChat(stream pb.Gnetcli_ExecChatServer) error {
 	}
 	defer devInited.Close()
 
-	opts := []gcmd.CmdOption{}
-	if m.defaultCmdTimeout > 0 {
-		opts = append(opts, gcmd.WithCmdTimeout(m.defaultCmdTimeout))
-	}
-	if m.defaultReadTimeout > 0 {
-		opts = append(opts, gcmd.WithReadTimeout(m.defaultReadTimeout))
-	}
+	opts := m.defaultCmdOpts()
 	cmd := firstCmd
 	for {
 		var traceRes []*pb.CMDTraceItem
@@ -319,6 +322,86 @@ func (m *Server) Exec(ctx context.Context, cmd *pb.CMD) (*pb.CMDResult, error) {
 	return stream.res, nil
 }
 
+// ExecStream executes command and sends output chunks as they are read from device,
+// before terminal parsing and escape stripping, chunks have Chunk set. The last message is the same as result of Exec.
+// Cancellation of the call closes device connection and interrupts the command.
+func (m *Server) ExecStream(cmd *pb.CMD, stream pb.Gnetcli_ExecStreamServer) error {
+	authData, ok := getAuthFromContext(stream.Context())
+	if !ok {
+		return errors.New("empty auth in exec stream")
+	}
+	logger := zap.New(m.log.Core()).With(zap.String("cmd_login", authData.GetUser()), zap.String("cmd_host", cmd.GetHost()))
+	err := validateCmd(cmd)
+	if err != nil {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	params, err := m.getHostParams(cmd.GetHost(), cmd.GetHostParams())
+	if err != nil {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	ctx, cancel := context.WithTimeout(stream.Context(), 20*time.Second)
+	sess, err := m.getSession(ctx, cmd.GetHost(), params)
+	cancel()
+	if err != nil {
+		return status.Errorf(codes.Internal, err.Error())
+	}
+	// device commands are not cancellable, closing of connection interrupts them
+	stopClose := context.AfterFunc(stream.Context(), sess.Close)
+
+	var cmdTr gtrace.Trace
+	traceIndex := -1
+	if cmd.GetTrace() {
+		cmdTr = gtrace.NewTraceLimited(cmdTraceLimit)
+		traceIndex = sess.trace.AddTrace(cmdTr)
+	}
+	// callback goroutine is finished when Execute returns
+	var sendErr error
+	opts := m.defaultCmdOpts()
+	opts = append(opts, gcmd.WithOutputCallback(func(data []byte) {
+		if sendErr == nil {
+			sendErr = stream.Send(makeServerChunk(cmd, data))
+		}
+	}))
+	start := time.Now()
+	res, err := sess.dev.Execute(makeGnetcliCmd(cmd, opts...))
+	logger.Debug("executed", zap.String("cmd", cmd.String()), zap.Duration("duration", time.Since(start)), zap.Error(err))
+	var traceRes []*pb.CMDTraceItem
+	if cmd.GetTrace() {
+		traceRes = gnetcliTraceToTrace(cmdTr)
+		_ = sess.trace.DelTrace(traceIndex)
+	}
+	if !stopClose() {
+		m.releaseSession(sess, stream.Context().Err())
+		return status.FromContextError(stream.Context().Err()).Err()
+	}
+	m.releaseSession(sess, err)
+	if sendErr != nil {
+		return status.Errorf(codes.Internal, sendErr.Error())
+	}
+	if err != nil {
+		return makeGRPCDeviceExecError(err)
+	}
+	return stream.Send(makeServerRes(cmd, res, traceRes))
+}
+
+// Close closes idle connections of pool, see WithConnPool.
+func (m *Server) Close() {
+	if m.pool != nil {
+		m.pool.Close()
+	}
+}
+
+func (m *Server) defaultCmdOpts() []gcmd.CmdOption {
+	opts := []gcmd.CmdOption{}
+	if m.defaultCmdTimeout > 0 {
+		opts = append(opts, gcmd.WithCmdTimeout(m.defaultCmdTimeout))
+	}
+	if m.defaultReadTimeout > 0 {
+		opts = append(opts, gcmd.WithReadTimeout(m.defaultReadTimeout))
+	}
+	return opts
+}
+
 type execChatWrapper struct {
 	cmd  *pb.CMD
 	seen bool
@@ -596,6 +679,10 @@ func New(devAuthApp authApp, deviceFilePath string, opts ...Option) (*Server, er
 	} else {
 		s.deviceMaps = deviceMap
 	}
+	if s.poolEnabled {
+		poolOpts := append([]streamer.PoolOption{streamer.WithPoolLogger(s.log)}, s.poolOpts...)
+		s.pool = newSessionPool(s.connectSession, poolOpts...)
+	}
 	return s, nil
 }
 
@@ -644,6 +731,17 @@ func makeServerRes(cmd *pb.CMD, cmdRes gcmd.CmdRes, tr []*pb.CMDTraceItem) *pb.C
 	return &res
 }
 
+// makeServerChunk makes ExecStream message with raw output chunk.
+func makeServerChunk(cmd *pb.CMD, data []byte) *pb.CMDResult {
+	res := pb.CMDResult{Chunk: true}
+	if cmd.GetStringResult() {
+		res.OutStr = string(data)
+	} else {
+		res.Out = append([]byte(nil), data...)
+	}
+	return &res
+}
+
 func validateCmd(cmd *pb.CMD) error {
 	if len(cmd.GetCmd()) == 0 {
 		return errEmptyCmd
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/device/genericcli"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	pb "github.com/annetutil/gnetcli/pkg/server/proto"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+	gmock "github.com/annetutil/gnetcli/pkg/testutils/mock"
+)
+
+const testDevice = "test"
+
+// startDevice runs mock device and returns host params of it.
+func startDevice(t *testing.T, dialog []gmock.Action) (*pb.HostParams, <-chan error) {
+	server, err := gmock.NewMockSSHServer(dialog)
+	require.NoError(t, err)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Run(context.Background())
+	}()
+	host, port := server.GetAddress()
+	return &pb.HostParams{Ip: host, Port: int32(port), Device: testDevice}, serverErr
+}
+
+// startServer runs GRPC server with testDevice type and returns connection to it.
+func startServer(t *testing.T, opts ...Option) (*Server, *grpc.ClientConn) {
+	s, err := New(NewAuthApp(authAppConfig{Login: "user"}, zap.NewNop()), "", opts...)
+	require.NoError(t, err)
+	s.deviceMaps[testDevice] = func(connector streamer.Connector) device.Device {
+		cli := genericcli.MakeGenericCLI(
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)(?P<prompt>(<\w+>))$`),
+			expr.NewSimpleExprLast200().FromPattern(`(\r\n|^)% Error: .+$`),
+		)
+		dev := genericcli.MakeGenericDevice(cli, connector)
+		return &dev
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	auth := NewAuthInsecure(zap.NewNop())
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auth.AuthenticateUnary), grpc.StreamInterceptor(auth.AuthenticateStream))
+	pb.RegisterGnetcliServer(grpcServer, s)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		grpcServer.Stop()
+		s.Close()
+	})
+	return s, conn
+}
+
+// recvAll returns chunks and the last message of ExecStream.
+func recvAll(stream pb.Gnetcli_ExecStreamClient) ([]*pb.CMDResult, *pb.CMDResult, error) {
+	var chunks []*pb.CMDResult
+	var last *pb.CMDResult
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return chunks, last, nil
+		}
+		if err != nil {
+			return chunks, last, err
+		}
+		if res.GetChunk() {
+			chunks = append(chunks, res)
+		} else {
+			last = res
+		}
+	}
+}
+
+func chunksOutput(chunks []*pb.CMDResult) string {
+	var res strings.Builder
+	for _, chunk := range chunks {
+		res.WriteString(chunk.GetOutStr())
+	}
+	return res.String()
+}
+
+func TestExecStream(t *testing.T) {
+	params, deviceErr := startDevice(t, []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("show\n"),
+		gmock.SendEcho("show\r\n"),
+		gmock.Send("line 1\r\n"),
+		gmock.Send("line 2\r\n<device>"),
+		gmock.Close(),
+	})
+	_, conn := startServer(t)
+	client := pb.NewGnetcliClient(conn)
+	stream, err := client.ExecStream(context.Background(), &pb.CMD{Host: "device", Cmd: "show", StringResult: true, HostParams: params})
+	require.NoError(t, err)
+	chunks, last, err := recvAll(stream)
+	require.NoError(t, err)
+	require.NotEmpty(t, chunks)
+	require.Contains(t, chunksOutput(chunks), "line 1\r\nline 2")
+	require.NotNil(t, last)
+	require.Equal(t, "line 1\nline 2", last.GetOutStr())
+	require.NoError(t, <-deviceErr)
+}
+
+func TestExecStreamPool(t *testing.T) {
+	// mock device accepts single connection, so the second call must reuse it
+	params, deviceErr := startDevice(t, []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("show\n"),
+		gmock.SendEcho("show\r\n"),
+		gmock.Send("first\r\n<device>"),
+		gmock.Expect("show\n"),
+		gmock.SendEcho("show\r\n"),
+		gmock.Send("second\r\n<device>"),
+		gmock.Close(),
+	})
+	s, conn := startServer(t, WithConnPool(streamer.WithPoolMaxIdle(time.Minute)))
+	client := pb.NewGnetcliClient(conn)
+	for _, expected := range []string{"first", "second"} {
+		stream, err := client.ExecStream(context.Background(), &pb.CMD{Host: "device", Cmd: "show", StringResult: true, HostParams: params})
+		require.NoError(t, err)
+		_, last, err := recvAll(stream)
+		require.NoError(t, err)
+		require.Equal(t, expected, last.GetOutStr())
+		stats := s.pool.pool.Stats()
+		require.Equal(t, 1, stats.Open)
+		require.Equal(t, 1, stats.Idle)
+	}
+	s.Close()
+	require.NoError(t, <-deviceErr)
+}
+
+func TestExecStreamDiscard(t *testing.T) {
+	params, _ := startDevice(t, []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("show\n"),
+		gmock.SendEcho("show\r\n"),
+		gmock.Close(),
+	})
+	s, conn := startServer(t, WithConnPool(streamer.WithPoolMaxIdle(time.Minute)))
+	client := pb.NewGnetcliClient(conn)
+	stream, err := client.ExecStream(context.Background(), &pb.CMD{Host: "device", Cmd: "show", HostParams: params})
+	require.NoError(t, err)
+	_, _, err = recvAll(stream)
+	require.Error(t, err)
+	stats := s.pool.pool.Stats()
+	require.Equal(t, 0, stats.Open)
+	require.Equal(t, 0, stats.Idle)
+}
+
+func TestExecStreamCancel(t *testing.T) {
+	params, deviceErr := startDevice(t, []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("tail\n"),
+		gmock.SendEcho("tail\r\n"),
+		gmock.Send("line 1\r\n"),
+		// prompt never comes, device waits for input till connection is closed
+		gmock.Expect("never\n"),
+	})
+	s, conn := startServer(t, WithConnPool(streamer.WithPoolMaxIdle(time.Minute)))
+	client := pb.NewGnetcliClient(conn)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.ExecStream(ctx, &pb.CMD{Host: "device", Cmd: "tail", StringResult: true, HostParams: params})
+	require.NoError(t, err)
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	require.True(t, res.GetChunk())
+	cancel()
+
+	select {
+	case err := <-deviceErr:
+		require.ErrorIs(t, err, gmock.ErrReadFailed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed after cancellation")
+	}
+	require.Eventually(t, func() bool {
+		return s.pool.pool.Stats().Open == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestExecStreamGateway(t *testing.T) {
+	params, deviceErr := startDevice(t, []gmock.Action{
+		gmock.Send("<device>"),
+		gmock.Expect("show\n"),
+		gmock.SendEcho("show\r\n"),
+		gmock.Send("line 1\r\n"),
+		gmock.Send("line 2\r\n<device>"),
+		gmock.Close(),
+	})
+	_, conn := startServer(t)
+	mux := runtime.NewServeMux()
+	require.NoError(t, pb.RegisterGnetcliHandler(context.Background(), mux, conn))
+	gateway := httptest.NewServer(mux)
+	defer gateway.Close()
+
+	body, err := protojson.Marshal(&pb.CMD{Host: "device", Cmd: "show", StringResult: true, HostParams: params})
+	require.NoError(t, err)
+	resp, err := http.Post(gateway.URL+"/api/v1/exec_stream", "application/json", strings.NewReader(string(body)))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var chunks []*pb.CMDResult
+	var last *pb.CMDResult
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line struct {
+			Result json.RawMessage `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		res := &pb.CMDResult{}
+		require.NoError(t, protojson.Unmarshal(line.Result, res))
+		if res.GetChunk() {
+			chunks = append(chunks, res)
+		} else {
+			last = res
+		}
+	}
+	require.NoError(t, scanner.Err())
+	require.Contains(t, chunksOutput(chunks), "line 1\r\nline 2")
+	require.NotNil(t, last)
+	require.Equal(t, "line 1\nline 2", last.GetOutStr())
+	require.NoError(t, <-deviceErr)
+}
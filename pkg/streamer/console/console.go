@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"regexp"
 	"strconv"
@@ -91,6 +92,11 @@ func (m *Streamer) InitAgentForward() error {
 	return errors.New("agent forwarding is not supported")
 }
 
+// StreamConn returns connection for custom protocols, see streamer.NewStreamConn.
+func (m *Streamer) StreamConn() io.ReadWriteCloser {
+	return streamer.NewStreamConn(m)
+}
+
 func (m *Streamer) SetTrace(cb trace.CB) {
 	m.trace = cb
 }
@@ -142,6 +148,9 @@ func NewStreamer(host, consolePort string, credentials credentials.Credentials,
 		trace:                  nil,
 	}
 
+	for _, opt := range streamer.DefaultOptions[StreamerOption]() {
+		opt(h)
+	}
 	for _, opt := range opts {
 		opt(h)
 	}
@@ -303,7 +312,7 @@ func (m *Streamer) setSSL(ctx context.Context) (net.Conn, error) {
 func (m *Streamer) login(ctx context.Context) (err error) {
 	login := anonymous
 	if m.credentials != nil {
-		login, err = m.credentials.GetUsername()
+		login, err = credentials.GetUsername(ctx, m.credentials)
 		if err != nil {
 			return err
 		}
@@ -572,7 +581,7 @@ func (m *Streamer) stopBufferReader() error {
 
 func (m *Streamer) setupConnection(ctx context.Context) error {
 	logger := m.logger.With(zap.String("host", m.host), zap.Int("port", m.port))
-	remote := fmt.Sprintf("%s:%d", m.host, m.port)
+	remote := net.JoinHostPort(m.host, strconv.Itoa(m.port))
 	if m.tunnel != nil || len(m.tunnelHost) > 0 {
 		logger.Debug("open connection", zap.String("tunnel", m.tunnel.Server.String()))
 		if m.tunnel == nil {
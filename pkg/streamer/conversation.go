@@ -0,0 +1,74 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var ErrUnexpectedPrompt = errors.New("unexpected prompt")
+
+const (
+	conversationStepExprName    = "step"
+	conversationSuccessExprName = "success"
+)
+
+// ConversationStep is a single step of ordered expect/send conversation.
+type ConversationStep struct {
+	Expect  expr.Expr
+	Answer  []byte
+	Timeout time.Duration // zero means no limit except ctx
+}
+
+func NewConversationStep(expect expr.Expr, answer []byte, timeout time.Duration) ConversationStep {
+	return ConversationStep{Expect: expect, Answer: answer, Timeout: timeout}
+}
+
+// Converse runs strictly ordered conversation: it waits for Expect of every step and writes its Answer.
+// Prompts of later steps or success prompt seen before expected one are reported as ErrUnexpectedPrompt.
+// Data left after a match stays in connector buffer, so prompts arriving in one burst are handled step by step,
+// but Expect anchored to the end of data ($) won't match if the next prompt is already received.
+// After all steps Converse waits for success expression. Returns everything read.
+func Converse(ctx context.Context, conn Connector, steps []ConversationStep, success expr.Expr) ([]byte, error) {
+	var read []byte
+	for i, step := range steps {
+		exprs := expr.NewSimpleExprListNamedOrdered(nil)
+		for _, nextStep := range steps[i:] {
+			exprs.Add(conversationStepExprName, nextStep.Expect)
+		}
+		if success != nil {
+			exprs.Add(conversationSuccessExprName, success)
+		}
+		stepCtx, cancel := ctx, context.CancelFunc(func() {})
+		if step.Timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+		}
+		res, err := conn.ReadTo(stepCtx, exprs)
+		cancel()
+		if err != nil {
+			return read, fmt.Errorf("conversation step %d: %w", i, err)
+		}
+		read = append(read, res.GetBefore()...)
+		read = append(read, res.GetMatched()...)
+		if res.GetPatternNo() != 0 {
+			return read, fmt.Errorf("conversation step %d: %w %q", i, ErrUnexpectedPrompt, res.GetMatched())
+		}
+		err = conn.Write(step.Answer)
+		if err != nil {
+			return read, fmt.Errorf("conversation step %d: write error %w", i, err)
+		}
+	}
+	if success == nil {
+		return read, nil
+	}
+	res, err := conn.ReadTo(ctx, success)
+	if err != nil {
+		return read, fmt.Errorf("conversation success: %w", err)
+	}
+	read = append(read, res.GetBefore()...)
+	read = append(read, res.GetMatched()...)
+	return read, nil
+}
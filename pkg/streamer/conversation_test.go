@@ -0,0 +1,62 @@
+package streamer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+// chanConnector is a Connector reading from channel and recording writes
+type chanConnector struct {
+	Connector
+	ch      chan []byte
+	extra   []byte
+	written []byte
+}
+
+func (m *chanConnector) ReadTo(ctx context.Context, ex expr.Expr) (ReadRes, error) {
+	res, extra, _, err := GenericReadX(ctx, m.extra, m.ch, 100, time.Second, ex, 0, 0)
+	m.extra = extra
+	if err != nil {
+		return nil, err
+	}
+	return res.ExprRes, nil
+}
+
+func (m *chanConnector) Write(data []byte) error {
+	m.written = append(m.written, data...)
+	return nil
+}
+
+func TestConverseBurst(t *testing.T) {
+	ch := make(chan []byte, 10)
+	ch <- []byte("Hostname: Domain: ")
+	ch <- []byte("Password: ")
+	ch <- []byte("welcome\r\n<device>")
+	conn := &chanConnector{ch: ch}
+	steps := []ConversationStep{
+		NewConversationStep(expr.NewSimpleExpr().FromPattern(`Hostname: `), []byte("dev\n"), time.Second),
+		NewConversationStep(expr.NewSimpleExpr().FromPattern(`Domain: `), []byte("example.com\n"), time.Second),
+		NewConversationStep(expr.NewSimpleExpr().FromPattern(`Password: `), []byte("secret\n"), time.Second),
+	}
+	_, err := Converse(context.Background(), conn, steps, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	require.Equal(t, "dev\nexample.com\nsecret\n", string(conn.written))
+}
+
+func TestConverseUnexpectedOrder(t *testing.T) {
+	ch := make(chan []byte, 10)
+	ch <- []byte("Password: ")
+	conn := &chanConnector{ch: ch}
+	steps := []ConversationStep{
+		NewConversationStep(expr.NewSimpleExpr().FromPattern(`Hostname: `), []byte("dev\n"), time.Second),
+		NewConversationStep(expr.NewSimpleExpr().FromPattern(`Password: `), []byte("secret\n"), time.Second),
+	}
+	_, err := Converse(context.Background(), conn, steps, nil)
+	require.ErrorIs(t, err, ErrUnexpectedPrompt)
+	require.Empty(t, conn.written)
+}
@@ -0,0 +1,31 @@
+package streamer
+
+import "sync"
+
+var defaults struct {
+	mu   sync.RWMutex
+	opts []any
+}
+
+// SetDefaults sets options applied by constructors of streamers before options passed to them,
+// so per-streamer options override defaults. Options of different packages may be mixed,
+// e.g. ssh.StreamerOption and telnet.StreamerOption, every constructor applies options of its own type only.
+// It replaces previous defaults and doesn't affect existing streamers.
+func SetDefaults(opts ...any) {
+	defaults.mu.Lock()
+	defer defaults.mu.Unlock()
+	defaults.opts = append([]any(nil), opts...)
+}
+
+// DefaultOptions returns options of type T set by SetDefaults in order of setting.
+func DefaultOptions[T any]() []T {
+	defaults.mu.RLock()
+	defer defaults.mu.RUnlock()
+	var res []T
+	for _, opt := range defaults.opts {
+		if typed, ok := opt.(T); ok {
+			res = append(res, typed)
+		}
+	}
+	return res
+}
@@ -0,0 +1,133 @@
+package streamer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/terminal"
+)
+
+const (
+	DefaultDetectProbes = 3
+	DefaultDetectSettle = 500 * time.Millisecond
+)
+
+var ErrPromptNotDetected = errors.New("prompt not detected")
+
+// DetectedPrompt is a result of DetectPrompt.
+type DetectedPrompt struct {
+	Prompt     []byte    // trailing line repeated by device, without surrounding spaces
+	Expr       expr.Expr // matches Prompt at the end of output, Prompt is captured as "prompt" group
+	Confidence float64   // share of probes which ended with Prompt, from 0 to 1
+	Banner     []byte    // output received before the first probe, like banner and MOTD
+}
+
+type detectPrompt struct {
+	probes int
+	settle time.Duration
+}
+
+type DetectPromptOption func(*detectPrompt)
+
+// DetectPromptWithProbes sets number of newlines sent to device.
+func DetectPromptWithProbes(probes int) DetectPromptOption {
+	return func(h *detectPrompt) {
+		h.probes = probes
+	}
+}
+
+// DetectPromptWithSettle sets period of silence after which output is considered complete.
+func DetectPromptWithSettle(settle time.Duration) DetectPromptOption {
+	return func(h *detectPrompt) {
+		h.settle = settle
+	}
+}
+
+// DetectPrompt discovers prompt of unknown device. It waits until banner and MOTD are printed,
+// sends newlines and takes the most repeated trailing line as prompt.
+// Connector must be initialized and implement Drainer.
+func DetectPrompt(ctx context.Context, conn Connector, opts ...DetectPromptOption) (*DetectedPrompt, error) {
+	drainer, ok := conn.(Drainer)
+	if !ok {
+		return nil, fmt.Errorf("prompt detection: %w", ErrNotSupported)
+	}
+	h := &detectPrompt{
+		probes: DefaultDetectProbes,
+		settle: DefaultDetectSettle,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.probes <= 0 {
+		return nil, errors.New("number of probes must be positive")
+	}
+	banner, err := drainQuiet(ctx, drainer, h.settle)
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	var best string
+	for i := 0; i < h.probes; i++ {
+		err = conn.Write([]byte("\n"))
+		if err != nil {
+			return nil, fmt.Errorf("write error %w", err)
+		}
+		data, err := drainQuiet(ctx, drainer, h.settle)
+		if err != nil {
+			return nil, err
+		}
+		line := trailingLine(data)
+		if len(line) == 0 {
+			continue
+		}
+		counts[string(line)]++
+		if counts[string(line)] > counts[best] {
+			best = string(line)
+		}
+	}
+	if len(best) == 0 {
+		return nil, ErrPromptNotDetected
+	}
+	pattern := fmt.Sprintf(`(\r\n|^)(?P<prompt>%s)\s*$`, regexp.QuoteMeta(best))
+	return &DetectedPrompt{
+		Prompt:     []byte(best),
+		Expr:       expr.NewSimpleExprLast200().FromPattern(pattern),
+		Confidence: float64(counts[best]) / float64(h.probes),
+		Banner:     banner,
+	}, nil
+}
+
+// drainQuiet reads until nothing arrives during settle period.
+func drainQuiet(ctx context.Context, drainer Drainer, settle time.Duration) ([]byte, error) {
+	var res []byte
+	for {
+		data, err := drainer.Drain(ctx, settle)
+		if err != nil {
+			return nil, fmt.Errorf("drain error %w", err)
+		}
+		if len(data) == 0 {
+			return res, nil
+		}
+		res = append(res, data...)
+	}
+}
+
+// trailingLine returns the last non-blank line of terminal output.
+func trailingLine(data []byte) []byte {
+	parsed, err := terminal.Parse(data)
+	if err != nil {
+		parsed = data
+	}
+	lines := bytes.Split(parsed, []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := bytes.TrimSpace(lines[i]); len(line) > 0 {
+			return line
+		}
+	}
+	return nil
+}
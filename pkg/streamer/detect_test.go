@@ -0,0 +1,34 @@
+package streamer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectPrompt(t *testing.T) {
+	rec := NewRecorder(
+		RecorderWithGreeting([]byte("Welcome\r\nrouter# is not a prompt\r\nMOTD: be nice\r\n\r\nrouter# ")),
+		RecorderWithEcho(),
+		RecorderWithPrompt([]byte("\r\nrouter# ")),
+	)
+	require.NoError(t, rec.Init(context.Background()))
+	res, err := DetectPrompt(context.Background(), rec)
+	require.NoError(t, err)
+	require.Equal(t, "router#", string(res.Prompt))
+	require.Equal(t, 1.0, res.Confidence)
+	require.Contains(t, string(res.Banner), "MOTD: be nice")
+	mres, ok := res.Expr.Match([]byte("show\r\nok\r\nrouter# "))
+	require.True(t, ok)
+	require.Equal(t, "router#", string(mres.GroupDict["prompt"]))
+	_, ok = res.Expr.Match([]byte("router# is not a prompt\r\n"))
+	require.False(t, ok)
+}
+
+func TestDetectPromptSilent(t *testing.T) {
+	rec := NewRecorder(RecorderWithGreeting([]byte("Welcome\r\n")))
+	require.NoError(t, rec.Init(context.Background()))
+	_, err := DetectPrompt(context.Background(), rec, DetectPromptWithProbes(2))
+	require.ErrorIs(t, err, ErrPromptNotDetected)
+}
@@ -0,0 +1,96 @@
+package streamer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+)
+
+var ErrStreamBufferFull = errors.New("stream buffer is full")
+
+// OutputDispatcher delivers output chunks to callback in separate goroutine,
+// so slow callback doesn't stall reading from device. Behavior on full buffer is set by cmd.BackpressureMode.
+type OutputDispatcher struct {
+	cb     func([]byte)
+	mode   cmd.BackpressureMode
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  [][]byte
+	size   int
+	closed bool
+	done   chan struct{}
+}
+
+func NewOutputDispatcher(cb func([]byte), mode cmd.BackpressureMode, size int) *OutputDispatcher {
+	if size <= 0 {
+		size = 1
+	}
+	h := &OutputDispatcher{
+		cb:     cb,
+		mode:   mode,
+		mu:     sync.Mutex{},
+		queue:  make([][]byte, 0, size),
+		size:   size,
+		closed: false,
+		done:   make(chan struct{}),
+	}
+	h.cond = sync.NewCond(&h.mu)
+	go h.run()
+	return h
+}
+
+// Push queues chunk for delivery. Data is copied.
+func (m *OutputDispatcher) Push(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	chunk := make([]byte, len(data))
+	copy(chunk, data)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.queue) >= m.size && !m.closed {
+		switch m.mode {
+		case cmd.BackpressureDropOldest:
+			m.queue = m.queue[1:]
+		case cmd.BackpressureError:
+			return ErrStreamBufferFull
+		default:
+			m.cond.Wait()
+		}
+	}
+	if m.closed {
+		return nil
+	}
+	m.queue = append(m.queue, chunk)
+	m.cond.Broadcast()
+	return nil
+}
+
+// Close waits for delivery of queued chunks.
+func (m *OutputDispatcher) Close() {
+	m.mu.Lock()
+	m.closed = true
+	m.cond.Broadcast()
+	m.mu.Unlock()
+	<-m.done
+}
+
+func (m *OutputDispatcher) run() {
+	defer close(m.done)
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 && !m.closed {
+			m.cond.Wait()
+		}
+		if len(m.queue) == 0 && m.closed {
+			m.mu.Unlock()
+			return
+		}
+		chunk := m.queue[0]
+		m.queue = m.queue[1:]
+		m.cond.Broadcast()
+		m.mu.Unlock()
+		m.cb(chunk)
+	}
+}
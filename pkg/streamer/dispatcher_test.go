@@ -0,0 +1,59 @@
+package streamer
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+)
+
+func TestOutputDispatcherBlock(t *testing.T) {
+	var res []byte
+	d := NewOutputDispatcher(func(data []byte) {
+		res = append(res, data...)
+	}, cmd.BackpressureBlock, 1)
+	for _, chunk := range []string{"a", "b", "c"} {
+		require.NoError(t, d.Push([]byte(chunk)))
+	}
+	d.Close()
+	require.Equal(t, "abc", string(res))
+}
+
+func TestOutputDispatcherError(t *testing.T) {
+	release := make(chan struct{})
+	started := sync.WaitGroup{}
+	started.Add(1)
+	once := sync.Once{}
+	d := NewOutputDispatcher(func(data []byte) {
+		once.Do(started.Done)
+		<-release
+	}, cmd.BackpressureError, 1)
+	require.NoError(t, d.Push([]byte("a")))
+	started.Wait() // "a" is in callback
+	require.NoError(t, d.Push([]byte("b")))
+	require.ErrorIs(t, d.Push([]byte("c")), ErrStreamBufferFull)
+	close(release)
+	d.Close()
+}
+
+func TestOutputDispatcherDropOldest(t *testing.T) {
+	release := make(chan struct{})
+	started := sync.WaitGroup{}
+	started.Add(1)
+	once := sync.Once{}
+	var res []byte
+	d := NewOutputDispatcher(func(data []byte) {
+		once.Do(started.Done)
+		<-release
+		res = append(res, data...)
+	}, cmd.BackpressureDropOldest, 1)
+	require.NoError(t, d.Push([]byte("a")))
+	started.Wait()
+	require.NoError(t, d.Push([]byte("b")))
+	require.NoError(t, d.Push([]byte("c")))
+	close(release)
+	d.Close()
+	require.Equal(t, "ac", string(res))
+}
@@ -1,11 +1,17 @@
 package streamer
 
 import (
+	"errors"
 	"fmt"
 )
 
+// ErrReadTimeout matches ReadTimeoutException caused by absence of data during read timeout,
+// but not by expiration of context.
+var ErrReadTimeout = errors.New("read timeout")
+
 type ReadTimeoutException struct {
-	LastRead []byte
+	LastRead  []byte
+	byContext bool
 }
 
 func (m *ReadTimeoutException) Error() string {
@@ -16,6 +22,9 @@ func (m *ReadTimeoutException) Is(target error) bool {
 	if _, ok := target.(*ReadTimeoutException); ok {
 		return true
 	}
+	if target == ErrReadTimeout {
+		return !m.byContext
+	}
 	return false
 }
 
@@ -38,6 +47,10 @@ func ThrowReadTimeoutException(lastRead []byte) error {
 	return &ReadTimeoutException{LastRead: lastRead}
 }
 
+func throwContextReadTimeoutException(lastRead []byte) error {
+	return &ReadTimeoutException{LastRead: lastRead, byContext: true}
+}
+
 func ThrowEOFException(lastRead []byte) error {
 	return &EOFException{LastRead: lastRead}
 }
@@ -0,0 +1,135 @@
+package streamer
+
+// EscapeMode sets which escape sequences are removed from device output.
+type EscapeMode int
+
+const (
+	// EscapeOff passes output as is.
+	EscapeOff EscapeMode = iota
+	// EscapeCSIOnly removes CSI sequences (colors, cursor movement, erase).
+	EscapeCSIOnly
+	// EscapeFull removes CSI, OSC, DCS and other escape sequences.
+	EscapeFull
+)
+
+const (
+	maxEscapeSeqLen    = 64
+	maxEscapeStringLen = 4096
+)
+
+type escapeState int
+
+const (
+	escNormal escapeState = iota
+	escStart
+	escCSI
+	escIntermediate
+	escString    // OSC, DCS, SOS, PM, APC, terminated by ST or BEL, unterminated string ends on newline or length limit
+	escStringEsc // ESC inside string, may be start of ST
+)
+
+// EscapeStripper removes escape sequences from a stream. Sequences split between chunks are buffered,
+// so partial bytes never leak into output.
+type EscapeStripper struct {
+	mode      EscapeMode
+	state     escapeState
+	pending   []byte
+	stringLen int // length of current string sequence
+}
+
+func NewEscapeStripper(mode EscapeMode) *EscapeStripper {
+	return &EscapeStripper{mode: mode, state: escNormal, pending: nil}
+}
+
+// Process returns chunk without escape sequences.
+func (m *EscapeStripper) Process(data []byte) []byte {
+	if m == nil || m.mode == EscapeOff {
+		return data
+	}
+	res := make([]byte, 0, len(data))
+	for _, b := range data {
+		switch m.state {
+		case escNormal:
+			if b == 0x1b {
+				m.state = escStart
+				m.pending = append(m.pending[:0], b)
+			} else {
+				res = append(res, b)
+			}
+		case escStart:
+			m.pending = append(m.pending, b)
+			switch {
+			case b == '[':
+				m.state = escCSI
+			case m.mode != EscapeFull:
+				res = m.flush(res)
+			case b == ']' || b == 'P' || b == 'X' || b == '^' || b == '_':
+				m.state = escString
+				m.stringLen = 0
+			case b >= 0x20 && b <= 0x2f:
+				m.state = escIntermediate
+			case b >= 0x30 && b <= 0x7e:
+				m.drop()
+			default:
+				res = m.flush(res)
+			}
+		case escCSI:
+			m.pending = append(m.pending, b)
+			switch {
+			case b >= 0x40 && b <= 0x7e:
+				m.drop()
+			case b >= 0x20 && b <= 0x3f && len(m.pending) < maxEscapeSeqLen:
+			default:
+				res = m.flush(res)
+			}
+		case escIntermediate:
+			m.pending = append(m.pending, b)
+			switch {
+			case b >= 0x30 && b <= 0x7e:
+				m.drop()
+			case b >= 0x20 && b <= 0x2f && len(m.pending) < maxEscapeSeqLen:
+			default:
+				res = m.flush(res)
+			}
+		case escString:
+			// string content is never flushed, so don't keep it
+			m.stringLen++
+			switch {
+			case b == 0x07:
+				m.drop()
+			case b == 0x1b:
+				m.state = escStringEsc
+			case b == '\r' || b == '\n':
+				// unterminated string must not swallow following lines
+				m.drop()
+				res = append(res, b)
+			case m.stringLen >= maxEscapeStringLen:
+				m.drop()
+			}
+		case escStringEsc:
+			switch {
+			case b == '\\':
+				m.drop()
+			case b == '\r' || b == '\n':
+				m.drop()
+				res = append(res, b)
+			case b == 0x1b:
+			default:
+				m.state = escString
+			}
+		}
+	}
+	return res
+}
+
+// flush returns malformed sequence to output.
+func (m *EscapeStripper) flush(res []byte) []byte {
+	res = append(res, m.pending...)
+	m.drop()
+	return res
+}
+
+func (m *EscapeStripper) drop() {
+	m.pending = m.pending[:0]
+	m.state = escNormal
+}
@@ -0,0 +1,42 @@
+package streamer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func stripChunks(mode EscapeMode, chunks ...string) string {
+	s := NewEscapeStripper(mode)
+	res := []byte{}
+	for _, chunk := range chunks {
+		res = append(res, s.Process([]byte(chunk))...)
+	}
+	return string(res)
+}
+
+func TestEscapeStripper(t *testing.T) {
+	colored := "\x1b[1;32mup\x1b[0m \x1b[2K<device>"
+	assert.Equal(t, colored, stripChunks(EscapeOff, colored))
+	assert.Equal(t, "up <device>", stripChunks(EscapeCSIOnly, colored))
+	assert.Equal(t, "up <device>", stripChunks(EscapeFull, colored))
+
+	// sequences split between chunks
+	assert.Equal(t, "up <device>", stripChunks(EscapeCSIOnly, "\x1b", "[1;3", "2mup\x1b[", "0m <device>"))
+	assert.Equal(t, "title<device>", stripChunks(EscapeFull, "title\x1b]0;ho", "st\x07<dev", "ice>"))
+	assert.Equal(t, "<device>", stripChunks(EscapeFull, "\x1b]0;host\x1b", "\\<device>"))
+
+	// only CSI is removed in CSIOnly mode
+	assert.Equal(t, "\x1b(Bok", stripChunks(EscapeCSIOnly, "\x1b(Bok"))
+	assert.Equal(t, "ok", stripChunks(EscapeFull, "\x1b(Bok"))
+	assert.Equal(t, "ok", stripChunks(EscapeFull, "\x1b=ok"))
+
+	// unterminated string ends on newline or length limit
+	assert.Equal(t, "\r\n<device>", stripChunks(EscapeFull, "\x1b]0;host", "\r\n<device>"))
+	assert.Equal(t, "\n<device>", stripChunks(EscapeFull, "\x1bPq\x1b", "\n<device>"))
+	assert.Equal(t, "<device>", stripChunks(EscapeFull, "\x1b]"+strings.Repeat("x", maxEscapeStringLen), "<device>"))
+
+	// malformed sequence is passed as is
+	assert.Equal(t, "\x1b[1\nok", stripChunks(EscapeCSIOnly, "\x1b[1\nok"))
+}
@@ -0,0 +1,41 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+// Expect reads until ex matches or ctx expires and returns everything read including the match.
+// Read timeout of connector is not applied, so ctx must have deadline for output which may never come.
+// On error, returns the last read data if it is known. Data after the match stays in connector buffer.
+func Expect(ctx context.Context, conn Connector, ex expr.Expr) ([]byte, error) {
+	prevTimeout := conn.SetReadTimeout(0)
+	defer conn.SetReadTimeout(prevTimeout)
+	res, err := conn.ReadTo(ctx, ex)
+	if err != nil {
+		var timeoutErr *ReadTimeoutException
+		if errors.As(err, &timeoutErr) {
+			return timeoutErr.LastRead, err
+		}
+		var eofErr *EOFException
+		if errors.As(err, &eofErr) {
+			return eofErr.LastRead, err
+		}
+		return nil, err
+	}
+	read := append([]byte{}, res.GetBefore()...)
+	read = append(read, res.GetMatched()...)
+	return read, nil
+}
+
+// Send writes raw data as is, without newline and waiting for echo. It is a counterpart of Expect.
+func Send(conn Connector, raw []byte) error {
+	err := conn.Write(raw)
+	if err != nil {
+		return fmt.Errorf("write error %w", err)
+	}
+	return nil
+}
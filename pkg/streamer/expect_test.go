@@ -0,0 +1,40 @@
+package streamer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+func TestExpect(t *testing.T) {
+	rec := NewRecorder(
+		RecorderWithGreeting([]byte("Booting\r\n")),
+		RecorderWithResponse(`reload\n`, []byte("Reloading...\r\nSystem Ready\r\nlogin: ")),
+	)
+	require.NoError(t, rec.Init(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, Send(rec, []byte("reload\n")))
+	read, err := Expect(ctx, rec, expr.NewSimpleExpr().FromPattern(`System Ready\r\n`))
+	require.NoError(t, err)
+	require.Equal(t, "Booting\r\nReloading...\r\nSystem Ready\r\n", string(read))
+	read, err = Expect(ctx, rec, expr.NewSimpleExpr().FromPattern(`login: $`))
+	require.NoError(t, err)
+	require.Equal(t, "login: ", string(read))
+}
+
+func TestExpectTimeout(t *testing.T) {
+	rec := NewRecorder(RecorderWithGreeting([]byte("Booting\r\n")))
+	rec.SetReadTimeout(time.Millisecond)
+	require.NoError(t, rec.Init(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	read, err := Expect(ctx, rec, expr.NewSimpleExpr().FromPattern(`System Ready`))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, "Booting\r\n", string(read))
+	require.Equal(t, time.Millisecond, rec.SetReadTimeout(0))
+}
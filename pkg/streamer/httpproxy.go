@@ -0,0 +1,165 @@
+package streamer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ProxyAuth is credentials for Basic authentication on proxy.
+type ProxyAuth struct {
+	Username string
+	Password string
+}
+
+// ProxyConnectError is returned when HTTP proxy refuses CONNECT request.
+type ProxyConnectError struct {
+	Proxy      string
+	Addr       string
+	StatusCode int
+	Status     string
+}
+
+func (e *ProxyConnectError) Error() string {
+	return fmt.Sprintf("proxy %s refused CONNECT to %s: %s", e.Proxy, e.Addr, e.Status)
+}
+
+type httpConnectDialer struct {
+	proxy     *url.URL
+	auth      *ProxyAuth
+	tlsConfig *tls.Config
+	dialer    Dialer
+}
+
+type HTTPConnectOption func(*httpConnectDialer)
+
+// HTTPConnectWithTLSConfig sets TLS config for connection to https proxy.
+func HTTPConnectWithTLSConfig(config *tls.Config) HTTPConnectOption {
+	return func(h *httpConnectDialer) {
+		h.tlsConfig = config
+	}
+}
+
+// HTTPConnectWithDialer sets dialer for connection to proxy itself.
+func HTTPConnectWithDialer(dialer Dialer) HTTPConnectOption {
+	return func(h *httpConnectDialer) {
+		h.dialer = dialer
+	}
+}
+
+// HTTPConnectDialer returns Dialer which makes connections through HTTP proxy using CONNECT method.
+// proxyURL scheme is http or https, the latter means TLS to proxy. Nil auth means credentials from proxyURL user info if any.
+func HTTPConnectDialer(proxyURL string, auth *ProxyAuth, opts ...HTTPConnectOption) (Dialer, error) {
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("proxy url error: %w", err)
+	}
+	if proxy.Scheme != "http" && proxy.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	if auth == nil && proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth = &ProxyAuth{Username: proxy.User.Username(), Password: password}
+	}
+	h := &httpConnectDialer{
+		proxy:     proxy,
+		auth:      auth,
+		tlsConfig: nil,
+		dialer:    nil,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+func (m *httpConnectDialer) proxyAddr() string {
+	if m.proxy.Port() != "" {
+		return m.proxy.Host
+	}
+	if m.proxy.Scheme == "https" {
+		return net.JoinHostPort(m.proxy.Hostname(), "443")
+	}
+	return net.JoinHostPort(m.proxy.Hostname(), "80")
+}
+
+func (m *httpConnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := DialCtx(ctx, m.dialer, "tcp", m.proxyAddr())
+	if err != nil {
+		return nil, fmt.Errorf("proxy dial error: %w", err)
+	}
+	// unblock handshake on context cancellation
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	res, err := m.connect(ctx, conn, addr)
+	if !stop() || err != nil {
+		_ = conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return res, nil
+}
+
+func (m *httpConnectDialer) connect(ctx context.Context, conn net.Conn, addr string) (net.Conn, error) {
+	if m.proxy.Scheme == "https" {
+		config := m.tlsConfig
+		if config == nil {
+			config = &tls.Config{}
+		} else {
+			config = config.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = m.proxy.Hostname()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("proxy tls error: %w", err)
+		}
+		conn = tlsConn
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if m.auth != nil {
+		token := base64.StdEncoding.EncodeToString([]byte(m.auth.Username + ":" + m.auth.Password))
+		req.Header.Set("Proxy-Authorization", "Basic "+token)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("proxy write error: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("proxy read error: %w", err)
+	}
+	// body is not read: on success there is tunneled data after headers, on failure connection is closed
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ProxyConnectError{Proxy: m.proxy.Redacted(), Addr: addr, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns data read ahead by proxy response parser before reading from connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (m *bufferedConn) Read(p []byte) (int, error) {
+	return m.reader.Read(p)
+}
@@ -0,0 +1,101 @@
+package streamer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// connectProxyHandler serves CONNECT requests with Basic auth user:pass.
+func connectProxyHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if user, pass, ok := parseProxyAuth(r); !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// greeting right after response checks that data read ahead is not lost
+		_ = buf.Flush()
+		_, _ = conn.Write([]byte("hello"))
+		go func() {
+			_, _ = io.Copy(target, conn)
+			_ = target.Close()
+		}()
+		_, _ = io.Copy(conn, target)
+		_ = conn.Close()
+	})
+}
+
+func parseProxyAuth(r *http.Request) (string, string, bool) {
+	req := &http.Request{Header: http.Header{"Authorization": r.Header["Proxy-Authorization"]}}
+	return req.BasicAuth()
+}
+
+func runEchoServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+	return listener.Addr().String()
+}
+
+func TestHTTPConnectDialer(t *testing.T) {
+	proxy := httptest.NewTLSServer(connectProxyHandler(t))
+	defer proxy.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(proxy.Certificate())
+	target := runEchoServer(t)
+	ctx := context.Background()
+
+	dialer, err := HTTPConnectDialer(proxy.URL, &ProxyAuth{Username: "user", Password: "pass"},
+		HTTPConnectWithTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}))
+	require.NoError(t, err)
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte(" world"))
+	require.NoError(t, err)
+	buf := make([]byte, len("hello world"))
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(buf))
+
+	dialer, err = HTTPConnectDialer(proxy.URL, &ProxyAuth{Username: "user", Password: "wrong"},
+		HTTPConnectWithTLSConfig(&tls.Config{RootCAs: roots, ServerName: "example.com"}))
+	require.NoError(t, err)
+	_, err = dialer.DialContext(ctx, "tcp", target)
+	var connectErr *ProxyConnectError
+	require.ErrorAs(t, err, &connectErr)
+	require.Equal(t, http.StatusProxyAuthRequired, connectErr.StatusCode)
+
+	_, err = HTTPConnectDialer("socks5://127.0.0.1:1080", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package streamer
+
+import (
+	"time"
+)
+
+// ConnectEvent describes finished connection attempt.
+type ConnectEvent struct {
+	Host     string
+	Duration time.Duration
+	Err      error
+}
+
+// CommandEvent describes finished command.
+type CommandEvent struct {
+	Host         string
+	Command      []byte
+	BytesWritten int
+	BytesRead    int // size of command result
+	Duration     time.Duration
+	Err          error
+}
+
+// ReconnectEvent describes reconnect attempt after connection loss.
+type ReconnectEvent struct {
+	Host    string
+	Attempt int
+	Err     error
+}
+
+// AuthEvent describes successful authentication.
+type AuthEvent struct {
+	Host   string
+	Method string // SSH method name like "publickey", "password" or "keyboard-interactive"
+}
+
+// Observer receives timing and outcome events for metrics. Methods are called synchronously and must not block.
+// Embed NopObserver to implement only needed methods.
+type Observer interface {
+	ConnectStart(host string)
+	ConnectDone(ConnectEvent)
+	CommandStart(host string, command []byte)
+	CommandDone(CommandEvent)
+	Reconnect(ReconnectEvent)
+	Auth(AuthEvent)
+}
+
+// NopObserver is Observer which does nothing.
+type NopObserver struct{}
+
+var _ Observer = NopObserver{}
+
+func (NopObserver) ConnectStart(string)         {}
+func (NopObserver) ConnectDone(ConnectEvent)    {}
+func (NopObserver) CommandStart(string, []byte) {}
+func (NopObserver) CommandDone(CommandEvent)    {}
+func (NopObserver) Reconnect(ReconnectEvent)    {}
+func (NopObserver) Auth(AuthEvent)              {}
+
+// CommandObserver is implemented by connectors which report commands executed by device over them to Observer.
+// Returned function must be called when command is finished.
+type CommandObserver interface {
+	ObserveCommand(command []byte) func(bytesWritten, bytesRead int, err error)
+}
+
+// ObserveConnect reports connection start and returns function reporting its end.
+func ObserveConnect(observer Observer, host string) func(err error) {
+	if observer == nil {
+		return func(error) {}
+	}
+	started := time.Now()
+	observer.ConnectStart(host)
+	return func(err error) {
+		observer.ConnectDone(ConnectEvent{Host: host, Duration: time.Since(started), Err: err})
+	}
+}
+
+// ObserveCommand reports command start and returns function reporting its end.
+func ObserveCommand(observer Observer, host string, command []byte) func(bytesWritten, bytesRead int, err error) {
+	if observer == nil {
+		return func(int, int, error) {}
+	}
+	started := time.Now()
+	observer.CommandStart(host, command)
+	return func(bytesWritten, bytesRead int, err error) {
+		observer.CommandDone(CommandEvent{
+			Host:         host,
+			Command:      command,
+			BytesWritten: bytesWritten,
+			BytesRead:    bytesRead,
+			Duration:     time.Since(started),
+			Err:          err,
+		})
+	}
+}
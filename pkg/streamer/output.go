@@ -0,0 +1,46 @@
+package streamer
+
+import (
+	"sync/atomic"
+)
+
+// OutputObserver is implemented by connectors which are able to report data as it is read from device,
+// before it is buffered for expression matching.
+type OutputObserver interface {
+	// SetOutputCallback sets callback for read data and returns previous one. Nil disables callback.
+	// Callback is called from read loop, so it must not block.
+	SetOutputCallback(cb func([]byte)) func([]byte)
+}
+
+// OutputHook holds output callback which may be changed concurrently with reading.
+type OutputHook struct {
+	cb atomic.Pointer[func([]byte)]
+}
+
+func NewOutputHook() *OutputHook {
+	return &OutputHook{}
+}
+
+// Set sets callback and returns previous one.
+func (m *OutputHook) Set(cb func([]byte)) func([]byte) {
+	var prev *func([]byte)
+	if cb == nil {
+		prev = m.cb.Swap(nil)
+	} else {
+		prev = m.cb.Swap(&cb)
+	}
+	if prev == nil {
+		return nil
+	}
+	return *prev
+}
+
+// Call passes data to callback if it is set.
+func (m *OutputHook) Call(data []byte) {
+	if m == nil || len(data) == 0 {
+		return
+	}
+	if cb := m.cb.Load(); cb != nil {
+		(*cb)(data)
+	}
+}
@@ -0,0 +1,430 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+var ErrPoolClosed = errors.New("pool is closed")
+
+// ConnectorFactory creates and initializes new connection to host.
+// Pool with WithPoolCredentials passes credentials which connection is keyed by in ctx, see PoolCredentials.
+type ConnectorFactory func(ctx context.Context, host string) (Connector, error)
+
+// poolCredentialsKey passes credentials fetched by Pool.Get to factory.
+type poolCredentialsKey struct{}
+
+// PoolCredentials returns credentials fetched by pool with WithPoolCredentials for the connection being created by factory.
+// Factory must connect with them instead of fetching credentials again, so connection matches its pool key.
+func PoolCredentials(ctx context.Context) (credentials.Credentials, bool) {
+	creds, ok := ctx.Value(poolCredentialsKey{}).(credentials.Credentials)
+	return creds, ok
+}
+
+// HealthCheck checks connection taken from idle list, failed connection is closed.
+type HealthCheck func(ctx context.Context, conn Connector) error
+
+// KeepAliver is implemented by connectors which can check that connection is alive without running commands.
+type KeepAliver interface {
+	KeepAlive(ctx context.Context) error
+}
+
+type PoolStats struct {
+	Open    int
+	Idle    int
+	InUse   int
+	Evicted int
+}
+
+type pooledConn struct {
+	conn      Connector
+	key       string
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+// Pool keeps initialized connections for reuse.
+type Pool struct {
+	factory      ConnectorFactory
+	logger       *zap.Logger
+	mu           sync.Mutex
+	idle         map[string][]*pooledConn
+	inUse        map[Connector]*pooledConn
+	evicted      int
+	closed       bool
+	maxIdle      time.Duration
+	maxLifetime  time.Duration
+	reapInterval time.Duration
+	reaperCancel context.CancelFunc
+	reaperDone   chan struct{}
+	maxPerHost   int
+	open         map[string]int // idle, in use and being created connections by key
+	released     chan struct{}  // closed and replaced when connection slot is freed
+	credentials  credentials.Provider
+	healthCheck  HealthCheck
+}
+
+type PoolOption func(*Pool)
+
+func WithPoolLogger(logger *zap.Logger) PoolOption {
+	return func(h *Pool) {
+		h.logger = logger
+	}
+}
+
+// WithPoolMaxIdle sets maximum duration connection can stay unused in pool
+func WithPoolMaxIdle(maxIdle time.Duration) PoolOption {
+	return func(h *Pool) {
+		h.maxIdle = maxIdle
+	}
+}
+
+// WithPoolMaxLifetime sets maximum duration since connection creation
+func WithPoolMaxLifetime(maxLifetime time.Duration) PoolOption {
+	return func(h *Pool) {
+		h.maxLifetime = maxLifetime
+	}
+}
+
+// WithPoolReapInterval sets how often reaper checks idle connections
+func WithPoolReapInterval(interval time.Duration) PoolOption {
+	return func(h *Pool) {
+		h.reapInterval = interval
+	}
+}
+
+// WithPoolMaxPerHost limits number of open connections to one host, Get waits for a free slot.
+func WithPoolMaxPerHost(max int) PoolOption {
+	return func(h *Pool) {
+		h.maxPerHost = max
+	}
+}
+
+// WithPoolCredentials makes pool key connections by host and fingerprint of credentials from provider,
+// so connections made with rotated or different credentials are not mixed up.
+// Fetched credentials are passed to factory, see PoolCredentials.
+func WithPoolCredentials(provider credentials.Provider) PoolOption {
+	return func(h *Pool) {
+		h.credentials = provider
+	}
+}
+
+// WithPoolHealthCheck sets check of idle connection on Get.
+// By default KeepAlive is called on connectors implementing KeepAliver.
+func WithPoolHealthCheck(check HealthCheck) PoolOption {
+	return func(h *Pool) {
+		h.healthCheck = check
+	}
+}
+
+func NewPool(factory ConnectorFactory, opts ...PoolOption) *Pool {
+	h := &Pool{
+		factory:      factory,
+		logger:       zap.NewNop(),
+		mu:           sync.Mutex{},
+		idle:         map[string][]*pooledConn{},
+		inUse:        map[Connector]*pooledConn{},
+		evicted:      0,
+		closed:       false,
+		maxIdle:      0,
+		maxLifetime:  0,
+		reapInterval: 0,
+		maxPerHost:   0,
+		open:         map[string]int{},
+		released:     make(chan struct{}),
+		credentials:  nil,
+		healthCheck:  keepAliveCheck,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.reapInterval == 0 {
+		h.reapInterval = minPositiveDuration(h.maxIdle, h.maxLifetime) / 2
+	}
+	if h.reapInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.reaperCancel = cancel
+		h.reaperDone = make(chan struct{})
+		go h.reaper(ctx)
+	}
+	return h
+}
+
+// Get returns idle connection to host or creates new one.
+// Idle connection is health checked and replaced with new one if the check fails.
+func (m *Pool) Get(ctx context.Context, host string) (Connector, error) {
+	key, creds, err := m.key(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if creds != nil {
+		ctx = context.WithValue(ctx, poolCredentialsKey{}, creds)
+	}
+	for {
+		m.mu.Lock()
+		if m.closed {
+			m.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		pc, expired := m.popIdle(key)
+		if pc != nil {
+			m.inUse[pc.conn] = pc
+			m.mu.Unlock()
+			closeConns(expired)
+			if err := m.check(ctx, pc); err != nil {
+				m.logger.Debug("health check failed", zap.String("key", key), zap.Error(err))
+				m.Discard(pc.conn)
+				continue
+			}
+			return pc.conn, nil
+		}
+		if m.maxPerHost > 0 && m.open[key] >= m.maxPerHost {
+			released := m.released
+			m.mu.Unlock()
+			closeConns(expired)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-released:
+			}
+			continue
+		}
+		m.open[key]++
+		m.mu.Unlock()
+		closeConns(expired)
+		return m.create(ctx, host, key)
+	}
+}
+
+// popIdle returns idle connection and expired connections to be closed after mu is unlocked, must be called with mu held
+func (m *Pool) popIdle(key string) (*pooledConn, []Connector) {
+	var expired []Connector
+	for len(m.idle[key]) > 0 {
+		idle := m.idle[key]
+		pc := idle[len(idle)-1]
+		m.idle[key] = idle[:len(idle)-1]
+		if m.isExpired(pc, time.Now()) {
+			expired = append(expired, m.evict(pc))
+			continue
+		}
+		return pc, expired
+	}
+	return nil, expired
+}
+
+func (m *Pool) create(ctx context.Context, host, key string) (Connector, error) {
+	conn, err := m.factory(ctx, host)
+	m.mu.Lock()
+	if err != nil {
+		m.release(key)
+		m.mu.Unlock()
+		return nil, err
+	}
+	if m.closed {
+		m.release(key)
+		m.mu.Unlock()
+		conn.Close()
+		return nil, ErrPoolClosed
+	}
+	now := time.Now()
+	pc := &pooledConn{conn: conn, key: key, createdAt: now, lastUsed: now}
+	m.inUse[conn] = pc
+	m.mu.Unlock()
+	return conn, nil
+}
+
+// key returns pool key of host and credentials it is made from.
+func (m *Pool) key(ctx context.Context, host string) (string, credentials.Credentials, error) {
+	if m.credentials == nil {
+		return host, nil, nil
+	}
+	creds, err := m.credentials.Get(ctx, host)
+	if err != nil {
+		return "", nil, err
+	}
+	fingerprint, err := credentials.Fingerprint(ctx, creds)
+	if err != nil {
+		return "", nil, err
+	}
+	return host + "/" + fingerprint, creds, nil
+}
+
+func (m *Pool) check(ctx context.Context, pc *pooledConn) error {
+	if m.healthCheck == nil {
+		return nil
+	}
+	return m.healthCheck(ctx, pc.conn)
+}
+
+func keepAliveCheck(ctx context.Context, conn Connector) error {
+	if keepAliver, ok := conn.(KeepAliver); ok {
+		return keepAliver.KeepAlive(ctx)
+	}
+	return nil
+}
+
+// Put returns connection obtained by Get to the pool.
+func (m *Pool) Put(conn Connector) {
+	m.mu.Lock()
+	pc, ok := m.inUse[conn]
+	if !ok {
+		m.mu.Unlock()
+		m.logger.Debug("put unknown connection")
+		return
+	}
+	delete(m.inUse, conn)
+	pc.lastUsed = time.Now()
+	if m.closed || m.isExpired(pc, pc.lastUsed) {
+		m.evict(pc)
+		m.mu.Unlock()
+		conn.Close()
+		return
+	}
+	m.idle[pc.key] = append(m.idle[pc.key], pc)
+	m.mu.Unlock()
+}
+
+// Discard closes connection obtained by Get instead of returning it to the pool.
+func (m *Pool) Discard(conn Connector) {
+	m.mu.Lock()
+	pc, ok := m.inUse[conn]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	delete(m.inUse, conn)
+	m.evict(pc)
+	m.mu.Unlock()
+	conn.Close()
+}
+
+func (m *Pool) Stats() PoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	idle := 0
+	for _, conns := range m.idle {
+		idle += len(conns)
+	}
+	return PoolStats{
+		Open:    idle + len(m.inUse),
+		Idle:    idle,
+		InUse:   len(m.inUse),
+		Evicted: m.evicted,
+	}
+}
+
+// Close stops reaper and closes idle connections. Connections in use are closed on Put.
+func (m *Pool) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	var evicted []Connector
+	for key, conns := range m.idle {
+		for _, pc := range conns {
+			evicted = append(evicted, m.evict(pc))
+		}
+		delete(m.idle, key)
+	}
+	// wake up Get calls waiting for a slot
+	close(m.released)
+	m.released = make(chan struct{})
+	m.mu.Unlock()
+	closeConns(evicted)
+	if m.reaperCancel != nil {
+		m.reaperCancel()
+		<-m.reaperDone
+	}
+}
+
+func (m *Pool) reaper(ctx context.Context) {
+	defer close(m.reaperDone)
+	ticker := time.NewTicker(m.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reap(time.Now())
+		}
+	}
+}
+
+// reap closes expired idle connections, connections in use are never touched
+func (m *Pool) reap(now time.Time) {
+	var evicted []Connector
+	defer func() {
+		closeConns(evicted)
+	}()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, conns := range m.idle {
+		alive := conns[:0]
+		for _, pc := range conns {
+			if m.isExpired(pc, now) {
+				evicted = append(evicted, m.evict(pc))
+				continue
+			}
+			alive = append(alive, pc)
+		}
+		if len(alive) == 0 {
+			delete(m.idle, key)
+		} else {
+			m.idle[key] = alive
+		}
+	}
+}
+
+func (m *Pool) isExpired(pc *pooledConn, now time.Time) bool {
+	if m.maxIdle > 0 && now.Sub(pc.lastUsed) > m.maxIdle {
+		return true
+	}
+	if m.maxLifetime > 0 && now.Sub(pc.createdAt) > m.maxLifetime {
+		return true
+	}
+	return false
+}
+
+// evict removes connection from pool and returns it, must be called with mu held.
+// Connection is closed by caller after mu is unlocked, so slow Close doesn't block the pool.
+func (m *Pool) evict(pc *pooledConn) Connector {
+	m.logger.Debug("evict connection", zap.String("key", pc.key))
+	m.evicted++
+	m.release(pc.key)
+	return pc.conn
+}
+
+func closeConns(conns []Connector) {
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+// release frees connection slot of key and wakes up Get calls waiting for it, must be called with mu held
+func (m *Pool) release(key string) {
+	m.open[key]--
+	if m.open[key] <= 0 {
+		delete(m.open, key)
+	}
+	close(m.released)
+	m.released = make(chan struct{})
+}
+
+func minPositiveDuration(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 || a < b {
+		return a
+	}
+	return b
+}
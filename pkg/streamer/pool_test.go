@@ -0,0 +1,215 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+type poolTestConn struct {
+	Connector
+	closed  bool
+	dead    bool
+	onClose func()
+	creds   credentials.Credentials
+}
+
+func (m *poolTestConn) KeepAlive(ctx context.Context) error {
+	if m.dead {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func (m *poolTestConn) Close() {
+	m.closed = true
+	if m.onClose != nil {
+		m.onClose()
+	}
+}
+
+func TestPoolReuse(t *testing.T) {
+	created := 0
+	pool := NewPool(func(ctx context.Context, host string) (Connector, error) {
+		created++
+		return &poolTestConn{}, nil
+	})
+	defer pool.Close()
+	ctx := context.Background()
+
+	conn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.Equal(t, PoolStats{Open: 1, Idle: 0, InUse: 1}, pool.Stats())
+	pool.Put(conn)
+	require.Equal(t, PoolStats{Open: 1, Idle: 1, InUse: 0}, pool.Stats())
+
+	conn2, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.Same(t, conn, conn2)
+	_, err = pool.Get(ctx, "host2")
+	require.NoError(t, err)
+	require.Equal(t, 2, created)
+}
+
+func TestPoolReap(t *testing.T) {
+	pool := NewPool(func(ctx context.Context, host string) (Connector, error) {
+		return &poolTestConn{}, nil
+	}, WithPoolMaxIdle(time.Minute), WithPoolMaxLifetime(time.Hour), WithPoolReapInterval(time.Hour))
+	defer pool.Close()
+	ctx := context.Background()
+
+	idleConn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	busyConn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	pool.Put(idleConn)
+
+	pool.reap(time.Now().Add(2 * time.Minute))
+	require.True(t, idleConn.(*poolTestConn).closed)
+	require.False(t, busyConn.(*poolTestConn).closed)
+	require.Equal(t, PoolStats{Open: 1, Idle: 0, InUse: 1, Evicted: 1}, pool.Stats())
+
+	// connection in use is never reaped even if its lifetime is exceeded
+	pool.reap(time.Now().Add(2 * time.Hour))
+	require.False(t, busyConn.(*poolTestConn).closed)
+}
+
+func TestPoolMaxPerHost(t *testing.T) {
+	pool := NewPool(func(ctx context.Context, host string) (Connector, error) {
+		return &poolTestConn{}, nil
+	}, WithPoolMaxPerHost(1))
+	defer pool.Close()
+	ctx := context.Background()
+
+	conn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	_, err = pool.Get(ctx, "host2")
+	require.NoError(t, err)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = pool.Get(timeoutCtx, "host1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	got := make(chan Connector)
+	go func() {
+		conn, _ := pool.Get(ctx, "host1")
+		got <- conn
+	}()
+	pool.Put(conn)
+	select {
+	case conn2 := <-got:
+		require.Same(t, conn, conn2)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get is not woken up by Put")
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	created := 0
+	pool := NewPool(func(ctx context.Context, host string) (Connector, error) {
+		created++
+		return &poolTestConn{}, nil
+	})
+	defer pool.Close()
+	ctx := context.Background()
+
+	conn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	pool.Put(conn)
+	conn.(*poolTestConn).dead = true
+
+	conn2, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.NotSame(t, conn, conn2)
+	require.True(t, conn.(*poolTestConn).closed)
+	require.Equal(t, 2, created)
+	require.Equal(t, PoolStats{Open: 1, Idle: 0, InUse: 1, Evicted: 1}, pool.Stats())
+}
+
+func TestPoolCredentials(t *testing.T) {
+	users := map[string]string{"host1": "user1"}
+	provider := credentials.ProviderFunc(func(ctx context.Context, host string) (credentials.Credentials, error) {
+		return credentials.NewSimpleCredentials(credentials.WithUsername(users[host])), nil
+	})
+	pool := NewPool(func(ctx context.Context, host string) (Connector, error) {
+		creds, ok := PoolCredentials(ctx)
+		require.True(t, ok)
+		return &poolTestConn{creds: creds}, nil
+	}, WithPoolCredentials(provider))
+	defer pool.Close()
+	ctx := context.Background()
+
+	conn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	pool.Put(conn)
+	conn2, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.Same(t, conn, conn2)
+	pool.Put(conn2)
+
+	// rotated credentials don't reuse connection
+	users["host1"] = "user2"
+	conn3, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.NotSame(t, conn, conn3)
+	require.Equal(t, PoolStats{Open: 2, Idle: 1, InUse: 1}, pool.Stats())
+	// connection is made with the same credentials as its key
+	username, err := conn3.(*poolTestConn).creds.GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "user2", username)
+}
+
+func TestPoolCloseUnlocked(t *testing.T) {
+	var pool *Pool
+	locked := 0
+	pool = NewPool(func(ctx context.Context, host string) (Connector, error) {
+		return &poolTestConn{onClose: func() {
+			// slow Close of one connection must not block the pool
+			if !pool.mu.TryLock() {
+				locked++
+				return
+			}
+			pool.mu.Unlock()
+		}}, nil
+	}, WithPoolMaxIdle(time.Minute), WithPoolReapInterval(time.Hour))
+	ctx := context.Background()
+
+	// reaped
+	conn, err := pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	pool.Put(conn)
+	pool.reap(time.Now().Add(2 * time.Minute))
+	require.True(t, conn.(*poolTestConn).closed)
+
+	// failed health check
+	conn, err = pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	pool.Put(conn)
+	conn.(*poolTestConn).dead = true
+	_, err = pool.Get(ctx, "host1")
+	require.NoError(t, err)
+	require.True(t, conn.(*poolTestConn).closed)
+
+	// expired on Get
+	conn, err = pool.Get(ctx, "host2")
+	require.NoError(t, err)
+	pool.Put(conn)
+	pool.idle["host2"][0].lastUsed = time.Now().Add(-2 * time.Minute)
+	_, err = pool.Get(ctx, "host2")
+	require.NoError(t, err)
+	require.True(t, conn.(*poolTestConn).closed)
+
+	// closed pool
+	conn, err = pool.Get(ctx, "host3")
+	require.NoError(t, err)
+	pool.Put(conn)
+	pool.Close()
+	require.True(t, conn.(*poolTestConn).closed)
+	require.Equal(t, 0, locked)
+}
@@ -0,0 +1,178 @@
+package streamer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultProbeBannerWait = 2 * time.Second
+	probeMaxBanner         = 1024
+)
+
+// ProbeProtocol is a protocol detected by Probe.
+type ProbeProtocol string
+
+const (
+	ProbeUnknown ProbeProtocol = "unknown" // port is open, but server sent nothing recognizable
+	ProbeSSH     ProbeProtocol = "ssh"
+	ProbeTelnet  ProbeProtocol = "telnet"
+)
+
+// ProbeResult is a result of Probe.
+type ProbeResult struct {
+	Protocol       ProbeProtocol
+	Banner         string        // SSH version line or telnet text without negotiation commands
+	ConnectLatency time.Duration // time of TCP connect
+	BannerLatency  time.Duration // time from connect to the first data, zero if nothing was received
+}
+
+type probe struct {
+	dialer     Dialer
+	bannerWait time.Duration
+}
+
+type ProbeOption func(*probe)
+
+// ProbeWithDialer sets dialer for TCP connection, e.g. through jump host.
+func ProbeWithDialer(dialer Dialer) ProbeOption {
+	return func(h *probe) {
+		h.dialer = dialer
+	}
+}
+
+// ProbeWithBannerWait sets how long to wait for data from server after connect.
+func ProbeWithBannerWait(wait time.Duration) ProbeOption {
+	return func(h *probe) {
+		h.bannerWait = wait
+	}
+}
+
+// Probe checks reachability of host:port. It connects over TCP and reads what server sends first:
+// SSH version banner or telnet negotiation. Authentication is not done.
+// Server which sends nothing in banner wait time is reported with ProbeUnknown protocol and no error.
+func Probe(ctx context.Context, host string, port int, opts ...ProbeOption) (ProbeResult, error) {
+	h := &probe{bannerWait: DefaultProbeBannerWait}
+	for _, opt := range opts {
+		opt(h)
+	}
+	res := ProbeResult{Protocol: ProbeUnknown}
+	started := time.Now()
+	conn, err := DialCtx(ctx, h.dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return res, err
+	}
+	defer conn.Close()
+	connected := time.Now()
+	res.ConnectLatency = connected.Sub(started)
+
+	deadline := connected.Add(h.bannerWait)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetReadDeadline(deadline)
+	cancel := CloserCTX(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now())
+	})
+	defer cancel()
+
+	var data []byte
+	buf := make([]byte, probeMaxBanner)
+	for len(data) < probeMaxBanner {
+		n, err := conn.Read(buf[:probeMaxBanner-len(data)])
+		if n > 0 && len(data) == 0 {
+			res.BannerLatency = time.Since(connected)
+		}
+		data = append(data, buf[:n]...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if len(data) == 0 {
+				return res, err
+			}
+			break
+		}
+		if probeComplete(data) {
+			break
+		}
+	}
+	res.Protocol, res.Banner = parseProbeBanner(data)
+	return res, nil
+}
+
+// probeComplete reports whether data is enough to detect protocol.
+func probeComplete(data []byte) bool {
+	if data[0] == telnetIAC {
+		return true
+	}
+	// server may send other lines before version, RFC 4253 4.2
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("SSH-")) && bytes.HasSuffix(line, []byte("\n")) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseProbeBanner(data []byte) (ProbeProtocol, string) {
+	if len(data) == 0 {
+		return ProbeUnknown, ""
+	}
+	if data[0] == telnetIAC {
+		return ProbeTelnet, string(bytes.TrimSpace(stripTelnetCommands(data)))
+	}
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("SSH-")) {
+			return ProbeSSH, string(bytes.TrimRight(line, "\r\n"))
+		}
+	}
+	return ProbeUnknown, string(bytes.TrimSpace(data))
+}
+
+const (
+	telnetIAC  = 255
+	telnetSB   = 250
+	telnetSE   = 240
+	telnetWILL = 251
+	telnetDONT = 254
+)
+
+// stripTelnetCommands removes IAC sequences from data, RFC 854.
+func stripTelnetCommands(data []byte) []byte {
+	res := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] != telnetIAC {
+			res = append(res, data[i])
+			continue
+		}
+		if i+1 >= len(data) {
+			break
+		}
+		cmd := data[i+1]
+		switch {
+		case cmd == telnetIAC:
+			res = append(res, telnetIAC)
+			i++
+		case cmd >= telnetWILL && cmd <= telnetDONT:
+			i += 2
+		case cmd == telnetSB:
+			end := bytes.Index(data[i:], []byte{telnetIAC, telnetSE})
+			if end < 0 {
+				return res
+			}
+			i += end + 1
+		default:
+			i++
+		}
+	}
+	return res
+}
@@ -0,0 +1,59 @@
+package streamer
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	cases := []struct {
+		name     string
+		greeting []byte
+		protocol ProbeProtocol
+		banner   string
+	}{
+		{name: "ssh", greeting: []byte("SSH-2.0-TestDevice\r\n"), protocol: ProbeSSH, banner: "SSH-2.0-TestDevice"},
+		{name: "ssh with preceding lines", greeting: []byte("maintenance\r\nSSH-2.0-TestDevice\r\n"), protocol: ProbeSSH, banner: "SSH-2.0-TestDevice"},
+		{name: "telnet", greeting: []byte("\xff\xfd\x01\xff\xfb\x03\xff\xfa\x18\x01\xff\xf0\r\nlogin: "), protocol: ProbeTelnet, banner: "login:"},
+		{name: "silent", protocol: ProbeUnknown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			go func() {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_, _ = conn.Write(tc.greeting)
+				_, _ = conn.Read(make([]byte, 1))
+			}()
+
+			addr := listener.Addr().(*net.TCPAddr)
+			res, err := Probe(context.Background(), "127.0.0.1", addr.Port, ProbeWithBannerWait(100*time.Millisecond))
+			require.NoError(t, err)
+			require.Equal(t, tc.protocol, res.Protocol)
+			require.Equal(t, tc.banner, res.Banner)
+			require.Positive(t, res.ConnectLatency)
+			if len(tc.greeting) > 0 {
+				require.Positive(t, res.BannerLatency)
+			}
+		})
+	}
+}
+
+func TestProbeRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().(*net.TCPAddr)
+	require.NoError(t, listener.Close())
+	_, err = Probe(context.Background(), "127.0.0.1", addr.Port)
+	require.Error(t, err)
+}
@@ -0,0 +1,228 @@
+package streamer
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/trace"
+)
+
+var _ Connector = (*Recorder)(nil)
+
+type recorderRule struct {
+	input    *regexp.Regexp
+	response []byte
+}
+
+// Recorder is a Connector for dry runs: it doesn't touch network, records every write
+// and answers with scripted responses.
+type Recorder struct {
+	rules       []recorderRule
+	greeting    []byte
+	prompt      []byte
+	echo        bool
+	pending     []byte // input since last response
+	writes      [][]byte
+	output      []byte // responses which are not read yet
+	extra       []byte
+	readTimeout time.Duration
+	trace       trace.CB
+	credentials credentials.Credentials
+	mu          sync.Mutex
+}
+
+type RecorderOption func(*Recorder)
+
+// RecorderWithGreeting sets data returned right after Init, like banner and first prompt.
+func RecorderWithGreeting(greeting []byte) RecorderOption {
+	return func(h *Recorder) {
+		h.greeting = greeting
+	}
+}
+
+// RecorderWithEcho makes Recorder return written data back, as terminal does.
+func RecorderWithEcho() RecorderOption {
+	return func(h *Recorder) {
+		h.echo = true
+	}
+}
+
+// RecorderWithPrompt sets data returned after every input line which has no scripted response.
+func RecorderWithPrompt(prompt []byte) RecorderOption {
+	return func(h *Recorder) {
+		h.prompt = prompt
+	}
+}
+
+// RecorderWithResponse adds scripted response for input written since previous response.
+// Input must match pattern entirely, rules are checked in order of adding.
+func RecorderWithResponse(pattern string, response []byte) RecorderOption {
+	return func(h *Recorder) {
+		h.rules = append(h.rules, recorderRule{
+			input:    regexp.MustCompile(`\A(?:` + pattern + `)\z`),
+			response: response,
+		})
+	}
+}
+
+// RecorderWithCredentials sets credentials returned by GetCredentials.
+func RecorderWithCredentials(creds credentials.Credentials) RecorderOption {
+	return func(h *Recorder) {
+		h.credentials = creds
+	}
+}
+
+func NewRecorder(opts ...RecorderOption) *Recorder {
+	res := &Recorder{
+		rules:       nil,
+		greeting:    nil,
+		prompt:      nil,
+		echo:        false,
+		pending:     nil,
+		writes:      nil,
+		output:      nil,
+		extra:       nil,
+		readTimeout: defaultReplayReadTimeout,
+		trace:       nil,
+		credentials: credentials.NewSimpleCredentials(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// Writes returns recorded writes.
+func (m *Recorder) Writes() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make([][]byte, 0, len(m.writes))
+	for _, data := range m.writes {
+		res = append(res, append([]byte{}, data...))
+	}
+	return res
+}
+
+func (m *Recorder) Init(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.output = append(m.output, m.greeting...)
+	return nil
+}
+
+func (m *Recorder) Write(data []byte) error {
+	if m.trace != nil {
+		m.trace(trace.Write, data)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writes = append(m.writes, append([]byte{}, data...))
+	if m.echo {
+		m.output = append(m.output, data...)
+	}
+	m.pending = append(m.pending, data...)
+	for _, rule := range m.rules {
+		if rule.input.Match(m.pending) {
+			m.output = append(m.output, rule.response...)
+			m.pending = nil
+			return nil
+		}
+	}
+	if len(m.prompt) > 0 && len(m.pending) > 0 && m.pending[len(m.pending)-1] == '\n' {
+		m.output = append(m.output, m.prompt...)
+		m.pending = nil
+	}
+	return nil
+}
+
+// takeOutput moves responses to read buffer.
+func (m *Recorder) takeOutput() {
+	m.mu.Lock()
+	output := m.output
+	m.output = nil
+	m.mu.Unlock()
+	if len(output) > 0 && m.trace != nil {
+		m.trace(trace.Read, output)
+	}
+	m.extra = append(m.extra, output...)
+}
+
+func (m *Recorder) ReadTo(ctx context.Context, ex expr.Expr) (ReadRes, error) {
+	m.takeOutput()
+	// nothing else comes, so reading ends with match or timeout
+	res, extra, _, err := GenericReadX(ctx, m.extra, nil, readBufferSize, m.readTimeout, ex, 0, 0)
+	m.extra = extra
+	if err != nil {
+		return nil, err
+	}
+	return res.ExprRes, nil
+}
+
+func (m *Recorder) Read(ctx context.Context, n int) ([]byte, error) {
+	m.takeOutput()
+	res, extra, _, err := GenericReadX(ctx, m.extra, nil, n, m.readTimeout, nil, n, 0)
+	m.extra = extra
+	if err != nil {
+		return nil, err
+	}
+	return res.BytesRes, nil
+}
+
+// Drain returns responses which are not read yet, nothing else may arrive.
+func (m *Recorder) Drain(ctx context.Context, duration time.Duration) ([]byte, error) {
+	m.takeOutput()
+	res := m.extra
+	m.extra = nil
+	return res, nil
+}
+
+func (m *Recorder) GetCredentials() credentials.Credentials {
+	return m.credentials
+}
+
+func (m *Recorder) SetCredentialsInterceptor(func(credentials.Credentials) credentials.Credentials) {
+}
+
+func (m *Recorder) SetTrace(cb trace.CB) {
+	m.trace = cb
+}
+
+func (m *Recorder) SetReadTimeout(timeout time.Duration) time.Duration {
+	prev := m.readTimeout
+	m.readTimeout = timeout
+	return prev
+}
+
+func (m *Recorder) Close() {
+}
+
+func (m *Recorder) Cmd(ctx context.Context, cmd string) (cmd.CmdRes, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *Recorder) HasFeature(feature Const) bool {
+	return feature == AutoLogin
+}
+
+func (m *Recorder) Download(paths []string, recurse bool) (map[string]File, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *Recorder) Upload(map[string]File) error {
+	return ErrNotSupported
+}
+
+func (m *Recorder) InitAgentForward() error {
+	return ErrNotSupported
+}
+
+// StreamConn returns connection for custom protocols, see NewStreamConn.
+func (m *Recorder) StreamConn() io.ReadWriteCloser {
+	return NewStreamConn(m)
+}
@@ -0,0 +1,312 @@
+package streamer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/trace"
+)
+
+const defaultReplayReadTimeout = 5 * time.Second
+
+var ErrReplayMismatch = errors.New("write doesn't match transcript")
+
+var _ Connector = (*Replay)(nil)
+
+// replayStep is a sequence of writes and following reads.
+type replayStep struct {
+	write   []byte
+	writeRe *regexp.Regexp // used instead of write if set
+	read    []byte
+}
+
+// FixtureStep is a line of replay fixture: expected write and response to it.
+// WriteRe is a regular expression which must match whole input written since previous step, it is used instead of Write if set.
+// Step without write and regexp is read right after Init.
+type FixtureStep struct {
+	Write   string `json:"write,omitempty"`
+	WriteRe string `json:"write_re,omitempty"`
+	Read    string `json:"read,omitempty"`
+}
+
+// Replay is a Connector which serves recorded transcript: data read from device is returned
+// only after the code under test writes exactly the same bytes as were written in recorded session.
+type Replay struct {
+	steps       []replayStep
+	step        int
+	written     int    // written bytes of current step
+	pending     []byte // input written for regexp step
+	err         error  // first mismatch
+	ch          chan []byte
+	extra       []byte
+	readTimeout time.Duration
+	trace       trace.CB
+	credentials credentials.Credentials
+	autoLogin   bool
+	mu          sync.Mutex
+}
+
+type ReplayOption func(*Replay)
+
+// ReplayWithAutoLogin sets whether login is done by connector, as in SSH. Default is true.
+// Set it to false for transcripts containing login dialog, e.g. telnet ones.
+func ReplayWithAutoLogin(autoLogin bool) ReplayOption {
+	return func(h *Replay) {
+		h.autoLogin = autoLogin
+	}
+}
+
+// ReplayWithCredentials sets credentials returned by GetCredentials.
+func ReplayWithCredentials(creds credentials.Credentials) ReplayOption {
+	return func(h *Replay) {
+		h.credentials = creds
+	}
+}
+
+// NewReplay makes Replay from transcript written by trace.TranscriptWriter.
+func NewReplay(transcript io.Reader, opts ...ReplayOption) (*Replay, error) {
+	items, err := trace.ReadTranscript(transcript)
+	if err != nil {
+		return nil, fmt.Errorf("transcript read error: %w", err)
+	}
+	// data before first write (banner, prompt) goes to step without write
+	steps := []replayStep{{}}
+	for _, item := range items {
+		last := &steps[len(steps)-1]
+		switch item.GetOperation() {
+		case trace.Write:
+			if len(last.read) > 0 {
+				steps = append(steps, replayStep{})
+				last = &steps[len(steps)-1]
+			}
+			last.write = append(last.write, item.GetData()...)
+		case trace.Read:
+			last.read = append(last.read, item.GetData()...)
+		}
+	}
+	return newReplay(steps, opts...), nil
+}
+
+// NewReplayFixture makes Replay from fixture with FixtureStep JSON objects, one per line.
+// Empty lines and lines starting with # are skipped.
+func NewReplayFixture(fixture io.Reader, opts ...ReplayOption) (*Replay, error) {
+	var steps []replayStep
+	scanner := bufio.NewScanner(fixture)
+	scanner.Buffer(nil, 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		var item FixtureStep
+		if err := json.Unmarshal(line, &item); err != nil {
+			return nil, fmt.Errorf("fixture line %d: %w", lineNo, err)
+		}
+		step := replayStep{write: []byte(item.Write), read: []byte(item.Read)}
+		if len(item.WriteRe) > 0 {
+			re, err := regexp.Compile(`\A(?:` + item.WriteRe + `)\z`)
+			if err != nil {
+				return nil, fmt.Errorf("fixture line %d: %w", lineNo, err)
+			}
+			step.writeRe = re
+		}
+		steps = append(steps, step)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("fixture read error: %w", err)
+	}
+	return newReplay(steps, opts...), nil
+}
+
+func newReplay(steps []replayStep, opts ...ReplayOption) *Replay {
+	res := &Replay{
+		steps:       steps,
+		step:        0,
+		written:     0,
+		ch:          make(chan []byte, len(steps)),
+		extra:       nil,
+		readTimeout: defaultReplayReadTimeout,
+		trace:       nil,
+		credentials: credentials.NewSimpleCredentials(),
+		autoLogin:   true,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// Verify returns error if there was unexpected write or not all steps were replayed.
+func (m *Replay) Verify() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	if m.step < len(m.steps) {
+		return fmt.Errorf("%w: %d of %d steps are not replayed", ErrReplayMismatch, len(m.steps)-m.step, len(m.steps))
+	}
+	return nil
+}
+
+func (m *Replay) Init(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance()
+	return nil
+}
+
+// advance delivers reads of steps which don't wait for writes.
+func (m *Replay) advance() {
+	for m.step < len(m.steps) && m.steps[m.step].writeRe == nil && m.written == len(m.steps[m.step].write) {
+		if len(m.steps[m.step].read) > 0 {
+			m.ch <- m.steps[m.step].read
+		}
+		m.step++
+		m.written = 0
+	}
+	if m.step == len(m.steps) {
+		close(m.ch)
+		m.step++
+	}
+}
+
+func (m *Replay) Write(data []byte) error {
+	if m.trace != nil {
+		m.trace(trace.Write, data)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.write(data)
+	if err != nil && m.err == nil {
+		m.err = err
+	}
+	return err
+}
+
+func (m *Replay) write(data []byte) error {
+	for len(data) > 0 {
+		if m.step >= len(m.steps) {
+			return fmt.Errorf("%w: unexpected write %q after end of transcript", ErrReplayMismatch, data)
+		}
+		if re := m.steps[m.step].writeRe; re != nil {
+			return m.writeRe(re, data)
+		}
+		expected := m.steps[m.step].write[m.written:]
+		n := min(len(expected), len(data))
+		if !bytes.Equal(expected[:n], data[:n]) {
+			return fmt.Errorf("%w: expected %q, got %q", ErrReplayMismatch, expected, data)
+		}
+		m.written += n
+		data = data[n:]
+		m.advance()
+	}
+	return nil
+}
+
+// writeRe checks input against regexp step. Input is accumulated until it matches,
+// complete line which doesn't match is a mismatch.
+func (m *Replay) writeRe(re *regexp.Regexp, data []byte) error {
+	m.pending = append(m.pending, data...)
+	if re.Match(m.pending) {
+		if len(m.steps[m.step].read) > 0 {
+			m.ch <- m.steps[m.step].read
+		}
+		m.pending = nil
+		m.step++
+		m.written = 0
+		m.advance()
+		return nil
+	}
+	if bytes.HasSuffix(m.pending, []byte("\n")) {
+		pending := m.pending
+		m.pending = nil
+		return fmt.Errorf("%w: expected match of %s, got %q", ErrReplayMismatch, re, pending)
+	}
+	return nil
+}
+
+func (m *Replay) ReadTo(ctx context.Context, ex expr.Expr) (ReadRes, error) {
+	res, extra, read, err := GenericReadX(ctx, m.extra, m.ch, readBufferSize, m.readTimeout, ex, 0, 0)
+	if m.trace != nil {
+		m.trace(trace.Read, read)
+	}
+	m.extra = extra
+	if err != nil {
+		return nil, err
+	}
+	if res.RetType == EOF {
+		return nil, ThrowEOFException(GetLastBytes(read, readBufferSize))
+	}
+	return res.ExprRes, nil
+}
+
+func (m *Replay) Read(ctx context.Context, n int) ([]byte, error) {
+	res, extra, read, err := GenericReadX(ctx, m.extra, m.ch, n, m.readTimeout, nil, n, 0)
+	if m.trace != nil {
+		m.trace(trace.Read, read)
+	}
+	m.extra = extra
+	if err != nil {
+		return nil, err
+	}
+	return res.BytesRes, nil
+}
+
+func (m *Replay) GetCredentials() credentials.Credentials {
+	return m.credentials
+}
+
+func (m *Replay) SetCredentialsInterceptor(func(credentials.Credentials) credentials.Credentials) {
+}
+
+func (m *Replay) SetTrace(cb trace.CB) {
+	m.trace = cb
+}
+
+func (m *Replay) SetReadTimeout(timeout time.Duration) time.Duration {
+	prev := m.readTimeout
+	m.readTimeout = timeout
+	return prev
+}
+
+func (m *Replay) Close() {
+}
+
+func (m *Replay) Cmd(ctx context.Context, cmd string) (cmd.CmdRes, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *Replay) HasFeature(feature Const) bool {
+	return feature == AutoLogin && m.autoLogin
+}
+
+func (m *Replay) Download(paths []string, recurse bool) (map[string]File, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *Replay) Upload(map[string]File) error {
+	return ErrNotSupported
+}
+
+func (m *Replay) InitAgentForward() error {
+	return ErrNotSupported
+}
+
+// StreamConn returns connection for custom protocols, see NewStreamConn.
+func (m *Replay) StreamConn() io.ReadWriteCloser {
+	return NewStreamConn(m)
+}
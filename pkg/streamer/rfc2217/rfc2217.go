@@ -88,6 +88,11 @@ func (m *Streamer) InitAgentForward() error {
 	return errors.New("agent forwarding is not supported")
 }
 
+// StreamConn returns connection for custom protocols, see streamer.NewStreamConn.
+func (m *Streamer) StreamConn() io.ReadWriteCloser {
+	return streamer.NewStreamConn(m)
+}
+
 func (m *Streamer) SetReadTimeout(duration time.Duration) time.Duration {
 	prev := m.readTimeout
 	m.readTimeout = duration
@@ -208,6 +213,9 @@ func NewStreamer(host string, port int, credentials credentials.Credentials, opt
 		readTimeout:            defaultReadTimeout,
 		expectedTelnet:         []telnetOption{},
 	}
+	for _, opt := range streamer.DefaultOptions[StreamerOption]() {
+		opt(h)
+	}
 	for _, opt := range opts {
 		opt(h)
 	}
@@ -0,0 +1,122 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// ErrWorkerPanic matches TargetError caused by panic in worker of RunAll.
+var ErrWorkerPanic = errors.New("worker panic")
+
+// Target is a device for RunAll, Host must be unique among targets.
+type Target struct {
+	Host    string
+	Connect ConnectorFactory
+}
+
+// TargetError describes failure of target in RunAll.
+type TargetError struct {
+	Host    string
+	Connect bool // failed to connect, worker was not called
+	Err     error
+}
+
+func (e *TargetError) Error() string {
+	if e.Connect {
+		return fmt.Sprintf("%s: connect error: %v", e.Host, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Host, e.Err)
+}
+
+func (e *TargetError) Unwrap() error {
+	return e.Err
+}
+
+// RunResults maps host of every target to its *TargetError or nil on success.
+type RunResults map[string]error
+
+// Err joins errors of failed targets in order of hosts, nil if all targets succeeded.
+func (m RunResults) Err() error {
+	var errs []error
+	for _, host := range m.Failed() {
+		errs = append(errs, m[host])
+	}
+	return errors.Join(errs...)
+}
+
+// Failed returns sorted hosts of failed targets.
+func (m RunResults) Failed() []string {
+	var res []string
+	for host, err := range m {
+		if err != nil {
+			res = append(res, host)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// RunAll connects to targets with at most concurrency connections at once and calls worker for each of them.
+// Non-positive concurrency means no limit. Connection is closed after worker returns or panics,
+// panic is reported as error matching ErrWorkerPanic. When ctx is done, targets which are not started
+// fail with ctx error and connections of running workers are closed.
+func RunAll(ctx context.Context, targets []Target, worker func(Connector) error, concurrency int) RunResults {
+	if concurrency <= 0 || concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+	res := make(RunResults, len(targets))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for _, target := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			mu.Lock()
+			res[target.Host] = &TargetError{Host: target.Host, Connect: true, Err: ctx.Err()}
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(target Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := runTarget(ctx, target, worker)
+			mu.Lock()
+			res[target.Host] = err
+			mu.Unlock()
+		}(target)
+	}
+	wg.Wait()
+	return res
+}
+
+func runTarget(ctx context.Context, target Target, worker func(Connector) error) (err error) {
+	conn, err := target.Connect(ctx, target.Host)
+	if err != nil {
+		return &TargetError{Host: target.Host, Connect: true, Err: err}
+	}
+	var closeOnce sync.Once
+	closeConn := func() {
+		closeOnce.Do(conn.Close)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &TargetError{Host: target.Host, Err: fmt.Errorf("%w: %v\n%s", ErrWorkerPanic, r, debug.Stack())}
+		}
+	}()
+	defer closeConn()
+	cancel := CloserCTX(ctx, closeConn)
+	defer cancel()
+	err = worker(conn)
+	if err != nil {
+		return &TargetError{Host: target.Host, Err: err}
+	}
+	return nil
+}
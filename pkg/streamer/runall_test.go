@@ -0,0 +1,95 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type closeCountingConn struct {
+	Connector
+	closed *atomic.Int32
+}
+
+func (m closeCountingConn) Close() {
+	m.closed.Add(1)
+}
+
+func TestRunAll(t *testing.T) {
+	var closed atomic.Int32
+	connect := func(ctx context.Context, host string) (Connector, error) {
+		if host == "down" {
+			return nil, errors.New("no route to host")
+		}
+		return closeCountingConn{Connector: NewRecorder(), closed: &closed}, nil
+	}
+	var targets []Target
+	for i := 0; i < 10; i++ {
+		targets = append(targets, Target{Host: fmt.Sprintf("sw%d", i), Connect: connect})
+	}
+	targets = append(targets, Target{Host: "down", Connect: connect}, Target{Host: "panic", Connect: connect})
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	workerErr := errors.New("worker error")
+	res := RunAll(context.Background(), targets, func(conn Connector) error {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}, 3)
+	require.Len(t, res, 12)
+	require.LessOrEqual(t, maxRunning, 3)
+	require.Equal(t, []string{"down"}, res.Failed())
+	var targetErr *TargetError
+	require.ErrorAs(t, res.Err(), &targetErr)
+	require.True(t, targetErr.Connect)
+	require.Equal(t, int32(11), closed.Load())
+
+	closed.Store(0)
+	res = RunAll(context.Background(), targets[10:], func(conn Connector) error {
+		panic("boom")
+	}, 0)
+	require.Equal(t, []string{"down", "panic"}, res.Failed())
+	require.ErrorIs(t, res["panic"], ErrWorkerPanic)
+	require.Equal(t, int32(1), closed.Load())
+
+	res = RunAll(context.Background(), targets[:1], func(conn Connector) error {
+		return workerErr
+	}, 1)
+	require.ErrorIs(t, res["sw0"], workerErr)
+}
+
+func TestRunAllCancel(t *testing.T) {
+	var closed atomic.Int32
+	connect := func(ctx context.Context, host string) (Connector, error) {
+		return closeCountingConn{Connector: NewRecorder(), closed: &closed}, nil
+	}
+	targets := []Target{{Host: "sw1", Connect: connect}, {Host: "sw2", Connect: connect}}
+	ctx, cancel := context.WithCancel(context.Background())
+	res := RunAll(ctx, targets, func(conn Connector) error {
+		cancel()
+		// connection is closed on cancel
+		for conn.(closeCountingConn).closed.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		return context.Canceled
+	}, 1)
+	require.ErrorIs(t, res["sw1"], context.Canceled)
+	require.ErrorIs(t, res["sw2"], context.Canceled)
+	var targetErr *TargetError
+	require.ErrorAs(t, res["sw2"], &targetErr)
+	require.True(t, targetErr.Connect)
+	require.Equal(t, int32(1), closed.Load())
+}
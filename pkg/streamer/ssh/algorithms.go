@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Weak algorithms which are still the only option on old devices.
+var (
+	LegacyKeyExchanges = []string{
+		"diffie-hellman-group14-sha1",
+		"diffie-hellman-group-exchange-sha1",
+		"diffie-hellman-group1-sha1",
+	}
+	LegacyCiphers = []string{
+		"aes128-cbc",
+		"aes192-cbc",
+		"aes256-cbc",
+		"3des-cbc",
+		"arcfour256",
+		"arcfour128",
+		"arcfour",
+	}
+	LegacyMACs = []string{
+		"hmac-sha1",
+		"hmac-sha1-96",
+	}
+)
+
+// WithSSHAlgorithms replaces lists of key exchange, cipher and MAC algorithms offered to server in order of preference.
+// Nil list keeps default one. Algorithms unknown to golang.org/x/crypto/ssh make connection fail.
+// Compression can't be set: golang.org/x/crypto/ssh offers only "none", so zlib@openssh.com is never negotiated.
+// Connection through OpenSSH control master started with Compression=yes is compressed, see WithSSHControlFIle.
+func WithSSHAlgorithms(kex, ciphers, macs []string) StreamerOption {
+	return func(h *Streamer) {
+		h.keyExchanges = kex
+		h.ciphers = ciphers
+		h.macs = macs
+	}
+}
+
+// WithLegacyAlgorithms enables weak algorithms (SHA-1 key exchanges, CBC and RC4 ciphers, SHA-1 MACs)
+// after default ones for devices which support nothing else.
+// It reduces security of the connection, so use it only for hosts which require it.
+func WithLegacyAlgorithms() StreamerOption {
+	conf := ssh.Config{}
+	conf.SetDefaults()
+	return WithSSHAlgorithms(
+		appendMissing(conf.KeyExchanges, LegacyKeyExchanges),
+		appendMissing(conf.Ciphers, LegacyCiphers),
+		appendMissing(conf.MACs, LegacyMACs),
+	)
+}
+
+func appendMissing(list, items []string) []string {
+	res := slices.Clone(list)
+	for _, item := range items {
+		if !slices.Contains(res, item) {
+			res = append(res, item)
+		}
+	}
+	return res
+}
@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+func TestCertificateAuth(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ca, err := ssh.NewSignerFromKey(caKey)
+	require.NoError(t, err)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"user"},
+		ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	certPath := filepath.Join(dir, "id_ed25519-cert.pub")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0o600))
+	require.NoError(t, os.WriteFile(certPath, ssh.MarshalAuthorizedKey(cert), 0o600))
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), ca.PublicKey().Marshal())
+		},
+	}
+	listener, endpoint := listenLocal(t)
+	go serveExec(t, listener, &ssh.ServerConfig{PublicKeyCallback: checker.Authenticate})
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithCertificate(certPath, keyPath))
+	conn := NewStreamer(endpoint.Host, creds, WithPort(endpoint.Port))
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	res, err := conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(res.Output()))
+}
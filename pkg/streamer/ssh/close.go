@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrStreamerClosing is returned by Cmd called after CloseContext.
+var ErrStreamerClosing = errors.New("streamer is closing")
+
+// ContextCloser is implemented by Streamer and SSHTunnel, see CloseContext.
+type ContextCloser interface {
+	CloseContext(ctx context.Context) error
+}
+
+var _ ContextCloser = (*Streamer)(nil)
+var _ ContextCloser = (*SSHTunnel)(nil)
+
+// CloseContext stops accepting new forwards, waits for active ones to finish until ctx is done,
+// then closes them and the tunnel. It returns ctx error if forwards were closed forcibly.
+func (m *SSHTunnel) CloseContext(ctx context.Context) error {
+	if !m.isOpen {
+		return nil
+	}
+	m.forwardsMu.Lock()
+	m.closing = true
+	m.forwardsMu.Unlock()
+	err := waitGroupContext(ctx, &m.forwardsWg)
+	m.closeForwards()
+	m.close()
+	if m.jump != nil && m.jump.IsConnected() {
+		if closer, ok := m.jump.(ContextCloser); ok {
+			_ = closer.CloseContext(ctx)
+		} else {
+			m.jump.Close()
+		}
+	}
+	return err
+}
+
+// CloseContext waits for running Cmd calls to finish until ctx is done, then closes Streamer.
+// It returns ctx error if commands were interrupted.
+func (m *Streamer) CloseContext(ctx context.Context) error {
+	return m.cmds.closeContext(ctx, m.Close)
+}
+
+// cmdTracker counts running commands and rejects new ones after closing.
+type cmdTracker struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+}
+
+func (m *cmdTracker) start() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closing {
+		return ErrStreamerClosing
+	}
+	m.wg.Add(1)
+	return nil
+}
+
+func (m *cmdTracker) done() {
+	if m == nil {
+		return
+	}
+	m.wg.Done()
+}
+
+// closeContext waits for running commands until ctx is done and calls closeFn which interrupts the rest.
+func (m *cmdTracker) closeContext(ctx context.Context, closeFn func()) error {
+	if m == nil {
+		closeFn()
+		return nil
+	}
+	m.mu.Lock()
+	m.closing = true
+	m.mu.Unlock()
+	err := waitGroupContext(ctx, &m.wg)
+	closeFn()
+	return err
+}
+
+func waitGroupContext(ctx context.Context, wg interface{ Wait() }) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,189 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+const (
+	msgKexInit     = 20
+	maxPacketSize  = 35000
+	kexCookieSize  = 16
+	kexInitNameLen = 6 // name-lists we are interested in: kex, host key, ciphers and macs of both directions
+)
+
+// ciphers with integrated MAC, negotiated MAC is not used with them
+var aeadCiphers = map[string]bool{
+	"aes128-gcm@openssh.com":        true,
+	"aes256-gcm@openssh.com":        true,
+	"chacha20-poly1305@openssh.com": true,
+}
+
+// ConnInfo describes established SSH connection.
+type ConnInfo struct {
+	ClientVersion      string
+	ServerVersion      string
+	Banner             string
+	RemoteAddr         net.Addr
+	KeyExchange        string
+	HostKey            string
+	CipherClientServer string
+	CipherServerClient string
+	MACClientServer    string // empty for AEAD ciphers
+	MACServerClient    string // empty for AEAD ciphers
+}
+
+// ConnectionInfo returns negotiated algorithms and versions of current connection.
+// Info is not available for connections made through OpenSSH control master.
+func (m *Streamer) ConnectionInfo() (ConnInfo, error) {
+	if m.getConn() == nil {
+		return ConnInfo{}, errNotConnected
+	}
+	if m.connInfo == nil {
+		return ConnInfo{}, errors.New("connection info is not available")
+	}
+	return *m.connInfo, nil
+}
+
+// makeConnInfo fills ConnInfo from connection metadata and KEXINIT messages recorded by diag.
+func makeConnInfo(conn sshClient, diag *ConnectDiagnostics) *ConnInfo {
+	client, ok := conn.(interface {
+		ClientVersion() []byte
+		ServerVersion() []byte
+		RemoteAddr() net.Addr
+	})
+	if !ok {
+		return nil
+	}
+	diag.mu.Lock()
+	defer diag.mu.Unlock()
+	if diag.clientKex == nil || diag.serverKex == nil {
+		return nil
+	}
+	res := &ConnInfo{
+		ClientVersion:      string(client.ClientVersion()),
+		ServerVersion:      string(client.ServerVersion()),
+		Banner:             diag.Banner,
+		RemoteAddr:         client.RemoteAddr(),
+		KeyExchange:        findCommon(diag.clientKex.KexAlgos, diag.serverKex.KexAlgos),
+		HostKey:            findCommon(diag.clientKex.ServerHostKeyAlgos, diag.serverKex.ServerHostKeyAlgos),
+		CipherClientServer: findCommon(diag.clientKex.CiphersClientServer, diag.serverKex.CiphersClientServer),
+		CipherServerClient: findCommon(diag.clientKex.CiphersServerClient, diag.serverKex.CiphersServerClient),
+	}
+	if !aeadCiphers[res.CipherClientServer] {
+		res.MACClientServer = findCommon(diag.clientKex.MACsClientServer, diag.serverKex.MACsClientServer)
+	}
+	if !aeadCiphers[res.CipherServerClient] {
+		res.MACServerClient = findCommon(diag.clientKex.MACsServerClient, diag.serverKex.MACsServerClient)
+	}
+	return res
+}
+
+// findCommon returns first client algorithm supported by server, as in RFC 4253 7.1.
+func findCommon(client, server []string) string {
+	for _, c := range client {
+		for _, s := range server {
+			if c == s {
+				return c
+			}
+		}
+	}
+	return ""
+}
+
+type kexInitMsg struct {
+	KexAlgos            []string
+	ServerHostKeyAlgos  []string
+	CiphersClientServer []string
+	CiphersServerClient []string
+	MACsClientServer    []string
+	MACsServerClient    []string
+}
+
+// packetSniffer collects first binary packet after identification string.
+type packetSniffer struct {
+	buf         []byte
+	versionSeen bool
+	done        bool
+}
+
+// add consumes data and returns KEXINIT message once first packet is complete.
+func (m *packetSniffer) add(data []byte) *kexInitMsg {
+	if m.done {
+		return nil
+	}
+	m.buf = append(m.buf, data...)
+	if !m.versionSeen {
+		idx := bytes.IndexByte(m.buf, '\n')
+		if idx == -1 {
+			if len(m.buf) > 255 {
+				m.stop()
+			}
+			return nil
+		}
+		m.buf = m.buf[idx+1:]
+		m.versionSeen = true
+	}
+	if len(m.buf) < 4 {
+		return nil
+	}
+	length := binary.BigEndian.Uint32(m.buf)
+	if length > maxPacketSize {
+		m.stop()
+		return nil
+	}
+	if uint32(len(m.buf)-4) < length {
+		return nil
+	}
+	packet := m.buf[4 : 4+length]
+	m.stop()
+	return parseKexInit(packet)
+}
+
+func (m *packetSniffer) stop() {
+	m.done = true
+	m.buf = nil
+}
+
+// parseKexInit parses unencrypted packet (without length field) with KEXINIT message.
+func parseKexInit(packet []byte) *kexInitMsg {
+	if len(packet) < 1 {
+		return nil
+	}
+	padding := int(packet[0])
+	if len(packet) < 1+padding {
+		return nil
+	}
+	payload := packet[1 : len(packet)-padding]
+	if len(payload) < 1+kexCookieSize || payload[0] != msgKexInit {
+		return nil
+	}
+	payload = payload[1+kexCookieSize:]
+	lists := make([][]string, 0, kexInitNameLen)
+	for i := 0; i < kexInitNameLen; i++ {
+		if len(payload) < 4 {
+			return nil
+		}
+		size := binary.BigEndian.Uint32(payload)
+		if uint32(len(payload)-4) < size {
+			return nil
+		}
+		var names []string
+		if size > 0 {
+			names = strings.Split(string(payload[4:4+size]), ",")
+		}
+		lists = append(lists, names)
+		payload = payload[4+size:]
+	}
+	return &kexInitMsg{
+		KexAlgos:            lists[0],
+		ServerHostKeyAlgos:  lists[1],
+		CiphersClientServer: lists[2],
+		CiphersServerClient: lists[3],
+		MACsClientServer:    lists[4],
+		MACsServerClient:    lists[5],
+	}
+}
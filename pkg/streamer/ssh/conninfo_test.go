@@ -0,0 +1,69 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+func TestConnectionInfo(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runSessionLimitServer(t, listener, 1)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port))
+	_, err = conn.ConnectionInfo()
+	require.ErrorIs(t, err, errNotConnected)
+	require.NoError(t, conn.Init(context.Background()))
+	defer conn.Close()
+
+	info, err := conn.ConnectionInfo()
+	require.NoError(t, err)
+	require.Equal(t, "SSH-2.0-Go", info.ServerVersion)
+	require.Equal(t, addr.String(), info.RemoteAddr.String())
+	require.Equal(t, "ssh-ed25519", info.HostKey)
+	require.NotEmpty(t, info.KeyExchange)
+	require.NotEmpty(t, info.CipherClientServer)
+	require.Equal(t, info.CipherClientServer, info.CipherServerClient)
+	require.Equal(t, aeadCiphers[info.CipherClientServer], info.MACClientServer == "")
+}
+
+func TestFindCommon(t *testing.T) {
+	require.Equal(t, "b", findCommon([]string{"a", "b", "c"}, []string{"c", "b"}))
+	require.Equal(t, "", findCommon([]string{"a"}, []string{"b"}))
+}
+
+func TestSSHAlgorithms(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runSessionLimitServer(t, listener, 1)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port),
+		WithSSHAlgorithms([]string{"curve25519-sha256"}, []string{"aes128-ctr"}, []string{"hmac-sha2-256"}))
+	require.NoError(t, conn.Init(context.Background()))
+	defer conn.Close()
+
+	info, err := conn.ConnectionInfo()
+	require.NoError(t, err)
+	require.Equal(t, "curve25519-sha256", info.KeyExchange)
+	require.Equal(t, "aes128-ctr", info.CipherClientServer)
+	require.Equal(t, "hmac-sha2-256", info.MACServerClient)
+}
+
+func TestLegacyAlgorithms(t *testing.T) {
+	conn := NewStreamer("localhost", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithLegacyAlgorithms())
+	conf, err := conn.GetConfig(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, conf.KeyExchanges, "diffie-hellman-group1-sha1")
+	require.Contains(t, conf.Ciphers, "aes128-cbc")
+	require.Contains(t, conf.MACs, "hmac-sha1-96")
+	require.Equal(t, "curve25519-sha256", conf.KeyExchanges[0])
+}
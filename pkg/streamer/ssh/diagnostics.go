@@ -0,0 +1,205 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/gerror"
+)
+
+var attemptedMethodsRe = regexp.MustCompile(`attempted methods \[([^\]]*)\]`)
+
+// DialAttempt describes single attempt to dial an endpoint.
+type DialAttempt struct {
+	Address  string
+	Duration time.Duration
+	Err      error
+}
+
+// ConnectDiagnostics is returned from Init on connection failure and can be extracted with errors.As.
+type ConnectDiagnostics struct {
+	Attempts             []DialAttempt
+	AttemptedAuthMethods []string
+	ServerVersion        string
+	Banner               string
+	Started              time.Time
+	Duration             time.Duration
+	Err                  error
+	Reason               error // one of gerror connect failure reasons like gerror.ErrConnRefused, nil if unknown
+	mu                   sync.Mutex
+	clientKex            *kexInitMsg
+	serverKex            *kexInitMsg
+}
+
+func newConnectDiagnostics() *ConnectDiagnostics {
+	return &ConnectDiagnostics{Started: time.Now()}
+}
+
+func (m *ConnectDiagnostics) Error() string {
+	return m.Err.Error()
+}
+
+func (m *ConnectDiagnostics) Unwrap() error {
+	return m.Err
+}
+
+// Is makes ConnectDiagnostics match its Reason.
+func (m *ConnectDiagnostics) Is(target error) bool {
+	return m.Reason != nil && target == m.Reason
+}
+
+// String returns human-readable report.
+func (m *ConnectDiagnostics) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := strings.Builder{}
+	fmt.Fprintf(&res, "connect failed after %s: %v\n", m.Duration, m.Err)
+	for _, attempt := range m.Attempts {
+		fmt.Fprintf(&res, "dial %s took %s", attempt.Address, attempt.Duration)
+		if attempt.Err != nil {
+			fmt.Fprintf(&res, ": %v", attempt.Err)
+		}
+		res.WriteString("\n")
+	}
+	if len(m.ServerVersion) > 0 {
+		fmt.Fprintf(&res, "server version: %s\n", m.ServerVersion)
+	}
+	if len(m.Banner) > 0 {
+		fmt.Fprintf(&res, "banner: %s\n", m.Banner)
+	}
+	if len(m.AttemptedAuthMethods) > 0 {
+		fmt.Fprintf(&res, "auth methods: %s\n", strings.Join(m.AttemptedAuthMethods, ", "))
+	}
+	return res.String()
+}
+
+func (m *ConnectDiagnostics) addAttempt(address string, started time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Attempts = append(m.Attempts, DialAttempt{Address: address, Duration: time.Since(started), Err: err})
+}
+
+func (m *ConnectDiagnostics) setBanner(banner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Banner = banner
+	return nil
+}
+
+func (m *ConnectDiagnostics) setServerVersion(version string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ServerVersion = version
+}
+
+func (m *ConnectDiagnostics) setKexInit(kex *kexInitMsg, client bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client {
+		m.clientKex = kex
+	} else {
+		m.serverKex = kex
+	}
+}
+
+// fail finalizes diagnostics with err.
+func (m *ConnectDiagnostics) fail(err error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Err = err
+	m.Duration = time.Since(m.Started)
+	if match := attemptedMethodsRe.FindStringSubmatch(err.Error()); match != nil {
+		m.AttemptedAuthMethods = strings.Fields(match[1])
+	}
+	m.Reason = classifyConnectError(err, m.AttemptedAuthMethods)
+	return m
+}
+
+// classifyConnectError maps SSH and network errors to gerror connect failure reasons.
+func classifyConnectError(err error, attemptedMethods []string) error {
+	var mismatchErr *HostKeyMismatchError
+	if errors.As(err, &mismatchErr) {
+		return gerror.ErrHostKeyMismatch
+	}
+	if errors.Is(err, gerror.ErrAuthFailed) {
+		return gerror.ErrAuthFailed
+	}
+	if strings.Contains(err.Error(), "unable to authenticate") {
+		for _, method := range attemptedMethods {
+			if method != "none" {
+				return gerror.ErrAuthFailed
+			}
+		}
+		return gerror.ErrNoAuthMethod
+	}
+	return gerror.ClassifyNetError(err)
+}
+
+// versionConn records SSH identification string sent by server and first KEXINIT packets of both sides.
+type versionConn struct {
+	net.Conn
+	diag     *ConnectDiagnostics
+	buf      []byte
+	done     bool
+	kexRead  packetSniffer
+	kexWrite packetSniffer
+}
+
+func newVersionConn(conn net.Conn, diag *ConnectDiagnostics) net.Conn {
+	if diag == nil {
+		return conn
+	}
+	return &versionConn{Conn: conn, diag: diag}
+}
+
+func (m *versionConn) Read(b []byte) (int, error) {
+	n, err := m.Conn.Read(b)
+	if !m.done && n > 0 {
+		m.buf = append(m.buf, b[:n]...)
+		// server may send other lines before version, RFC 4253 4.2
+		for {
+			idx := bytes.IndexByte(m.buf, '\n')
+			if idx == -1 {
+				break
+			}
+			line := strings.TrimRight(string(m.buf[:idx]), "\r")
+			m.buf = m.buf[idx+1:]
+			if strings.HasPrefix(line, "SSH-") {
+				m.diag.setServerVersion(line)
+				m.done = true
+				m.kexRead.versionSeen = true
+				if kex := m.kexRead.add(m.buf); kex != nil {
+					m.diag.setKexInit(kex, false)
+				}
+				m.buf = nil
+				break
+			}
+		}
+		if len(m.buf) > 255 {
+			m.done = true
+			m.kexRead.done = true
+			m.buf = nil
+		}
+	} else if n > 0 {
+		if kex := m.kexRead.add(b[:n]); kex != nil {
+			m.diag.setKexInit(kex, false)
+		}
+	}
+	return n, err
+}
+
+func (m *versionConn) Write(b []byte) (int, error) {
+	if kex := m.kexWrite.add(b); kex != nil {
+		m.diag.setKexInit(kex, true)
+	}
+	return m.Conn.Write(b)
+}
@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/gerror"
+)
+
+func TestConnectDiagnostics(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			return nil, errors.New("denied")
+		},
+		BannerCallback: func(conn ssh.ConnMetadata) string {
+			return "authorized access only"
+		},
+		ServerVersion: "SSH-2.0-TestDevice",
+	}
+	config.AddHostKey(makeSigner(t))
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_, _, _, _ = ssh.NewServerConn(conn, config)
+		_ = conn.Close()
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("wrong"))
+	conn := NewStreamer("127.0.0.1", creds, WithPort(addr.Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	err = conn.Init(context.Background())
+	require.Error(t, err)
+
+	var diag *ConnectDiagnostics
+	require.ErrorAs(t, err, &diag)
+	require.Len(t, diag.Attempts, 1)
+	require.Equal(t, addr.String(), diag.Attempts[0].Address)
+	require.NoError(t, diag.Attempts[0].Err)
+	require.Equal(t, "SSH-2.0-TestDevice", diag.ServerVersion)
+	require.Equal(t, "authorized access only", diag.Banner)
+	require.Contains(t, diag.AttemptedAuthMethods, "password")
+	require.Positive(t, diag.Duration)
+	require.ErrorIs(t, err, gerror.ErrAuthFailed)
+}
+
+func TestConnectErrorReason(t *testing.T) {
+	t.Run("refused", func(t *testing.T) {
+		listener, endpoint := listenLocal(t)
+		require.NoError(t, listener.Close())
+		conn := NewStreamer(endpoint.Host, credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(endpoint.Port))
+		err := conn.Init(context.Background())
+		require.ErrorIs(t, err, gerror.ErrConnRefused)
+	})
+	t.Run("no auth method", func(t *testing.T) {
+		listener, endpoint := listenLocal(t)
+		go serveExec(t, listener, &ssh.ServerConfig{
+			PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+				return nil, errors.New("denied")
+			},
+		})
+		creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+		conn := NewStreamer(endpoint.Host, creds, WithPort(endpoint.Port))
+		err := conn.Init(context.Background())
+		require.ErrorIs(t, err, gerror.ErrNoAuthMethod)
+		require.NotErrorIs(t, err, gerror.ErrAuthFailed)
+	})
+	t.Run("timeout", func(t *testing.T) {
+		listener, endpoint := listenLocal(t)
+		go func() {
+			// accepts connection but never answers
+			conn, err := listener.Accept()
+			if err == nil {
+				t.Cleanup(func() { _ = conn.Close() })
+			}
+		}()
+		conn := NewStreamer(endpoint.Host, credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(endpoint.Port))
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := conn.Init(ctx)
+		require.ErrorIs(t, err, gerror.ErrTimeout)
+	})
+}
+
+func TestClassifyConnectError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		exp  error
+	}{
+		{name: "host key", err: fmt.Errorf("handshake failed: %w", &HostKeyMismatchError{Host: "device"}), exp: gerror.ErrHostKeyMismatch},
+		{name: "no route", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}, exp: gerror.ErrNoRouteToHost},
+		{name: "network unreachable", err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ENETUNREACH)}, exp: gerror.ErrNoRouteToHost},
+		{name: "public key rejected", err: errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"), exp: gerror.ErrAuthFailed},
+		{name: "unknown", err: errors.New("ssh: handshake failed: EOF")},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			diag := newConnectDiagnostics()
+			err := diag.fail(tc.err)
+			require.Equal(t, tc.exp, diag.Reason)
+			if tc.exp != nil {
+				require.ErrorIs(t, err, tc.exp)
+			}
+		})
+	}
+}
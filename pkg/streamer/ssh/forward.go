@@ -0,0 +1,89 @@
+package ssh
+
+import (
+	"net"
+	"sync"
+)
+
+var _ net.Conn = (*ForwardConn)(nil)
+
+// ForwardConn is local end of forward made by SSHTunnel.StartForward.
+// Close closes both ends of forward and waits for copy goroutines to exit.
+type ForwardConn struct {
+	net.Conn
+	rconn      net.Conn // socket pair end copied to remote
+	remoteConn net.Conn
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func (m *ForwardConn) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		err = m.Conn.Close()
+		m.closeRemote()
+	})
+	<-m.done
+	return err
+}
+
+// Done is closed when forward is finished by either side.
+func (m *ForwardConn) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *ForwardConn) closeRemote() {
+	_ = m.rconn.Close()
+	_ = m.remoteConn.Close()
+}
+
+// ActiveForwards returns number of forwards which are not finished yet.
+func (m *SSHTunnel) ActiveForwards() int {
+	m.forwardsMu.Lock()
+	defer m.forwardsMu.Unlock()
+	return len(m.forwards)
+}
+
+// reserveForward counts forward which is being started, so CloseContext waits for it.
+// Check of closing and counting are done under the same lock, otherwise forward may start after Wait.
+// Forward must be passed to trackForward or released by forwardsWg.Done.
+func (m *SSHTunnel) reserveForward() error {
+	m.forwardsMu.Lock()
+	defer m.forwardsMu.Unlock()
+	if m.closing {
+		return ErrTunnelClosing
+	}
+	m.forwardsWg.Add(1)
+	return nil
+}
+
+// trackForward adds reserved forward to active ones, forward is closed right away if tunnel started closing.
+func (m *SSHTunnel) trackForward(fwd *ForwardConn) {
+	m.forwardsMu.Lock()
+	defer m.forwardsMu.Unlock()
+	if m.closing {
+		fwd.closeRemote()
+	}
+	if m.forwards == nil {
+		m.forwards = map[*ForwardConn]struct{}{}
+	}
+	m.forwards[fwd] = struct{}{}
+}
+
+func (m *SSHTunnel) untrackForward(fwd *ForwardConn) {
+	m.forwardsMu.Lock()
+	defer m.forwardsMu.Unlock()
+	delete(m.forwards, fwd)
+	m.forwardsWg.Done()
+}
+
+// closeForwards closes active forwards and waits for their goroutines.
+func (m *SSHTunnel) closeForwards() {
+	m.forwardsMu.Lock()
+	m.closing = true
+	for fwd := range m.forwards {
+		fwd.closeRemote()
+	}
+	m.forwardsMu.Unlock()
+	m.forwardsWg.Wait()
+}
@@ -0,0 +1,98 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/annetutil/gnetcli/pkg/gerror"
+)
+
+// HostKeyMismatchError is returned when host presents key which differs from one in known_hosts.
+type HostKeyMismatchError struct {
+	Host        string
+	Fingerprint string // SHA256 fingerprint of presented key
+	Known       []knownhosts.KnownKey
+	Err         error
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key mismatch for %s: got %s", e.Host, e.Fingerprint)
+}
+
+func (e *HostKeyMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes HostKeyMismatchError match gerror.ErrHostKeyMismatch.
+func (e *HostKeyMismatchError) Is(target error) bool {
+	return target == gerror.ErrHostKeyMismatch
+}
+
+// WithKnownHosts enables host key verification using known_hosts file.
+// Hashed hostnames and @cert-authority lines are supported. File is read on every connect.
+// Overrides WithHostKeyCallback.
+func WithKnownHosts(path string) StreamerOption {
+	return func(h *Streamer) {
+		h.knownHostsFiles = append(h.knownHostsFiles, path)
+	}
+}
+
+// WithHostKeyCallback sets custom host key verification.
+func WithHostKeyCallback(cb ssh.HostKeyCallback) StreamerOption {
+	return func(h *Streamer) {
+		h.hostKeyCallback = cb
+	}
+}
+
+func wrapKnownHostsCallback(cb ssh.HostKeyCallback) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := cb(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) > 0 {
+			return &HostKeyMismatchError{
+				Host:        hostname,
+				Fingerprint: ssh.FingerprintSHA256(key),
+				Known:       keyErr.Want,
+				Err:         err,
+			}
+		}
+		return err
+	}
+}
+
+// WithHostCertAuthorities enables verification of host certificates signed by one of given CAs.
+// Certificate principals must contain connected host and certificate must be valid at the moment.
+// Hosts presenting plain keys are checked with regular host key callback.
+func WithHostCertAuthorities(authorities []ssh.PublicKey) StreamerOption {
+	return func(h *Streamer) {
+		h.hostCertAuthorities = authorities
+	}
+}
+
+func makeHostCertCallback(authorities []ssh.PublicKey, fallback ssh.HostKeyCallback) ssh.HostKeyCallback {
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			for _, ca := range authorities {
+				if bytes.Equal(ca.Marshal(), auth.Marshal()) {
+					return true
+				}
+			}
+			return false
+		},
+		HostKeyFallback: fallback,
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := checker.CheckHostKey(hostname, remote, key)
+		if err != nil {
+			if _, ok := key.(*ssh.Certificate); ok {
+				return fmt.Errorf("host certificate verification error for %s: %w", hostname, err)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,119 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+func makeSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return signer
+}
+
+func makeHostCert(t *testing.T, ca ssh.Signer, principals []string, validAfter, validBefore time.Time) *ssh.Certificate {
+	hostKey := makeSigner(t)
+	cert := &ssh.Certificate{
+		Key:             hostKey.PublicKey(),
+		CertType:        ssh.HostCert,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+	return cert
+}
+
+func TestHostCertCallback(t *testing.T) {
+	ca := makeSigner(t)
+	otherCA := makeSigner(t)
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	now := time.Now()
+	cb := makeHostCertCallback([]ssh.PublicKey{ca.PublicKey()}, ssh.InsecureIgnoreHostKey())
+
+	cert := makeHostCert(t, ca, []string{"device1"}, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, cb("device1:22", remote, cert))
+	require.ErrorContains(t, cb("device2:22", remote, cert), "principal")
+
+	expired := makeHostCert(t, ca, []string{"device1"}, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	require.ErrorContains(t, cb("device1:22", remote, expired), "expired")
+
+	unknownCA := makeHostCert(t, otherCA, []string{"device1"}, now.Add(-time.Hour), now.Add(time.Hour))
+	require.Error(t, cb("device1:22", remote, unknownCA))
+
+	// plain keys go to fallback
+	require.NoError(t, cb("device1:22", remote, makeSigner(t).PublicKey()))
+}
+
+func TestKnownHosts(t *testing.T) {
+	hostKey := makeSigner(t)
+	ca := makeSigner(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	lines := []string{
+		knownhosts.Line([]string{knownhosts.HashHostname("device1")}, hostKey.PublicKey()),
+		"@cert-authority *.example.net " + string(ssh.MarshalAuthorizedKey(ca.PublicKey())),
+	}
+	require.NoError(t, os.WriteFile(knownHosts, []byte(strings.Join(lines, "\n")), 0o600))
+
+	conn := NewStreamer("device1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithKnownHosts(knownHosts))
+	conf, err := conn.GetConfig(context.Background())
+	require.NoError(t, err)
+	cb := conf.HostKeyCallback
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	require.NoError(t, cb("device1:22", remote, hostKey.PublicKey()))
+
+	otherKey := makeSigner(t).PublicKey()
+	err = cb("device1:22", remote, otherKey)
+	var mismatch *HostKeyMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	require.Equal(t, ssh.FingerprintSHA256(otherKey), mismatch.Fingerprint)
+
+	now := time.Now()
+	cert := makeHostCert(t, ca, []string{"device2.example.net"}, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, cb("device2.example.net:22", remote, cert))
+}
+
+func TestKnownHostsMissingFile(t *testing.T) {
+	conn := NewStreamer("device1", credentials.NewSimpleCredentials(credentials.WithUsername("user")),
+		WithKnownHosts(filepath.Join(t.TempDir(), "known_hosts")))
+	_, err := conn.GetConfig(context.Background())
+	require.Error(t, err)
+}
+
+func TestKnownHostsWithCertAuthorities(t *testing.T) {
+	hostKey := makeSigner(t)
+	ca := makeSigner(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{"device1"}, hostKey.PublicKey())
+	require.NoError(t, os.WriteFile(knownHosts, []byte(line+"\n"), 0o600))
+
+	conn := NewStreamer("device1", credentials.NewSimpleCredentials(credentials.WithUsername("user")),
+		WithKnownHosts(knownHosts), WithHostCertAuthorities([]ssh.PublicKey{ca.PublicKey()}))
+	conf, err := conn.GetConfig(context.Background())
+	require.NoError(t, err)
+	cb := conf.HostKeyCallback
+	remote := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 22}
+	// plain keys are still checked against known_hosts
+	require.NoError(t, cb("device1:22", remote, hostKey.PublicKey()))
+	var mismatch *HostKeyMismatchError
+	require.ErrorAs(t, cb("device1:22", remote, makeSigner(t).PublicKey()), &mismatch)
+
+	now := time.Now()
+	cert := makeHostCert(t, ca, []string{"device1"}, now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, cb("device1:22", remote, cert))
+}
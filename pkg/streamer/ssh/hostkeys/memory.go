@@ -0,0 +1,159 @@
+/*
+Package hostkeys implements host key stores for verification of SSH servers.
+*/
+package hostkeys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	gssh "github.com/annetutil/gnetcli/pkg/streamer/ssh"
+)
+
+// ErrUnknownHost is returned for host which has no known keys.
+var ErrUnknownHost = errors.New("unknown host")
+
+// Decision is a result of DecisionFunc.
+type Decision int
+
+const (
+	// Reject fails connection.
+	Reject Decision = iota
+	// AcceptOnce allows connection without storing of the key.
+	AcceptOnce
+	// Accept allows connection and stores the key, it replaces known keys of host on mismatch.
+	Accept
+)
+
+// DecisionFunc decides whether to trust key presented by host. known is empty for unknown host,
+// otherwise it contains stored keys which don't match presented one. Returned error fails connection.
+type DecisionFunc func(host string, key ssh.PublicKey, known []ssh.PublicKey) (Decision, error)
+
+// AcceptNew is DecisionFunc for trust on first use: keys of unknown hosts are stored, mismatched keys are rejected.
+func AcceptNew(host string, key ssh.PublicKey, known []ssh.PublicKey) (Decision, error) {
+	if len(known) == 0 {
+		return Accept, nil
+	}
+	return Reject, nil
+}
+
+// MemoryStore keeps host keys in memory, it is safe for concurrent use.
+// Hosts are stored in known_hosts form: host for port 22 and [host]:port for others.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	keys map[string][]ssh.PublicKey
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: map[string][]ssh.PublicKey{}}
+}
+
+// Add stores key of host, address may contain port.
+func (m *MemoryStore) Add(address string, key ssh.PublicKey) {
+	host := knownhosts.Normalize(address)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, known := range m.keys[host] {
+		if keysEqual(known, key) {
+			return
+		}
+	}
+	m.keys[host] = append(m.keys[host], key)
+}
+
+// Keys returns known keys of host.
+func (m *MemoryStore) Keys(address string) []ssh.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]ssh.PublicKey(nil), m.keys[knownhosts.Normalize(address)]...)
+}
+
+// Verify checks key of host. It returns ErrUnknownHost if host has no known keys
+// and *ssh.HostKeyMismatchError if key is not one of them.
+func (m *MemoryStore) Verify(address string, key ssh.PublicKey) error {
+	known := m.Keys(address)
+	if len(known) == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHost, address)
+	}
+	for _, knownKey := range known {
+		if keysEqual(knownKey, key) {
+			return nil
+		}
+	}
+	return mismatchError(address, key, known)
+}
+
+// HostKeyCallback returns callback for ssh.WithHostKeyCallback which verifies keys with the store
+// and asks decide about unknown hosts and mismatched keys.
+func (m *MemoryStore) HostKeyCallback(decide DecisionFunc) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := m.Verify(hostname, key)
+		if err == nil {
+			return nil
+		}
+		known := m.Keys(hostname)
+		decision, decideErr := decide(hostname, key, known)
+		if decideErr != nil {
+			return decideErr
+		}
+		switch decision {
+		case AcceptOnce:
+			return nil
+		case Accept:
+			m.replace(hostname, key)
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// WriteTo writes keys in known_hosts format sorted by host.
+func (m *MemoryStore) WriteTo(w io.Writer) (int64, error) {
+	m.mu.RLock()
+	hosts := make([]string, 0, len(m.keys))
+	for host := range m.keys {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var buf bytes.Buffer
+	for _, host := range hosts {
+		for _, key := range m.keys[host] {
+			buf.WriteString(knownhosts.Line([]string{host}, key))
+			buf.WriteByte('\n')
+		}
+	}
+	m.mu.RUnlock()
+	return buf.WriteTo(w)
+}
+
+// replace makes key the only known key of host.
+func (m *MemoryStore) replace(address string, key ssh.PublicKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[knownhosts.Normalize(address)] = []ssh.PublicKey{key}
+}
+
+func mismatchError(address string, key ssh.PublicKey, known []ssh.PublicKey) error {
+	knownKeys := make([]knownhosts.KnownKey, 0, len(known))
+	for _, knownKey := range known {
+		knownKeys = append(knownKeys, knownhosts.KnownKey{Key: knownKey})
+	}
+	return &gssh.HostKeyMismatchError{
+		Host:        address,
+		Fingerprint: ssh.FingerprintSHA256(key),
+		Known:       knownKeys,
+	}
+}
+
+func keysEqual(a, b ssh.PublicKey) bool {
+	return bytes.Equal(a.Marshal(), b.Marshal())
+}
@@ -0,0 +1,87 @@
+package hostkeys
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/gerror"
+)
+
+func makeKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return key
+}
+
+func TestMemoryStoreVerify(t *testing.T) {
+	key := makeKey(t)
+	other := makeKey(t)
+	store := NewMemoryStore()
+
+	require.ErrorIs(t, store.Verify("host:22", key), ErrUnknownHost)
+	store.Add("host:22", key)
+	require.NoError(t, store.Verify("host:22", key))
+	require.NoError(t, store.Verify("host", key))
+	require.ErrorIs(t, store.Verify("host:2222", key), ErrUnknownHost)
+	require.ErrorIs(t, store.Verify("host:22", other), gerror.ErrHostKeyMismatch)
+}
+
+func TestMemoryStoreCallback(t *testing.T) {
+	key := makeKey(t)
+	newKey := makeKey(t)
+	store := NewMemoryStore()
+
+	cb := store.HostKeyCallback(AcceptNew)
+	require.NoError(t, cb("host:22", nil, key))
+	require.Len(t, store.Keys("host"), 1)
+	require.ErrorIs(t, cb("host:22", nil, newKey), gerror.ErrHostKeyMismatch)
+
+	var asked []ssh.PublicKey
+	cb = store.HostKeyCallback(func(host string, presented ssh.PublicKey, known []ssh.PublicKey) (Decision, error) {
+		asked = known
+		return Accept, nil
+	})
+	require.NoError(t, cb("host:22", nil, newKey))
+	require.Equal(t, []ssh.PublicKey{key}, asked)
+	require.Equal(t, []ssh.PublicKey{newKey}, store.Keys("host"))
+
+	cb = store.HostKeyCallback(func(string, ssh.PublicKey, []ssh.PublicKey) (Decision, error) {
+		return AcceptOnce, nil
+	})
+	require.NoError(t, cb("other:22", nil, key))
+	require.Empty(t, store.Keys("other"))
+
+	abort := errors.New("aborted")
+	cb = store.HostKeyCallback(func(string, ssh.PublicKey, []ssh.PublicKey) (Decision, error) {
+		return Reject, abort
+	})
+	require.ErrorIs(t, cb("other:22", nil, key), abort)
+}
+
+func TestMemoryStoreWriteTo(t *testing.T) {
+	key := makeKey(t)
+	store := NewMemoryStore()
+	store.Add("b:2222", key)
+	store.Add("a:22", key)
+
+	var buf bytes.Buffer
+	_, err := store.WriteTo(&buf)
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	for i, host := range []string{"[b]:2222", "a"} {
+		_, hosts, parsed, _, _, err := ssh.ParseKnownHosts(lines[i])
+		require.NoError(t, err)
+		require.Equal(t, []string{host}, hosts)
+		require.Equal(t, key.Marshal(), parsed.Marshal())
+	}
+}
@@ -0,0 +1,39 @@
+package ssh
+
+import (
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+type jumpHost struct {
+	endpoint    Endpoint
+	credentials credentials.Credentials
+}
+
+// WithJumpHost connects to target through jump host. Repeat it for chain of jump hosts, the first one is dialed first.
+// Tunnel is made in NewStreamer with logger, observer, tracer and dialer of the streamer and is closed by Close.
+// Overrides WithSSHTunnel.
+func WithJumpHost(endpoint Endpoint, creds credentials.Credentials) StreamerOption {
+	return func(h *Streamer) {
+		h.jumpHosts = append(h.jumpHosts, jumpHost{endpoint: endpoint, credentials: creds})
+	}
+}
+
+func (m *Streamer) makeJumpTunnel() Tunnel {
+	endpoints := make([]Endpoint, 0, len(m.jumpHosts))
+	creds := make([]credentials.Credentials, 0, len(m.jumpHosts))
+	for _, jump := range m.jumpHosts {
+		endpoints = append(endpoints, jump.endpoint)
+		creds = append(creds, jump.credentials)
+	}
+	opts := []SSHTunnelOption{
+		SSHTunnelWithLogging(m.logger),
+		SSHTunnelWithObserver(m.observer),
+		SSHTunnelWithTracer(m.tracer),
+	}
+	if m.dialer != nil {
+		opts = append(opts, SSHTunnelWithDialer(m.dialer))
+	}
+	// endpoints and creds have the same length, chain can't fail
+	tunnel, _ := NewChainedTunnel(endpoints, creds, opts...)
+	return tunnel
+}
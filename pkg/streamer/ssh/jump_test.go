@@ -0,0 +1,41 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+func TestWithJumpHost(t *testing.T) {
+	target, targetEndpoint := listenLocal(t)
+	go runExecServer(t, target)
+	first, firstEndpoint := listenLocal(t)
+	firstDone := make(chan struct{})
+	go runForwardServer(t, first, firstDone)
+	second, secondEndpoint := listenLocal(t)
+	secondDone := make(chan struct{})
+	go runForwardServer(t, second, secondDone)
+
+	jumpCreds := credentials.NewSimpleCredentials(credentials.WithUsername("jumper"))
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+	conn := NewStreamer(targetEndpoint.Host, creds, WithPort(targetEndpoint.Port),
+		WithJumpHost(firstEndpoint, jumpCreds), WithJumpHost(secondEndpoint, jumpCreds))
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	res, err := conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(res.Output()))
+
+	conn.Close()
+	for _, done := range []chan struct{}{firstDone, secondDone} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("jump host connection is not closed")
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+const keepAliveRequest = "keepalive@openssh.com"
+
+// DefaultKeepaliveCountMax is number of unanswered keepalives after which connection is closed.
+const DefaultKeepaliveCountMax = 3
+
+var _ streamer.KeepAliver = (*Streamer)(nil)
+
+type requestSender interface {
+	SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error)
+}
+
+// WithKeepalive makes Streamer send keepalive request every interval after connect.
+// Connection is closed if WithKeepaliveCountMax requests in a row get no reply within interval.
+func WithKeepalive(interval time.Duration) StreamerOption {
+	return func(h *Streamer) {
+		h.keepaliveInterval = interval
+	}
+}
+
+// WithKeepaliveCountMax sets number of unanswered keepalives after which connection is closed.
+func WithKeepaliveCountMax(count int) StreamerOption {
+	return func(h *Streamer) {
+		h.keepaliveCountMax = count
+	}
+}
+
+// KeepAlive sends keepalive request and waits for reply. Any reply, including refusal, means connection is alive.
+func (m *Streamer) KeepAlive(ctx context.Context) error {
+	conn := m.getConn()
+	if conn == nil {
+		return errNotConnected
+	}
+	sender, ok := conn.(requestSender)
+	if !ok {
+		return streamer.ErrNotSupported
+	}
+	return sendKeepAlive(ctx, sender)
+}
+
+func sendKeepAlive(ctx context.Context, sender requestSender) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := sender.SendRequest(keepAliveRequest, true, nil)
+		errCh <- err
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// startKeepalive starts keepalive loop for current connection, previous loop is stopped.
+func (m *Streamer) startKeepalive() {
+	m.stopKeepalive()
+	if m.keepaliveInterval <= 0 || m.sharedConn {
+		return
+	}
+	conn := m.getConn()
+	sender, ok := conn.(requestSender)
+	if !ok {
+		m.logger.Debug("keepalive is not supported", logging.String("conn", fmt.Sprintf("%T", conn)))
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.keepaliveStop = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		m.keepaliveLoop(ctx, conn, sender)
+	}()
+}
+
+func (m *Streamer) stopKeepalive() {
+	if m.keepaliveStop != nil {
+		m.keepaliveStop()
+		m.keepaliveStop = nil
+	}
+}
+
+func (m *Streamer) keepaliveLoop(ctx context.Context, conn sshClient, sender requestSender) {
+	ticker := time.NewTicker(m.keepaliveInterval)
+	defer ticker.Stop()
+	missed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, m.keepaliveInterval)
+		err := sendKeepAlive(reqCtx, sender)
+		cancel()
+		if err == nil {
+			missed = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			missed++
+			m.logger.Debug("keepalive timeout", logging.Int("missed", missed))
+			if missed < m.keepaliveCountMax {
+				continue
+			}
+		}
+		m.logger.Debug("keepalive failed, closing connection", logging.Error(err))
+		_ = conn.Close()
+		return
+	}
+}
@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// runKeepaliveServer answers global requests while respond is set, done is closed when client connection is gone.
+func runKeepaliveServer(t *testing.T, listener net.Listener, respond *atomic.Bool, requests *atomic.Int32, done chan struct{}) {
+	defer close(done)
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go func() {
+		for newChannel := range chans {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+		}
+	}()
+	go func() {
+		for req := range reqs {
+			requests.Add(1)
+			if respond.Load() {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}()
+	_ = sconn.Wait()
+}
+
+func TestKeepalive(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	respond := &atomic.Bool{}
+	respond.Store(true)
+	requests := &atomic.Int32{}
+	done := make(chan struct{})
+	go runKeepaliveServer(t, listener, respond, requests, done)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(port),
+		WithKeepalive(20*time.Millisecond), WithKeepaliveCountMax(2))
+	require.NoError(t, conn.Init(context.Background()))
+	defer conn.Close()
+	require.NoError(t, conn.KeepAlive(context.Background()))
+
+	require.Eventually(t, func() bool { return requests.Load() >= 3 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("connection is closed while server replies")
+	default:
+	}
+
+	respond.Store(false)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed after missed keepalives")
+	}
+}
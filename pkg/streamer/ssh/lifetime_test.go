@@ -0,0 +1,72 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+func TestNewStreamerWithContext(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runPTYServer(t, listener)
+
+	lifetime, cancel := context.WithCancel(context.Background())
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	conn := NewStreamerWithContext(lifetime, "127.0.0.1", creds, WithPort(listener.Addr().(*net.TCPAddr).Port))
+	conn.SetReadTimeout(10 * time.Second)
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	_, err = conn.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`>`))
+	require.NoError(t, err)
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = conn.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`never`))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	conn = NewStreamerWithContext(lifetime, "127.0.0.1", creds, WithPort(listener.Addr().(*net.TCPAddr).Port))
+	require.ErrorIs(t, conn.Init(ctx), context.Canceled)
+}
+
+func TestNewSessionLifetime(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runSessionLimitServer(t, listener, 2)
+
+	lifetime, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	conn := NewStreamerWithContext(lifetime, "127.0.0.1", creds, WithPort(listener.Addr().(*net.TCPAddr).Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+
+	sess, err := conn.NewSession(ctx)
+	require.NoError(t, err)
+	// closing of session must not stop watch of parent connection
+	sess.Close()
+	closed := make(chan struct{})
+	go func() {
+		_ = conn.getConn().(*ssh.Client).Wait()
+		close(closed)
+	}()
+	cancel()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed after context is done")
+	}
+}
@@ -0,0 +1,51 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// acceptRemoteIP returns channel with source IP of the first accepted connection.
+func acceptRemoteIP(listener net.Listener) <-chan string {
+	res := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(res)
+			return
+		}
+		res <- conn.RemoteAddr().(*net.TCPAddr).IP.String()
+		_ = conn.Close()
+	}()
+	return res
+}
+
+func TestLocalAddr(t *testing.T) {
+	localAddr := &net.TCPAddr{IP: net.ParseIP("127.0.0.2")}
+	bound, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("127.0.0.2 is not available: %v", err)
+	}
+	_ = bound.Close()
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+
+	t.Run("streamer", func(t *testing.T) {
+		listener, endpoint := listenLocal(t)
+		remoteIP := acceptRemoteIP(listener)
+		conn := NewStreamer(endpoint.Host, creds, WithPort(endpoint.Port), WithLocalAddr(localAddr))
+		_ = conn.Init(context.Background())
+		require.Equal(t, "127.0.0.2", <-remoteIP)
+	})
+	t.Run("tunnel", func(t *testing.T) {
+		listener, endpoint := listenLocal(t)
+		remoteIP := acceptRemoteIP(listener)
+		tunnel := NewSSHTunnel(endpoint.Host, creds, SSHTunnelWitPort(endpoint.Port), SSHTunnelWithLocalAddr(localAddr))
+		_ = tunnel.CreateConnect(context.Background())
+		require.Equal(t, "127.0.0.2", <-remoteIP)
+	})
+}
@@ -0,0 +1,119 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/logging"
+)
+
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (m *recordingHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (m *recordingHandler) Handle(_ context.Context, record slog.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *recordingHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return m
+}
+
+func (m *recordingHandler) WithGroup(string) slog.Handler {
+	return m
+}
+
+// find returns attributes of the first record with msg.
+func (m *recordingHandler) find(msg string) (slog.Level, map[string]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, record := range m.records {
+		if record.Message != msg {
+			continue
+		}
+		attrs := map[string]string{}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+		return record.Level, attrs, true
+	}
+	return 0, nil, false
+}
+
+func TestSlogConnectAndForward(t *testing.T) {
+	echo, echoEndpoint := listenLocal(t)
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(conn, conn)
+		_ = conn.Close()
+	}()
+	server, serverEndpoint := listenLocal(t)
+	serverDone := make(chan struct{})
+	go runForwardServer(t, server, serverDone)
+
+	handler := &recordingHandler{}
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	tun := NewSSHTunnel(serverEndpoint.Host, creds, SSHTunnelWitPort(serverEndpoint.Port),
+		SSHTunnelWithLogging(logging.NewSlog(slog.New(handler))))
+	require.NoError(t, tun.CreateConnect(context.Background()))
+	conn, err := tun.StartForward(TCP, echoEndpoint.Addr())
+	require.NoError(t, err)
+	_ = conn.Close()
+	tun.Close()
+	<-serverDone
+
+	level, attrs, ok := handler.find("connected to tunnel")
+	require.True(t, ok)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Equal(t, serverEndpoint.String(), attrs["server"])
+
+	level, attrs, ok = handler.find("start forward")
+	require.True(t, ok)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Equal(t, echoEndpoint.Addr(), attrs["to"])
+	require.Equal(t, serverEndpoint.Addr(), attrs["from"])
+
+	level, attrs, ok = handler.find("tcp dial")
+	require.True(t, ok)
+	require.Equal(t, slog.LevelDebug, level)
+	require.Contains(t, attrs, "address")
+}
+
+func TestSlogSkippedKeyLevel(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	require.NoError(t, err)
+
+	handler := &recordingHandler{}
+	conn := NewStreamer("localhost", credentials.NewSimpleCredentials(credentials.WithUsername("user"),
+		credentials.WithPrivateKey(pem.EncodeToMemory(block))), WithLogging(logging.NewSlog(slog.New(handler))))
+	_, err = conn.GetConfig(context.Background())
+	require.NoError(t, err)
+
+	level, _, ok := handler.find("skipping key, missing passphrase")
+	require.True(t, ok)
+	require.Equal(t, slog.LevelWarn, level)
+}
@@ -0,0 +1,46 @@
+package ssh
+
+import (
+	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+var _ streamer.CommandObserver = (*Streamer)(nil)
+
+// WithObserver sets observer of connects, reconnects, authentication and commands.
+func WithObserver(observer streamer.Observer) StreamerOption {
+	return func(h *Streamer) {
+		h.observer = observer
+	}
+}
+
+// SSHTunnelWithObserver sets observer of tunnel connects and authentication.
+func SSHTunnelWithObserver(observer streamer.Observer) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.observer = observer
+	}
+}
+
+// ObserveCommand reports command executed by device over the streamer to observer.
+func (m *Streamer) ObserveCommand(command []byte) func(bytesWritten, bytesRead int, err error) {
+	return streamer.ObserveCommand(m.observer, m.endpoint.Host, command)
+}
+
+// observeAuth reports method of the last auth attempt, which is the successful one after connect.
+func (m *Streamer) observeAuth(host string) {
+	if m.observer == nil {
+		return
+	}
+	method := m.authMethod
+	if len(method) == 0 {
+		method = "none"
+	}
+	m.observer.Auth(streamer.AuthEvent{Host: host, Method: method})
+}
+
+func resultSize(res gcmd.CmdRes) int {
+	if res == nil {
+		return 0
+	}
+	return len(res.Output()) + len(res.Error())
+}
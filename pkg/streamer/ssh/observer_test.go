@@ -0,0 +1,112 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+type recordingObserver struct {
+	streamer.NopObserver
+	mu       sync.Mutex
+	connects []streamer.ConnectEvent
+	commands []streamer.CommandEvent
+	auths    []streamer.AuthEvent
+}
+
+func (m *recordingObserver) ConnectDone(ev streamer.ConnectEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connects = append(m.connects, ev)
+}
+
+func (m *recordingObserver) CommandDone(ev streamer.CommandEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = append(m.commands, ev)
+}
+
+func (m *recordingObserver) Auth(ev streamer.AuthEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.auths = append(m.auths, ev)
+}
+
+// runExecServer accepts password "secret" and answers "ok\n" to every exec request.
+func runExecServer(t *testing.T, listener net.Listener) {
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	serveExec(t, listener, config)
+}
+
+// serveExec accepts single connection with config and answers "ok\n" to every exec request.
+func serveExec(t *testing.T, listener net.Listener, config *ssh.ServerConfig) {
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "exec", nil)
+				if req.Type == "exec" {
+					_, _ = channel.Write([]byte("ok\n"))
+					_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+					_ = channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestObserver(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runExecServer(t, listener)
+
+	observer := &recordingObserver{}
+	addr := listener.Addr().(*net.TCPAddr)
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+	conn := NewStreamer("127.0.0.1", creds, WithPort(addr.Port), WithObserver(observer))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	_, err = conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	require.Len(t, observer.connects, 1)
+	require.NoError(t, observer.connects[0].Err)
+	require.Equal(t, "127.0.0.1", observer.connects[0].Host)
+	require.Equal(t, []streamer.AuthEvent{{Host: "127.0.0.1", Method: "password"}}, observer.auths)
+	require.Len(t, observer.commands, 1)
+	require.Equal(t, "show version", string(observer.commands[0].Command))
+	require.Equal(t, len("show version"), observer.commands[0].BytesWritten)
+	require.Equal(t, len("ok\n"), observer.commands[0].BytesRead)
+}
@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// DefaultPostLoginWait is how long to wait for repeated login or password prompt after password was sent, see PostLoginWithWait.
+const DefaultPostLoginWait = time.Second
+
+const (
+	postLoginLogin    = "login"
+	postLoginPassword = "password"
+	postLoginPrompt   = "prompt"
+)
+
+type postLogin struct {
+	login    expr.Expr
+	password expr.Expr
+	prompt   expr.Expr // nil means that absence of login prompts during wait is success
+	wait     time.Duration
+}
+
+// PostLoginOption configures interactive login, see WithPostLogin.
+type PostLoginOption func(*postLogin)
+
+// PostLoginWithPrompt sets device prompt, login is successful when it is matched.
+// Prompt and output before it are left for the next ReadTo.
+func PostLoginWithPrompt(prompt expr.Expr) PostLoginOption {
+	return func(h *postLogin) {
+		h.prompt = prompt
+	}
+}
+
+// PostLoginWithWait sets how long to wait for repeated login or password prompt after password was sent,
+// default is DefaultPostLoginWait. It is not used with PostLoginWithPrompt.
+func PostLoginWithWait(wait time.Duration) PostLoginOption {
+	return func(h *postLogin) {
+		h.wait = wait
+	}
+}
+
+// WithPostLogin enables interactive login in shell after SSH connection is established.
+// It is for devices which accept any SSH authentication and ask for credentials by themselves, like telnet does.
+// Username and passwords are taken from credentials. Passwords are tried in order until device prompt is matched,
+// see PostLoginWithPrompt, or login and password prompts stop repeating.
+func WithPostLogin(login, password expr.Expr, opts ...PostLoginOption) StreamerOption {
+	return func(h *Streamer) {
+		h.postLogin = &postLogin{login: login, password: password, wait: DefaultPostLoginWait}
+		for _, opt := range opts {
+			opt(h.postLogin)
+		}
+	}
+}
+
+// runPostLogin answers login and password prompts in shell session.
+// Output following the successful login is left for the next ReadTo.
+func (m *Streamer) runPostLogin(ctx context.Context, creds credentials.Credentials) error {
+	passwords := creds.GetPasswords(ctx)
+	if len(passwords) == 0 {
+		return errors.New("post login: empty password")
+	}
+	exprs := expr.NewSimpleExprListNamedOrdered([]expr.NamedExpr{
+		{Name: postLoginLogin, Exprs: []expr.Expr{m.postLogin.login}},
+		{Name: postLoginPassword, Exprs: []expr.Expr{m.postLogin.password}},
+	})
+	if m.postLogin.prompt != nil {
+		exprs.Add(postLoginPrompt, m.postLogin.prompt)
+	}
+	tried := 0
+	for {
+		res, err := m.readPostLogin(ctx, exprs, tried > 0)
+		if err != nil {
+			var timeoutErr *streamer.ReadTimeoutException
+			if tried > 0 && m.postLogin.prompt == nil && ctx.Err() == nil && errors.As(err, &timeoutErr) {
+				return nil
+			}
+			return fmt.Errorf("post login: %w", err)
+		}
+		switch exprs.GetName(res.GetPatternNo()) {
+		case postLoginPrompt:
+			// prompt belongs to device session, so it is returned to buffer
+			unread := append(append([]byte{}, res.GetBefore()...), res.GetMatched()...)
+			m.session.stdoutBufferExtra = append(unread, m.session.stdoutBufferExtra...)
+			return nil
+		case postLoginLogin:
+			username, err := credentials.GetUsername(ctx, creds)
+			if err != nil {
+				return err
+			}
+			err = m.Write([]byte(username + "\n"))
+			if err != nil {
+				return fmt.Errorf("post login: %w", err)
+			}
+		case postLoginPassword:
+			if tried == len(passwords) {
+				return credentials.NewPasswordsError(tried)
+			}
+			err = m.Write([]byte(passwords[tried].Value() + "\n"))
+			if err != nil {
+				return fmt.Errorf("post login: %w", err)
+			}
+			tried++
+		}
+	}
+}
+
+// readPostLogin reads to login prompts. After password was sent, the wait is limited unless device prompt is known.
+func (m *Streamer) readPostLogin(ctx context.Context, exprs expr.Expr, afterPassword bool) (streamer.ReadRes, error) {
+	if !afterPassword || m.postLogin.prompt != nil {
+		return m.ReadTo(ctx, exprs)
+	}
+	readCtx, cancel := context.WithTimeout(ctx, m.postLogin.wait)
+	defer cancel()
+	return m.ReadTo(readCtx, exprs)
+}
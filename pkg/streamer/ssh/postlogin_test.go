@@ -0,0 +1,113 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+// runLoginShellServer serves shell sessions which ask for username and password like telnet device.
+func runLoginShellServer(t *testing.T, listener net.Listener, password string) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "shell" || req.Type == "pty-req", nil)
+				if req.Type != "shell" {
+					continue
+				}
+				go func() {
+					defer channel.Close()
+					reader := bufio.NewReader(channel)
+					for {
+						_, _ = channel.Write([]byte("\r\nUsername: "))
+						if _, err := reader.ReadString('\n'); err != nil {
+							return
+						}
+						_, _ = channel.Write([]byte("\r\nPassword: "))
+						line, err := reader.ReadString('\n')
+						if err != nil {
+							return
+						}
+						if strings.TrimSpace(line) == password {
+							_, _ = channel.Write([]byte("\r\nwelcome\r\n<device>"))
+							_, _ = reader.ReadString('\n')
+							return
+						}
+						_, _ = channel.Write([]byte("\r\n% Login invalid"))
+					}
+				}()
+			}
+		}()
+	}
+}
+
+func TestPostLogin(t *testing.T) {
+	cases := []struct {
+		name      string
+		passwords []string
+		opts      []PostLoginOption
+		err       bool
+	}{
+		{name: "first password", passwords: []string{"secret"}},
+		{name: "second password", passwords: []string{"wrong", "secret"}},
+		{name: "rejected", passwords: []string{"wrong"}, err: true},
+		{name: "short wait", passwords: []string{"wrong", "secret"}, opts: []PostLoginOption{PostLoginWithWait(300 * time.Millisecond)}},
+		{name: "prompt", passwords: []string{"wrong", "secret"}, opts: []PostLoginOption{PostLoginWithPrompt(expr.NewSimpleExpr().FromPattern(`<device>$`))}},
+		{name: "prompt rejected", passwords: []string{"wrong"}, opts: []PostLoginOption{PostLoginWithPrompt(expr.NewSimpleExpr().FromPattern(`<device>$`))}, err: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, endpoint := listenLocal(t)
+			go runLoginShellServer(t, listener, "secret")
+
+			passwords := make([]credentials.Secret, 0, len(tc.passwords))
+			for _, password := range tc.passwords {
+				passwords = append(passwords, credentials.Secret(password))
+			}
+			creds := credentials.NewSimpleCredentials(
+				credentials.WithUsername("user"),
+				credentials.WithPasswords(passwords),
+			)
+			conn := NewStreamer(endpoint.Host, creds, WithPort(endpoint.Port),
+				WithPostLogin(expr.NewSimpleExpr().FromPattern(`Username: $`), expr.NewSimpleExpr().FromPattern(`Password: $`), tc.opts...))
+			ctx := context.Background()
+			err := conn.Init(ctx)
+			defer conn.Close()
+			if tc.err {
+				var passwordsErr *credentials.PasswordsError
+				require.ErrorAs(t, err, &passwordsErr)
+				require.Nil(t, conn.getConn())
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := conn.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>`))
+			require.NoError(t, err)
+			require.Contains(t, string(res.GetBefore()), "welcome")
+		})
+	}
+}
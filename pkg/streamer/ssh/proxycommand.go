@@ -0,0 +1,224 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+)
+
+const (
+	// proxyCommandExitWait is how long failed handshake waits for exit of proxy command to report its status.
+	proxyCommandExitWait = time.Second
+	// proxyCommandStderrLimit is how much of stderr of proxy command is kept for error reports.
+	proxyCommandStderrLimit = 4096
+)
+
+// ProxyCommandError is returned when proxy command exits before SSH connection is established.
+type ProxyCommandError struct {
+	Command string
+	Stderr  string
+	Err     error // exit error, nil if command exited with zero status
+}
+
+func (e *ProxyCommandError) Error() string {
+	msg := fmt.Sprintf("proxy command %q exited", e.Command)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Stderr) > 0 {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *ProxyCommandError) Unwrap() error {
+	return e.Err
+}
+
+// WithProxyCommand connects through stdin and stdout of command like ProxyCommand of OpenSSH.
+// Command is run with /bin/sh -c, tokens %h, %p, %r and %% are expanded. Command is killed on Close.
+func WithProxyCommand(cmdline string) StreamerOption {
+	return func(h *Streamer) {
+		h.proxyCommand = cmdline
+	}
+}
+
+func (m *Streamer) dialProxyCommand(ctx context.Context, conf *ssh.ClientConfig, diag *ConnectDiagnostics) (*ssh.Client, error) {
+	cmdline := expandProxyCommand(m.proxyCommand, m.endpoint, conf.User)
+	m.logger.Debug("start proxy command", logging.String("cmd", cmdline))
+	started := time.Now()
+	conn, err := startProxyCommand(cmdline)
+	diag.addAttempt(m.endpoint.Addr(), started, err)
+	if err != nil {
+		return nil, err
+	}
+	res, err := DialConnCtx(ctx, newVersionConn(conn, diag), m.endpoint.Addr(), conf)
+	if err != nil {
+		if exitErr := conn.exitError(proxyCommandExitWait); exitErr != nil {
+			err = fmt.Errorf("%w: %w", err, exitErr)
+		}
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to connect to host %s: %w", m.endpoint.String(), err)
+	}
+	return res, nil
+}
+
+func expandProxyCommand(cmdline string, endpoint Endpoint, user string) string {
+	return strings.NewReplacer(
+		"%%", "%",
+		"%h", endpoint.Host,
+		"%p", strconv.Itoa(endpoint.Port),
+		"%r", user,
+	).Replace(cmdline)
+}
+
+// proxyCommandConn is net.Conn over stdio of proxy command. Deadlines are not supported.
+type proxyCommandConn struct {
+	cmdline   string
+	cmd       *exec.Cmd
+	stdin     *os.File
+	stdout    *os.File
+	stderr    *syncBuffer
+	waitDone  chan struct{}
+	waitErr   error
+	closeOnce sync.Once
+}
+
+var _ net.Conn = (*proxyCommandConn)(nil)
+
+func startProxyCommand(cmdline string) (*proxyCommandConn, error) {
+	// os.Pipe instead of cmd.StdoutPipe, because Wait closes the latter and unread data is lost
+	stdinR, stdinW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		_ = stdinR.Close()
+		_ = stdinW.Close()
+		return nil, err
+	}
+	res := &proxyCommandConn{
+		cmdline:  cmdline,
+		cmd:      exec.Command("/bin/sh", "-c", cmdline),
+		stdin:    stdinW,
+		stdout:   stdoutR,
+		stderr:   &syncBuffer{},
+		waitDone: make(chan struct{}),
+	}
+	res.cmd.Stdin = stdinR
+	res.cmd.Stdout = stdoutW
+	res.cmd.Stderr = res.stderr
+	err = res.cmd.Start()
+	_ = stdinR.Close()
+	_ = stdoutW.Close()
+	if err != nil {
+		_ = stdinW.Close()
+		_ = stdoutR.Close()
+		return nil, fmt.Errorf("failed to start proxy command %q: %w", cmdline, err)
+	}
+	go func() {
+		res.waitErr = res.cmd.Wait()
+		close(res.waitDone)
+	}()
+	return res, nil
+}
+
+// exitError waits up to timeout for command exit and describes it.
+func (m *proxyCommandConn) exitError(timeout time.Duration) error {
+	select {
+	case <-m.waitDone:
+	case <-time.After(timeout):
+		return nil
+	}
+	return &ProxyCommandError{
+		Command: m.cmdline,
+		Stderr:  strings.TrimSpace(m.stderr.String()),
+		Err:     m.waitErr,
+	}
+}
+
+func (m *proxyCommandConn) Read(b []byte) (int, error) {
+	return m.stdout.Read(b)
+}
+
+func (m *proxyCommandConn) Write(b []byte) (int, error) {
+	return m.stdin.Write(b)
+}
+
+// Close closes stdio and kills command if it is still running.
+func (m *proxyCommandConn) Close() error {
+	m.closeOnce.Do(func() {
+		_ = m.stdin.Close()
+		_ = m.stdout.Close()
+		select {
+		case <-m.waitDone:
+		default:
+			_ = m.cmd.Process.Kill()
+		}
+		<-m.waitDone
+	})
+	return nil
+}
+
+func (m *proxyCommandConn) LocalAddr() net.Addr {
+	return proxyCommandAddr(m.cmdline)
+}
+
+func (m *proxyCommandConn) RemoteAddr() net.Addr {
+	return proxyCommandAddr(m.cmdline)
+}
+
+func (m *proxyCommandConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (m *proxyCommandConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (m *proxyCommandConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+type proxyCommandAddr string
+
+func (proxyCommandAddr) Network() string {
+	return "proxycommand"
+}
+
+func (m proxyCommandAddr) String() string {
+	return string(m)
+}
+
+// syncBuffer keeps the first proxyCommandStderrLimit bytes written to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (m *syncBuffer) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if left := proxyCommandStderrLimit - m.buf.Len(); left > 0 {
+		m.buf.Write(p[:min(left, len(p))])
+	}
+	return len(p), nil
+}
+
+func (m *syncBuffer) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.String()
+}
@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// TestProxyCommandHelper is run as proxy command by other tests and connects stdio to host and port from args.
+func TestProxyCommandHelper(t *testing.T) {
+	if os.Getenv("GNETCLI_PROXY_COMMAND_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	args := os.Args
+	for i, arg := range args {
+		if arg == "--" {
+			args = args[i+1:]
+			break
+		}
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(args[0], args[1]))
+	if err != nil {
+		os.Exit(2)
+	}
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		_ = conn.Close()
+	}()
+	_, _ = io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+func TestProxyCommand(t *testing.T) {
+	t.Setenv("GNETCLI_PROXY_COMMAND_HELPER", "1")
+	listener, endpoint := listenLocal(t)
+	go runExecServer(t, listener)
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+	cmdline := fmt.Sprintf("%s -test.run=TestProxyCommandHelper -- %%h %%p", os.Args[0])
+	conn := NewStreamer("127.0.0.1", creds, WithPort(endpoint.Port), WithProxyCommand(cmdline))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	res, err := conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(res.Output()))
+	conn.Close()
+}
+
+func TestProxyCommandExit(t *testing.T) {
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	conn := NewStreamer("127.0.0.1", creds, WithProxyCommand("echo denied for %r@%h >&2; exit 3"))
+	err := conn.Init(context.Background())
+	var proxyErr *ProxyCommandError
+	require.ErrorAs(t, err, &proxyErr)
+	require.Equal(t, "denied for user@127.0.0.1", proxyErr.Stderr)
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr))
+	require.Equal(t, 3, exitErr.ExitCode())
+}
@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultPTYTerm is terminal type requested by WithPTY when term is empty.
+const DefaultPTYTerm = "vt100"
+
+type ptyParams struct {
+	enabled bool
+	term    string
+	modes   ssh.TerminalModes // nil means DefaultTerminalModes
+}
+
+// DefaultTerminalModes returns terminal modes requested with PTY: echo is disabled and speed is 14.4kbaud.
+func DefaultTerminalModes() ssh.TerminalModes {
+	return ssh.TerminalModes{
+		ssh.ECHO:          0,     // disable echoing
+		ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
+		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
+	}
+}
+
+// WithPTY sets whether PTY is requested for shell session, its terminal type and modes.
+// Empty term means DefaultPTYTerm and nil modes mean DefaultTerminalModes.
+// Without this option PTY is requested with xterm terminal type.
+func WithPTY(enabled bool, term string, modes ssh.TerminalModes) StreamerOption {
+	return func(h *Streamer) {
+		if term == "" {
+			term = DefaultPTYTerm
+		}
+		h.pty = ptyParams{enabled: enabled, term: term, modes: modes}
+	}
+}
+
+// WithTerminalSize sets PTY dimensions in characters, zero height means no limit.
+// genericcli devices set them with SetTerminalSize on connect.
+func WithTerminalSize(width, height int) StreamerOption {
+	return func(h *Streamer) {
+		h.SetTerminalSize(width, height)
+	}
+}
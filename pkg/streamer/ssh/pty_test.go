@@ -0,0 +1,93 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+// runPTYServer serves single shell session which prints requested PTY parameters.
+func runPTYServer(t *testing.T, listener net.Listener) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			pty := "none"
+			for req := range requests {
+				switch req.Type {
+				case "pty-req":
+					var ptyReq struct {
+						Term          string
+						Columns, Rows uint32
+						Width, Height uint32
+						Modes         string
+					}
+					if err := ssh.Unmarshal(req.Payload, &ptyReq); err != nil {
+						_ = req.Reply(false, nil)
+						continue
+					}
+					pty = fmt.Sprintf("%s %dx%d modes=%d", ptyReq.Term, ptyReq.Columns, ptyReq.Rows, len(ptyReq.Modes))
+					_ = req.Reply(true, nil)
+				case "shell":
+					_ = req.Reply(true, nil)
+					_, _ = channel.Write([]byte(pty + ">"))
+				default:
+					_ = req.Reply(false, nil)
+				}
+			}
+		}()
+	}
+}
+
+func TestPTY(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []StreamerOption
+		expected string
+	}{
+		{name: "default", expected: "xterm 200x0 modes=16>"},
+		{name: "vt100", opts: []StreamerOption{WithPTY(true, "", nil), WithTerminalSize(80, 24)}, expected: "vt100 80x24 modes=16>"},
+		{name: "custom modes", opts: []StreamerOption{WithPTY(true, "ansi", ssh.TerminalModes{ssh.ECHO: 1})}, expected: "ansi 200x0 modes=6>"},
+		{name: "disabled", opts: []StreamerOption{WithPTY(false, "", nil)}, expected: "none>"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+			go runPTYServer(t, listener)
+
+			addr := listener.Addr().(*net.TCPAddr)
+			opts := append([]StreamerOption{WithPort(addr.Port)}, tc.opts...)
+			conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), opts...)
+			conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+			ctx := context.Background()
+			require.NoError(t, conn.Init(ctx))
+			defer conn.Close()
+
+			res, err := conn.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`>`))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(res.GetBefore())+string(res.GetMatched()))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// ErrConnectionLost is returned by Cmd when connection is dropped during command execution.
+var ErrConnectionLost = errors.New("connection lost")
+
+// isConnectionLost reports whether err means that connection to server is dropped,
+// only such errors are retried by WithReconnect.
+func isConnectionLost(err error) bool {
+	var exitMissing *ssh.ExitMissingError
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.As(err, &exitMissing)
+}
+
+// getConn returns current connection, it is swapped by reconnect while keepalive and other goroutines may use it.
+func (m *Streamer) getConn() sshClient {
+	m.connMu.RLock()
+	defer m.connMu.RUnlock()
+	return m.conn
+}
+
+// setConn replaces current connection and returns previous one.
+func (m *Streamer) setConn(conn sshClient) sshClient {
+	m.connMu.Lock()
+	defer m.connMu.Unlock()
+	prev := m.conn
+	m.conn = conn
+	return prev
+}
+
+// BackoffPolicy returns delay before reconnect attempt, attempt starts from 1.
+type BackoffPolicy func(attempt int) time.Duration
+
+// ExponentialBackoff returns policy which doubles delay on every attempt starting from initial, delay is capped by max.
+func ExponentialBackoff(initial, max time.Duration) BackoffPolicy {
+	return func(attempt int) time.Duration {
+		delay := initial
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// WithReconnect enables reconnection when connection is lost during Cmd.
+// Interrupted command is replayed only if its ctx is marked with streamer.WithIdempotent (see cmd.Idempotent),
+// other commands fail with ErrConnectionLost. maxRetries limits number of dial attempts per Cmd call.
+// Interactive session opened by Init is not reestablished.
+func WithReconnect(maxRetries int, backoff BackoffPolicy) StreamerOption {
+	return func(h *Streamer) {
+		h.reconnectRetries = maxRetries
+		h.reconnectBackoff = backoff
+	}
+}
+
+// WithReconnectCallback sets function which is called after every reconnect attempt with its result.
+func WithReconnectCallback(cb func(attempt int, err error)) StreamerOption {
+	return func(h *Streamer) {
+		h.onReconnect = cb
+	}
+}
+
+// Reopen closes connection and runs Init again, interactive session is opened on the next read or write.
+// Streamer made by NewSession can't be reopened because its connection is shared.
+func (m *Streamer) Reopen(ctx context.Context) error {
+	if m.sharedConn {
+		return errors.New("streamer with shared connection can't be reopened")
+	}
+	m.Close()
+	m.setConn(nil)
+	m.session = nil
+	m.connInfo = nil
+	m.inited = false
+	return m.Init(ctx)
+}
+
+func (m *Streamer) retryCmd(ctx context.Context, cmd string, cmdErr error) (gcmd.CmdRes, error) {
+	if !streamer.IsIdempotent(ctx) || m.sharedConn {
+		return nil, cmdErr
+	}
+	attempt := 0
+	for attempt < m.reconnectRetries {
+		var err error
+		attempt, err = m.reconnect(ctx, attempt)
+		if err != nil {
+			return nil, errors.Join(cmdErr, err)
+		}
+		m.logger.Debug("replay cmd after reconnect", logging.String("cmd", cmd))
+		var res gcmd.CmdRes
+		res, cmdErr = m.runCmd(ctx, cmd)
+		if cmdErr == nil || !errors.Is(cmdErr, ErrConnectionLost) {
+			return res, cmdErr
+		}
+	}
+	return nil, cmdErr
+}
+
+// reconnect dials until success or until retries are exhausted, returns number of the last attempt.
+func (m *Streamer) reconnect(ctx context.Context, attempt int) (int, error) {
+	var err error
+	for attempt < m.reconnectRetries {
+		attempt++
+		var delay time.Duration
+		if m.reconnectBackoff != nil {
+			delay = m.reconnectBackoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(delay):
+		}
+		if prev := m.setConn(nil); prev != nil {
+			_ = prev.Close()
+		}
+		var conn sshClient
+		var creds credentials.Credentials
+		conn, creds, err = m.openConnect(ctx)
+		m.logger.Debug("reconnect", logging.Int("attempt", attempt), logging.Error(err))
+		if m.onReconnect != nil {
+			m.onReconnect(attempt, err)
+		}
+		if m.observer != nil {
+			m.observer.Reconnect(streamer.ReconnectEvent{Host: m.endpoint.Host, Attempt: attempt, Err: err})
+		}
+		if err == nil {
+			m.setConn(conn)
+			m.startKeepalive()
+			m.watchLifetime(conn)
+			// credentials may be changed by provider since the last connect
+			m.addTranscriptSecrets(ctx, creds)
+			return attempt, nil
+		}
+	}
+	return attempt, err
+}
@@ -0,0 +1,188 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// runDroppingExecServer drops first connection on exec request, next connections run exec and print "ok".
+func runDroppingExecServer(t *testing.T, listener net.Listener) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	for connNo := 1; ; connNo++ {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		drop := connNo == 1
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for newChannel := range chans {
+				channel, requests, err := newChannel.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					for req := range requests {
+						if req.Type != "exec" {
+							_ = req.Reply(false, nil)
+							continue
+						}
+						if drop {
+							_ = conn.Close()
+							return
+						}
+						_ = req.Reply(true, nil)
+						_, _ = channel.Write([]byte("ok"))
+						_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+						_ = channel.Close()
+					}
+				}()
+			}
+		}()
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	for _, idempotent := range []bool{true, false} {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go runDroppingExecServer(t, listener)
+
+		var attempts []int
+		port := listener.Addr().(*net.TCPAddr).Port
+		conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(port),
+			WithReconnect(3, ExponentialBackoff(time.Millisecond, 10*time.Millisecond)),
+			WithReconnectCallback(func(attempt int, err error) {
+				require.NoError(t, err)
+				attempts = append(attempts, attempt)
+			}))
+		ctx := context.Background()
+		require.NoError(t, conn.Init(ctx))
+		if idempotent {
+			ctx = streamer.WithIdempotent(ctx)
+		}
+		res, err := conn.Cmd(ctx, "show version")
+		if idempotent {
+			require.NoError(t, err)
+			require.Equal(t, "ok", string(res.Output()))
+			require.Equal(t, []int{1}, attempts)
+		} else {
+			require.ErrorIs(t, err, ErrConnectionLost)
+			require.Empty(t, attempts)
+		}
+		conn.Close()
+		_ = listener.Close()
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(100*time.Millisecond, time.Second)
+	require.Equal(t, 100*time.Millisecond, backoff(1))
+	require.Equal(t, 400*time.Millisecond, backoff(3))
+	require.Equal(t, time.Second, backoff(10))
+}
+
+func TestIsConnectionLost(t *testing.T) {
+	require.True(t, isConnectionLost(io.EOF))
+	require.True(t, isConnectionLost(fmt.Errorf("read: %w", syscall.ECONNRESET)))
+	require.True(t, isConnectionLost(&ssh.ExitMissingError{}))
+	require.False(t, isConnectionLost(&ssh.OpenChannelError{Reason: ssh.Prohibited}))
+	require.False(t, isConnectionLost(errors.New("ssh: command failed")))
+	require.False(t, isConnectionLost(context.DeadlineExceeded))
+}
+
+func TestReopen(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		// the second connection is accepted after the first one is closed
+		runExecServer(t, listener)
+		runExecServer(t, listener)
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+	conn := NewStreamer("127.0.0.1", creds, WithPort(port))
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	require.Error(t, conn.Init(ctx))
+	require.NoError(t, conn.Reopen(ctx))
+	res, err := conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(res.Output()))
+}
+
+func TestReconnectLifetime(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runDroppingExecServer(t, listener)
+
+	lifetime, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	port := listener.Addr().(*net.TCPAddr).Port
+	conn := NewStreamerWithContext(lifetime, "127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(port),
+		WithReconnect(3, ExponentialBackoff(time.Millisecond, 10*time.Millisecond)))
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	res, err := conn.Cmd(streamer.WithIdempotent(ctx), "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+
+	// new connection must be closed when context is done
+	closed := make(chan struct{})
+	go func() {
+		_ = conn.getConn().(*ssh.Client).Wait()
+		close(closed)
+	}()
+	cancel()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed after context is done")
+	}
+}
+
+func TestReconnectCredentialsProvider(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runDroppingExecServer(t, listener)
+
+	fetched := 0
+	provider := credentials.ProviderFunc(func(ctx context.Context, host string) (credentials.Credentials, error) {
+		fetched++
+		return credentials.NewSimpleCredentials(credentials.WithUsername("user")), nil
+	})
+	port := listener.Addr().(*net.TCPAddr).Port
+	conn := NewStreamer("127.0.0.1", nil, WithPort(port), WithCredentialsProvider(provider),
+		WithReconnect(3, ExponentialBackoff(time.Millisecond, 10*time.Millisecond)))
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	require.Equal(t, 1, fetched)
+	res, err := conn.Cmd(streamer.WithIdempotent(ctx), "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(res.Output()))
+	require.Equal(t, 2, fetched)
+}
@@ -0,0 +1,195 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// SCPError is an error reported by remote scp.
+type SCPError struct {
+	Fatal   bool
+	Message string
+}
+
+func (e *SCPError) Error() string {
+	return fmt.Sprintf("scp error: %s", e.Message)
+}
+
+// WithSCPShellQuoting makes CopyFrom and CopyTo quote remote path for POSIX shell.
+// It is needed for servers which run scp command through shell, like OpenSSH, when path contains spaces
+// or shell metacharacters. Network devices usually parse the command by themselves, so path is passed as is by default.
+func WithSCPShellQuoting() StreamerOption {
+	return func(h *Streamer) {
+		h.scpShellQuoting = true
+	}
+}
+
+// CopyFrom downloads single remote file using SCP protocol and writes its content to w.
+func (m *Streamer) CopyFrom(ctx context.Context, remotePath string, w io.Writer) error {
+	return m.runSCP(ctx, "scp -f "+m.scpPath(remotePath), func(stdin io.Writer, stdout *bufio.Reader) error {
+		// ready to receive
+		if err := scpAck(stdin); err != nil {
+			return err
+		}
+		for {
+			msgType, err := stdout.ReadByte()
+			if err != nil {
+				return fmt.Errorf("scp read error: %w", err)
+			}
+			line, err := stdout.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("scp read error: %w", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch msgType {
+			case 1, 2:
+				return &SCPError{Fatal: msgType == 2, Message: line}
+			case 'T': // times
+				if err := scpAck(stdin); err != nil {
+					return err
+				}
+				continue
+			case 'C':
+			default:
+				return fmt.Errorf("scp unsupported message %q", string(msgType)+line)
+			}
+			// C<mode> <size> <name>
+			fields := strings.SplitN(line, " ", 3)
+			if len(fields) != 3 {
+				return fmt.Errorf("scp malformed message %q", line)
+			}
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("scp malformed size %q", fields[1])
+			}
+			if err := scpAck(stdin); err != nil {
+				return err
+			}
+			if _, err := io.CopyN(w, stdout, size); err != nil {
+				return fmt.Errorf("scp read error: %w", err)
+			}
+			if err := scpReadAck(stdout); err != nil {
+				return err
+			}
+			return scpAck(stdin)
+		}
+	})
+}
+
+// CopyTo uploads size bytes of r as remote file using SCP protocol.
+func (m *Streamer) CopyTo(ctx context.Context, r io.Reader, size int64, remotePath string, mode os.FileMode) error {
+	return m.runSCP(ctx, "scp -t "+m.scpPath(remotePath), func(stdin io.Writer, stdout *bufio.Reader) error {
+		if err := scpReadAck(stdout); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(stdin, "C%04o %d %s\n", mode.Perm(), size, path.Base(remotePath))
+		if err != nil {
+			return fmt.Errorf("scp write error: %w", err)
+		}
+		if err := scpReadAck(stdout); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(stdin, r, size); err != nil {
+			return fmt.Errorf("scp write error: %w", err)
+		}
+		if err := scpAck(stdin); err != nil {
+			return err
+		}
+		return scpReadAck(stdout)
+	})
+}
+
+// runSCP runs scp command in new session and passes its streams to fn.
+func (m *Streamer) runSCP(ctx context.Context, command string, fn func(stdin io.Writer, stdout *bufio.Reader) error) error {
+	if m.getConn() == nil {
+		return errNotConnected
+	}
+	m.logger.Debug("scp", logging.String("cmd", command))
+	sessionTemplate, err := m.newSessionTemplate()
+	if err != nil {
+		return fmt.Errorf("failed to init session template: %w", err)
+	}
+	session := sessionTemplate.session
+	defer session.Close()
+	var ctxErr error
+	cancel := streamer.CloserCTX(ctx, func() {
+		ctxErr = ctx.Err()
+		_ = session.Close()
+	})
+	defer cancel()
+	stderr := &bytes.Buffer{}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		_, _ = io.Copy(stderr, sessionTemplate.stderr)
+	}()
+	err = session.Start(command)
+	if err != nil {
+		return fmt.Errorf("scp start error: %w", err)
+	}
+	err = fn(sessionTemplate.stdin, bufio.NewReader(sessionTemplate.stdout))
+	_ = sessionTemplate.stdin.Close()
+	waitErr := session.Wait()
+	<-stderrDone
+	if ctxErr != nil {
+		return ctxErr
+	}
+	if err == nil && waitErr != nil {
+		err = fmt.Errorf("scp exit error: %w", waitErr)
+	}
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+func scpAck(w io.Writer) error {
+	_, err := w.Write([]byte{0})
+	if err != nil {
+		return fmt.Errorf("scp write error: %w", err)
+	}
+	return nil
+}
+
+// scpReadAck reads response to the last message: zero byte or warning/error with message.
+func scpReadAck(r *bufio.Reader) error {
+	code, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("scp read error: %w", err)
+	}
+	if code == 0 {
+		return nil
+	}
+	if code != 1 && code != 2 {
+		return fmt.Errorf("scp unexpected response %q", code)
+	}
+	msg, err := r.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("scp read error: %w", err)
+	}
+	return &SCPError{Fatal: code == 2, Message: strings.TrimSuffix(msg, "\n")}
+}
+
+// scpPath returns remote path for scp command line.
+func (m *Streamer) scpPath(remotePath string) string {
+	if m.scpShellQuoting {
+		return shellQuote(remotePath)
+	}
+	return remotePath
+}
+
+// shellQuote quotes s for POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,154 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// scpServer serves scp -f and -t for in-memory files.
+type scpServer struct {
+	mu       sync.Mutex
+	files    map[string][]byte
+	modes    map[string]string
+	commands []string
+}
+
+func (m *scpServer) run(t *testing.T, listener net.Listener) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				var payload struct{ Command string }
+				if req.Type != "exec" || ssh.Unmarshal(req.Payload, &payload) != nil {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				_ = req.Reply(true, nil)
+				go m.serve(channel, payload.Command)
+			}
+		}()
+	}
+}
+
+func (m *scpServer) serve(channel ssh.Channel, command string) {
+	defer channel.Close()
+	m.mu.Lock()
+	m.commands = append(m.commands, command)
+	m.mu.Unlock()
+	fields := strings.SplitN(command, " ", 3)
+	filePath := strings.Trim(fields[2], "'")
+	r := bufio.NewReader(channel)
+	status := uint32(0)
+	switch fields[1] {
+	case "-f":
+		m.mu.Lock()
+		data, ok := m.files[filePath]
+		m.mu.Unlock()
+		_, _ = r.ReadByte()
+		if !ok {
+			_, _ = fmt.Fprintf(channel, "\x01scp: %s: No such file or directory\n", filePath)
+			status = 1
+			break
+		}
+		_, _ = fmt.Fprintf(channel, "C0644 %d file\n", len(data))
+		_, _ = r.ReadByte()
+		_, _ = channel.Write(append(data, 0))
+		_, _ = r.ReadByte()
+	case "-t":
+		_, _ = channel.Write([]byte{0})
+		line, _ := r.ReadString('\n')
+		header := strings.Fields(line)
+		size, _ := strconv.Atoi(header[1])
+		_, _ = channel.Write([]byte{0})
+		data := make([]byte, size)
+		_, _ = io.ReadFull(r, data)
+		_, _ = r.ReadByte()
+		m.mu.Lock()
+		m.files[filePath] = data
+		m.modes[filePath] = header[0]
+		m.mu.Unlock()
+		_, _ = channel.Write([]byte{0})
+	}
+	_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+}
+
+func TestSCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	server := &scpServer{files: map[string][]byte{"/var/log/messages": []byte("log line\n")}, modes: map[string]string{}}
+	go server.run(t, listener)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, conn.CopyFrom(ctx, "/var/log/messages", buf))
+	require.Equal(t, "log line\n", buf.String())
+
+	err = conn.CopyFrom(ctx, "/nonexistent", buf)
+	var scpErr *SCPError
+	require.ErrorAs(t, err, &scpErr)
+	require.Equal(t, "scp: /nonexistent: No such file or directory", scpErr.Message)
+
+	image := []byte("image data")
+	require.NoError(t, conn.CopyTo(ctx, bytes.NewReader(image), int64(len(image)), "/flash/image.bin", 0o600))
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Equal(t, image, server.files["/flash/image.bin"])
+	require.Equal(t, "C0600", server.modes["/flash/image.bin"])
+	require.Equal(t, "scp -t /flash/image.bin", server.commands[len(server.commands)-1])
+}
+
+func TestSCPShellQuoting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	server := &scpServer{files: map[string][]byte{"/tmp/my file": []byte("data")}, modes: map[string]string{}}
+	go server.run(t, listener)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port), WithSCPShellQuoting())
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, conn.CopyFrom(ctx, "/tmp/my file", buf))
+	require.Equal(t, "data", buf.String())
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	require.Equal(t, []string{"scp -f '/tmp/my file'"}, server.commands)
+}
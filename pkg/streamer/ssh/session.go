@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// SessionLimitError is returned by NewSession when server refuses to open one more session.
+// Usually it means that MaxSessions limit of server is reached (10 by default in OpenSSH),
+// caller may wait for other sessions to close and retry.
+type SessionLimitError struct {
+	Err error
+}
+
+func (e *SessionLimitError) Error() string {
+	return fmt.Sprintf("session limit reached: %s", e.Err)
+}
+
+func (e *SessionLimitError) Unwrap() error {
+	return e.Err
+}
+
+// NewSession opens new interactive session over already established connection of m without re-authentication.
+// Returned Streamer is an independent Connector, it is safe to call NewSession from multiple goroutines
+// and to use returned streamers concurrently. Closing of returned Streamer closes only its session,
+// the connection is closed by m.Close.
+// If server refuses to open session SessionLimitError is returned.
+func (m *Streamer) NewSession(ctx context.Context) (*Streamer, error) {
+	conn := m.getConn()
+	if conn == nil {
+		return nil, errNotConnected
+	}
+	res := *m
+	res.conn = conn
+	res.connMu = &sync.RWMutex{}
+	res.session = nil
+	res.forwardAgent = nil
+	res.sharedConn = true
+	res.cmds = &cmdTracker{}
+	res.sftpClients = &sftpClients{}
+	res.keepaliveStop = nil // keepalive belongs to connection owner
+	res.lifetimeStop = nil  // so does lifetime watch
+	res.outputHook = streamer.NewOutputHook()
+	res.onSessionOpenCallbacks = append([]func(*ssh.Session) error{}, m.onSessionOpenCallbacks...)
+	res.onChanCloseCallbacks = append([]func(*ssh.Session) error{}, m.onChanCloseCallbacks...)
+
+	var sess *sshSession
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sess, err = res.openSession()
+	}()
+	select {
+	case <-ctx.Done():
+		go func() {
+			<-done
+			if sess != nil {
+				_ = sess.session.Close()
+				sess.chanReaderCancel()
+			}
+		}()
+		return nil, ctx.Err()
+	case <-done:
+	}
+	if err != nil {
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) && (openErr.Reason == ssh.Prohibited || openErr.Reason == ssh.ResourceShortage) {
+			return nil, &SessionLimitError{Err: err}
+		}
+		return nil, err
+	}
+	res.session = sess
+	return &res, nil
+}
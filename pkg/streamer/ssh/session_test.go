@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+// runSessionLimitServer accepts single connection and serves at most maxSessions sessions,
+// each session prints its number.
+func runSessionLimitServer(t *testing.T, listener net.Listener, maxSessions int) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	opened := 0
+	for newChannel := range chans {
+		if opened >= maxSessions {
+			_ = newChannel.Reject(ssh.Prohibited, "open failed")
+			continue
+		}
+		opened++
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		no := opened
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "pty-req" || req.Type == "shell", nil)
+				if req.Type == "shell" {
+					_, _ = channel.Write([]byte{'0' + byte(no), '>'})
+				}
+			}
+		}()
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runSessionLimitServer(t, listener, 2)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+
+	prompt := expr.NewSimpleExpr().FromPattern(`\d>`)
+	first, err := conn.NewSession(ctx)
+	require.NoError(t, err)
+	defer first.Close()
+	second, err := conn.NewSession(ctx)
+	require.NoError(t, err)
+	defer second.Close()
+
+	res, err := first.ReadTo(ctx, prompt)
+	require.NoError(t, err)
+	require.Equal(t, "1>", string(res.GetMatched()))
+	res, err = second.ReadTo(ctx, prompt)
+	require.NoError(t, err)
+	require.Equal(t, "2>", string(res.GetMatched()))
+
+	_, err = conn.NewSession(ctx)
+	var limitErr *SessionLimitError
+	require.ErrorAs(t, err, &limitErr)
+}
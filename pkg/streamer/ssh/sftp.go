@@ -0,0 +1,59 @@
+package ssh
+
+import (
+	"sync"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTP opens SFTP client in new session over established connection, so no additional authentication is made.
+// Device must run SFTP subsystem. Client may be closed by caller, otherwise it is closed by Close of streamer.
+func (m *Streamer) SFTP() (*sftp.Client, error) {
+	if m.getConn() == nil {
+		return nil, errNotConnected
+	}
+	sc, _, err := m.makeSftpClient(false)
+	if err != nil {
+		return nil, err
+	}
+	m.sftpClients.add(sc)
+	return sc, nil
+}
+
+// sftpClients keeps open clients returned by SFTP.
+type sftpClients struct {
+	mu      sync.Mutex
+	clients map[*sftp.Client]struct{}
+}
+
+func (m *sftpClients) add(sc *sftp.Client) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if m.clients == nil {
+		m.clients = map[*sftp.Client]struct{}{}
+	}
+	m.clients[sc] = struct{}{}
+	m.mu.Unlock()
+	go func() {
+		// returns when client is closed
+		_ = sc.Wait()
+		m.mu.Lock()
+		delete(m.clients, sc)
+		m.mu.Unlock()
+	}()
+}
+
+func (m *sftpClients) closeAll() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	clients := m.clients
+	m.clients = nil
+	m.mu.Unlock()
+	for sc := range clients {
+		_ = sc.Close()
+	}
+}
@@ -0,0 +1,134 @@
+package ssh
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+
+	"github.com/annetutil/gnetcli/pkg/device"
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+const resumeMetaSuffix = ".resume"
+
+// resumeMeta describes remote file state for partially downloaded file.
+type resumeMeta struct {
+	Remote  string `json:"remote"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+}
+
+// GetFileResume downloads remote file into local path. Interrupted download is continued from current size of local file
+// unless remote file was changed, in that case download starts over.
+// Remote file state is stored next to local file with suffix ".resume" until download is completed.
+func (m *Streamer) GetFileResume(ctx context.Context, remote, local string) error {
+	if !m.sftpEnabled {
+		return device.ErrorStreamerNotSupportedByDevice
+	}
+	sc, stop, err := m.makeSftpClient(false)
+	if err != nil {
+		return fmt.Errorf("makeSftpClient err %w", err)
+	}
+	defer stop()
+	cancel := streamer.CloserCTX(ctx, stop)
+	defer cancel()
+	err = m.sftpGetFileResume(sc, remote, local)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (m *Streamer) sftpGetFileResume(sc *sftp.Client, remote, local string) error {
+	stat, err := sc.Stat(remote)
+	if err != nil {
+		return fmt.Errorf("stat %q: %w", remote, err)
+	}
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("%q is not a regular file", remote)
+	}
+	meta := resumeMeta{Remote: remote, Size: stat.Size(), ModTime: stat.ModTime().Unix()}
+	metaPath := local + resumeMetaSuffix
+
+	offset := int64(0)
+	prevMeta, err := readResumeMeta(metaPath)
+	if err != nil {
+		return err
+	}
+	if prevMeta != nil && *prevMeta == meta {
+		localStat, err := os.Stat(local)
+		if err == nil && localStat.Size() <= meta.Size {
+			offset = localStat.Size()
+		}
+	} else if prevMeta != nil {
+		m.logger.Debug("remote file changed, start over", logging.String("path", remote))
+	}
+	if err := writeResumeMeta(metaPath, meta); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(local, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+	if err := dst.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	if offset < meta.Size {
+		m.logger.Debug("download", logging.String("path", remote), logging.Int64("offset", offset), logging.Int64("size", meta.Size))
+		src, err := sc.OpenFile(remote, os.O_RDONLY)
+		if err != nil {
+			return fmt.Errorf("open %q: %w", remote, err)
+		}
+		defer src.Close()
+		if _, err := src.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		written, err := io.Copy(dst, io.LimitReader(src, meta.Size-offset))
+		if err != nil {
+			return fmt.Errorf("download %q: %w", remote, err)
+		}
+		offset += written
+	}
+	if offset != meta.Size {
+		return fmt.Errorf("download %q: got %d bytes, expected %d", remote, offset, meta.Size)
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(metaPath)
+}
+
+func readResumeMeta(path string) (*resumeMeta, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &resumeMeta{}
+	if err := json.Unmarshal(data, res); err != nil {
+		// broken metadata means that we can't trust local file
+		return nil, nil
+	}
+	return res, nil
+}
+
+func writeResumeMeta(path string, meta resumeMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
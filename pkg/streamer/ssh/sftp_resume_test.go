@@ -0,0 +1,72 @@
+package ssh
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+)
+
+func makeTestSftpClient(t *testing.T) *sftp.Client {
+	clientConn, serverConn := net.Pipe()
+	server, err := sftp.NewServer(serverConn)
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve()
+	}()
+	sc, err := sftp.NewClientPipe(clientConn, clientConn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sc.Close()
+		_ = server.Close()
+	})
+	return sc
+}
+
+func TestSftpGetFileResume(t *testing.T) {
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.bin")
+	local := filepath.Join(dir, "local.bin")
+	content := strings.Repeat("0123456789", 1000)
+	require.NoError(t, os.WriteFile(remote, []byte(content), 0o644))
+	stat, err := os.Stat(remote)
+	require.NoError(t, err)
+
+	// emulate interrupted download
+	require.NoError(t, os.WriteFile(local, []byte(content[:4000]), 0o644))
+	require.NoError(t, writeResumeMeta(local+resumeMetaSuffix, resumeMeta{Remote: remote, Size: stat.Size(), ModTime: stat.ModTime().Unix()}))
+
+	m := &Streamer{logger: logging.Nop()}
+	sc := makeTestSftpClient(t)
+	require.NoError(t, m.sftpGetFileResume(sc, remote, local))
+	res, err := os.ReadFile(local)
+	require.NoError(t, err)
+	require.Equal(t, content, string(res))
+	_, err = os.Stat(local + resumeMetaSuffix)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSftpGetFileResumeChanged(t *testing.T) {
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.bin")
+	local := filepath.Join(dir, "local.bin")
+	require.NoError(t, os.WriteFile(remote, []byte("new content"), 0o644))
+
+	// local part belongs to previous version of remote file
+	require.NoError(t, os.WriteFile(local, []byte("old"), 0o644))
+	require.NoError(t, writeResumeMeta(local+resumeMetaSuffix, resumeMeta{Remote: remote, Size: 11, ModTime: time.Now().Add(-time.Hour).Unix()}))
+
+	m := &Streamer{logger: logging.Nop()}
+	sc := makeTestSftpClient(t)
+	require.NoError(t, m.sftpGetFileResume(sc, remote, local))
+	res, err := os.ReadFile(local)
+	require.NoError(t, err)
+	require.Equal(t, "new content", string(res))
+}
@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// runSftpServer serves sftp subsystem on local filesystem.
+func runSftpServer(t *testing.T, listener net.Listener) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				var payload struct{ Name string }
+				ok := req.Type == "subsystem" && ssh.Unmarshal(req.Payload, &payload) == nil && payload.Name == "sftp"
+				_ = req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+				server, err := sftp.NewServer(channel)
+				if err != nil {
+					_ = channel.Close()
+					continue
+				}
+				go func() {
+					_ = server.Serve()
+					_ = channel.Close()
+				}()
+			}
+		}()
+	}
+}
+
+func TestSFTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runSftpServer(t, listener)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	require.NoError(t, conn.Init(context.Background()))
+
+	sc, err := conn.SFTP()
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "config.txt")
+	f, err := sc.Create(path)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("hostname router"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "hostname router", string(data))
+
+	conn.Close()
+	_, err = sc.Stat(path)
+	require.Error(t, err)
+}
@@ -6,6 +6,7 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
@@ -15,22 +16,26 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/sftp"
-	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh"
 	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
 	"golang.org/x/sync/errgroup"
 
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
+	"go.uber.org/zap"
 
 	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
 	"github.com/annetutil/gnetcli/pkg/credentials"
 	"github.com/annetutil/gnetcli/pkg/device"
 	"github.com/annetutil/gnetcli/pkg/expr"
 	"github.com/annetutil/gnetcli/pkg/gerror"
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/logging/zaplog"
 	"github.com/annetutil/gnetcli/pkg/streamer"
 	"github.com/annetutil/gnetcli/pkg/trace"
 )
@@ -54,6 +59,10 @@ const (
 )
 
 var _ streamer.Connector = (*Streamer)(nil)
+var _ streamer.Drainer = (*Streamer)(nil)
+var _ streamer.OutputObserver = (*Streamer)(nil)
+var _ streamer.Reopener = (*Streamer)(nil)
+var _ streamer.SecretRedactor = (*Streamer)(nil)
 
 type sshSessionTemplate struct {
 	stdin   io.WriteCloser
@@ -72,13 +81,13 @@ type sshSession struct {
 	chanReaderCancel  context.CancelFunc
 }
 
-func newSSHSession(in *sshSessionTemplate, logger *zap.Logger) *sshSession {
+func newSSHSession(in *sshSessionTemplate, logger logging.Logger, hook *streamer.OutputHook, stripper *streamer.EscapeStripper) *sshSession {
 	stdoutBuffer := make(chan []byte, 100)
 	newCtx, cancel := context.WithCancel(context.Background())
 	go func() { // will be closed after closing stdout
-		err := chanReader(newCtx, in.stdout, stdoutBuffer, time.Second, logger)
+		err := chanReader(newCtx, in.stdout, stdoutBuffer, time.Second, logger, hook, stripper)
 		if err != nil {
-			logger.Debug("sessionStdoutReader error", zap.Error(err))
+			logger.Debug("sessionStdoutReader error", logging.Error(err))
 			close(stdoutBuffer)
 		}
 	}()
@@ -105,11 +114,44 @@ type Endpoint struct {
 }
 
 func (endpoint Endpoint) String() string {
-	return fmt.Sprintf("{host: %s, port: %d, network: %s}", endpoint.Host, endpoint.Port, endpoint.Network)
+	host := endpointHost(endpoint.Host)
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return fmt.Sprintf("{host: %s, port: %d, network: %s}", host, endpoint.Port, endpoint.Network)
 }
 
+// Addr returns address suitable for dialing. IPv6 hosts are bracketed and keep zone, zero port is replaced with default one.
 func (endpoint *Endpoint) Addr() string {
-	return net.JoinHostPort(endpoint.Host, strconv.Itoa(endpoint.Port))
+	port := endpoint.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(endpointHost(endpoint.Host), strconv.Itoa(port))
+}
+
+// endpointHost strips brackets from IPv6 host and unescapes zone written as in URLs (fe80::1%25eth0).
+func endpointHost(host string) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.Contains(host, ":") {
+		host = strings.Replace(host, "%25", "%", 1)
+	}
+	return host
+}
+
+// ContextDialer is implemented by net.Dialer and proxy dialers.
+type ContextDialer = streamer.Dialer
+
+// DialContext dials endpoint using dialer. Nil dialer means net.Dialer with default settings.
+func (endpoint *Endpoint) DialContext(ctx context.Context, dialer ContextDialer) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	network := endpoint.Network
+	if len(network) == 0 {
+		network = TCP
+	}
+	return dialer.DialContext(ctx, string(network), endpoint.Addr())
 }
 
 func NewEndpoint(host string, port int, network Network) Endpoint {
@@ -125,13 +167,18 @@ type Streamer struct {
 	endpoint               Endpoint
 	additionalEndpoints    []Endpoint
 	credentials            credentials.Credentials
-	logger                 *zap.Logger
-	conn                   sshClient
+	logger                 logging.Logger
+	conn                   sshClient // swapped on reconnect, see getConn
+	connMu                 *sync.RWMutex
 	program                string // session params
 	programData            string
 	env                    map[string]string
 	terminalParams         terminalParams
+	pty                    ptyParams
 	tunnel                 Tunnel
+	jumpHosts              []jumpHost
+	ownTunnel              bool // tunnel is made from jumpHosts and closed with streamer
+	postLogin              *postLogin
 	credentialsInterceptor func(credentials.Credentials) credentials.Credentials
 	session                *sshSession
 	onSessionOpenCallbacks []func(*ssh.Session) error
@@ -143,13 +190,72 @@ type Streamer struct {
 	readTimeout            time.Duration
 	forwardAgent           agent.Agent
 	hostKeyCallback        ssh.HostKeyCallback
+	hostCertAuthorities    []ssh.PublicKey
 	controlFile            string // openssh control file
+	proxyCommand           string
+	sharedConn             bool // conn is owned by another Streamer, see NewSession
+	outputHook             *streamer.OutputHook
+	escapeMode             streamer.EscapeMode
+	reconnectRetries       int
+	reconnectBackoff       BackoffPolicy
+	onReconnect            func(attempt int, err error)
+	passwordsTried         int // number of passwords offered during last auth
+	credentialsProvider    credentials.Provider
+	knownHostsFiles        []string
+	connInfo               *ConnInfo
+	keyExchanges           []string // algorithm overrides, nil means default list
+	ciphers                []string
+	macs                   []string
+	transcript             *trace.TranscriptWriter
+	dialer                 streamer.Dialer // nil means direct connection
+	cmds                   *cmdTracker     // running Cmd calls, see CloseContext
+	sftpClients            *sftpClients    // clients returned by SFTP, closed by Close
+	observer               streamer.Observer
+	authMethod             string // method of the last auth attempt, see observeAuth
+	tracer                 oteltrace.Tracer
+	keepaliveInterval      time.Duration
+	keepaliveCountMax      int
+	keepaliveStop          func()
+	lifetime               context.Context // connection is closed when it is done, nil means no limit
+	lifetimeStop           func() bool
+	scpShellQuoting        bool // see WithSCPShellQuoting
+}
+
+// SetOutputCallback sets callback for data read from session, see streamer.OutputObserver.
+func (m *Streamer) SetOutputCallback(cb func([]byte)) func([]byte) {
+	return m.outputHook.Set(cb)
 }
 
 func (m *Streamer) SetTrace(cb trace.CB) {
 	m.trace = cb
 }
 
+func (m *Streamer) addTrace(op trace.Operation, data []byte) {
+	if m.trace != nil {
+		m.trace(op, data)
+	}
+	if m.transcript != nil {
+		m.transcript.Add(op, data)
+	}
+}
+
+// AddSecrets passes secrets written during session to transcript for redaction, see streamer.SecretRedactor.
+func (m *Streamer) AddSecrets(secrets ...[]byte) {
+	if m.transcript != nil {
+		m.transcript.AddSecrets(secrets...)
+	}
+}
+
+// addTranscriptSecrets passes passwords of creds to transcript for redaction.
+func (m *Streamer) addTranscriptSecrets(ctx context.Context, creds credentials.Credentials) {
+	if m.transcript == nil || !m.transcript.Redact() || creds == nil {
+		return
+	}
+	for _, password := range creds.GetPasswords(ctx) {
+		m.transcript.AddSecrets([]byte(password.Value()))
+	}
+}
+
 func (m *Streamer) SetReadTimeout(timeout time.Duration) time.Duration {
 	prev := m.readTimeout
 	m.readTimeout = timeout
@@ -184,6 +290,7 @@ func NewStreamer(host string, credentials credentials.Credentials, opts ...Strea
 		programData:            "",
 		env:                    map[string]string{},
 		terminalParams:         terminalParams{w: defaultTerminalWidth, h: defaultTerminalHeight},
+		pty:                    ptyParams{enabled: true, term: "xterm", modes: nil},
 		tunnel:                 nil,
 		credentialsInterceptor: nil,
 		session:                nil,
@@ -196,12 +303,26 @@ func NewStreamer(host string, credentials credentials.Credentials, opts ...Strea
 		readTimeout:            defaultReadTimeout,
 		hostKeyCallback:        ssh.InsecureIgnoreHostKey(),
 		controlFile:            "",
+		outputHook:             streamer.NewOutputHook(),
+		escapeMode:             streamer.EscapeOff,
+		cmds:                   &cmdTracker{},
+		connMu:                 &sync.RWMutex{},
+		sftpClients:            &sftpClients{},
+		observer:               streamer.NopObserver{},
+		keepaliveCountMax:      DefaultKeepaliveCountMax,
+	}
+	for _, opt := range streamer.DefaultOptions[StreamerOption]() {
+		opt(h)
 	}
 	for _, opt := range opts {
 		opt(h)
 	}
 	if h.logger == nil {
-		h.logger = zap.NewNop()
+		h.logger = logging.Nop()
+	}
+	if len(h.jumpHosts) > 0 {
+		h.tunnel = h.makeJumpTunnel()
+		h.ownTunnel = true
 	}
 	return h
 }
@@ -218,19 +339,18 @@ func (m *Streamer) Write(text []byte) error {
 			return err
 		}
 	}
-	if m.trace != nil {
-		m.trace(trace.Write, text)
-	}
+	m.addTrace(trace.Write, text)
 	written, err := m.session.stdin.Write(text)
 	if err != nil {
-		return err
+		return m.lifetimeErr(err)
 	}
-	m.logger.Debug("write", zap.ByteString("text", text), zap.Int("written", written))
+	m.logger.Debug("write", logging.ByteString("text", text), logging.Int("written", written))
 	return nil
 }
 
 // It's impossible to set timeout for Read, so read here and put in channel
-func chanReader(ctx context.Context, reader io.Reader, stdoutBuffer chan []byte, readTimeout time.Duration, logger *zap.Logger) error {
+func chanReader(ctx context.Context, reader io.Reader, stdoutBuffer chan []byte, readTimeout time.Duration, logger logging.Logger,
+	hook *streamer.OutputHook, stripper *streamer.EscapeStripper) error {
 	tmpBuffer := make(chan []byte, defaultReadSize)
 	wg, wCtx := errgroup.WithContext(ctx)
 	wg.Go(func() error {
@@ -245,8 +365,12 @@ func chanReader(ctx context.Context, reader io.Reader, stdoutBuffer chan []byte,
 			_ = wg.Wait()
 			return err
 		}
-		logger.Debug("read", zap.ByteString("data", readBuffer[:readLen]))
-		tmpBuffer <- readBuffer[:readLen]
+		logger.Debug("read", logging.ByteString("data", readBuffer[:readLen]))
+		hook.Call(readBuffer[:readLen])
+		data := stripper.Process(readBuffer[:readLen])
+		if len(data) > 0 {
+			tmpBuffer <- data
+		}
 	}
 }
 
@@ -293,7 +417,7 @@ L:
 }
 
 func (m *Streamer) Read(ctx context.Context, size int) ([]byte, error) {
-	m.logger.Debug("read", zap.Int("size", size))
+	m.logger.Debug("read", logging.Int("size", size))
 	if m.session == nil {
 		err := m.startSession()
 		if err != nil {
@@ -301,9 +425,7 @@ func (m *Streamer) Read(ctx context.Context, size int) ([]byte, error) {
 		}
 	}
 	res, extra, read, err := streamer.GenericReadX(ctx, m.session.stdoutBufferExtra, m.session.stdoutBuffer, defaultReadSize, m.readTimeout, nil, size, 0)
-	if m.trace != nil {
-		m.trace(trace.Read, read)
-	}
+	m.addTrace(trace.Read, read)
 	m.session.stdoutBufferExtra = extra
 	if err != nil {
 		return nil, err
@@ -316,31 +438,46 @@ func (m *Streamer) Read(ctx context.Context, size int) ([]byte, error) {
 }
 
 func (m *Streamer) ReadTo(ctx context.Context, expr expr.Expr) (streamer.ReadRes, error) {
-	m.logger.Debug("read to", zap.String("expr", expr.Repr()))
+	m.logger.Debug("read to", logging.String("expr", expr.Repr()))
 	if m.session == nil {
 		err := m.startSession()
 		if err != nil {
 			return nil, err
 		}
 	}
+	ctx, cancel := m.withLifetime(ctx)
+	defer cancel()
 	res, extra, read, err := streamer.GenericReadX(ctx, m.session.stdoutBufferExtra, m.session.stdoutBuffer, defaultReadSize, m.readTimeout, expr, 0, 0)
-	if m.trace != nil {
-		m.trace(trace.Read, read)
-	}
+	m.addTrace(trace.Read, read)
 	m.session.stdoutBufferExtra = extra
 	if err != nil {
-		return nil, err
+		return nil, m.lifetimeErr(err)
 	}
 
 	if res.RetType == streamer.Timeout {
 		return nil, streamer.ThrowReadTimeoutException(streamer.GetLastBytes(read, defaultReadSize))
 	}
 	if res.RetType == streamer.EOF {
-		return nil, streamer.ThrowEOFException(streamer.GetLastBytes(read, defaultReadSize))
+		return nil, m.lifetimeErr(streamer.ThrowEOFException(streamer.GetLastBytes(read, defaultReadSize)))
 	}
 	return res.ExprRes, nil
 }
 
+// Drain reads everything that arrives during duration and returns it.
+func (m *Streamer) Drain(ctx context.Context, duration time.Duration) ([]byte, error) {
+	if m.session == nil {
+		return nil, nil
+	}
+	res, extra, read, err := streamer.GenericReadX(ctx, m.session.stdoutBufferExtra, m.session.stdoutBuffer, defaultReadSize, 0, nil, 0, duration)
+	m.addTrace(trace.Read, read)
+	m.session.stdoutBufferExtra = extra
+	if err != nil {
+		return nil, err
+	}
+	m.session.stdoutBufferExtra = nil
+	return res.BytesRes, nil
+}
+
 func (m *Streamer) HasFeature(feature streamer.Const) bool {
 	if feature == streamer.AutoLogin || feature == streamer.Cmd {
 		return true
@@ -363,7 +500,7 @@ func WithKnownHostsFiles(files ...string) (StreamerOption, error) {
 		return nil, err
 	}
 	return func(h *Streamer) {
-		h.hostKeyCallback = hostKeyCallback
+		h.hostKeyCallback = wrapKnownHostsCallback(hostKeyCallback)
 	}, nil
 }
 
@@ -375,6 +512,13 @@ func WithProgram(program, programData string) StreamerOption {
 }
 
 func WithLogger(log *zap.Logger) StreamerOption {
+	return func(h *Streamer) {
+		h.logger = zaplog.New(log)
+	}
+}
+
+// WithLogging sets logger of any logging library, see logging and zaplog packages for adapters.
+func WithLogging(log logging.Logger) StreamerOption {
 	return func(h *Streamer) {
 		h.logger = log
 	}
@@ -420,6 +564,47 @@ func WithEnv(key, value string) StreamerOption {
 	}
 }
 
+// WithEscapeStripping sets removal of escape sequences from session output before expression matching
+func WithEscapeStripping(mode streamer.EscapeMode) StreamerOption {
+	return func(h *Streamer) {
+		h.escapeMode = mode
+	}
+}
+
+// WithTranscript writes data written to and read from session to w, see trace.TranscriptWriter for format.
+// With trace.TranscriptWithRedaction passwords from credentials are redacted.
+func WithTranscript(w io.Writer, opts ...trace.TranscriptOption) StreamerOption {
+	return func(h *Streamer) {
+		h.transcript = trace.NewTranscriptWriter(w, opts...)
+	}
+}
+
+// WithCredentialsProvider makes Streamer fetch credentials from provider on every connect and reconnect,
+// credentials passed to NewStreamer are not used. Fetched credentials are not kept,
+// GetCredentials returns credentials which fetch them again, see credentials.ProviderCredentials.
+func WithCredentialsProvider(provider credentials.Provider) StreamerOption {
+	return func(h *Streamer) {
+		h.credentialsProvider = provider
+	}
+}
+
+// WithDialer sets dialer for connection to device, e.g. SOCKS5 dialer from golang.org/x/net/proxy.
+// It is not used when connection goes through tunnel, see SSHTunnelWithDialer.
+func WithDialer(dialer streamer.Dialer) StreamerOption {
+	return func(h *Streamer) {
+		h.dialer = dialer
+	}
+}
+
+// WithLocalAddr binds connections to device to local address, e.g. &net.TCPAddr{IP: net.ParseIP("10.0.0.1")}.
+// It replaces dialer set by WithDialer. Jump hosts made by WithJumpHost are dialed from it,
+// but connection through tunnel set by WithSSHTunnel originates on tunnel server and is not affected.
+func WithLocalAddr(addr net.Addr) StreamerOption {
+	return func(h *Streamer) {
+		h.dialer = &net.Dialer{LocalAddr: addr}
+	}
+}
+
 // WithAdditionalEndpoints adds slice of endpoints that Streamer will sequentially try to connect to until success of dial,
 // if original host dial fails
 func WithAdditionalEndpoints(endpoints []Endpoint) StreamerOption {
@@ -429,28 +614,56 @@ func WithAdditionalEndpoints(endpoints []Endpoint) StreamerOption {
 }
 
 func (m *Streamer) Close() {
+	m.stopKeepalive()
+	m.stopLifetime()
+	m.sftpClients.closeAll()
 	m.forwardAgent = nil
 	if m.session != nil && m.session.session != nil {
 		err := m.onSessionClose(m.session.session)
 		if err != nil {
-			m.logger.Error("onSessionClose error", zap.Error(err))
+			m.logger.Error("onSessionClose error", logging.Error(err))
 		}
 		_ = m.session.stdin.Close()
 		_ = m.session.session.Close()
 	}
-	if m.conn != nil {
-		_ = m.conn.Close()
+	if conn := m.getConn(); conn != nil && !m.sharedConn {
+		_ = conn.Close()
 	}
 	// cancel chanReader goroutine
 	if m.session != nil && m.session.chanReaderCancel != nil {
 		m.session.chanReaderCancel()
 	}
+	if m.ownTunnel && !m.sharedConn && m.tunnel.IsConnected() {
+		m.tunnel.Close()
+	}
 }
 
 func (m *Streamer) Cmd(ctx context.Context, cmd string) (gcmd.CmdRes, error) {
-	m.logger.Debug("run cmd", zap.String("cmd", cmd))
+	if err := m.cmds.start(); err != nil {
+		return nil, err
+	}
+	defer m.cmds.done()
+	observeDone := streamer.ObserveCommand(m.observer, m.endpoint.Host, []byte(cmd))
+	ctx, cancel := m.withLifetime(ctx)
+	defer cancel()
+	ctx, span := startSpan(ctx, m.tracer, "ssh.cmd", endpointAttrs(m.endpoint)...)
+	res, err := m.runCmd(ctx, cmd)
+	if err != nil && m.reconnectRetries > 0 && errors.Is(err, ErrConnectionLost) {
+		res, err = m.retryCmd(ctx, cmd, err)
+	}
+	observeDone(len(cmd), resultSize(res), err)
+	span.SetAttributes(attrBytesWritten.Int(len(cmd)), attrBytesRead.Int(resultSize(res)))
+	endSpan(span, err)
+	return res, err
+}
+
+func (m *Streamer) runCmd(ctx context.Context, cmd string) (gcmd.CmdRes, error) {
+	m.logger.Debug("run cmd", logging.String("cmd", cmd))
 	sessionTemplate, err := m.newSessionTemplate()
 	if err != nil {
+		if isConnectionLost(err) {
+			err = fmt.Errorf("%w: %w", ErrConnectionLost, err)
+		}
 		return nil, fmt.Errorf("failed to init session template: %w", err)
 	}
 
@@ -465,7 +678,7 @@ func (m *Streamer) Cmd(ctx context.Context, cmd string) (gcmd.CmdRes, error) {
 	cancel()
 	onSessionCloseErr := m.onSessionCloseCallbacks(sessionTemplate.session)
 	if onSessionCloseErr != nil {
-		m.logger.Error("onSessionCloseCallbacks error %w", zap.Error(err))
+		m.logger.Error("onSessionCloseCallbacks error %w", logging.Error(err))
 	}
 	status := 0
 	isStatusGettingOk := false
@@ -503,37 +716,106 @@ func (m *Streamer) Cmd(ctx context.Context, cmd string) (gcmd.CmdRes, error) {
 		return nil, fmt.Errorf("context timeout status=%d out=%s err=%s", res.Status(), res.Output(), res.Error())
 	}
 	if execErr != nil {
-		return nil, fmt.Errorf("session %w status=%d out=%s err=%s", err, res.Status(), res.Output(), res.Error())
+		if isConnectionLost(execErr) {
+			execErr = fmt.Errorf("%w: %w", ErrConnectionLost, execErr)
+		}
+		return nil, fmt.Errorf("session %w status=%d out=%s err=%s", execErr, res.Status(), res.Output(), res.Error())
 	}
 	return res, nil
 }
 
+// callContext runs fn and returns ctx error without waiting for fn if ctx is done earlier.
+func callContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		res T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := fn()
+		done <- result{res: res, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case res := <-done:
+		return res.res, res.err
+	}
+}
+
+// GetConfig makes client config from credentials. It returns ctx error as soon as ctx is done,
+// even if credentials provider, ssh-agent or decryption of key is still in progress.
 func (m *Streamer) GetConfig(ctx context.Context) (*ssh.ClientConfig, error) {
-	creds := m.credentials
+	creds, err := m.fetchCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.makeConfig(ctx, creds)
+}
+
+// fetchCredentials returns credentials from provider if it is set, otherwise credentials passed to NewStreamer.
+func (m *Streamer) fetchCredentials(ctx context.Context) (credentials.Credentials, error) {
+	if m.credentialsProvider == nil {
+		return m.credentials, nil
+	}
+	// provider may ignore ctx
+	provided, err := callContext(ctx, func() (credentials.Credentials, error) {
+		return m.credentialsProvider.Get(ctx, m.endpoint.Host)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("credentials provider error: %w", err)
+	}
+	return provided, nil
+}
+
+func (m *Streamer) makeConfig(ctx context.Context, creds credentials.Credentials) (*ssh.ClientConfig, error) {
 	if m.credentialsInterceptor != nil {
 		creds = m.credentialsInterceptor(creds)
 	}
-	username, err := creds.GetUsername()
+	m.authMethod = ""
+	username, err := credentials.GetUsername(ctx, creds)
 	var auths []ssh.AuthMethod
 	if err != nil {
 		return nil, err
 	}
 	passwords := creds.GetPasswords(ctx)
+	m.passwordsTried = 0
 	if len(passwords) > 0 {
 		auths = append(auths, ssh.RetryableAuthMethod(ssh.PasswordCallback(m.passwordCallbackWrapper(passwords)), len(passwords)))
 		auths = append(auths, ssh.RetryableAuthMethod(ssh.KeyboardInteractive(m.passwordKICallbackWrapper(passwords)), len(passwords)))
 	}
 
 	var signers []ssh.Signer
-	keys := creds.GetPrivateKeys()
+	keys, err := credentials.GetPrivateKeys(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	certSigners, err := credentials.GetCertSigners(creds)
+	if err != nil {
+		return nil, err
+	}
+	for _, signer := range certSigners {
+		signers = append(signers, wrapSigner(signer, m.logger))
+	}
 	for _, pk := range keys {
 		signer, err := ssh.ParsePrivateKey(pk)
 		if err != nil { // try to encode with passphrase
 			if _, ok := err.(*ssh.PassphraseMissingError); ok {
-				passphrase := creds.GetPassphrase()
+				passphrase, passErr := credentials.GetPassphrase(ctx, creds)
+				if passErr != nil {
+					return nil, passErr
+				}
 				if len(passphrase) > 0 {
-					signer, err = ssh.ParsePrivateKeyWithPassphrase(pk, []byte(passphrase))
-					if err != nil {
+					// key derivation of encrypted key may take long
+					signer, err = callContext(ctx, func() (ssh.Signer, error) {
+						return ssh.ParsePrivateKeyWithPassphrase(pk, []byte(passphrase))
+					})
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					} else if errors.Is(err, x509.IncorrectPasswordError) {
+						return nil, fmt.Errorf("%w: %w", credentials.ErrKeyPassphrase, err)
+					} else if err != nil {
 						return nil, fmt.Errorf("failed to parse private key with passphrase: %w", err)
 					}
 					err = nil
@@ -557,7 +839,14 @@ func (m *Streamer) GetConfig(ctx context.Context) (*ssh.ClientConfig, error) {
 			return nil, err
 		}
 		agentClient := agent.NewClient(conn)
+		// agent protocol has no timeouts, so connection is closed to interrupt the request
+		stop := context.AfterFunc(ctx, func() {
+			_ = conn.Close()
+		})
 		agentSigners, err := agentClient.Signers()
+		if !stop() {
+			return nil, ctx.Err()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -566,7 +855,10 @@ func (m *Streamer) GetConfig(ctx context.Context) (*ssh.ClientConfig, error) {
 		}
 	}
 	if len(signers) != 0 {
-		auths = append(auths, ssh.PublicKeys(signers...))
+		auths = append(auths, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			m.authMethod = "publickey"
+			return signers, nil
+		}))
 	}
 
 	sshConf := ssh.Config{}
@@ -584,10 +876,30 @@ func (m *Streamer) GetConfig(ctx context.Context) (*ssh.ClientConfig, error) {
 		"aes192-cbc",
 		"aes256-cbc",
 	)
+	if m.keyExchanges != nil {
+		sshConf.KeyExchanges = m.keyExchanges
+	}
+	if m.ciphers != nil {
+		sshConf.Ciphers = m.ciphers
+	}
+	if m.macs != nil {
+		sshConf.MACs = m.macs
+	}
+	hostKeyCallback := m.hostKeyCallback
+	if len(m.knownHostsFiles) > 0 {
+		knownHostsCallback, err := knownhosts.New(m.knownHostsFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("known_hosts error: %w", err)
+		}
+		hostKeyCallback = wrapKnownHostsCallback(knownHostsCallback)
+	}
+	if len(m.hostCertAuthorities) > 0 {
+		hostKeyCallback = makeHostCertCallback(m.hostCertAuthorities, hostKeyCallback)
+	}
 	conf := &ssh.ClientConfig{
 		User:            username,
 		Auth:            auths,
-		HostKeyCallback: m.hostKeyCallback,
+		HostKeyCallback: hostKeyCallback,
 		Config:          sshConf,
 		Timeout:         15 * time.Second,
 	}
@@ -595,14 +907,14 @@ func (m *Streamer) GetConfig(ctx context.Context) (*ssh.ClientConfig, error) {
 	return conf, nil
 }
 
-func wrapSigner(signer ssh.Signer, logger *zap.Logger) ssh.Signer {
+func wrapSigner(signer ssh.Signer, logger logging.Logger) ssh.Signer {
 	switch v := signer.(type) {
 	case ssh.MultiAlgorithmSigner:
-		return NewSSHMultiSignersAlgorithmSignerLogger(v, logger)
+		return &SSHMultiSignersLoggerAlgorithmSigner{s: v, log: logger}
 	case ssh.AlgorithmSigner:
-		return NewSSHSignersAlgorithmSignerLogger(v, logger)
+		return &SSHSignersLoggerAlgorithmSigner{s: v, log: logger}
 	}
-	return NewSSHSignersLogger(signer, logger)
+	return &SSHSignersLogger{s: signer, log: logger}
 }
 
 type sshClient interface {
@@ -610,26 +922,56 @@ type sshClient interface {
 	NewSession() (*ssh.Session, error)
 }
 
-func (m *Streamer) openConnect(ctx context.Context) (sshClient, error) {
-	conf, err := m.GetConfig(ctx)
+// openConnect returns connection and credentials which it is made with.
+func (m *Streamer) openConnect(ctx context.Context) (_ sshClient, _ credentials.Credentials, err error) {
+	observeDone := streamer.ObserveConnect(m.observer, m.endpoint.Host)
+	ctx, span := startSpan(ctx, m.tracer, "ssh.connect", endpointAttrs(m.endpoint)...)
+	defer func() {
+		observeDone(err)
+		if err == nil && len(m.controlFile) == 0 {
+			m.observeAuth(m.endpoint.Host)
+			span.SetAttributes(attrAuthMethod.String(m.authMethod))
+		}
+		endSpan(span, err)
+	}()
+	creds, err := m.fetchCredentials(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	conf, err := m.makeConfig(ctx, creds)
+	if err != nil {
+		return nil, nil, err
 	}
+	diag := newConnectDiagnostics()
+	conf.BannerCallback = diag.setBanner
 	var conn sshClient
 	if m.tunnel != nil {
-		conn, err = m.dialTunnel(ctx, conf)
+		conn, err = m.dialTunnel(ctx, conf, diag)
 	} else if len(m.controlFile) > 0 {
-		m.logger.Debug("dial control master", zap.String("controlFile", m.controlFile))
+		m.logger.Debug("dial control master", logging.String("controlFile", m.controlFile))
 		// TODO: add support additionalEndpoints
 		conn, err = OpenControl(m.controlFile)
+	} else if len(m.proxyCommand) > 0 {
+		conn, err = m.dialProxyCommand(ctx, conf, diag)
 	} else {
-		conn, err = DialCtx(ctx, m.endpoint, m.additionalEndpoints, conf, m.logger)
+		conn, err = dialEndpoints(ctx, m.dialer, m.endpoint, m.additionalEndpoints, conf, m.logger, diag)
+	}
+	if err != nil && m.passwordsTried > 0 && strings.Contains(err.Error(), "unable to authenticate") {
+		err = fmt.Errorf("%w: %w", credentials.NewPasswordsError(m.passwordsTried), err)
 	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// connection closed on deadline fails with unrelated error
+			err = fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+		return nil, nil, diag.fail(err)
+	}
+	m.connInfo = makeConnInfo(conn, diag)
 
-	return conn, err
+	return conn, creds, nil
 }
 
-func (m *Streamer) dialTunnel(ctx context.Context, conf *ssh.ClientConfig) (*ssh.Client, error) {
+func (m *Streamer) dialTunnel(ctx context.Context, conf *ssh.ClientConfig, diag *ConnectDiagnostics) (*ssh.Client, error) {
 	if !m.tunnel.IsConnected() {
 		err := m.tunnel.CreateConnect(ctx)
 		if err != nil {
@@ -642,18 +984,20 @@ func (m *Streamer) dialTunnel(ctx context.Context, conf *ssh.ClientConfig) (*ssh
 	endpoints := append([]Endpoint{m.endpoint}, m.additionalEndpoints...)
 	for _, endpoint := range endpoints {
 		connectedEndpoint = endpoint
-		tunConn, err = m.tunnel.StartForward(endpoint.Network, endpoint.Addr())
+		started := time.Now()
+		tunConn, err = startForward(ctx, m.tunnel, endpoint.Network, endpoint.Addr())
+		diag.addAttempt(endpoint.Addr(), started, err)
 		if err == nil {
 			break
 		}
-		m.logger.Debug("failed to open tunnel for endpoint", zap.String("address", endpoint.String()), zap.Error(err))
+		m.logger.Debug("failed to open tunnel for endpoint", logging.String("address", endpoint.String()), logging.Error(err))
 	}
 	if err != nil {
 		m.tunnel.Close()
 		return nil, fmt.Errorf("failed to open tunnel for any of given hosts: %v, last error: %w", m.endpoint, err)
 	}
-	m.logger.Debug("dial tunnel", zap.String("address", connectedEndpoint.String()))
-	res, err := DialConnCtx(ctx, tunConn, connectedEndpoint.Addr(), conf)
+	m.logger.Debug("dial tunnel", logging.String("address", connectedEndpoint.String()))
+	res, err := DialConnCtx(ctx, newVersionConn(tunConn, diag), connectedEndpoint.Addr(), conf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to host %s: %w", connectedEndpoint.String(), err)
 	}
@@ -685,7 +1029,7 @@ func (m *Streamer) onSessionClose(sess *ssh.Session) error {
 func (m *Streamer) onSessionCloseCallbacks(sess *ssh.Session) error {
 	var errs []error
 	for _, cb := range m.onChanCloseCallbacks {
-		m.logger.Debug("call callback", zap.Any("cb", cb))
+		m.logger.Debug("call callback", logging.Any("cb", cb))
 		err := cb(sess)
 		if err != nil {
 			errs = append(errs, err)
@@ -695,7 +1039,7 @@ func (m *Streamer) onSessionCloseCallbacks(sess *ssh.Session) error {
 }
 
 func (m *Streamer) newSessionTemplate() (*sshSessionTemplate, error) {
-	session, err := m.conn.NewSession()
+	session, err := m.getConn().NewSession()
 	if err != nil {
 		return nil, fmt.Errorf("session error %w", err)
 	}
@@ -738,7 +1082,7 @@ func (m *Streamer) openSession() (*sshSession, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to init session template: %w", err)
 	}
-	m.logger.Debug("request", zap.String("program", m.program), zap.String("program_data", m.programData))
+	m.logger.Debug("request", logging.String("program", m.program), logging.String("program_data", m.programData))
 	switch m.program {
 	case "shell":
 		if err := m.requestPty(sessionTemplate.session); err != nil {
@@ -757,20 +1101,25 @@ func (m *Streamer) openSession() (*sshSession, error) {
 		return nil, fmt.Errorf("unknown ssh session program %s", m.program)
 	}
 
-	sess := newSSHSession(sessionTemplate, m.logger)
+	sess := newSSHSession(sessionTemplate, m.logger, m.outputHook, streamer.NewEscapeStripper(m.escapeMode))
 	return sess, nil
 }
 
 func (m *Streamer) requestPty(session *ssh.Session) error {
-	modes := ssh.TerminalModes{
-		ssh.ECHO:          0,     // disable echoing
-		ssh.TTY_OP_ISPEED: 14400, // input speed = 14.4kbaud
-		ssh.TTY_OP_OSPEED: 14400, // output speed = 14.4kbaud
+	if !m.pty.enabled {
+		return nil
+	}
+	modes := m.pty.modes
+	if modes == nil {
+		modes = DefaultTerminalModes()
 	}
-	return session.RequestPty("xterm", m.terminalParams.h, m.terminalParams.w, modes)
+	return session.RequestPty(m.pty.term, m.terminalParams.h, m.terminalParams.w, modes)
 }
 
 func (m *Streamer) GetCredentials() credentials.Credentials {
+	if m.credentialsProvider != nil {
+		return credentials.NewProviderCredentials(m.credentialsProvider, m.endpoint.Host)
+	}
 	return m.credentials
 }
 
@@ -779,13 +1128,33 @@ func (m *Streamer) Init(ctx context.Context) error {
 		return fmt.Errorf("already inited")
 	}
 	m.inited = true
-	m.logger.Debug("open connection", zap.Stringer("endpoint", m.endpoint), zap.Stringers("additional endpoints", m.additionalEndpoints))
+	m.logger.Debug("open connection", logging.Stringer("endpoint", m.endpoint), logging.Any("additional endpoints", m.additionalEndpoints))
+	if m.lifetime != nil && m.lifetime.Err() != nil {
+		return m.lifetimeErr(m.lifetime.Err())
+	}
+	ctx, cancel := m.withLifetime(ctx)
+	defer cancel()
 
-	conn, err := m.openConnect(ctx)
+	conn, creds, err := m.openConnect(ctx)
 	if err != nil {
-		return err
+		return m.lifetimeErr(err)
+	}
+	m.setConn(conn)
+	m.startKeepalive()
+	m.watchLifetime(conn)
+	m.addTranscriptSecrets(ctx, creds)
+	if m.postLogin != nil {
+		err = m.runPostLogin(ctx, creds)
+		if err != nil {
+			// connection is useless without login, so it is not left open
+			m.stopKeepalive()
+			m.stopLifetime()
+			if prev := m.setConn(nil); prev != nil && !m.sharedConn {
+				_ = prev.Close()
+			}
+			return err
+		}
 	}
-	m.conn = conn
 
 	return nil
 }
@@ -796,6 +1165,11 @@ func (m *Streamer) InitAgentForward() error {
 	return nil
 }
 
+// StreamConn returns connection for custom protocols, see streamer.NewStreamConn.
+func (m *Streamer) StreamConn() io.ReadWriteCloser {
+	return streamer.NewStreamConn(m)
+}
+
 func (m *Streamer) WithOpenSessionCallback(fn func(*ssh.Session) error) {
 	m.onSessionOpenCallbacks = append(m.onSessionOpenCallbacks, fn)
 }
@@ -811,14 +1185,15 @@ func (m *Streamer) startForwarding(sess *ssh.Session) error {
 	}
 	keyring := agent.NewKeyring()
 
-	privKeysRaw := m.credentials.GetPrivateKeys()
+	creds := m.GetCredentials()
+	privKeysRaw := creds.GetPrivateKeys()
 	if len(privKeysRaw) == 0 {
 		return errors.New("no private keys found")
 	}
 	for _, privKeyRaw := range privKeysRaw {
 		privKey, err := ssh.ParseRawPrivateKey(privKeyRaw)
 		if _, ok := err.(*ssh.PassphraseMissingError); ok {
-			passphrase := m.credentials.GetPassphrase()
+			passphrase := creds.GetPassphrase()
 			if len(passphrase) > 0 {
 				privKey, err = ssh.ParseRawPrivateKeyWithPassphrase(privKeyRaw, []byte(passphrase))
 				if err != nil {
@@ -836,9 +1211,10 @@ func (m *Streamer) startForwarding(sess *ssh.Session) error {
 	if err := agent.RequestAgentForwarding(sess); err != nil {
 		return fmt.Errorf("error RequestAgentForwarding: %w", err)
 	}
-	sshC, ok := m.conn.(*ssh.Client)
+	conn := m.getConn()
+	sshC, ok := conn.(*ssh.Client)
 	if !ok {
-		return fmt.Errorf("unexpected connection type %T", m.conn)
+		return fmt.Errorf("unexpected connection type %T", conn)
 	}
 	if err := agent.ForwardToAgent(sshC, keyring); err != nil {
 		return fmt.Errorf("error ForwardToAgent: %w", err)
@@ -870,7 +1246,7 @@ func (m *Streamer) startSession() error {
 func (m *Streamer) passwordKICallbackWrapper(passwords []credentials.Secret) func(name, instruction string, questions []string, echos []bool) ([]string, error) {
 	passwordIndex := 0
 	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
-		m.logger.Debug("passwordCallback", zap.String("name", name), zap.String("instruction", instruction), zap.Strings("questions", questions))
+		m.logger.Debug("passwordCallback", logging.String("name", name), logging.String("instruction", instruction), logging.Strings("questions", questions))
 		if len(questions) > 1 {
 			return nil, errors.New("unexpected number of questions")
 		} else if len(questions) == 0 {
@@ -881,6 +1257,8 @@ func (m *Streamer) passwordKICallbackWrapper(passwords []credentials.Secret) fun
 		}
 		password := passwords[passwordIndex]
 		passwordIndex++
+		m.passwordsTried = max(m.passwordsTried, passwordIndex)
+		m.authMethod = "keyboard-interactive"
 		return []string{password.Value()}, nil
 	}
 }
@@ -888,12 +1266,14 @@ func (m *Streamer) passwordKICallbackWrapper(passwords []credentials.Secret) fun
 func (m *Streamer) passwordCallbackWrapper(passwords []credentials.Secret) func() (secret string, err error) {
 	passwordIndex := 0
 	return func() (secret string, err error) {
-		m.logger.Debug("passwordCallback", zap.Int("passwordIndex", passwordIndex))
+		m.logger.Debug("passwordCallback", logging.Int("passwordIndex", passwordIndex))
 		if passwordIndex >= len(passwords) { // prevent endless loop
 			return "", gerror.NewAuthException("password auth error")
 		}
 		password := passwords[passwordIndex]
 		passwordIndex++
+		m.passwordsTried = max(m.passwordsTried, passwordIndex)
+		m.authMethod = "password"
 		return password.Value(), nil
 	}
 }
@@ -937,7 +1317,7 @@ func (m *Streamer) makeSftpClient(useSudo bool) (sc *sftp.Client, stop func(), e
 	}
 
 	cmd := strings.TrimSpace(string(res.Output()))
-	m.logger.Debug("resolved sftp-server", zap.String("path", cmd))
+	m.logger.Debug("resolved sftp-server", logging.String("path", cmd))
 
 	sessionTemplate, err = m.newSessionTemplate()
 	if err != nil {
@@ -946,12 +1326,12 @@ func (m *Streamer) makeSftpClient(useSudo bool) (sc *sftp.Client, stop func(), e
 	}
 	err = sessionTemplate.session.Start("sudo " + cmd)
 	if err != nil {
-		m.logger.Warn("cannot run sudo sftp-server", zap.Error(err))
+		m.logger.Warn("cannot run sudo sftp-server", logging.Error(err))
 		return
 	}
 	sc, err = sftp.NewClientPipe(sessionTemplate.stdout, sessionTemplate.stdin)
 	if err != nil {
-		m.logger.Warn("cannot create client for sudo sftp-server", zap.Error(err))
+		m.logger.Warn("cannot create client for sudo sftp-server", logging.Error(err))
 		return
 	}
 	stop = func() {
@@ -1054,7 +1434,7 @@ func (m *Streamer) sftpDownloadFile(sc *sftp.Client, filePath string) streamer.F
 		return streamer.NewFileError(err)
 	}
 	fileMode := stat.Mode()
-	m.logger.Debug("file", zap.String("path", filePath), zap.Any("stat", stat))
+	m.logger.Debug("file", logging.String("path", filePath), logging.Any("stat", stat))
 	if fileMode.Type() == fs.ModeSocket {
 		return streamer.NewFileError(fmt.Errorf("skip socket file"))
 	}
@@ -1074,7 +1454,7 @@ func (m *Streamer) Upload(filePaths map[string]streamer.File) error {
 	if m.sftpEnabled {
 		err := m.uploadSftp(filePaths, false)
 		if err != nil && m.sftpSudoTry {
-			m.logger.Info("retry upload with sudo", zap.Error(err))
+			m.logger.Info("retry upload with sudo", logging.Error(err))
 			err := m.uploadSftp(filePaths, true)
 			return err
 		}
@@ -1121,25 +1501,31 @@ func (m *Streamer) uploadSftp(filePaths map[string]streamer.File, useSudo bool)
 
 // DialCtx ssh.Dial version with context arg
 func DialCtx(ctx context.Context, endpoint Endpoint, additionalEndpoints []Endpoint, config *ssh.ClientConfig, logger *zap.Logger) (*ssh.Client, error) {
+	return dialEndpoints(ctx, nil, endpoint, additionalEndpoints, config, zaplog.New(logger), nil)
+}
+
+func dialEndpoints(ctx context.Context, dialer streamer.Dialer, endpoint Endpoint, additionalEndpoints []Endpoint, config *ssh.ClientConfig, logger logging.Logger, diag *ConnectDiagnostics) (*ssh.Client, error) {
 	var err error
 	var conn net.Conn
 	var connectedEndpoint Endpoint
 	endpoints := append([]Endpoint{endpoint}, additionalEndpoints...)
 	for _, endpoint := range endpoints {
 		connectedEndpoint = endpoint
-		logger.Debug("tcp dial", zap.String("address", connectedEndpoint.String()))
-		conn, err = streamer.TCPDialCtx(ctx, string(endpoint.Network), endpoint.Addr())
+		logger.Debug("tcp dial", logging.String("address", connectedEndpoint.String()))
+		started := time.Now()
+		conn, err = endpoint.DialContext(ctx, dialer)
+		diag.addAttempt(endpoint.Addr(), started, err)
 		if err == nil {
 			break
 		}
 		// always continue attempts to connect in case of dial failure
-		logger.Debug("dial failed for endpoint", zap.String("endpoint", endpoint.String()), zap.Error(err))
+		logger.Debug("dial failed for endpoint", logging.String("endpoint", endpoint.String()), logging.Error(err))
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial any of given endpoints: %v, last error: %w", endpoint, err)
 	}
-	logger.Debug("tcp ssh", zap.String("address", connectedEndpoint.String()))
-	res, err := DialConnCtx(ctx, conn, connectedEndpoint.Addr(), config)
+	logger.Debug("tcp ssh", logging.String("address", connectedEndpoint.String()))
+	res, err := DialConnCtx(ctx, newVersionConn(conn, diag), connectedEndpoint.Addr(), config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to host %s: %w", connectedEndpoint.String(), err)
 	}
@@ -1168,68 +1554,68 @@ func DialConnCtx(ctx context.Context, conn net.Conn, addr string, config *ssh.Cl
 // SSHSignersLogger wraps ssh.Signer interface in order to log actions related to keys
 type SSHSignersLogger struct {
 	s   ssh.Signer
-	log *zap.Logger
+	log logging.Logger
 }
 
 type SSHSignersLoggerAlgorithmSigner struct {
 	s   ssh.AlgorithmSigner
-	log *zap.Logger
+	log logging.Logger
 }
 
 func (m SSHSignersLogger) PublicKey() ssh.PublicKey {
 	// it doesn't necessary means that we called in validateKey(), but it is better than nothing
-	m.log.Debug("check", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("check", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.PublicKey()
 }
 
 func (m SSHSignersLogger) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
-	m.log.Debug("sign", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("sign", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.Sign(rand, data)
 }
 
 func NewSSHSignersLogger(s ssh.Signer, logger *zap.Logger) *SSHSignersLogger {
-	return &SSHSignersLogger{s: s, log: logger}
+	return &SSHSignersLogger{s: s, log: zaplog.New(logger)}
 }
 
 func (m SSHSignersLoggerAlgorithmSigner) PublicKey() ssh.PublicKey {
-	m.log.Debug("check", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("check", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.PublicKey()
 }
 
 func (m SSHSignersLoggerAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
-	m.log.Debug("sign", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("sign", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.Sign(rand, data)
 }
 
 func (m SSHSignersLoggerAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
-	m.log.Debug("sign", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("sign", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.SignWithAlgorithm(rand, data, algorithm)
 }
 
 func NewSSHSignersAlgorithmSignerLogger(s ssh.AlgorithmSigner, logger *zap.Logger) *SSHSignersLoggerAlgorithmSigner {
 	return &SSHSignersLoggerAlgorithmSigner{
 		s:   s,
-		log: logger,
+		log: zaplog.New(logger),
 	}
 }
 
 type SSHMultiSignersLoggerAlgorithmSigner struct {
 	s   ssh.MultiAlgorithmSigner
-	log *zap.Logger
+	log logging.Logger
 }
 
 func (m SSHMultiSignersLoggerAlgorithmSigner) PublicKey() ssh.PublicKey {
-	m.log.Debug("check", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("check", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.PublicKey()
 }
 
 func (m SSHMultiSignersLoggerAlgorithmSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
-	m.log.Debug("sign", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("sign", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.Sign(rand, data)
 }
 
 func (m SSHMultiSignersLoggerAlgorithmSigner) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
-	m.log.Debug("sign", zap.Any("pubkey", m.s.PublicKey()))
+	m.log.Debug("sign", logging.Any("pubkey", m.s.PublicKey()))
 	return m.s.SignWithAlgorithm(rand, data, algorithm)
 }
 
@@ -1238,5 +1624,5 @@ func (m *SSHMultiSignersLoggerAlgorithmSigner) Algorithms() []string {
 }
 
 func NewSSHMultiSignersAlgorithmSignerLogger(s ssh.MultiAlgorithmSigner, logger *zap.Logger) *SSHMultiSignersLoggerAlgorithmSigner {
-	return &SSHMultiSignersLoggerAlgorithmSigner{s: s, log: logger}
+	return &SSHMultiSignersLoggerAlgorithmSigner{s: s, log: zaplog.New(logger)}
 }
@@ -10,8 +10,8 @@ import (
 	"strings"
 
 	"github.com/annetutil/gnetcli/internal/tssh"
+	"github.com/annetutil/gnetcli/pkg/logging"
 	"github.com/mitchellh/go-homedir"
-	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -24,14 +24,14 @@ func resolveHomeDir(path string) string {
 	return path
 }
 
-func dialControlMasterConf(_ context.Context, controlFile string, endpoint Endpoint, conf *ssh.ClientConfig, logger *zap.Logger) (*ControlConn, error) {
+func dialControlMasterConf(_ context.Context, controlFile string, endpoint Endpoint, conf *ssh.ClientConfig, logger logging.Logger) (*ControlConn, error) {
 	params := tssh.NewSshParam(endpoint.Host, strconv.Itoa(endpoint.Port), conf.User, nil)
 	expandedPath, err := tssh.ExpandTokens(controlFile, params, "%CdhijkLlnpru")
 	if err != nil {
 		return nil, err
 	}
 	resolvedPath := resolveHomeDir(expandedPath)
-	logger.Debug("open control file", zap.String("path", resolvedPath))
+	logger.Debug("open control file", logging.String("path", resolvedPath))
 	c, err := OpenControl(resolvedPath)
 	if err != nil {
 		return nil, err
@@ -1,10 +1,22 @@
 package ssh
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"net"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/gerror"
 	"github.com/annetutil/gnetcli/pkg/streamer"
 )
 
@@ -36,6 +48,31 @@ func TestEndpoint_Addr(t *testing.T) {
 			endpoint: Endpoint{Host: "2001:db8::1", Port: 22},
 			expected: "[2001:db8::1]:22",
 		},
+		{
+			name:     "bracketed IPv6",
+			endpoint: Endpoint{Host: "[2001:db8::1]", Port: 22},
+			expected: "[2001:db8::1]:22",
+		},
+		{
+			name:     "zero port",
+			endpoint: Endpoint{Host: "localhost"},
+			expected: "localhost:22",
+		},
+		{
+			name:     "IPv6 zone",
+			endpoint: Endpoint{Host: "fe80::1%eth0", Port: 22},
+			expected: "[fe80::1%eth0]:22",
+		},
+		{
+			name:     "bracketed IPv6 zone",
+			endpoint: Endpoint{Host: "[fe80::1%eth0]", Port: 22},
+			expected: "[fe80::1%eth0]:22",
+		},
+		{
+			name:     "escaped IPv6 zone",
+			endpoint: Endpoint{Host: "[fe80::1%25eth0]", Port: 2222},
+			expected: "[fe80::1%eth0]:2222",
+		},
 	}
 
 	for _, tt := range tests {
@@ -44,3 +81,240 @@ func TestEndpoint_Addr(t *testing.T) {
 		})
 	}
 }
+
+func TestEndpoint_String(t *testing.T) {
+	assert.Equal(t, "{host: example.com, port: 22, network: tcp}", NewEndpoint("example.com", 22, TCP).String())
+	assert.Equal(t, "{host: [2001:db8::1], port: 22, network: tcp}", NewEndpoint("2001:db8::1", 22, TCP).String())
+	assert.Equal(t, "{host: [fe80::1%eth0], port: 22, network: tcp}", NewEndpoint("[fe80::1%25eth0]", 22, TCP).String())
+}
+
+type recordDialer struct {
+	network string
+	addr    string
+}
+
+func (m *recordDialer) DialContext(_ context.Context, network, addr string) (net.Conn, error) {
+	m.network = network
+	m.addr = addr
+	return nil, errors.New("dial is not allowed")
+}
+
+func TestEndpoint_DialContext(t *testing.T) {
+	dialer := &recordDialer{}
+	endpoint := Endpoint{Host: "::1"}
+	_, err := endpoint.DialContext(context.Background(), dialer)
+	assert.Error(t, err)
+	assert.Equal(t, "tcp", dialer.network)
+	assert.Equal(t, "[::1]:22", dialer.addr)
+}
+
+func TestTunnelForwardPolicy(t *testing.T) {
+	policyErr := errors.New("not allowed")
+	tun := NewSSHTunnel("localhost", credentials.NewSimpleCredentials(), SSHTunnelWithForwardPolicy(func(network Network, addr string) error {
+		if addr == "10.0.0.1:22" {
+			return nil
+		}
+		return policyErr
+	}))
+	_, err := tun.StartForward(TCP, "192.168.0.1:22")
+	assert.ErrorIs(t, err, ErrForwardDenied)
+	assert.ErrorIs(t, err, policyErr)
+
+	_, err = tun.StartForward(TCP, "10.0.0.1:22")
+	assert.NotErrorIs(t, err, ErrForwardDenied)
+}
+
+func TestTunnelAgentForwardingNoSocket(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	tun := NewSSHTunnel("localhost", credentials.NewSimpleCredentials(), SSHTunnelWithAgentForwarding())
+	err := tun.CreateConnect(context.Background())
+	assert.ErrorIs(t, err, ErrNoAgentSocket)
+}
+
+type blockingCredentials struct {
+	credentials.Credentials
+}
+
+func (m blockingCredentials) GetUsernameContext(ctx context.Context) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (m blockingCredentials) GetPrivateKeysContext(ctx context.Context) ([][]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m blockingCredentials) GetPassphraseContext(ctx context.Context) (credentials.Secret, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestGetConfigContextCredentials(t *testing.T) {
+	creds := blockingCredentials{Credentials: credentials.NewSimpleCredentials()}
+	conn := NewStreamer("localhost", creds)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := conn.GetConfig(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetConfigKeyPassphrase(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	require.NoError(t, err)
+	key := pem.EncodeToMemory(block)
+
+	conn := NewStreamer("localhost", credentials.NewSimpleCredentials(credentials.WithUsername("user"),
+		credentials.WithPrivateKey(key), credentials.WithPassphrase("secret")))
+	_, err = conn.GetConfig(context.Background())
+	require.NoError(t, err)
+
+	conn = NewStreamer("localhost", credentials.NewSimpleCredentials(credentials.WithUsername("user"),
+		credentials.WithPrivateKey(key), credentials.WithPassphrase("wrong")))
+	_, err = conn.GetConfig(context.Background())
+	require.ErrorIs(t, err, credentials.ErrKeyPassphrase)
+}
+
+// runPasswordServer accepts connections authenticated with password.
+func runPasswordServer(t *testing.T, listener net.Listener, password string) {
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if string(pass) == password {
+				return nil, nil
+			}
+			return nil, errors.New("wrong password")
+		},
+	}
+	config.AddHostKey(makeSigner(t))
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			_, chans, reqs, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for newChannel := range chans {
+				_ = newChannel.Reject(ssh.Prohibited, "not supported")
+			}
+		}()
+	}
+}
+
+func TestFallbackPasswords(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runPasswordServer(t, listener, "new")
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	conn := NewStreamer("127.0.0.1", credentials.NewFromPasswords([]string{"old", "new"}, credentials.WithUsername("user")), WithPort(port))
+	require.NoError(t, conn.Init(context.Background()))
+	conn.Close()
+
+	conn = NewStreamer("127.0.0.1", credentials.NewFromPasswords([]string{"old", "older"}, credentials.WithUsername("user")), WithPort(port))
+	err = conn.Init(context.Background())
+	var passErr *credentials.PasswordsError
+	require.ErrorAs(t, err, &passErr)
+	require.Equal(t, []int{1, 2}, passErr.Tried)
+	require.ErrorIs(t, err, &gerror.AuthException{})
+	require.NotContains(t, passErr.Error(), "older")
+}
+
+func TestGetConfigCredentialsProvider(t *testing.T) {
+	var gotHost string
+	provider := credentials.ProviderFunc(func(ctx context.Context, host string) (credentials.Credentials, error) {
+		gotHost = host
+		return credentials.NewSimpleCredentials(credentials.WithUsername("vault")), nil
+	})
+	conn := NewStreamer("device1", nil, WithCredentialsProvider(provider))
+	conf, err := conn.GetConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "vault", conf.User)
+	require.Equal(t, "device1", gotHost)
+	// fetched credentials are not kept
+	require.Nil(t, conn.credentials)
+	gotHost = ""
+	username, err := conn.GetCredentials().GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "vault", username)
+	require.Equal(t, "device1", gotHost)
+
+	providerErr := errors.New("sealed")
+	conn = NewStreamer("device1", nil, WithCredentialsProvider(credentials.ProviderFunc(func(ctx context.Context, host string) (credentials.Credentials, error) {
+		return nil, providerErr
+	})))
+	_, err = conn.GetConfig(context.Background())
+	require.ErrorIs(t, err, providerErr)
+}
+
+func TestGetConfigDeadline(t *testing.T) {
+	unblock := make(chan struct{})
+	defer close(unblock)
+	// provider which ignores ctx
+	provider := credentials.ProviderFunc(func(ctx context.Context, host string) (credentials.Credentials, error) {
+		<-unblock
+		return credentials.NewSimpleCredentials(), nil
+	})
+	// agent which never answers
+	agentSocket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", agentSocket)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-unblock
+				_ = conn.Close()
+			}()
+		}
+	}()
+	agentCreds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithSSHAgentSocket(agentSocket))
+
+	for name, conn := range map[string]*Streamer{
+		"provider": NewStreamer("localhost", nil, WithCredentialsProvider(provider)),
+		"agent":    NewStreamer("localhost", agentCreds),
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		started := time.Now()
+		_, err := conn.GetConfig(ctx)
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded, name)
+		require.Less(t, time.Since(started), time.Second, name)
+	}
+}
+
+// redirectDialer connects to fixed address and records requested ones, like proxy does.
+type redirectDialer struct {
+	target    string
+	requested []string
+}
+
+func (m *redirectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	m.requested = append(m.requested, addr)
+	var d net.Dialer
+	return d.DialContext(ctx, network, m.target)
+}
+
+func TestWithDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runPasswordServer(t, listener, "secret")
+
+	dialer := &redirectDialer{target: listener.Addr().String()}
+	conn := NewStreamer("device.invalid", credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret")),
+		WithDialer(dialer))
+	require.NoError(t, conn.Init(context.Background()))
+	conn.Close()
+	require.Equal(t, []string{"device.invalid:22"}, dialer.requested)
+}
@@ -11,30 +11,67 @@ import (
 	"sync"
 	"syscall"
 
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/logging/zaplog"
+	"github.com/annetutil/gnetcli/pkg/streamer"
 )
 
+var ErrForwardDenied = errors.New("forward denied")
+var ErrTunnelClosing = errors.New("tunnel is closing")
+var ErrNoAgentSocket = errors.New("ssh agent socket is not set, check SSH_AUTH_SOCK")
+
 type Tunnel interface {
 	Close()
 	IsConnected() bool
 	CreateConnect(context.Context) error
 	StartForward(network Network, addr string) (net.Conn, error)
+	ActiveForwards() int
 }
 
 type SSHTunnel struct {
-	Server       Endpoint
-	Config       *ssh.ClientConfig
-	svrConn      *ssh.Client
-	stdioForward *ControlConn
-	isOpen       bool
-	credentials  credentials.Credentials
-	logger       *zap.Logger
-	mu           sync.Mutex
-	controlFile  string
+	Server        Endpoint
+	Config        *ssh.ClientConfig
+	svrConn       *ssh.Client
+	stdioForward  *ControlConn
+	isOpen        bool
+	credentials   credentials.Credentials
+	logger        logging.Logger
+	mu            sync.Mutex
+	controlFile   string
+	jump          Tunnel
+	streamerOpts  []StreamerOption
+	forwardPolicy func(network Network, addr string) error
+	agentForward  bool
+	agentSession  *ssh.Session
+	agentConn     net.Conn
+	dialer        streamer.Dialer
+	forwardsMu    sync.Mutex
+	forwards      map[*ForwardConn]struct{} // active forwards, see CloseContext
+	forwardsWg    sync.WaitGroup
+	closing       bool
+	observer      streamer.Observer
+	tracer        oteltrace.Tracer
+}
+
+// TunnelHopError describes failure on particular hop of tunnel chain.
+type TunnelHopError struct {
+	Endpoint Endpoint
+	Err      error
+}
+
+func (e *TunnelHopError) Error() string {
+	return fmt.Sprintf("hop %s: %s", e.Endpoint.String(), e.Err)
+}
+
+func (e *TunnelHopError) Unwrap() error {
+	return e.Err
 }
 
 func NewSSHTunnel(host string, credentials credentials.Credentials, opts ...SSHTunnelOption) *SSHTunnel {
@@ -44,7 +81,7 @@ func NewSSHTunnel(host string, credentials credentials.Credentials, opts ...SSHT
 		svrConn:     nil,
 		isOpen:      false,
 		credentials: credentials,
-		logger:      zap.NewNop(),
+		logger:      logging.Nop(),
 		mu:          sync.Mutex{},
 	}
 
@@ -54,9 +91,39 @@ func NewSSHTunnel(host string, credentials credentials.Credentials, opts ...SSHT
 	return h
 }
 
+// NewChainedTunnel makes tunnel through several jump hosts, endpoints[0] is dialed first
+// and every next hop is reached through the previous one. creds are matched with endpoints by index.
+// Options are applied to every hop. Close on returned tunnel closes hops in reverse order.
+func NewChainedTunnel(endpoints []Endpoint, creds []credentials.Credentials, opts ...SSHTunnelOption) (Tunnel, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("empty tunnel chain")
+	}
+	if len(endpoints) != len(creds) {
+		return nil, fmt.Errorf("got %d endpoints and %d credentials", len(endpoints), len(creds))
+	}
+	var prev Tunnel
+	for i, endpoint := range endpoints {
+		hopOpts := append([]SSHTunnelOption{}, opts...)
+		if prev != nil {
+			hopOpts = append(hopOpts, SSHTunnelWithJump(prev))
+		}
+		tun := NewSSHTunnel(endpoint.Host, creds[i], hopOpts...)
+		tun.Server = endpoint
+		prev = tun
+	}
+	return prev, nil
+}
+
 type SSHTunnelOption func(m *SSHTunnel)
 
 func SSHTunnelWithLogger(log *zap.Logger) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.logger = zaplog.New(log)
+	}
+}
+
+// SSHTunnelWithLogging sets logger of any logging library, see logging and zaplog packages for adapters.
+func SSHTunnelWithLogging(log logging.Logger) SSHTunnelOption {
 	return func(h *SSHTunnel) {
 		h.logger = log
 	}
@@ -68,6 +135,55 @@ func SSHTunnelWithControlFIle(path string) SSHTunnelOption {
 	}
 }
 
+// SSHTunnelWithJump makes tunnel reach its server through jump tunnel (like OpenSSH ProxyJump).
+// Jump tunnel is connected on demand and closed after own connection on Close.
+func SSHTunnelWithJump(jump Tunnel) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.jump = jump
+	}
+}
+
+// SSHTunnelWithStreamerOpts sets options for Streamer which is used to build connection config,
+// so host key checks and other Streamer features apply to the hop too.
+func SSHTunnelWithStreamerOpts(opts ...StreamerOption) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.streamerOpts = append(h.streamerOpts, opts...)
+	}
+}
+
+// SSHTunnelWithForwardPolicy sets function which is called before every forward.
+// Returned error rejects the forward with ErrForwardDenied.
+func SSHTunnelWithForwardPolicy(policy func(network Network, addr string) error) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.forwardPolicy = policy
+	}
+}
+
+// SSHTunnelWithAgentForwarding forwards local ssh agent to tunnel server,
+// so the next hop can authenticate with keys held by local agent.
+// Agent socket is taken from credentials or SSH_AUTH_SOCK, CreateConnect fails with ErrNoAgentSocket if it is not set.
+func SSHTunnelWithAgentForwarding() SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.agentForward = true
+	}
+}
+
+// SSHTunnelWithDialer sets dialer for connection to tunnel server, e.g. SOCKS5 dialer from golang.org/x/net/proxy.
+// Forwarded connections go through tunnel server, so they are routed through the dialer too.
+func SSHTunnelWithDialer(dialer streamer.Dialer) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.dialer = dialer
+	}
+}
+
+// SSHTunnelWithLocalAddr binds connection to tunnel server to local address. It replaces dialer set by SSHTunnelWithDialer.
+// Forwarded connections originate on tunnel server, so their source address is not affected.
+func SSHTunnelWithLocalAddr(addr net.Addr) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.dialer = &net.Dialer{LocalAddr: addr}
+	}
+}
+
 func SSHTunnelWithNetwork(network Network) SSHTunnelOption {
 	return func(h *SSHTunnel) {
 		h.Server.Network = network
@@ -80,16 +196,24 @@ func SSHTunnelWitPort(port int) SSHTunnelOption {
 	}
 }
 
-func (m *SSHTunnel) CreateConnect(ctx context.Context) error {
+func (m *SSHTunnel) CreateConnect(ctx context.Context) (err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	observeDone := streamer.ObserveConnect(m.observer, m.Server.Host)
+	ctx, span := startSpan(ctx, m.tracer, "ssh.tunnel.connect", endpointAttrs(m.Server)...)
+	defer func() {
+		observeDone(err)
+		endSpan(span, err)
+	}()
 	strOpts := []StreamerOption{
-		WithLogger(m.logger),
+		WithLogging(m.logger),
 	}
 	if len(m.controlFile) > 0 {
 		strOpts = append(strOpts, WithSSHControlFIle(m.controlFile))
 	}
+	strOpts = append(strOpts, m.streamerOpts...)
 	connector := NewStreamer(m.Server.Host, m.credentials, strOpts...)
+	connector.observer = m.observer
 	conf, err := connector.GetConfig(ctx)
 	if err != nil {
 		m.logger.Error(err.Error())
@@ -97,6 +221,16 @@ func (m *SSHTunnel) CreateConnect(ctx context.Context) error {
 	}
 
 	m.Config = conf
+	agentSocket := ""
+	if m.agentForward {
+		agentSocket = m.credentials.GetAgentSocket()
+		if len(agentSocket) == 0 {
+			agentSocket = credentials.GetDefaultAgentSocket()
+		}
+		if len(agentSocket) == 0 {
+			return ErrNoAgentSocket
+		}
+	}
 	var conn *ssh.Client
 
 	if len(m.controlFile) != 0 {
@@ -106,23 +240,97 @@ func (m *SSHTunnel) CreateConnect(ctx context.Context) error {
 		}
 		m.stdioForward = mConn
 		conn = nil
+	} else if m.jump != nil {
+		conn, err = m.dialJump(ctx)
 	} else {
-		conn, err = DialCtx(ctx, m.Server, nil, m.Config, m.logger)
+		conn, err = dialEndpoints(ctx, m.dialer, m.Server, nil, m.Config, m.logger, nil)
 	}
 	if err != nil {
-		m.logger.Debug("unable to connect to tunnel", zap.Error(err))
+		m.logger.Debug("unable to connect to tunnel", logging.Error(err))
 		if !errors.Is(err, context.Canceled) {
 			m.logger.Error(err.Error())
 		}
 		return err
 	}
-	m.logger.Debug("connected to tunnel", zap.String("server", m.Server.String()))
+	m.logger.Debug("connected to tunnel", logging.String("server", m.Server.String()))
+	if conn != nil {
+		connector.observeAuth(m.Server.Host)
+		span.SetAttributes(attrAuthMethod.String(connector.authMethod))
+	}
+	if m.agentForward && conn != nil {
+		err = m.startAgentForwarding(ctx, conn, agentSocket)
+		if err != nil {
+			_ = conn.Close()
+			return fmt.Errorf("agent forwarding error: %w", err)
+		}
+	}
 	m.svrConn = conn
 	m.isOpen = true
+	m.forwardsMu.Lock()
+	m.closing = false
+	m.forwardsMu.Unlock()
 	return nil
 }
 
+func (m *SSHTunnel) startAgentForwarding(ctx context.Context, conn *ssh.Client, agentSocket string) error {
+	var d net.Dialer
+	agentConn, err := d.DialContext(ctx, "unix", agentSocket)
+	if err != nil {
+		return err
+	}
+	err = agent.ForwardToAgent(conn, agent.NewClient(agentConn))
+	if err != nil {
+		_ = agentConn.Close()
+		return err
+	}
+	// forwarding is requested per session, so keep it open while tunnel is alive
+	session, err := conn.NewSession()
+	if err != nil {
+		_ = agentConn.Close()
+		return err
+	}
+	err = agent.RequestAgentForwarding(session)
+	if err != nil {
+		_ = session.Close()
+		_ = agentConn.Close()
+		return err
+	}
+	m.agentConn = agentConn
+	m.agentSession = session
+	return nil
+}
+
+func (m *SSHTunnel) dialJump(ctx context.Context) (*ssh.Client, error) {
+	if !m.jump.IsConnected() {
+		err := m.jump.CreateConnect(ctx)
+		if err != nil {
+			var hopErr *TunnelHopError
+			if jump, ok := m.jump.(*SSHTunnel); ok && !errors.As(err, &hopErr) {
+				return nil, &TunnelHopError{Endpoint: jump.Server, Err: err}
+			}
+			return nil, err
+		}
+	}
+	jumpConn, err := startForward(ctx, m.jump, m.Server.Network, m.Server.Addr())
+	if err != nil {
+		return nil, &TunnelHopError{Endpoint: m.Server, Err: fmt.Errorf("forward error: %w", err)}
+	}
+	conn, err := DialConnCtx(ctx, jumpConn, m.Server.Addr(), m.Config)
+	if err != nil {
+		_ = jumpConn.Close()
+		return nil, &TunnelHopError{Endpoint: m.Server, Err: err}
+	}
+	return conn, nil
+}
+
+// StartForward opens connection to addr through tunnel. Returned conn is *ForwardConn
+// unless tunnel uses control file.
 func (m *SSHTunnel) StartForward(network Network, remoteAddr string) (net.Conn, error) {
+	if m.forwardPolicy != nil {
+		if err := m.forwardPolicy(network, remoteAddr); err != nil {
+			return nil, fmt.Errorf("%w to %s: %w", ErrForwardDenied, remoteAddr, err)
+		}
+	}
 	if m.stdioForward != nil {
 		host, port, err := net.SplitHostPort(remoteAddr)
 		if err != nil {
@@ -141,20 +349,33 @@ func (m *SSHTunnel) StartForward(network Network, remoteAddr string) (net.Conn,
 	if !m.isOpen {
 		return nil, errors.New("connection is closed")
 	}
+	if err := m.reserveForward(); err != nil {
+		return nil, err
+	}
 	lconn, rconn, err := m.makeSocketFromSocketPair()
 	if err != nil {
+		m.forwardsWg.Done()
 		return nil, err
 	}
 	remoteConn, err := m.svrConn.Dial(string(network), remoteAddr)
 	if err != nil {
+		_ = lconn.Close()
+		_ = rconn.Close()
+		m.forwardsWg.Done()
 		return nil, err
 	}
 
-	m.logger.Debug("start forward", zap.String("to", remoteAddr), zap.String("from", m.svrConn.RemoteAddr().String()))
+	m.logger.Debug("start forward", logging.String("to", remoteAddr), logging.String("from", m.svrConn.RemoteAddr().String()))
 
+	fwd := &ForwardConn{
+		Conn:       lconn,
+		rconn:      rconn,
+		remoteConn: remoteConn,
+		done:       make(chan struct{}),
+	}
 	copyConn := func(writer, reader net.Conn) error {
 		_, err := io.Copy(writer, reader)
-		m.logger.Debug("forward done", zap.Error(err))
+		m.logger.Debug("forward done", logging.Error(err))
 		return err
 	}
 	wg, _ := errgroup.WithContext(context.Background())
@@ -169,29 +390,45 @@ func (m *SSHTunnel) StartForward(network Network, remoteAddr string) (net.Conn,
 		return err
 	})
 
+	m.trackForward(fwd)
 	go func() {
 		err := wg.Wait()
-		m.logger.Debug("tunnel done", zap.String("remote", remoteAddr), zap.Error(err))
+		m.untrackForward(fwd)
+		close(fwd.done)
+		m.logger.Debug("tunnel done", logging.String("remote", remoteAddr), logging.Error(err))
 	}()
 
-	// There is no easy way to make key string from unix conn, so we can't track forwarded cons
-	return lconn, nil
+	return fwd, nil
 }
 
 func (m *SSHTunnel) IsConnected() bool {
 	return m.isOpen
 }
 
+// Close closes tunnel along with active forwards, use CloseContext to let forwards finish.
 func (m *SSHTunnel) Close() {
 	if !m.isOpen {
 		err := errors.New("connection is closed")
 		m.logger.Error(err.Error())
 		return
 	}
+	m.closeForwards()
+	m.close()
+	if m.jump != nil && m.jump.IsConnected() {
+		m.jump.Close()
+	}
+}
 
+func (m *SSHTunnel) close() {
 	m.isOpen = false
 
 	m.logger.Debug("closing the serverConn")
+	if m.agentSession != nil {
+		_ = m.agentSession.Close()
+		_ = m.agentConn.Close()
+		m.agentSession = nil
+		m.agentConn = nil
+	}
 	if m.svrConn != nil {
 		err := m.svrConn.Close()
 		if err != nil {
@@ -0,0 +1,325 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// runForwardServer accepts single connection and serves direct-tcpip channels,
+// done is closed when client connection is gone.
+func runForwardServer(t *testing.T, listener net.Listener, done chan struct{}) {
+	defer close(done)
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	go func() {
+		for newChannel := range chans {
+			if newChannel.ChannelType() != "direct-tcpip" {
+				_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported")
+				continue
+			}
+			var payload struct {
+				Host     string
+				Port     uint32
+				OrigHost string
+				OrigPort uint32
+			}
+			if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				_ = target.Close()
+				continue
+			}
+			go ssh.DiscardRequests(requests)
+			go func() {
+				_, _ = io.Copy(channel, target)
+				_ = channel.Close()
+			}()
+			go func() {
+				_, _ = io.Copy(target, channel)
+				_ = target.Close()
+			}()
+		}
+	}()
+	_ = sconn.Wait()
+}
+
+func listenLocal(t *testing.T) (net.Listener, Endpoint) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	return listener, NewEndpoint("127.0.0.1", listener.Addr().(*net.TCPAddr).Port, TCP)
+}
+
+func TestChainedTunnel(t *testing.T) {
+	echo, echoEndpoint := listenLocal(t)
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(conn, conn)
+		_ = conn.Close()
+	}()
+	first, firstEndpoint := listenLocal(t)
+	firstDone := make(chan struct{})
+	go runForwardServer(t, first, firstDone)
+	second, secondEndpoint := listenLocal(t)
+	secondDone := make(chan struct{})
+	go runForwardServer(t, second, secondDone)
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	tun, err := NewChainedTunnel([]Endpoint{firstEndpoint, secondEndpoint}, []credentials.Credentials{creds, creds})
+	require.NoError(t, err)
+	require.NoError(t, tun.CreateConnect(context.Background()))
+
+	conn, err := tun.StartForward(TCP, echoEndpoint.Addr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+	_ = conn.Close()
+
+	tun.Close()
+	for _, done := range []chan struct{}{firstDone, secondDone} {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("hop connection is not closed")
+		}
+	}
+}
+
+func TestChainedTunnelHopError(t *testing.T) {
+	dead, deadEndpoint := listenLocal(t)
+	_ = dead.Close()
+	first, firstEndpoint := listenLocal(t)
+	go runForwardServer(t, first, make(chan struct{}))
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	for _, tc := range []struct {
+		name      string
+		endpoints []Endpoint
+	}{
+		{name: "first hop", endpoints: []Endpoint{deadEndpoint, firstEndpoint}},
+		{name: "last hop", endpoints: []Endpoint{firstEndpoint, deadEndpoint}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tun, err := NewChainedTunnel(tc.endpoints, []credentials.Credentials{creds, creds})
+			require.NoError(t, err)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = tun.CreateConnect(ctx)
+			var hopErr *TunnelHopError
+			require.ErrorAs(t, err, &hopErr)
+			require.Equal(t, deadEndpoint, hopErr.Endpoint)
+			require.Contains(t, err.Error(), deadEndpoint.String())
+			tun.Close()
+		})
+	}
+}
+
+func TestChainedTunnelMismatch(t *testing.T) {
+	_, err := NewChainedTunnel([]Endpoint{NewEndpoint("localhost", 22, TCP)}, nil)
+	require.Error(t, err)
+}
+
+func TestTunnelCloseContext(t *testing.T) {
+	echo, echoEndpoint := listenLocal(t)
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	server, serverEndpoint := listenLocal(t)
+	serverDone := make(chan struct{})
+	go runForwardServer(t, server, serverDone)
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	tun := NewSSHTunnel(serverEndpoint.Host, creds)
+	tun.Server = serverEndpoint
+	require.NoError(t, tun.CreateConnect(context.Background()))
+
+	finished, err := tun.StartForward(TCP, echoEndpoint.Addr())
+	require.NoError(t, err)
+	_ = finished.Close()
+	stuck, err := tun.StartForward(TCP, echoEndpoint.Addr())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = tun.CloseContext(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, tun.IsConnected())
+
+	_ = stuck.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = stuck.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	select {
+	case <-serverDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed")
+	}
+}
+
+func TestTunnelReserveForward(t *testing.T) {
+	server, serverEndpoint := listenLocal(t)
+	go runForwardServer(t, server, make(chan struct{}))
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	tun := NewSSHTunnel(serverEndpoint.Host, creds)
+	tun.Server = serverEndpoint
+	require.NoError(t, tun.CreateConnect(context.Background()))
+
+	// forward which passed closing check is waited by CloseContext
+	require.NoError(t, tun.reserveForward())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() {
+		closed <- tun.CloseContext(ctx)
+	}()
+	require.Eventually(t, func() bool {
+		return errors.Is(tun.reserveForward(), ErrTunnelClosing)
+	}, time.Second, 10*time.Millisecond)
+	tun.forwardsWg.Done()
+	require.NoError(t, <-closed)
+}
+
+func TestForwardConnClose(t *testing.T) {
+	echo, echoEndpoint := listenLocal(t)
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	server, serverEndpoint := listenLocal(t)
+	go runForwardServer(t, server, make(chan struct{}))
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	tun := NewSSHTunnel(serverEndpoint.Host, creds)
+	tun.Server = serverEndpoint
+	require.NoError(t, tun.CreateConnect(context.Background()))
+	defer tun.Close()
+
+	var conns []*ForwardConn
+	for i := 0; i < 3; i++ {
+		conn, err := tun.StartForward(TCP, echoEndpoint.Addr())
+		require.NoError(t, err)
+		conns = append(conns, conn.(*ForwardConn))
+	}
+	require.Equal(t, 3, tun.ActiveForwards())
+
+	require.NoError(t, conns[0].Close())
+	require.Equal(t, 2, tun.ActiveForwards())
+	select {
+	case <-conns[0].Done():
+	default:
+		t.Fatal("forward is not done")
+	}
+
+	_, err := conns[1].Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conns[1], buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+
+	for _, conn := range conns[1:] {
+		require.NoError(t, conn.Close())
+	}
+	require.Equal(t, 0, tun.ActiveForwards())
+}
+
+func listenLocalIPv6(t *testing.T) (net.Listener, Endpoint) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	return listener, NewEndpoint("::1", listener.Addr().(*net.TCPAddr).Port, TCP)
+}
+
+func TestTunnelIPv6(t *testing.T) {
+	echo, echoEndpoint := listenLocalIPv6(t)
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		_, _ = io.Copy(conn, conn)
+		_ = conn.Close()
+	}()
+	server, serverEndpoint := listenLocalIPv6(t)
+	go runForwardServer(t, server, make(chan struct{}))
+
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"))
+	zone := ""
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			zone = "%25" + iface.Name
+			break
+		}
+	}
+	// bracketed host with zone is dialed directly
+	tun := NewSSHTunnel("[::1"+zone+"]", creds)
+	tun.Server.Port = serverEndpoint.Port
+	require.NoError(t, tun.CreateConnect(context.Background()))
+	defer tun.Close()
+
+	echoEndpoint.Host = "[::1]"
+	conn, err := tun.StartForward(TCP, echoEndpoint.Addr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(buf))
+}
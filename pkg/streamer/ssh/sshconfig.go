@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// WithSSHConfig resolves host alias through OpenSSH client config file. Empty path means ~/.ssh/config.
+// HostName, Port and UserKnownHostsFile of alias are applied to the streamer, missing known hosts files are skipped.
+// ProxyJump hosts are resolved through the same config and added as WithJumpHost, so the tunnel is closed by Close.
+// ProxyCommand is used if there is no ProxyJump. Credentials of the streamer are not changed, use credentials.FromSSHConfig for them.
+func WithSSHConfig(path, alias string) (StreamerOption, error) {
+	config, err := credentials.LoadSSHConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := resolveSSHConfigEndpoint(config, alias, "")
+	if err != nil {
+		return nil, err
+	}
+	knownHosts, err := config.Get(alias, "UserKnownHostsFile")
+	if err != nil {
+		return nil, err
+	}
+	var knownHostsFiles []string
+	for _, file := range strings.Fields(knownHosts) {
+		if file == "none" {
+			continue
+		}
+		expanded, err := homedir.Expand(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand path of known hosts %s: %w", file, err)
+		}
+		// OpenSSH ignores missing files, e.g. default ~/.ssh/known_hosts2
+		if _, err := os.Stat(expanded); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		knownHostsFiles = append(knownHostsFiles, expanded)
+	}
+	proxyJump, err := config.Get(alias, "ProxyJump")
+	if err != nil {
+		return nil, err
+	}
+	var jumpHosts []jumpHost
+	if len(proxyJump) > 0 && proxyJump != "none" {
+		for _, jump := range strings.Split(proxyJump, ",") {
+			jumpEndpoint, jumpCred, err := resolveSSHConfigJump(config, strings.TrimSpace(jump))
+			if err != nil {
+				return nil, fmt.Errorf("proxy jump %s: %w", jump, err)
+			}
+			jumpHosts = append(jumpHosts, jumpHost{endpoint: jumpEndpoint, credentials: jumpCred})
+		}
+	}
+	proxyCommand := ""
+	if len(jumpHosts) == 0 {
+		proxyCommand, err = config.Get(alias, "ProxyCommand")
+		if err != nil {
+			return nil, err
+		}
+		if proxyCommand == "none" {
+			proxyCommand = ""
+		}
+		proxyCommand = strings.ReplaceAll(proxyCommand, "%n", alias)
+	}
+	return func(h *Streamer) {
+		endpoint.Network = h.endpoint.Network
+		h.endpoint = endpoint
+		h.knownHostsFiles = append(h.knownHostsFiles, knownHostsFiles...)
+		h.jumpHosts = append(h.jumpHosts, jumpHosts...)
+		if len(proxyCommand) > 0 {
+			h.proxyCommand = proxyCommand
+		}
+	}, nil
+}
+
+// resolveSSHConfigEndpoint returns endpoint of alias, port overrides the Port keyword.
+func resolveSSHConfigEndpoint(config *credentials.SSHConfig, alias, port string) (Endpoint, error) {
+	host, err := config.Get(alias, "HostName")
+	if err != nil {
+		return Endpoint{}, err
+	}
+	if len(host) == 0 {
+		host = alias
+	}
+	host = strings.ReplaceAll(host, "%h", alias)
+	if len(port) == 0 {
+		port, err = config.Get(alias, "Port")
+		if err != nil {
+			return Endpoint{}, err
+		}
+	}
+	portVal := defaultPort
+	if len(port) > 0 {
+		portVal, err = strconv.Atoi(port)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("invalid port %q of %s", port, alias)
+		}
+	}
+	return NewEndpoint(host, portVal, TCP), nil
+}
+
+// resolveSSHConfigJump parses ProxyJump entry [user@]host[:port].
+func resolveSSHConfigJump(config *credentials.SSHConfig, jump string) (Endpoint, credentials.Credentials, error) {
+	var opts []credentials.CredentialsOption
+	if i := strings.LastIndex(jump, "@"); i >= 0 {
+		opts = append(opts, credentials.WithUsername(jump[:i]))
+		jump = jump[i+1:]
+	}
+	alias, port := jump, ""
+	if host, p, err := net.SplitHostPort(jump); err == nil {
+		alias, port = host, p
+	}
+	endpoint, err := resolveSSHConfigEndpoint(config, alias, port)
+	if err != nil {
+		return Endpoint{}, nil, err
+	}
+	creds, err := config.Credentials(alias, opts...)
+	if err != nil {
+		return Endpoint{}, nil, err
+	}
+	return endpoint, creds, nil
+}
@@ -0,0 +1,75 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+func TestWithSSHConfig(t *testing.T) {
+	target, targetEndpoint := listenLocal(t)
+	go runExecServer(t, target)
+	jump, jumpEndpoint := listenLocal(t)
+	jumpDone := make(chan struct{})
+	go runForwardServer(t, jump, jumpDone)
+
+	config := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(config, []byte(fmt.Sprintf(`
+Host dev
+  HostName 127.0.0.1
+  Port %d
+  User admin
+  ProxyJump jumper@jump
+  UserKnownHostsFile /nonexistent/known_hosts
+
+Host jump
+  HostName 127.0.0.1
+  Port %d
+`, targetEndpoint.Port, jumpEndpoint.Port)), 0o600))
+
+	opt, err := WithSSHConfig(config, "dev")
+	require.NoError(t, err)
+	creds, err := credentials.FromSSHConfig(config, "dev", credentials.WithPassword("secret"))
+	require.NoError(t, err)
+	conn := NewStreamer("dev", creds, opt)
+	require.Equal(t, targetEndpoint, conn.endpoint)
+	tun, ok := conn.tunnel.(*SSHTunnel)
+	require.True(t, ok)
+	require.Equal(t, jumpEndpoint, tun.Server)
+	username, err := tun.credentials.GetUsername()
+	require.NoError(t, err)
+	require.Equal(t, "jumper", username)
+	require.Empty(t, conn.knownHostsFiles)
+
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	res, err := conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", string(res.Output()))
+	conn.Close()
+	require.False(t, tun.IsConnected())
+	<-jumpDone
+}
+
+func TestWithSSHConfigInvalidPort(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(config, []byte("Host dev\n  Port ssh\n"), 0o600))
+	_, err := WithSSHConfig(config, "dev")
+	require.Error(t, err)
+}
+
+func TestWithSSHConfigProxyCommand(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(config, []byte("Host bastion\n  ProxyCommand cloudflared access ssh --hostname %n\n"), 0o600))
+	opt, err := WithSSHConfig(config, "bastion")
+	require.NoError(t, err)
+	conn := NewStreamer("bastion", credentials.NewSimpleCredentials(), opt)
+	require.Equal(t, "cloudflared access ssh --hostname bastion", conn.proxyCommand)
+	require.Nil(t, conn.tunnel)
+}
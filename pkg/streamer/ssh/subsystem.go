@@ -0,0 +1,83 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/logging"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+// Subsystem is a channel of SSH subsystem like netconf or sftp.
+type Subsystem struct {
+	name      string
+	stdin     io.WriteCloser
+	stdout    io.Reader
+	session   *ssh.Session
+	onClose   func(*ssh.Session) error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenSubsystem requests subsystem in new session over established connection.
+// Interactive session of m is not affected. Closing of returned Subsystem closes only its session.
+func (m *Streamer) OpenSubsystem(ctx context.Context, name string) (*Subsystem, error) {
+	if m.getConn() == nil {
+		return nil, errNotConnected
+	}
+	m.logger.Debug("open subsystem", logging.String("name", name))
+	sessionTemplate, err := m.newSessionTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to init session template: %w", err)
+	}
+	session := sessionTemplate.session
+	cancel := streamer.CloserCTX(ctx, func() {
+		_ = session.Close()
+	})
+	err = session.RequestSubsystem(name)
+	cancel()
+	if err != nil {
+		_ = session.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("subsystem %s request error: %w", name, err)
+	}
+	// unread stderr stalls the channel
+	go func() {
+		_, _ = io.Copy(io.Discard, sessionTemplate.stderr)
+	}()
+	return &Subsystem{
+		name:    name,
+		stdin:   sessionTemplate.stdin,
+		stdout:  sessionTemplate.stdout,
+		session: session,
+		onClose: m.onSessionCloseCallbacks,
+	}, nil
+}
+
+func (m *Subsystem) Read(p []byte) (int, error) {
+	return m.stdout.Read(p)
+}
+
+func (m *Subsystem) Write(p []byte) (int, error) {
+	return m.stdin.Write(p)
+}
+
+// Close sends EOF and closes subsystem channel.
+func (m *Subsystem) Close() error {
+	m.closeOnce.Do(func() {
+		_ = m.stdin.Close()
+		err := m.session.Close()
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+		m.closeErr = errors.Join(err, m.onClose(m.session))
+	})
+	return m.closeErr
+}
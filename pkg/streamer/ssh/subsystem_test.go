@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// runSubsystemServer serves echo subsystem, closed channels are reported to closed.
+func runSubsystemServer(t *testing.T, listener net.Listener, closed chan<- struct{}) {
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(makeSigner(t))
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				var payload struct{ Name string }
+				ok := req.Type == "subsystem" && ssh.Unmarshal(req.Payload, &payload) == nil && payload.Name == "echo"
+				_ = req.Reply(ok, nil)
+				if ok {
+					go func() {
+						_, _ = io.Copy(channel, channel)
+						_ = channel.Close()
+						closed <- struct{}{}
+					}()
+				}
+			}
+		}()
+	}
+}
+
+func TestOpenSubsystem(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closed := make(chan struct{}, 1)
+	go runSubsystemServer(t, listener, closed)
+
+	addr := listener.Addr().(*net.TCPAddr)
+	conn := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithUsername("user")), WithPort(addr.Port))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	ctx := context.Background()
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+
+	_, err = conn.OpenSubsystem(ctx, "unknown")
+	require.Error(t, err)
+
+	sub, err := conn.OpenSubsystem(ctx, "echo")
+	require.NoError(t, err)
+	_, err = sub.Write([]byte("<hello/>"))
+	require.NoError(t, err)
+	buf := make([]byte, 8)
+	_, err = io.ReadFull(sub, buf)
+	require.NoError(t, err)
+	require.Equal(t, "<hello/>", string(buf))
+	require.NoError(t, sub.Close())
+	<-closed
+	require.NoError(t, sub.Close())
+}
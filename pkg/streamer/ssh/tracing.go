@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"context"
+	"net"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	attrHost         = attribute.Key("server.address")
+	attrPort         = attribute.Key("server.port")
+	attrNetwork      = attribute.Key("network.transport")
+	attrAuthMethod   = attribute.Key("gnetcli.auth.method")
+	attrBytesWritten = attribute.Key("gnetcli.bytes.written")
+	attrBytesRead    = attribute.Key("gnetcli.bytes.read")
+)
+
+// WithTracer sets tracer for connect and command spans. Spans are children of span in ctx passed to methods.
+func WithTracer(tracer oteltrace.Tracer) StreamerOption {
+	return func(h *Streamer) {
+		h.tracer = tracer
+	}
+}
+
+// SSHTunnelWithTracer sets tracer for tunnel connect and forward spans.
+func SSHTunnelWithTracer(tracer oteltrace.Tracer) SSHTunnelOption {
+	return func(h *SSHTunnel) {
+		h.tracer = tracer
+	}
+}
+
+// StartForwardContext is StartForward which takes parent span from ctx.
+func (m *SSHTunnel) StartForwardContext(ctx context.Context, network Network, remoteAddr string) (_ net.Conn, err error) {
+	_, span := startSpan(ctx, m.tracer, "ssh.tunnel.forward", addrAttrs(network, remoteAddr)...)
+	defer func() {
+		endSpan(span, err)
+	}()
+	return m.StartForward(network, remoteAddr)
+}
+
+type contextForwarder interface {
+	StartForwardContext(ctx context.Context, network Network, addr string) (net.Conn, error)
+}
+
+// startForward passes ctx to tunnel if it supports it.
+func startForward(ctx context.Context, tunnel Tunnel, network Network, addr string) (net.Conn, error) {
+	if t, ok := tunnel.(contextForwarder); ok {
+		return t.StartForwardContext(ctx, network, addr)
+	}
+	return tunnel.StartForward(network, addr)
+}
+
+func endpointAttrs(endpoint Endpoint) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attrHost.String(endpoint.Host),
+		attrPort.Int(endpoint.Port),
+		attrNetwork.String(string(endpoint.Network)),
+	}
+}
+
+func addrAttrs(network Network, addr string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []attribute.KeyValue{attrHost.String(addr), attrNetwork.String(string(network))}
+	}
+	return []attribute.KeyValue{attrHost.String(host), attrPort.String(port), attrNetwork.String(string(network))}
+}
+
+// startSpan starts span if tracer is set, otherwise returns ctx and noop span.
+func startSpan(ctx context.Context, tracer oteltrace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, oteltrace.Span) {
+	if tracer == nil {
+		return ctx, noop.Span{}
+	}
+	return tracer.Start(ctx, name, oteltrace.WithAttributes(attrs...))
+}
+
+func endSpan(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,94 @@
+package ssh
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/embedded"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+type recordedSpan struct {
+	noop.Span
+	name   string
+	parent oteltrace.Span
+	attrs  map[attribute.Key]attribute.Value
+	ended  bool
+}
+
+func (m *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		m.attrs[attr.Key] = attr.Value
+	}
+}
+
+func (m *recordedSpan) End(...oteltrace.SpanEndOption) {
+	m.ended = true
+}
+
+type recordingTracer struct {
+	embedded.Tracer
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (m *recordingTracer) Start(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	span := &recordedSpan{name: name, parent: oteltrace.SpanFromContext(ctx), attrs: map[attribute.Key]attribute.Value{}}
+	conf := oteltrace.NewSpanStartConfig(opts...)
+	span.SetAttributes(conf.Attributes()...)
+	m.spans = append(m.spans, span)
+	return oteltrace.ContextWithSpan(ctx, span), span
+}
+
+func TestTracer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go runExecServer(t, listener)
+
+	tracer := &recordingTracer{}
+	addr := listener.Addr().(*net.TCPAddr)
+	creds := credentials.NewSimpleCredentials(credentials.WithUsername("user"), credentials.WithPassword("secret"))
+	conn := NewStreamer("127.0.0.1", creds, WithPort(addr.Port), WithTracer(tracer))
+	conn.hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	root := &recordedSpan{name: "root"}
+	ctx := oteltrace.ContextWithSpan(context.Background(), root)
+	require.NoError(t, conn.Init(ctx))
+	defer conn.Close()
+	_, err = conn.Cmd(ctx, "show version")
+	require.NoError(t, err)
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+	require.Len(t, tracer.spans, 2)
+	connect, cmd := tracer.spans[0], tracer.spans[1]
+	require.Equal(t, "ssh.connect", connect.name)
+	require.Same(t, root, connect.parent)
+	require.True(t, connect.ended)
+	require.Equal(t, "127.0.0.1", connect.attrs[attrHost].AsString())
+	require.Equal(t, int64(addr.Port), connect.attrs[attrPort].AsInt64())
+	require.Equal(t, "tcp", connect.attrs[attrNetwork].AsString())
+	require.Equal(t, "password", connect.attrs[attrAuthMethod].AsString())
+	require.Equal(t, "ssh.cmd", cmd.name)
+	require.Same(t, root, cmd.parent)
+	require.True(t, cmd.ended)
+	require.Equal(t, int64(len("show version")), cmd.attrs[attrBytesWritten].AsInt64())
+	require.Equal(t, int64(len("ok\n")), cmd.attrs[attrBytesRead].AsInt64())
+}
+
+func TestNoTracer(t *testing.T) {
+	ctx, span := startSpan(context.Background(), nil, "test")
+	require.False(t, span.IsRecording())
+	require.Equal(t, context.Background(), ctx)
+	endSpan(span, context.Canceled)
+}
@@ -0,0 +1,91 @@
+package streamer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/annetutil/gnetcli/pkg/expr"
+)
+
+var anyDataExpr = expr.NewSimpleExpr().FromPattern(`(?s).+`)
+
+// streamConn is io.ReadWriteCloser on top of Connector. Data buffered by connector is read first.
+type streamConn struct {
+	conn    Connector
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pending []byte
+	mu      sync.Mutex // serializes reads
+	once    sync.Once
+}
+
+// NewStreamConn returns connection which reads connector output without expression matching and writes data as is.
+// Output goes through connector read path, so it is processed in the same way as for ReadTo:
+// escape sequences are stripped and echo is removed if connector is configured so, read data is traced.
+// Read blocks until data arrives, connector read timeout is not changed. Close closes connector.
+func NewStreamConn(conn Connector) io.ReadWriteCloser {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &streamConn{
+		conn:   conn,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (m *streamConn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.pending) == 0 {
+		if m.ctx.Err() != nil {
+			return 0, io.ErrClosedPipe
+		}
+		res, err := m.conn.ReadTo(m.ctx, anyDataExpr)
+		for isReadTimeout(err) && m.ctx.Err() == nil {
+			// no data in read timeout, wait more
+			res, err = m.conn.ReadTo(m.ctx, anyDataExpr)
+		}
+		if err != nil {
+			if m.ctx.Err() != nil {
+				return 0, io.ErrClosedPipe
+			}
+			var eofErr *EOFException
+			if errors.As(err, &eofErr) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		m.pending = append(m.pending, res.GetMatched()...)
+	}
+	n := copy(p, m.pending)
+	m.pending = m.pending[n:]
+	return n, nil
+}
+
+func (m *streamConn) Write(p []byte) (int, error) {
+	if m.ctx.Err() != nil {
+		return 0, io.ErrClosedPipe
+	}
+	err := m.conn.Write(p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (m *streamConn) Close() error {
+	m.once.Do(func() {
+		m.cancel()
+		m.conn.Close()
+	})
+	return nil
+}
+
+func isReadTimeout(err error) bool {
+	var timeoutErr *ReadTimeoutException
+	return errors.As(err, &timeoutErr)
+}
@@ -0,0 +1,44 @@
+package streamer
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamConn(t *testing.T) {
+	rec := NewRecorder(
+		RecorderWithGreeting([]byte("hello\n")),
+		RecorderWithResponse(`ping\n`, []byte("pong\n")),
+	)
+	rec.SetReadTimeout(time.Millisecond)
+	require.NoError(t, rec.Init(context.Background()))
+	raw := rec.StreamConn()
+	buf := make([]byte, 3)
+	n, err := raw.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "hel", string(buf[:n]))
+	n, err = raw.Write([]byte("ping\n"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	rest := make([]byte, 8)
+	_, err = io.ReadFull(raw, rest)
+	require.NoError(t, err)
+	require.Equal(t, "lo\npong\n", string(rest))
+	require.Equal(t, time.Millisecond, rec.SetReadTimeout(time.Millisecond))
+
+	// read waits for data till close
+	done := make(chan error)
+	go func() {
+		_, err := raw.Read(buf)
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, raw.Close())
+	require.ErrorIs(t, <-done, io.ErrClosedPipe)
+	_, err = raw.Write([]byte("ping\n"))
+	require.ErrorIs(t, err, io.ErrClosedPipe)
+}
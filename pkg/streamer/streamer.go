@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"time"
@@ -37,6 +38,32 @@ type Connector interface {
 	Download(paths []string, recurse bool) (map[string]File, error)
 	Upload(map[string]File) error
 	InitAgentForward() error
+	// StreamConn returns connection for custom protocols which reuses connection setup and buffered data.
+	// It reads output processed by connector, see NewStreamConn.
+	StreamConn() io.ReadWriteCloser
+}
+
+// Drainer is implemented by connectors which are able to read everything arriving during given period.
+type Drainer interface {
+	Drain(ctx context.Context, duration time.Duration) ([]byte, error)
+}
+
+// Reopener is implemented by connectors which are able to open connection again with the same parameters,
+// e.g. after device reload. Current connection is closed.
+type Reopener interface {
+	Reopen(ctx context.Context) error
+}
+
+// EchoStripper is implemented by connectors which may remove echo of written data from output.
+// EchoStripped returns true if echo is removed at the moment and must not be expected.
+type EchoStripper interface {
+	EchoStripped() bool
+}
+
+// SecretRedactor is implemented by connectors which write transcript. AddSecrets passes data which is written
+// during session, like enable secret, for redaction in transcript.
+type SecretRedactor interface {
+	AddSecrets(secrets ...[]byte)
 }
 
 type ReadRes interface {
@@ -136,16 +163,41 @@ func NewReadResImpl(before, after []byte, matchedGroups map[string][]byte, match
 	return ReadResImpl{before: before, after: after, matchedGroups: matchedGroups, matched: matched, patternNo: patternNo}
 }
 
+// Dialer makes connections, it is implemented by net.Dialer and proxy dialers like golang.org/x/net/proxy SOCKS5.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
 // TCPDialCtx net.Dial version with context arg
 func TCPDialCtx(ctx context.Context, network, addr string) (net.Conn, error) {
-	d := net.Dialer{}
-	conn, err := d.DialContext(ctx, network, addr)
+	return DialCtx(ctx, nil, network, addr)
+}
+
+// DialCtx dials addr using dialer, nil dialer means net.Dialer with default settings.
+func DialCtx(ctx context.Context, dialer Dialer, network, addr string) (net.Conn, error) {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	conn, err := dialer.DialContext(ctx, network, addr)
 	if err != nil {
 		return nil, err
 	}
 	return conn, nil
 }
 
+type idempotentKey struct{}
+
+// WithIdempotent marks ctx of Connector.Cmd call as idempotent, so connector may repeat the command after reconnect.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// IsIdempotent reports whether ctx was marked with WithIdempotent.
+func IsIdempotent(ctx context.Context) bool {
+	val, _ := ctx.Value(idempotentKey{}).(bool)
+	return val
+}
+
 // CloserCTX calls fn if ctx is cancelled. Returns cancel function.
 func CloserCTX(ctx context.Context, fn func()) context.CancelFunc {
 	innerCtx, cancel := context.WithCancel(context.Background())
@@ -273,7 +325,7 @@ func GenericReadX(ctx context.Context, inBuffer []byte, readCh chan []byte, read
 		case <-ctx.Done():
 			StopTimer(readIterTimeout)
 			StopTimer(maxDurationTimeout)
-			return nil, buffer, buffer[len(inBuffer):], multierr.Combine(ctx.Err(), ThrowReadTimeoutException(GetLastBytes(buffer, readSize)))
+			return nil, buffer, buffer[len(inBuffer):], multierr.Combine(ctx.Err(), throwContextReadTimeoutException(GetLastBytes(buffer, readSize)))
 		case readData, ok := <-readCh:
 			StopTimer(readIterTimeout)
 			if ok {
@@ -97,3 +97,28 @@ func TestGenericSplitBytes(t *testing.T) {
 	assert.Equal(t, []byte("1234"), a)
 	assert.Equal(t, []byte{}, b)
 }
+
+func TestGenericReadXIdleTimeout(t *testing.T) {
+	ch := make(chan []byte, 10)
+	go func() {
+		// data keeps arriving longer than readTimeout, but each gap is shorter
+		for i := 0; i < 5; i++ {
+			time.Sleep(30 * time.Millisecond)
+			ch <- []byte("x")
+		}
+		ch <- []byte("end")
+	}()
+	ex := expr.NewSimpleExpr().FromPattern(`end`)
+	res, _, _, err := GenericReadX(context.Background(), nil, ch, 100, 100*time.Millisecond, ex, 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("xxxxx"), res.ExprRes.GetBefore())
+
+	_, _, _, err = GenericReadX(context.Background(), nil, ch, 100, 50*time.Millisecond, ex, 0, 0)
+	assert.ErrorIs(t, err, ErrReadTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, _, _, err = GenericReadX(ctx, nil, ch, 100, time.Second, ex, 0, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrReadTimeout)
+}
@@ -0,0 +1,36 @@
+package telnet
+
+import "bytes"
+
+// WithTelnetBinaryMode requests TRANSMIT-BINARY option (RFC 856) in both directions,
+// so data is passed without NVT translation, e.g. CR NUL is not replaced with CR.
+func WithTelnetBinaryMode(enabled bool) StreamerOption {
+	return func(h *Streamer) {
+		h.binaryMode = enabled
+	}
+}
+
+// BinaryMode returns whether TRANSMIT-BINARY is enabled in both directions.
+func (m *Streamer) BinaryMode() bool {
+	return m.binary.Load()
+}
+
+// updateBinary is called on negotiation of TRANSMIT-BINARY option.
+func (m *Streamer) updateBinary() {
+	m.binary.Store(m.telnet.local[BBINARY] == optionEnabled && m.telnet.remote[BBINARY] == optionEnabled)
+}
+
+// escapeIAC doubles IAC in data, it is required in both NVT and binary mode.
+func escapeIAC(data []byte) []byte {
+	if bytes.IndexByte(data, BIAC) < 0 {
+		return data
+	}
+	res := make([]byte, 0, len(data)+1)
+	for _, b := range data {
+		res = append(res, b)
+		if b == BIAC {
+			res = append(res, b)
+		}
+	}
+	return res
+}
@@ -0,0 +1,67 @@
+package telnet
+
+// WithEchoStripping removes echo of written data from output while server performs ECHO option (RFC 857).
+// genericcli doesn't wait for command echo from such Streamer, see streamer.EchoStripper.
+func WithEchoStripping() StreamerOption {
+	return func(h *Streamer) {
+		h.stripEcho = true
+	}
+}
+
+// EchoStripped returns whether echo of written data is removed from output, it is true when
+// WithEchoStripping is set and server performs ECHO.
+func (m *Streamer) EchoStripped() bool {
+	return m.stripEcho && m.RemoteEcho()
+}
+
+// RemoteEcho returns whether server agreed to perform ECHO option.
+func (m *Streamer) RemoteEcho() bool {
+	m.echoMu.Lock()
+	defer m.echoMu.Unlock()
+	return m.remoteEcho
+}
+
+// addPendingEcho remembers written data to remove its echo from output.
+func (m *Streamer) addPendingEcho(data []byte) {
+	if !m.stripEcho {
+		return
+	}
+	m.echoMu.Lock()
+	defer m.echoMu.Unlock()
+	if m.remoteEcho {
+		m.pendingEcho = append(m.pendingEcho, data...)
+	}
+}
+
+// setRemoteEcho is called on negotiation of ECHO option.
+func (m *Streamer) setRemoteEcho(enabled bool) {
+	m.echoMu.Lock()
+	defer m.echoMu.Unlock()
+	m.remoteEcho = enabled
+	if !enabled {
+		m.pendingEcho = nil
+	}
+}
+
+// removeEcho drops echo of written data from the beginning of data. Server sends newline as CR LF,
+// so CR before expected LF is skipped. Stripping stops on first mismatch, e.g. on password which is not echoed.
+func (m *Streamer) removeEcho(data []byte) []byte {
+	if !m.stripEcho {
+		return data
+	}
+	m.echoMu.Lock()
+	defer m.echoMu.Unlock()
+	i := 0
+	for i < len(data) && len(m.pendingEcho) > 0 {
+		switch {
+		case data[i] == m.pendingEcho[0]:
+			m.pendingEcho = m.pendingEcho[1:]
+			i++
+		case data[i] == '\r' && m.pendingEcho[0] == '\n':
+			i++
+		default:
+			m.pendingEcho = nil
+		}
+	}
+	return data[i:]
+}
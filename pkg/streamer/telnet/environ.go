@@ -0,0 +1,135 @@
+package telnet
+
+import (
+	"maps"
+	"slices"
+)
+
+const (
+	NEWENVIRON  = "\x27"
+	BNEWENVIRON = 39
+)
+
+// NEW-ENVIRON subnegotiation codes (RFC 1572)
+const (
+	environIS   = 0
+	environSEND = 1
+
+	environVAR     = 0
+	environVALUE   = 1
+	environESC     = 2
+	environUSERVAR = 3
+)
+
+// wellKnownEnviron are variables sent as VAR, others are sent as USERVAR.
+var wellKnownEnviron = map[string]bool{
+	"USER":       true,
+	"JOB":        true,
+	"ACCT":       true,
+	"PRINTER":    true,
+	"SYSTEMTYPE": true,
+	"DISPLAY":    true,
+}
+
+type environRequest struct {
+	typ  byte
+	name string // empty means all variables of typ
+}
+
+// WithTelnetEnviron enables NEW-ENVIRON option (RFC 1572) and sets variables sent on server request.
+// Well-known variables like USER are sent as VAR, others as USERVAR. Only requested variables are sent,
+// requested but unknown ones are reported as undefined.
+func WithTelnetEnviron(env map[string]string) StreamerOption {
+	env = maps.Clone(env)
+	return WithTelnetOptionHandler(BNEWENVIRON, func(cmd byte, sub []byte) []byte {
+		if cmd != BSB || len(sub) == 0 || sub[0] != environSEND {
+			return nil
+		}
+		return environReply(env, parseEnvironSend(sub[1:]))
+	})
+}
+
+// parseEnvironSend parses list of variables requested by SEND.
+func parseEnvironSend(data []byte) []environRequest {
+	var res []environRequest
+	var name []byte
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch {
+		case b == environVAR || b == environUSERVAR:
+			if len(res) > 0 {
+				res[len(res)-1].name = string(name)
+			}
+			res = append(res, environRequest{typ: b})
+			name = name[:0]
+		case b == environESC && i+1 < len(data):
+			i++
+			name = append(name, data[i])
+		default:
+			name = append(name, b)
+		}
+	}
+	if len(res) > 0 {
+		res[len(res)-1].name = string(name)
+	}
+	return res
+}
+
+// environReply makes IS reply, empty requests means all variables.
+func environReply(env map[string]string, requests []environRequest) []byte {
+	if len(requests) == 0 {
+		requests = []environRequest{{typ: environVAR}, {typ: environUSERVAR}}
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	res := []byte{environIS}
+	sent := map[string]bool{}
+	for _, req := range requests {
+		if len(req.name) > 0 {
+			if !sent[req.name] {
+				sent[req.name] = true
+				value, ok := env[req.name]
+				res = appendEnvironVar(res, req.typ, req.name, value, ok)
+			}
+			continue
+		}
+		for _, name := range names {
+			if sent[name] || environType(name) != req.typ {
+				continue
+			}
+			sent[name] = true
+			res = appendEnvironVar(res, req.typ, name, env[name], true)
+		}
+	}
+	return res
+}
+
+func environType(name string) byte {
+	if wellKnownEnviron[name] {
+		return environVAR
+	}
+	return environUSERVAR
+}
+
+func appendEnvironVar(res []byte, typ byte, name, value string, defined bool) []byte {
+	res = append(res, typ)
+	res = appendEnvironEscaped(res, name)
+	if defined {
+		res = append(res, environVALUE)
+		res = appendEnvironEscaped(res, value)
+	}
+	return res
+}
+
+func appendEnvironEscaped(res []byte, data string) []byte {
+	for i := 0; i < len(data); i++ {
+		if data[i] <= environUSERVAR {
+			res = append(res, environESC)
+		}
+		res = append(res, data[i])
+	}
+	return res
+}
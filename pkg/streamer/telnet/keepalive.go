@@ -0,0 +1,130 @@
+package telnet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	NOP  = "\xf1"
+	BNOP = 241
+	AYT  = "\xf6"
+	BAYT = 246
+)
+
+// ErrKeepaliveTimeout is returned by ReadTo and Write after connection is closed
+// because peer sent nothing during keepalive timeout.
+var ErrKeepaliveTimeout = errors.New("telnet keepalive timeout")
+
+// WithTelnetKeepalive makes Streamer send IAC NOP every interval after Init. Keepalive is off by default.
+func WithTelnetKeepalive(interval time.Duration) StreamerOption {
+	return func(h *Streamer) {
+		h.keepaliveInterval = interval
+	}
+}
+
+// WithTelnetKeepaliveAYT makes keepalive send "Are You There" instead of NOP. Unlike NOP, AYT is answered by peer,
+// answers like "[Yes]" are removed from output.
+func WithTelnetKeepaliveAYT() StreamerOption {
+	return func(h *Streamer) {
+		h.keepaliveCmd = BAYT
+	}
+}
+
+// WithTelnetKeepaliveTimeout closes connection if nothing is read from peer during timeout,
+// ReadTo and Write return ErrKeepaliveTimeout after that. Zero timeout disables the check.
+// Timeout must be a multiple of keepalive interval and is checked only while AYT keepalive is on:
+// NOP is not answered, so idle peer sends nothing and silence doesn't mean that connection is dead.
+func WithTelnetKeepaliveTimeout(timeout time.Duration) StreamerOption {
+	return func(h *Streamer) {
+		h.keepaliveTimeout = timeout
+	}
+}
+
+// aytReplyExpr matches common answers to AYT.
+var aytReplyExpr = regexp.MustCompile(`(\r?\n)?\[[Yy]es\](\r?\n)?`)
+
+// markRead remembers time of the last data from peer.
+func (m *Streamer) markRead() {
+	m.lastRead.Store(time.Now().UnixNano())
+}
+
+// stripAYTReply removes answers to sent AYT from data.
+func (m *Streamer) stripAYTReply(data []byte) []byte {
+	for m.aytPending.Load() > 0 {
+		loc := aytReplyExpr.FindIndex(data)
+		if loc == nil {
+			break
+		}
+		data = append(data[:loc[0]:loc[0]], data[loc[1]:]...)
+		m.aytPending.Add(-1)
+	}
+	return data
+}
+
+func (m *Streamer) keepaliveErr() error {
+	m.keepaliveMu.Lock()
+	defer m.keepaliveMu.Unlock()
+	return m.deadErr
+}
+
+func (m *Streamer) startKeepalive() {
+	if m.keepaliveInterval <= 0 {
+		return
+	}
+	m.markRead()
+	deadCtx, dead := context.WithCancel(context.Background())
+	m.deadCtx = deadCtx
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	m.keepaliveStop = func() {
+		cancel()
+		<-done
+	}
+	go func() {
+		defer close(done)
+		m.keepaliveLoop(ctx, dead)
+	}()
+}
+
+func (m *Streamer) stopKeepalive() {
+	if m.keepaliveStop != nil {
+		m.keepaliveStop()
+		m.keepaliveStop = nil
+	}
+}
+
+func (m *Streamer) keepaliveLoop(ctx context.Context, dead context.CancelFunc) {
+	ticker := time.NewTicker(m.keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		silence := time.Since(time.Unix(0, m.lastRead.Load()))
+		if m.keepaliveTimeout > 0 && m.keepaliveCmd == BAYT && silence >= m.keepaliveTimeout {
+			err := fmt.Errorf("%w: nothing is read for %s", ErrKeepaliveTimeout, silence.Round(time.Millisecond))
+			m.logger.Debug("keepalive failed, closing connection", zap.Error(err))
+			m.keepaliveMu.Lock()
+			m.deadErr = err
+			m.keepaliveMu.Unlock()
+			dead()
+			_ = m.conn.Close()
+			return
+		}
+		if m.keepaliveCmd == BAYT {
+			m.aytPending.Add(1)
+		}
+		if _, err := m.conn.Write([]byte{BIAC, m.keepaliveCmd}); err != nil {
+			m.logger.Debug("keepalive write error", zap.Error(err))
+			return
+		}
+	}
+}
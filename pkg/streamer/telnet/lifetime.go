@@ -0,0 +1,59 @@
+package telnet
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+)
+
+// NewStreamerWithContext makes Streamer which connection is closed when ctx is done, e.g. at the end of request.
+// Commands in progress fail with error matching ctx error, Close is still needed to release ctx.
+func NewStreamerWithContext(ctx context.Context, host string, credentials credentials.Credentials, opts ...StreamerOption) *Streamer {
+	h := NewStreamer(host, credentials, opts...)
+	h.lifetime = ctx
+	return h
+}
+
+// watchLifetime closes conn when lifetime context is done, previous watch is stopped.
+func (m *Streamer) watchLifetime(conn net.Conn) {
+	m.stopLifetime()
+	if m.lifetime == nil {
+		return
+	}
+	m.lifetimeStop = context.AfterFunc(m.lifetime, func() {
+		m.logger.Debug("context is done, closing connection")
+		_ = conn.Close()
+	})
+}
+
+func (m *Streamer) stopLifetime() {
+	if m.lifetimeStop != nil {
+		m.lifetimeStop()
+		m.lifetimeStop = nil
+	}
+}
+
+// withLifetime returns ctx which is also canceled when lifetime context is done.
+func (m *Streamer) withLifetime(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.lifetime == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(m.lifetime, func() {
+		cancel(context.Cause(m.lifetime))
+	})
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// lifetimeErr replaces err of operation interrupted by lifetime context.
+func (m *Streamer) lifetimeErr(err error) error {
+	if err == nil || m.lifetime == nil || m.lifetime.Err() == nil {
+		return err
+	}
+	return fmt.Errorf("connection is closed: %w", context.Cause(m.lifetime))
+}
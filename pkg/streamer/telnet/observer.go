@@ -0,0 +1,19 @@
+package telnet
+
+import (
+	"github.com/annetutil/gnetcli/pkg/streamer"
+)
+
+var _ streamer.CommandObserver = (*Streamer)(nil)
+
+// WithObserver sets observer of connects and commands.
+func WithObserver(observer streamer.Observer) StreamerOption {
+	return func(h *Streamer) {
+		h.observer = observer
+	}
+}
+
+// ObserveCommand reports command executed by device over the streamer to observer.
+func (m *Streamer) ObserveCommand(command []byte) func(bytesWritten, bytesRead int, err error) {
+	return streamer.ObserveCommand(m.observer, m.host, command)
+}
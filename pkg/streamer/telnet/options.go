@@ -0,0 +1,286 @@
+package telnet
+
+import (
+	"encoding/binary"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+const (
+	NAWS  = "\x1f"
+	BNAWS = 31
+)
+
+type parserState int
+
+const (
+	stateData parserState = iota
+	stateIAC
+	stateNegotiate
+	stateSB
+	stateSBIAC
+)
+
+// optionState is a simplified RFC 1143 option state, it is enough to avoid negotiation loops.
+type optionState int
+
+const (
+	optionDisabled optionState = iota
+	optionRequested
+	optionEnabled
+)
+
+type windowSize struct {
+	cols uint16
+	rows uint16
+}
+
+// telnetState is a state of telnet protocol parser and options negotiation.
+type telnetState struct {
+	state  parserState
+	cr     bool // previous data byte is CR
+	cmd    byte
+	sbData []byte
+	local  map[byte]optionState // options performed by us
+	remote map[byte]optionState // options performed by server
+}
+
+func newTelnetState() *telnetState {
+	return &telnetState{
+		state:  stateData,
+		cmd:    0,
+		sbData: nil,
+		local:  map[byte]optionState{},
+		remote: map[byte]optionState{},
+	}
+}
+
+// OptionHandler handles telnet option registered by WithTelnetOptionHandler.
+// It is called with DO, DONT, WILL or WONT and nil sub for negotiation commands of server
+// and with SB and its payload without option byte for subnegotiation.
+// Returned data, if it is not nil, is sent as subnegotiation of the option, IAC is escaped by streamer.
+// Handler is called by reader of connection, so it must not block.
+type OptionHandler func(cmd byte, sub []byte) []byte
+
+// WithTelnetOptionHandler makes option supported in both directions and passes its commands to handler.
+// Options without handlers are refused unless streamer supports them itself.
+func WithTelnetOptionHandler(option byte, handler OptionHandler) StreamerOption {
+	return func(h *Streamer) {
+		h.optionHandlers[option] = handler
+	}
+}
+
+// WithWindowSize enables NAWS option (RFC 1073) and sets window size reported to server
+func WithWindowSize(cols, rows uint16) StreamerOption {
+	return func(h *Streamer) {
+		h.windowSize = &windowSize{cols: cols, rows: rows}
+	}
+}
+
+// startNegotiation sends options which we want to perform without server request.
+func (m *Streamer) startNegotiation() error {
+	if m.windowSize != nil {
+		m.telnet.local[BNAWS] = optionRequested
+		err := m.sendCommand(BWILL, BNAWS)
+		if err != nil {
+			return err
+		}
+	}
+	if m.binaryMode {
+		m.telnet.local[BBINARY] = optionRequested
+		err := m.sendCommand(BWILL, BBINARY)
+		if err != nil {
+			return err
+		}
+		m.telnet.remote[BBINARY] = optionRequested
+		return m.sendCommand(BDO, BBINARY)
+	}
+	return nil
+}
+
+// processTelnet handles telnet commands in data read from connection and returns payload.
+func (m *Streamer) processTelnet(data []byte) []byte {
+	res := make([]byte, 0, len(data))
+	st := m.telnet
+	for _, b := range data {
+		switch st.state {
+		case stateData:
+			if b == BIAC {
+				st.state = stateIAC
+			} else if b == 0 && st.cr && st.remote[BBINARY] != optionEnabled {
+				// NVT sends bare CR as CR NUL
+				st.cr = false
+			} else {
+				res = append(res, b)
+				st.cr = b == '\r'
+			}
+		case stateIAC:
+			switch b {
+			case BIAC: // escaped 255
+				res = append(res, b)
+				st.state = stateData
+			case BDO, BDONT, BWILL, BWONT:
+				st.cmd = b
+				st.state = stateNegotiate
+			case BSB:
+				st.sbData = st.sbData[:0]
+				st.state = stateSB
+			default:
+				m.logger.Debug("telnet command", zap.Uint8("cmd", b))
+				st.state = stateData
+			}
+		case stateNegotiate:
+			m.negotiate(st.cmd, b)
+			st.state = stateData
+		case stateSB:
+			if b == BIAC {
+				st.state = stateSBIAC
+			} else {
+				st.sbData = append(st.sbData, b)
+			}
+		case stateSBIAC:
+			switch b {
+			case BSE:
+				m.logger.Debug("telnet subnegotiation", zap.Binary("data", st.sbData))
+				if len(st.sbData) > 0 {
+					m.callOptionHandler(BSB, st.sbData[0], slices.Clone(st.sbData[1:]))
+				}
+				st.state = stateData
+			case BIAC:
+				st.sbData = append(st.sbData, b)
+				st.state = stateSB
+			default:
+				st.state = stateSB
+			}
+		}
+	}
+	return res
+}
+
+func (m *Streamer) negotiate(cmd, option byte) {
+	m.logger.Debug("telnet negotiate", zap.Uint8("cmd", cmd), zap.Uint8("option", option))
+	var err error
+	switch cmd {
+	case BWILL:
+		err = m.handleRequest(m.telnet.remote, option, m.remoteSupported(option), BDO, BDONT)
+		if option == BECHO {
+			m.setRemoteEcho(m.telnet.remote[BECHO] == optionEnabled)
+		}
+	case BWONT:
+		err = m.handleRefusal(m.telnet.remote, option, BDONT)
+		if option == BECHO {
+			m.setRemoteEcho(false)
+		}
+	case BDO:
+		err = m.handleRequest(m.telnet.local, option, m.localSupported(option), BWILL, BWONT)
+		if err == nil && option == BNAWS && m.telnet.local[BNAWS] == optionEnabled {
+			err = m.sendWindowSize()
+		}
+	case BDONT:
+		err = m.handleRefusal(m.telnet.local, option, BWONT)
+	}
+	if err != nil {
+		m.logger.Debug("telnet negotiate error", zap.Error(err))
+		return
+	}
+	if option == BBINARY {
+		m.updateBinary()
+	}
+	m.callOptionHandler(cmd, option, nil)
+}
+
+// callOptionHandler passes command to handler registered for option and sends its reply.
+func (m *Streamer) callOptionHandler(cmd, option byte, sub []byte) {
+	handler, ok := m.optionHandlers[option]
+	if !ok {
+		return
+	}
+	reply := handler(cmd, sub)
+	if reply == nil {
+		return
+	}
+	err := m.sendSubnegotiation(option, reply)
+	if err != nil {
+		m.logger.Debug("telnet subnegotiation error", zap.Error(err))
+	}
+}
+
+func (m *Streamer) handleRequest(states map[byte]optionState, option byte, supported bool, yes, no byte) error {
+	switch states[option] {
+	case optionEnabled:
+		return nil
+	case optionRequested: // acknowledge of our request
+		states[option] = optionEnabled
+		return nil
+	}
+	if !supported {
+		return m.sendCommand(no, option)
+	}
+	states[option] = optionEnabled
+	return m.sendCommand(yes, option)
+}
+
+func (m *Streamer) handleRefusal(states map[byte]optionState, option byte, no byte) error {
+	prev := states[option]
+	states[option] = optionDisabled
+	if prev == optionEnabled {
+		return m.sendCommand(no, option)
+	}
+	return nil
+}
+
+// remoteSupported returns whether server is allowed to perform option.
+func (m *Streamer) remoteSupported(option byte) bool {
+	if _, ok := m.optionHandlers[option]; ok {
+		return true
+	}
+	switch option {
+	case BECHO, BSGA:
+		return true
+	case BBINARY:
+		return m.binaryMode
+	}
+	return false
+}
+
+// localSupported returns whether we are able to perform option.
+func (m *Streamer) localSupported(option byte) bool {
+	if _, ok := m.optionHandlers[option]; ok {
+		return true
+	}
+	switch option {
+	case BSGA:
+		return true
+	case BNAWS:
+		return m.windowSize != nil
+	case BBINARY:
+		return m.binaryMode
+	}
+	return false
+}
+
+func (m *Streamer) sendWindowSize() error {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint16(payload[0:2], m.windowSize.cols)
+	binary.BigEndian.PutUint16(payload[2:4], m.windowSize.rows)
+	return m.sendSubnegotiation(BNAWS, payload)
+}
+
+func (m *Streamer) sendCommand(cmd, option byte) error {
+	_, err := m.conn.Write([]byte{BIAC, cmd, option})
+	return err
+}
+
+func (m *Streamer) sendSubnegotiation(option byte, payload []byte) error {
+	data := []byte{BIAC, BSB, option}
+	for _, b := range payload {
+		data = append(data, b)
+		if b == BIAC {
+			data = append(data, b)
+		}
+	}
+	data = append(data, BIAC, BSE)
+	_, err := m.conn.Write(data)
+	return err
+}
@@ -1,5 +1,5 @@
 /*
-Package telnet implements telnet transport at very basic level.
+Package telnet implements telnet transport with basic options negotiation.
 */
 package telnet
 
@@ -9,10 +9,12 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
-	"golang.org/x/sync/errgroup"
 
 	gcmd "github.com/annetutil/gnetcli/pkg/cmd"
 	"github.com/annetutil/gnetcli/pkg/credentials"
@@ -22,10 +24,16 @@ import (
 )
 
 var _ streamer.Connector = (*Streamer)(nil)
+var _ streamer.Drainer = (*Streamer)(nil)
+var _ streamer.OutputObserver = (*Streamer)(nil)
+var _ streamer.EchoStripper = (*Streamer)(nil)
+var _ streamer.Reopener = (*Streamer)(nil)
+var _ streamer.SecretRedactor = (*Streamer)(nil)
 
 const (
 	defaultReadSize    = 4096
 	defaultReadTimeout = 20 * time.Second
+	defaultDialTimeout = 15 * time.Second
 	defaultPort        = 23
 )
 
@@ -59,28 +67,94 @@ type Streamer struct {
 	credentials            credentials.Credentials
 	logger                 *zap.Logger
 	host                   string
+	port                   int
+	dialTimeout            time.Duration
+	readBufferSize         int
 	conn                   net.Conn
 	stdoutBuffer           chan []byte
 	stdoutBufferExtra      []byte
 	credentialsInterceptor func(credentials.Credentials) credentials.Credentials
 	trace                  trace.CB
 	readTimeout            time.Duration
+	outputHook             *streamer.OutputHook
+	escapeStripper         *streamer.EscapeStripper
+	telnet                 *telnetState
+	windowSize             *windowSize
+	credentialsProvider    credentials.Provider
+	transcript             *trace.TranscriptWriter
+	stripEcho              bool
+	remoteEcho             bool   // server performs ECHO
+	pendingEcho            []byte // written data which echo is not read yet
+	echoMu                 sync.Mutex
+	dialer                 streamer.Dialer // nil means direct connection
+	keepaliveInterval      time.Duration
+	keepaliveTimeout       time.Duration
+	keepaliveCmd           byte
+	keepaliveStop          func()
+	keepaliveMu            sync.Mutex
+	deadErr                error           // reason of closing connection by keepalive
+	deadCtx                context.Context // canceled when connection is closed by keepalive
+	lastRead               atomic.Int64    // unix nano time of the last read
+	aytPending             atomic.Int32    // keepalive AYT sent, answers are removed from output
+	observer               streamer.Observer
+	readerDone             chan struct{} // closed when reader of current connection is stopped
+	optionHandlers         map[byte]OptionHandler
+	binaryMode             bool            // TRANSMIT-BINARY is requested
+	binary                 atomic.Bool     // TRANSMIT-BINARY is enabled in both directions
+	lifetime               context.Context // connection is closed when it is done, nil means no limit
+	lifetimeStop           func() bool
 }
 
 func (m *Streamer) InitAgentForward() error {
 	return errors.New("agent forwarding is not supported")
 }
 
+// StreamConn returns connection for custom protocols, see streamer.NewStreamConn.
+func (m *Streamer) StreamConn() io.ReadWriteCloser {
+	return streamer.NewStreamConn(m)
+}
+
 func (m *Streamer) SetReadTimeout(duration time.Duration) time.Duration {
 	prev := m.readTimeout
 	m.readTimeout = duration
 	return prev
 }
 
+// SetOutputCallback sets callback for data read from connection, see streamer.OutputObserver.
+func (m *Streamer) SetOutputCallback(cb func([]byte)) func([]byte) {
+	return m.outputHook.Set(cb)
+}
+
 func (m *Streamer) SetTrace(cb trace.CB) {
 	m.trace = cb
 }
 
+func (m *Streamer) addTrace(op trace.Operation, data []byte) {
+	if m.trace != nil {
+		m.trace(op, data)
+	}
+	if m.transcript != nil {
+		m.transcript.Add(op, data)
+	}
+}
+
+// AddSecrets passes secrets written during session to transcript for redaction, see streamer.SecretRedactor.
+func (m *Streamer) AddSecrets(secrets ...[]byte) {
+	if m.transcript != nil {
+		m.transcript.AddSecrets(secrets...)
+	}
+}
+
+// addTranscriptSecrets passes passwords of creds to transcript for redaction.
+func (m *Streamer) addTranscriptSecrets(ctx context.Context, creds credentials.Credentials) {
+	if m.transcript == nil || !m.transcript.Redact() || creds == nil {
+		return
+	}
+	for _, password := range creds.GetPasswords(ctx) {
+		m.transcript.AddSecrets([]byte(password.Value()))
+	}
+}
+
 func (m *Streamer) Download(paths []string, recurse bool) (map[string]streamer.File, error) {
 	return nil, streamer.ErrNotSupported
 }
@@ -94,19 +168,56 @@ func (m *Streamer) SetCredentialsInterceptor(inter func(credentials.Credentials)
 	m.credentialsInterceptor = inter
 }
 
-func (m *Streamer) Init(ctx context.Context) error {
-	m.logger.Debug("open connection", zap.String("host", m.host))
-	conn, err := streamer.TCPDialCtx(ctx, "tcp", fmt.Sprintf("%s:%d", m.host, defaultPort))
+func (m *Streamer) Init(ctx context.Context) (err error) {
+	m.logger.Debug("open connection", zap.String("host", m.host), zap.Int("port", m.port))
+	observeDone := streamer.ObserveConnect(m.observer, m.host)
+	defer func() {
+		observeDone(err)
+	}()
+	if m.lifetime != nil && m.lifetime.Err() != nil {
+		return m.lifetimeErr(m.lifetime.Err())
+	}
+	ctx, cancel := m.withLifetime(ctx)
+	defer cancel()
+	creds := m.credentials
+	if m.credentialsProvider != nil {
+		provided, err := m.credentialsProvider.Get(ctx, m.host)
+		if err != nil {
+			return fmt.Errorf("credentials provider error: %w", err)
+		}
+		creds = provided
+	}
+	m.addTranscriptSecrets(ctx, creds)
+	dialCtx := ctx
+	if m.dialTimeout > 0 {
+		newCtx, cancel := context.WithTimeout(ctx, m.dialTimeout)
+		defer cancel()
+		dialCtx = newCtx
+	}
+	conn, err := streamer.DialCtx(dialCtx, m.dialer, "tcp", net.JoinHostPort(m.host, strconv.Itoa(m.port)))
 	if err != nil {
-		return err
+		return m.lifetimeErr(err)
 	}
 	m.conn = conn
-	eg, _ := errgroup.WithContext(ctx)
-	eg.Go(func() error { return m.stdoutReader(m.conn) })
+	err = m.startNegotiation()
+	if err != nil {
+		return err
+	}
+	readerDone := make(chan struct{})
+	m.readerDone = readerDone
+	go func(conn net.Conn) {
+		defer close(readerDone)
+		_ = m.stdoutReader(conn)
+	}(m.conn)
+	m.startKeepalive()
+	m.watchLifetime(conn)
 	return nil
 }
 
 func (m *Streamer) GetCredentials() credentials.Credentials {
+	if m.credentialsProvider != nil {
+		return credentials.NewProviderCredentials(m.credentialsProvider, m.host)
+	}
 	return m.credentials
 }
 
@@ -116,12 +227,25 @@ func NewStreamer(host string, credentials credentials.Credentials, opts ...Strea
 		credentials:            credentials,
 		logger:                 zap.NewNop(),
 		host:                   host,
+		port:                   defaultPort,
+		dialTimeout:            defaultDialTimeout,
+		readBufferSize:         defaultReadSize,
 		conn:                   nil,
 		stdoutBuffer:           stdoutBuffer,
 		stdoutBufferExtra:      nil,
 		credentialsInterceptor: nil,
 		trace:                  nil,
 		readTimeout:            defaultReadTimeout,
+		outputHook:             streamer.NewOutputHook(),
+		escapeStripper:         nil,
+		telnet:                 newTelnetState(),
+		windowSize:             nil,
+		keepaliveCmd:           BNOP,
+		observer:               streamer.NopObserver{},
+		optionHandlers:         map[byte]OptionHandler{},
+	}
+	for _, opt := range streamer.DefaultOptions[StreamerOption]() {
+		opt(h)
 	}
 	for _, opt := range opts {
 		opt(h)
@@ -134,12 +258,14 @@ func (m *Streamer) Cmd(context.Context, string) (gcmd.CmdRes, error) {
 }
 
 func (m *Streamer) Write(text []byte) error {
-	if m.trace != nil {
-		m.trace(trace.Write, text)
-	}
-	written, err := m.conn.Write(text)
+	m.addTrace(trace.Write, text)
+	m.addPendingEcho(text)
+	written, err := m.conn.Write(escapeIAC(text))
 	if err != nil {
-		return err
+		if deadErr := m.keepaliveErr(); deadErr != nil {
+			return deadErr
+		}
+		return m.lifetimeErr(err)
 	}
 	m.logger.Debug("write", zap.ByteString("text", text), zap.Int("written", written))
 	return nil
@@ -151,20 +277,45 @@ func (m *Streamer) Read(context.Context, int) ([]byte, error) {
 
 func (m *Streamer) ReadTo(ctx context.Context, expr expr.Expr) (streamer.ReadRes, error) {
 	m.logger.Debug("read to", zap.String("expr", expr.Repr()))
-	res, extra, read, err := streamer.GenericReadX(ctx, m.stdoutBufferExtra, m.stdoutBuffer, defaultReadSize, m.readTimeout, expr, 0, 0)
-	if m.trace != nil {
-		m.trace(trace.Read, read)
+	if m.deadCtx != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		stop := context.AfterFunc(m.deadCtx, cancel)
+		defer stop()
 	}
+	ctx, cancel := m.withLifetime(ctx)
+	defer cancel()
+	res, extra, read, err := streamer.GenericReadX(ctx, m.stdoutBufferExtra, m.stdoutBuffer, defaultReadSize, m.readTimeout, expr, 0, 0)
+	m.addTrace(trace.Read, read)
 	m.stdoutBufferExtra = extra
 	if err != nil {
-		return nil, err
+		if deadErr := m.keepaliveErr(); deadErr != nil {
+			return nil, deadErr
+		}
+		return nil, m.lifetimeErr(err)
 	}
 	if res.RetType == streamer.Timeout {
+		if deadErr := m.keepaliveErr(); deadErr != nil {
+			return nil, deadErr
+		}
 		return nil, streamer.ThrowReadTimeoutException(streamer.GetLastBytes(read, defaultReadSize))
 	}
 	return res.ExprRes, nil
 }
 
+// Drain reads everything that arrives during duration and returns it.
+func (m *Streamer) Drain(ctx context.Context, duration time.Duration) ([]byte, error) {
+	res, extra, read, err := streamer.GenericReadX(ctx, m.stdoutBufferExtra, m.stdoutBuffer, defaultReadSize, 0, nil, 0, duration)
+	m.addTrace(trace.Read, read)
+	m.stdoutBufferExtra = extra
+	if err != nil {
+		return nil, err
+	}
+	m.stdoutBufferExtra = nil
+	return res.BytesRes, nil
+}
+
 type StreamerOption func(*Streamer)
 
 func WithLogger(log *zap.Logger) StreamerOption {
@@ -179,13 +330,111 @@ func WithTrace(trace trace.CB) StreamerOption {
 	}
 }
 
+// WithPort sets port to connect to
+func WithPort(port int) StreamerOption {
+	return func(h *Streamer) {
+		h.port = port
+	}
+}
+
+// WithDialTimeout sets timeout for establishing TCP connection
+func WithDialTimeout(timeout time.Duration) StreamerOption {
+	return func(h *Streamer) {
+		h.dialTimeout = timeout
+	}
+}
+
+// WithReadTimeout sets timeout between sequential reads
+func WithReadTimeout(timeout time.Duration) StreamerOption {
+	return func(h *Streamer) {
+		h.readTimeout = timeout
+	}
+}
+
+// WithReadBufferSize sets size of buffer used for single read from connection
+func WithReadBufferSize(size int) StreamerOption {
+	return func(h *Streamer) {
+		h.readBufferSize = size
+	}
+}
+
+// WithEscapeStripping sets removal of escape sequences from output before expression matching
+func WithEscapeStripping(mode streamer.EscapeMode) StreamerOption {
+	return func(h *Streamer) {
+		h.escapeStripper = streamer.NewEscapeStripper(mode)
+	}
+}
+
+// WithTranscript writes data written to and read from connection to w, see trace.TranscriptWriter for format.
+// With trace.TranscriptWithRedaction passwords from credentials are redacted.
+func WithTranscript(w io.Writer, opts ...trace.TranscriptOption) StreamerOption {
+	return func(h *Streamer) {
+		h.transcript = trace.NewTranscriptWriter(w, opts...)
+	}
+}
+
+// WithCredentialsProvider makes Streamer fetch credentials from provider in Init,
+// credentials passed to NewStreamer are not used. Fetched credentials are not kept,
+// GetCredentials returns credentials which fetch them again for login, see credentials.ProviderCredentials.
+func WithCredentialsProvider(provider credentials.Provider) StreamerOption {
+	return func(h *Streamer) {
+		h.credentialsProvider = provider
+	}
+}
+
+// WithDialer sets dialer for connection to device, e.g. SOCKS5 dialer from golang.org/x/net/proxy
+func WithDialer(dialer streamer.Dialer) StreamerOption {
+	return func(h *Streamer) {
+		h.dialer = dialer
+	}
+}
+
+// WithLocalAddr binds connection to device to local address. It replaces dialer set by WithDialer.
+func WithLocalAddr(addr net.Addr) StreamerOption {
+	return func(h *Streamer) {
+		h.dialer = &net.Dialer{LocalAddr: addr}
+	}
+}
+
 func (m *Streamer) Close() {
+	m.stopKeepalive()
+	m.stopLifetime()
 	if m.conn != nil {
 		_ = m.conn.Close()
 	}
 }
 
-func (m Streamer) HasFeature(feature streamer.Const) bool {
+// Reopen closes connection and runs Init again, data left from closed connection is dropped.
+func (m *Streamer) Reopen(ctx context.Context) error {
+	m.Close()
+	if m.readerDone != nil {
+		// reader may be blocked on full buffer
+	L:
+		for {
+			select {
+			case <-m.stdoutBuffer:
+			case <-m.readerDone:
+				break L
+			}
+		}
+	}
+	for len(m.stdoutBuffer) > 0 {
+		<-m.stdoutBuffer
+	}
+	m.conn = nil
+	m.stdoutBufferExtra = nil
+	m.telnet = newTelnetState()
+	m.setRemoteEcho(false)
+	m.binary.Store(false)
+	m.keepaliveMu.Lock()
+	m.deadErr = nil
+	m.keepaliveMu.Unlock()
+	m.deadCtx = nil
+	m.aytPending.Store(0)
+	return m.Init(ctx)
+}
+
+func (m *Streamer) HasFeature(feature streamer.Const) bool {
 	if feature == streamer.AutoLogin {
 		return false
 	}
@@ -195,12 +444,20 @@ func (m Streamer) HasFeature(feature streamer.Const) bool {
 // It's impossible to set timeout for Read, so read here and put in channel
 func (m *Streamer) stdoutReader(reader io.Reader) error {
 	for {
-		readBuffer := make([]byte, defaultReadSize)
+		readBuffer := make([]byte, m.readBufferSize)
 		readLen, err := reader.Read(readBuffer)
 		if err != nil {
 			return err
 		}
+		m.markRead()
 		m.logger.Debug("read", zap.ByteString("data", readBuffer[:readLen]))
-		m.stdoutBuffer <- readBuffer[:readLen]
+		data := m.processTelnet(readBuffer[:readLen])
+		data = m.removeEcho(data)
+		data = m.stripAYTReply(data)
+		m.outputHook.Call(data)
+		data = m.escapeStripper.Process(data)
+		if len(data) > 0 {
+			m.stdoutBuffer <- data
+		}
 	}
 }
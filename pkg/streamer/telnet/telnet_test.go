@@ -0,0 +1,606 @@
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/proxy"
+
+	"github.com/annetutil/gnetcli/pkg/credentials"
+	"github.com/annetutil/gnetcli/pkg/expr"
+	"github.com/annetutil/gnetcli/pkg/streamer"
+	"github.com/annetutil/gnetcli/pkg/trace"
+)
+
+// runTelnetServer accepts single connection and serves it with handler.
+func runTelnetServer(t *testing.T, handler func(conn net.Conn)) int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestTelnetInterface(t *testing.T) {
+	val := Streamer{}
+
+	_, ok := interface{}(&val).(streamer.Connector)
+	assert.True(t, ok, "not a Connector interface")
+}
+
+func TestStreamerOptions(t *testing.T) {
+	h := NewStreamer("localhost", credentials.NewSimpleCredentials())
+	assert.Equal(t, defaultPort, h.port)
+	assert.Equal(t, defaultDialTimeout, h.dialTimeout)
+	assert.Equal(t, defaultReadSize, h.readBufferSize)
+
+	h = NewStreamer("localhost", credentials.NewSimpleCredentials(),
+		WithPort(2323),
+		WithDialTimeout(time.Second),
+		WithReadTimeout(2*time.Second),
+		WithReadBufferSize(128),
+	)
+	assert.Equal(t, 2323, h.port)
+	assert.Equal(t, time.Second, h.dialTimeout)
+	assert.Equal(t, 2*time.Second, h.readTimeout)
+	assert.Equal(t, 128, h.readBufferSize)
+}
+
+func TestDefaults(t *testing.T) {
+	t.Cleanup(func() { streamer.SetDefaults() })
+	streamer.SetDefaults(WithPort(2323), WithReadTimeout(time.Second), "not an option")
+
+	h := NewStreamer("localhost", credentials.NewSimpleCredentials())
+	assert.Equal(t, 2323, h.port)
+	assert.Equal(t, time.Second, h.readTimeout)
+
+	h = NewStreamer("localhost", credentials.NewSimpleCredentials(), WithReadTimeout(2*time.Second))
+	assert.Equal(t, 2323, h.port)
+	assert.Equal(t, 2*time.Second, h.readTimeout)
+
+	streamer.SetDefaults()
+	h = NewStreamer("localhost", credentials.NewSimpleCredentials())
+	assert.Equal(t, defaultPort, h.port)
+}
+
+func TestOutputCallback(t *testing.T) {
+	chunks := []string{"line1\r\n", "line2\r\n", "<device>"}
+	port := runTelnetServer(t, func(conn net.Conn) {
+		for _, chunk := range chunks {
+			_, _ = conn.Write([]byte(chunk))
+			time.Sleep(50 * time.Millisecond)
+		}
+		time.Sleep(time.Second)
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port))
+	var mu sync.Mutex
+	var got []string
+	h.SetOutputCallback(func(data []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, string(data))
+	})
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, chunks, got)
+}
+
+func TestEscapeStripping(t *testing.T) {
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("\x1b[1;32mup\x1b"))
+		time.Sleep(50 * time.Millisecond)
+		_, _ = conn.Write([]byte("[0m\r\n<device>"))
+		time.Sleep(time.Second)
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithEscapeStripping(streamer.EscapeCSIOnly))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.Equal(t, "up\r\n", string(res.GetBefore()))
+}
+
+func TestNAWS(t *testing.T) {
+	received := make(chan []byte, 1)
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte{BIAC, BDO, BNAWS, BIAC, BWILL, BECHO})
+		_, _ = conn.Write([]byte("<device>"))
+		var res []byte
+		buf := make([]byte, 100)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			n, err := conn.Read(buf)
+			res = append(res, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		received <- res
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithWindowSize(80, 24))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.Empty(t, res.GetBefore())
+	assert.Equal(t, []byte{
+		BIAC, BWILL, BNAWS, // our request
+		BIAC, BSB, BNAWS, 0, 80, 0, 24, BIAC, BSE, // window size after server agreed
+		BIAC, BDO, BECHO,
+	}, <-received)
+}
+
+func TestOptionHandler(t *testing.T) {
+	const environ = 36
+	received := make(chan []byte, 1)
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte{BIAC, BDO, environ, BIAC, BSB, environ, 1, BIAC, BSE, BIAC, BWILL, 200})
+		_, _ = conn.Write([]byte("<device>"))
+		var res []byte
+		buf := make([]byte, 100)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			n, err := conn.Read(buf)
+			res = append(res, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		received <- res
+	})
+	var calls [][]byte
+	handler := func(cmd byte, sub []byte) []byte {
+		calls = append(calls, append([]byte{cmd}, sub...))
+		if cmd == BSB {
+			return []byte{0, 'A', BIAC}
+		}
+		return nil
+	}
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithTelnetOptionHandler(environ, handler))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{{BDO}, {BSB, 1}}, calls)
+	assert.Equal(t, []byte{
+		BIAC, BWILL, environ,
+		BIAC, BSB, environ, 0, 'A', BIAC, BIAC, BIAC, BSE,
+		BIAC, BDONT, 200, // not registered
+	}, <-received)
+}
+
+func TestEnviron(t *testing.T) {
+	received := make(chan []byte, 1)
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte{BIAC, BDO, BNEWENVIRON})
+		_, _ = conn.Write(append(append([]byte{BIAC, BSB, BNEWENVIRON, environSEND, environVAR}, "USER"...),
+			append(append([]byte{environUSERVAR}, "MISSING"...), BIAC, BSE)...))
+		_, _ = conn.Write([]byte{BIAC, BSB, BNEWENVIRON, environSEND, BIAC, BSE})
+		_, _ = conn.Write([]byte("<device>"))
+		var res []byte
+		buf := make([]byte, 100)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			n, err := conn.Read(buf)
+			res = append(res, buf[:n]...)
+			if err != nil {
+				break
+			}
+		}
+		received <- res
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port),
+		WithTelnetEnviron(map[string]string{"USER": "alice", "ROUTE": "r\x01"}))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+
+	expected := []byte{BIAC, BWILL, BNEWENVIRON}
+	// requested variables only
+	expected = append(expected, BIAC, BSB, BNEWENVIRON, environIS, environVAR)
+	expected = append(expected, "USER"...)
+	expected = append(expected, environVALUE)
+	expected = append(expected, "alice"...)
+	expected = append(expected, environUSERVAR)
+	expected = append(expected, "MISSING"...)
+	expected = append(expected, BIAC, BSE)
+	// all variables
+	expected = append(expected, BIAC, BSB, BNEWENVIRON, environIS, environVAR)
+	expected = append(expected, "USER"...)
+	expected = append(expected, environVALUE)
+	expected = append(expected, "alice"...)
+	expected = append(expected, environUSERVAR)
+	expected = append(expected, "ROUTE"...)
+	expected = append(expected, environVALUE, 'r', environESC, 1, BIAC, BSE)
+	assert.Equal(t, expected, <-received)
+}
+
+func TestBinaryMode(t *testing.T) {
+	payload := []byte{0x80, 0xc3, 0xa9, BIAC, 0xfe, '\r', 0, 0x7f}
+	negotiation := make(chan []byte, 1)
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte{BIAC, BDO, BBINARY, BIAC, BWILL, BBINARY})
+		_, _ = conn.Write([]byte("ready>"))
+		var res []byte
+		buf := make([]byte, 100)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		for !bytes.HasSuffix(res, []byte("\n")) {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			res = append(res, buf[:n]...)
+		}
+		negotiation <- res[:6]
+		// send wire data back as is, IAC is already doubled
+		_, _ = conn.Write(res[6:])
+		_, _ = conn.Write([]byte("<device>"))
+		time.Sleep(time.Second)
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithTelnetBinaryMode(true))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`ready>$`))
+	require.NoError(t, err)
+	require.True(t, h.BinaryMode())
+	require.NoError(t, h.Write(append(slices.Clone(payload), '\n')))
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{BIAC, BWILL, BBINARY, BIAC, BDO, BBINARY}, <-negotiation)
+	assert.Equal(t, append(payload, '\n'), res.GetBefore())
+}
+
+func TestNVTCarriageReturn(t *testing.T) {
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("a\r\x00b\r\n<device>"))
+		time.Sleep(time.Second)
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.False(t, h.BinaryMode())
+	assert.Equal(t, "a\rb\r\n", string(res.GetBefore()))
+}
+
+func TestNewStreamerWithContext(t *testing.T) {
+	closed := make(chan struct{})
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("<device>"))
+		_, _ = io.Copy(io.Discard, conn)
+		close(closed)
+	})
+	lifetime, cancel := context.WithCancel(context.Background())
+	h := NewStreamerWithContext(lifetime, "127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithReadTimeout(10*time.Second))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`never`))
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), 5*time.Second)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection is not closed")
+	}
+
+	h = NewStreamerWithContext(lifetime, "127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port))
+	require.ErrorIs(t, h.Init(ctx), context.Canceled)
+}
+
+func TestTranscript(t *testing.T) {
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("Password:"))
+		buf := make([]byte, 100)
+		_, _ = conn.Read(buf)
+		_, _ = conn.Write([]byte("\r\n<device>"))
+		time.Sleep(time.Second)
+	})
+	transcript := bytes.Buffer{}
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(credentials.WithPassword("secret")), WithPort(port),
+		WithTranscript(&transcript, trace.TranscriptWithRedaction()))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`Password:`))
+	require.NoError(t, err)
+	require.NoError(t, h.Write([]byte("secret\n")))
+	_, err = h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+
+	items, err := trace.ReadTranscript(&transcript)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.Equal(t, trace.Write, items[1].GetOperation())
+	assert.Equal(t, "<redacted>\n", string(items[1].GetData()))
+	assert.Equal(t, "\r\n<device>", string(items[2].GetData()))
+}
+
+func TestEcho(t *testing.T) {
+	cases := []struct {
+		name       string
+		serverEcho bool
+	}{
+		{name: "echo on", serverEcho: true},
+		{name: "echo off", serverEcho: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan []byte, 1)
+			port := runTelnetServer(t, func(conn net.Conn) {
+				if tc.serverEcho {
+					_, _ = conn.Write([]byte{BIAC, BWILL, BECHO})
+				} else {
+					_, _ = conn.Write([]byte{BIAC, BWONT, BECHO})
+				}
+				_, _ = conn.Write([]byte("<device>"))
+				var res []byte
+				buf := make([]byte, 100)
+				for !bytes.HasSuffix(res, []byte("\n")) {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					res = append(res, buf[:n]...)
+				}
+				received <- res
+				if tc.serverEcho {
+					_, _ = conn.Write([]byte("show version\r\n"))
+				}
+				_, _ = conn.Write([]byte("version 1\r\n<device>"))
+				time.Sleep(time.Second)
+			})
+			h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithEchoStripping())
+			ctx := context.Background()
+			require.NoError(t, h.Init(ctx))
+			defer h.Close()
+			prompt := expr.NewSimpleExpr().FromPattern(`<device>$`)
+			_, err := h.ReadTo(ctx, prompt)
+			require.NoError(t, err)
+			assert.Equal(t, tc.serverEcho, h.RemoteEcho())
+			assert.Equal(t, tc.serverEcho, h.EchoStripped())
+
+			require.NoError(t, h.Write([]byte("show version\n")))
+			res, err := h.ReadTo(ctx, prompt)
+			require.NoError(t, err)
+			assert.Equal(t, "version 1\r\n", string(res.GetBefore()))
+			if tc.serverEcho {
+				assert.Equal(t, []byte{BIAC, BDO, BECHO}, (<-received)[:3])
+			} else {
+				assert.Equal(t, "show version\n", string(<-received))
+			}
+		})
+	}
+}
+
+// runSOCKS5Server serves single SOCKS5 CONNECT request without authentication and reports requested address.
+func runSOCKS5Server(t *testing.T, requested chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 262)
+		// greeting: VER NMETHODS METHODS
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{5, 0})
+		// request: VER CMD RSV ATYP DST.ADDR DST.PORT, only IPv4 is expected
+		if _, err := io.ReadFull(conn, buf[:10]); err != nil {
+			return
+		}
+		addr := net.JoinHostPort(net.IP(buf[4:8]).String(), strconv.Itoa(int(binary.BigEndian.Uint16(buf[8:10]))))
+		requested <- addr
+		target, err := net.Dial("tcp", addr)
+		if err != nil {
+			_, _ = conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer target.Close()
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		go func() { _, _ = io.Copy(target, conn) }()
+		_, _ = io.Copy(conn, target)
+	}()
+	return listener.Addr().String()
+}
+
+func TestDialer(t *testing.T) {
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("<device>"))
+		time.Sleep(time.Second)
+	})
+	requested := make(chan string, 1)
+	dialer, err := proxy.SOCKS5("tcp", runSOCKS5Server(t, requested), nil, proxy.Direct)
+	require.NoError(t, err)
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithDialer(dialer.(proxy.ContextDialer)))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err = h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	assert.Equal(t, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), <-requested)
+}
+
+func TestKeepalive(t *testing.T) {
+	cases := []struct {
+		name string
+		opts []StreamerOption
+		cmd  byte
+		dead bool
+	}{
+		// NOP is not answered, so silent peer is alive
+		{name: "nop", opts: nil, cmd: BNOP, dead: false},
+		{name: "ayt", opts: []StreamerOption{WithTelnetKeepaliveAYT()}, cmd: BAYT, dead: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			received := make(chan struct{})
+			port := runTelnetServer(t, func(conn net.Conn) {
+				_, _ = conn.Write([]byte("<device>"))
+				var res []byte
+				buf := make([]byte, 100)
+				for !bytes.Contains(res, []byte{BIAC, tc.cmd, BIAC, tc.cmd}) {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					res = append(res, buf[:n]...)
+				}
+				close(received)
+				// stay silent till keepalive timeout
+				_, _ = io.Copy(io.Discard, conn)
+			})
+			opts := append([]StreamerOption{WithPort(port), WithReadTimeout(5 * time.Second),
+				WithTelnetKeepalive(20 * time.Millisecond), WithTelnetKeepaliveTimeout(200 * time.Millisecond)}, tc.opts...)
+			h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), opts...)
+			ctx := context.Background()
+			require.NoError(t, h.Init(ctx))
+			defer h.Close()
+			_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+			require.NoError(t, err)
+			select {
+			case <-received:
+			case <-time.After(5 * time.Second):
+				t.Fatal("keepalive is not sent")
+			}
+
+			started := time.Now()
+			readCtx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			_, err = h.ReadTo(readCtx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+			if tc.dead {
+				require.ErrorIs(t, err, ErrKeepaliveTimeout)
+				require.Less(t, time.Since(started), 2*time.Second)
+			} else {
+				require.NotErrorIs(t, err, ErrKeepaliveTimeout)
+				require.NoError(t, h.keepaliveErr())
+			}
+		})
+	}
+}
+
+func TestKeepaliveAYTReply(t *testing.T) {
+	port := runTelnetServer(t, func(conn net.Conn) {
+		_, _ = conn.Write([]byte("<device>"))
+		buf := make([]byte, 100)
+		for answered := 0; answered < 5; {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i < bytes.Count(buf[:n], []byte{BIAC, BAYT}); i++ {
+				_, _ = conn.Write([]byte("\r\n[Yes]\r\n"))
+				answered++
+			}
+		}
+		_, _ = conn.Write([]byte("out\r\n<device>"))
+		_, _ = io.Copy(io.Discard, conn)
+	})
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithReadTimeout(5*time.Second),
+		WithTelnetKeepalive(20*time.Millisecond), WithTelnetKeepaliveTimeout(100*time.Millisecond), WithTelnetKeepaliveAYT())
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`<device>$`))
+	require.NoError(t, err)
+	require.Equal(t, "out\r\n", string(res.GetBefore()))
+}
+
+func TestLocalAddr(t *testing.T) {
+	bound, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("127.0.0.2 is not available: %v", err)
+	}
+	_ = bound.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	remoteIP := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(remoteIP)
+			return
+		}
+		remoteIP <- conn.RemoteAddr().(*net.TCPAddr).IP.String()
+		_ = conn.Close()
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(port), WithLocalAddr(&net.TCPAddr{IP: net.ParseIP("127.0.0.2")}))
+	_ = h.Init(context.Background())
+	defer h.Close()
+	require.Equal(t, "127.0.0.2", <-remoteIP)
+}
+
+func TestReopen(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for _, greeting := range []string{"first>", "second>"} {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("banner\r\n" + greeting))
+			defer conn.Close()
+		}
+	}()
+	h := NewStreamer("127.0.0.1", credentials.NewSimpleCredentials(), WithPort(listener.Addr().(*net.TCPAddr).Port))
+	ctx := context.Background()
+	require.NoError(t, h.Init(ctx))
+	defer h.Close()
+	_, err = h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`banner`))
+	require.NoError(t, err)
+	require.NoError(t, h.Reopen(ctx))
+	// rest of the first connection output is dropped
+	res, err := h.ReadTo(ctx, expr.NewSimpleExpr().FromPattern(`\w+>$`))
+	require.NoError(t, err)
+	require.Equal(t, "second>", string(res.GetMatched()))
+}
@@ -0,0 +1,55 @@
+package terminal
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// CollapseReturns renders lines where bare \r moves cursor to line start and the following text
+// overwrites previous one, as terminal displays it. \r before \n is kept as a part of newline,
+// as well as \r at the end of data. Lines with escape sequences are left for Parse.
+func CollapseReturns(data []byte) []byte {
+	if bytes.IndexByte(data, RETURN) == -1 {
+		return data
+	}
+	res := make([]byte, 0, len(data))
+	for len(data) > 0 {
+		var line []byte
+		nl := bytes.IndexByte(data, NEWLINE)
+		if nl == -1 {
+			line, data = data, nil
+		} else {
+			line, data = data[:nl], data[nl+1:]
+		}
+		content := bytes.TrimRight(line, "\r")
+		res = append(res, collapseLine(content)...)
+		res = append(res, line[len(content):]...)
+		if nl != -1 {
+			res = append(res, NEWLINE)
+		}
+	}
+	return res
+}
+
+func collapseLine(line []byte) []byte {
+	if bytes.IndexByte(line, RETURN) == -1 || bytes.IndexByte(line, ESCAPE) != -1 {
+		return line
+	}
+	var screen []rune
+	col := 0
+	for len(line) > 0 {
+		r, size := utf8.DecodeRune(line)
+		line = line[size:]
+		if r == RETURN {
+			col = 0
+			continue
+		}
+		if col < len(screen) {
+			screen[col] = r
+		} else {
+			screen = append(screen, r)
+		}
+		col++
+	}
+	return []byte(string(screen))
+}
@@ -119,3 +119,21 @@ func checkError(t *testing.T, s string) {
 func cback(n int) string {
 	return fmt.Sprintf("\x1b[%dD", n)
 }
+
+func TestCollapseReturns(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{in: "copy 10%\rcopy 50%\rcopy 100%\r\ndone\r\n", want: "copy 100%\r\ndone\r\n"},
+		{in: "100%\r 99%\n", want: " 99%\n"},
+		{in: "long progress\rshort\n", want: "shortprogress\n"},
+		{in: "foo\r\r\nbar\r\n", want: "foo\r\r\nbar\r\n"},
+		{in: "ok\r\nприв\rПР\n", want: "ok\r\nПРив\n"},
+		{in: "line\r\n1%\r2%\r", want: "line\r\n2%\r"},
+		{in: "no returns\n", want: "no returns\n"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, string(CollapseReturns([]byte(tc.in))), tc.in)
+	}
+}
@@ -0,0 +1,173 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	transcriptWrite = ">"
+	transcriptRead  = "<"
+)
+
+var redacted = []byte("<redacted>")
+
+// TranscriptWriter writes session transcript as plain text, one operation per line:
+// time in RFC3339 format, direction (">" for write, "<" for read) and quoted data.
+type TranscriptWriter struct {
+	w       io.Writer
+	json    bool
+	redact  bool
+	secrets [][]byte
+	mu      sync.Mutex
+	err     error
+}
+
+type TranscriptOption func(*TranscriptWriter)
+
+// TranscriptWithJSON makes TranscriptWriter write JSON objects with time, dir and data fields instead of plain text.
+// Data is written as string, so invalid UTF-8 sequences are replaced.
+func TranscriptWithJSON() TranscriptOption {
+	return func(h *TranscriptWriter) {
+		h.json = true
+	}
+}
+
+// TranscriptWithRedaction replaces secrets added with AddSecrets in transcript.
+// Secret is found only if it is written in one chunk.
+func TranscriptWithRedaction() TranscriptOption {
+	return func(h *TranscriptWriter) {
+		h.redact = true
+	}
+}
+
+func NewTranscriptWriter(w io.Writer, opts ...TranscriptOption) *TranscriptWriter {
+	res := &TranscriptWriter{w: w}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// AddSecrets adds data which is redacted if redaction is enabled.
+func (m *TranscriptWriter) AddSecrets(secrets ...[]byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, secret := range secrets {
+		if len(secret) > 0 {
+			m.secrets = append(m.secrets, secret)
+		}
+	}
+}
+
+// Redact reports whether secrets are redacted.
+func (m *TranscriptWriter) Redact() bool {
+	return m.redact
+}
+
+type transcriptJSONItem struct {
+	Time string `json:"time"`
+	Dir  string `json:"dir"`
+	Data string `json:"data"`
+}
+
+// Add writes operation to transcript. It has CB signature, so it can be passed to Connector.SetTrace.
+func (m *TranscriptWriter) Add(op Operation, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	direction := transcriptRead
+	if op == Write {
+		direction = transcriptWrite
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return
+	}
+	if m.redact {
+		for _, secret := range m.secrets {
+			data = bytes.ReplaceAll(data, secret, redacted)
+		}
+	}
+	ts := time.Now().Format(time.RFC3339Nano)
+	if m.json {
+		var line []byte
+		line, m.err = json.Marshal(transcriptJSONItem{Time: ts, Dir: direction, Data: string(data)})
+		if m.err == nil {
+			_, m.err = m.w.Write(append(line, '\n'))
+		}
+		return
+	}
+	_, m.err = fmt.Fprintf(m.w, "%s %s %q\n", ts, direction, data)
+}
+
+// Err returns first write error.
+func (m *TranscriptWriter) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// ReadTranscript parses transcript written by TranscriptWriter in any format.
+func ReadTranscript(r io.Reader) ([]Item, error) {
+	var res []Item
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		item, err := parseTranscriptLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		res = append(res, item)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func parseTranscriptLine(line string) (traceItem, error) {
+	if strings.HasPrefix(line, "{") {
+		var item transcriptJSONItem
+		if err := json.Unmarshal([]byte(line), &item); err != nil {
+			return traceItem{}, err
+		}
+		line = fmt.Sprintf("%s %s %q", item.Time, item.Dir, item.Data)
+	}
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 {
+		return traceItem{}, fmt.Errorf("wrong format %q", line)
+	}
+	ts, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return traceItem{}, err
+	}
+	var op Operation
+	switch parts[1] {
+	case transcriptWrite:
+		op = Write
+	case transcriptRead:
+		op = Read
+	default:
+		return traceItem{}, fmt.Errorf("unknown direction %q", parts[1])
+	}
+	data, err := strconv.Unquote(parts[2])
+	if err != nil {
+		return traceItem{}, fmt.Errorf("data unquote error: %w", err)
+	}
+	return traceItem{operation: op, time: ts, data: []byte(data)}, nil
+}
@@ -0,0 +1,40 @@
+package trace
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	buf := bytes.Buffer{}
+	tw := NewTranscriptWriter(&buf)
+	tw.Add(Read, []byte("<device>"))
+	tw.Add(Write, []byte("show ver\n"))
+	tw.Add(Read, []byte("show ver\r\nVersion 1 \"quoted\"\r\n<device>"))
+	require.NoError(t, tw.Err())
+
+	items, err := ReadTranscript(&buf)
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	require.Equal(t, Write, items[1].GetOperation())
+	require.Equal(t, "show ver\n", string(items[1].GetData()))
+	require.Equal(t, "show ver\r\nVersion 1 \"quoted\"\r\n<device>", string(items[2].GetData()))
+}
+
+func TestTranscriptJSONRedaction(t *testing.T) {
+	buf := bytes.Buffer{}
+	tw := NewTranscriptWriter(&buf, TranscriptWithJSON(), TranscriptWithRedaction())
+	tw.AddSecrets([]byte("secret"))
+	tw.Add(Read, []byte("Password:"))
+	tw.Add(Write, []byte("secret\n"))
+	require.NoError(t, tw.Err())
+	require.NotContains(t, buf.String(), "secret")
+
+	items, err := ReadTranscript(&buf)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, Write, items[1].GetOperation())
+	require.Equal(t, "<redacted>\n", string(items[1].GetData()))
+}